@@ -2,11 +2,13 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -16,17 +18,23 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 
 	"github.com/KeremKalyoncu/MedYan/internal/cache"
 	"github.com/KeremKalyoncu/MedYan/internal/cleanup"
+	"github.com/KeremKalyoncu/MedYan/internal/credentials"
 	"github.com/KeremKalyoncu/MedYan/internal/dedup"
 	"github.com/KeremKalyoncu/MedYan/internal/extractor"
 	"github.com/KeremKalyoncu/MedYan/internal/handlers"
+	"github.com/KeremKalyoncu/MedYan/internal/ippool"
 	"github.com/KeremKalyoncu/MedYan/internal/metrics"
 	"github.com/KeremKalyoncu/MedYan/internal/middleware"
 	"github.com/KeremKalyoncu/MedYan/internal/pool"
 	"github.com/KeremKalyoncu/MedYan/internal/queue"
 	"github.com/KeremKalyoncu/MedYan/internal/types"
+	"github.com/KeremKalyoncu/MedYan/internal/webhooks"
+	"github.com/KeremKalyoncu/MedYan/pkg/logbuffer"
+	"github.com/KeremKalyoncu/MedYan/pkg/storage"
 )
 
 func main() {
@@ -37,11 +45,40 @@ func main() {
 	}
 	defer zapLogger.Sync()
 
+	// Wire a logger into the shared buffer pools so a persistently low hit
+	// rate (pool undersized for its workload) gets logged, not just
+	// silently tracked in /metrics' pool_stats
+	pool.SetLogger(zapLogger)
+
+	// Ring buffer for /debug/logs: tees every log record into a fixed-size,
+	// file-backed circular buffer so recent history can be tailed or
+	// downloaded without an external log aggregator.
+	var ringBuffer *logbuffer.Buffer
+	ringBuffer, err = logbuffer.Open(logbuffer.Config{
+		Dir:        getEnv("LOG_RING_DIR", "logs/ring"),
+		TotalBytes: 64 * 1024 * 1024,
+	})
+	if err != nil {
+		zapLogger.Warn("Failed to open log ring buffer, /debug/logs will be unavailable", zap.Error(err))
+		ringBuffer = nil
+	} else {
+		defer ringBuffer.Close()
+		ringEncoder := zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig())
+		zapLogger = zapLogger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return zapcore.NewTee(core, logbuffer.NewCore(ringBuffer, ringEncoder, zapcore.DebugLevel))
+		}))
+	}
+
 	// Initialize queue client
 	redisAddr := getEnv("REDIS_ADDR", "localhost:6379")
 	queueClient := queue.NewClient(redisAddr, zapLogger)
 	defer queueClient.Close()
 
+	// Drains queueClient's FairQueue in weighted-fair order and submits to
+	// Asynq - without this, jobs enqueued here would sit in the fair queue
+	// forever (see queue.FairDispatcher).
+	queue.NewFairDispatcher(queueClient, zapLogger).Start(context.Background())
+
 	// Initialize distributed cache for performance optimization
 	distCache, err := cache.NewDistributedCache(redisAddr, zapLogger)
 	if err != nil {
@@ -57,6 +94,25 @@ func main() {
 	defer workerPool.Shutdown()
 	zapLogger.Info("Worker pool initialized", zap.Int("workers", 10), zap.Int("queue_size", 100))
 
+	// Adaptive concurrency: size workerPool off real inflight/queue-depth
+	// signals instead of a fixed 10, via the gradient/AIMD controller in
+	// queue.DynamicConcurrency. The actual extraction work this sizes for
+	// runs in the separate cmd/worker process, not on workerPool itself, so
+	// RecordLatency's samples arrive via ConsumeLatency over the
+	// queue:job_latency pub/sub channel ExtractionHandler.HandleExtraction
+	// publishes to, rather than an in-process RecordLatency call here.
+	dynamicConcurrency := queue.NewDynamicConcurrency(2, 10, workerPoolLoadSampler{workerPool, queueClient}, zapLogger)
+	dynamicConcurrency.Start()
+	defer dynamicConcurrency.Stop()
+	if err := queueClient.ConsumeLatency(context.Background(), dynamicConcurrency); err != nil {
+		zapLogger.Warn("Failed to subscribe to job latency samples, gradient will stay neutral", zap.Error(err))
+	}
+	go func() {
+		for n := range dynamicConcurrency.Updates() {
+			workerPool.Resize(n)
+		}
+	}()
+
 	// Initialize request deduplication (prevents duplicate URL processing)
 	deduplicator := dedup.NewSingleflight()
 	defer deduplicator.Close()
@@ -72,10 +128,171 @@ func main() {
 	detectionHandler := handlers.NewDetectionHandler(ytdlp, zapLogger)
 	zapLogger.Info("Smart platform detection enabled")
 
+	// Egress pool: rotates yt-dlp through YTDLP_SOURCE_IPS/YTDLP_PROXIES and
+	// cools one down per-platform once it looks throttled or bot-blocked.
+	// Empty config (the default) makes this a no-op passthrough.
+	egressPool := ippool.New(ippool.Config{
+		SourceIPs: splitEnvList(getEnv("YTDLP_SOURCE_IPS", "")),
+		ProxyURLs: splitEnvList(getEnv("YTDLP_PROXIES", "")),
+		Cooldown:  10 * time.Minute,
+	}, zapLogger)
+	detectionHandler.SetIPPool(egressPool)
+
+	// Native YouTube extractor: a yt-dlp-independent fallback for YouTube
+	// URLs, used both to back GET /formats and as DetectURL's fallback when
+	// yt-dlp's circuit breaker trips or it keeps hitting rate limits.
+	nativeYouTube := extractor.NewNativeYouTube(zapLogger)
+	detectionHandler.SetNativeYouTube(nativeYouTube)
+	detectionHandler.SetFallback(extractor.NewFallbackExtractor(ytdlp, nativeYouTube, zapLogger))
+
 	// Initialize history handler for site-specific download history
 	historyHandler := handlers.NewHistoryHandler(queueClient, zapLogger)
 	zapLogger.Info("Site-specific download history enabled")
 
+	// Initialize the signed-download storage backend (optional: nil leaves
+	// /proxy/d/:token disabled and falls back to direct redirects)
+	tokenSigningKey := getEnv("DOWNLOAD_TOKEN_SIGNING_KEY", "")
+	s3Endpoint := getEnv("S3_ENDPOINT", "")
+	if s3Endpoint == "http://localhost:9000" || s3Endpoint == "localhost:9000" {
+		s3Endpoint = ""
+	}
+	storageBackend := getEnv("STORAGE_BACKEND", "")
+	if storageBackend == "" {
+		if s3Endpoint != "" && s3Endpoint != "disabled" {
+			storageBackend = "s3"
+		} else {
+			storageBackend = "local"
+		}
+	}
+
+	// CACHE_DIR enables an on-disk read cache for ranged S3 downloads (resumable
+	// downloads, mobile players re-fetching the same hot byte ranges).
+	cacheMaxBytes := int64(0)
+	if v := getEnv("CACHE_MAX_BYTES", ""); v != "" {
+		if parsed, err := strconv.ParseInt(v, 10, 64); err == nil {
+			cacheMaxBytes = parsed
+		} else {
+			zapLogger.Warn("Invalid CACHE_MAX_BYTES, using default", zap.String("value", v))
+		}
+	}
+
+	var fileStore storage.SignedFileStore
+	switch storageBackend {
+	case "s3":
+		s3Store, err := storage.NewS3Storage(context.Background(), storage.Config{
+			Region:               getEnv("S3_REGION", "us-east-1"),
+			Bucket:               getEnv("S3_BUCKET", "media-extraction-output"),
+			Endpoint:             s3Endpoint,
+			PresignedURLExpiry:   24 * time.Hour,
+			StreamThresholdBytes: 500 * 1024 * 1024,
+			TokenSigningKey:      tokenSigningKey,
+			CacheDir:             getEnv("CACHE_DIR", ""),
+			CacheMaxBytes:        cacheMaxBytes,
+			Logger:               zapLogger,
+		})
+		if err != nil {
+			zapLogger.Warn("Failed to initialize S3 storage for signed downloads", zap.Error(err))
+		} else {
+			fileStore = s3Store
+		}
+	case "local":
+		localStore, err := storage.NewLocalStorage(getEnv("LOCAL_STORAGE_DIR", "/app/downloads"), tokenSigningKey, zapLogger)
+		if err != nil {
+			zapLogger.Warn("Failed to initialize local storage for signed downloads", zap.Error(err))
+		} else {
+			fileStore = localStore
+		}
+	case "tiered":
+		localStore, err := storage.NewLocalStorage(getEnv("LOCAL_STORAGE_DIR", "/app/downloads"), tokenSigningKey, zapLogger)
+		if err != nil {
+			zapLogger.Warn("Failed to initialize local cache tier for signed downloads", zap.Error(err))
+			break
+		}
+		s3Store, err := storage.NewS3Storage(context.Background(), storage.Config{
+			Region:               getEnv("S3_REGION", "us-east-1"),
+			Bucket:               getEnv("S3_BUCKET", "media-extraction-output"),
+			Endpoint:             s3Endpoint,
+			PresignedURLExpiry:   24 * time.Hour,
+			StreamThresholdBytes: 500 * 1024 * 1024,
+			TokenSigningKey:      tokenSigningKey,
+			CacheDir:             getEnv("CACHE_DIR", ""),
+			CacheMaxBytes:        cacheMaxBytes,
+			Logger:               zapLogger,
+		})
+		if err != nil {
+			zapLogger.Warn("Failed to initialize S3 origin for tiered storage", zap.Error(err))
+			break
+		}
+		var tieredCacheMaxBytes int64 = 5 * 1024 * 1024 * 1024
+		if v := getEnv("TIERED_CACHE_MAX_BYTES", ""); v != "" {
+			if parsed, err := strconv.ParseInt(v, 10, 64); err == nil {
+				tieredCacheMaxBytes = parsed
+			} else {
+				zapLogger.Warn("Invalid TIERED_CACHE_MAX_BYTES, using default", zap.String("value", v))
+			}
+		}
+		fileStore = storage.NewTieredStorage(localStore, s3Store, zapLogger, storage.TieredConfig{
+			MaxCacheBytes: tieredCacheMaxBytes,
+		})
+	default:
+		zapLogger.Warn("Invalid STORAGE_BACKEND, signed downloads disabled", zap.String("storage_backend", storageBackend))
+	}
+	downloadHandler := handlers.NewDownloadHandler(fileStore, queueClient, zapLogger)
+
+	// Credential store: holds per-platform cookie jars used by the
+	// Instagram/YouTube extraction fallbacks to recover from rate-limit and
+	// login-required walls. Defaults to on-disk storage; CREDENTIAL_STORE_BACKEND=redis
+	// shares one encrypted pool across every API/worker instance instead.
+	var credentialStore credentials.Store
+	credentialBackend := getEnv("CREDENTIAL_STORE_BACKEND", "file")
+	switch credentialBackend {
+	case "redis":
+		encryptionSecret := getEnv("CREDENTIAL_ENCRYPTION_KEY", "")
+		if encryptionSecret == "" {
+			zapLogger.Warn("CREDENTIAL_ENCRYPTION_KEY not set, credential store disabled")
+			break
+		}
+		keyHash := sha256.Sum256([]byte(encryptionSecret))
+		redisStore, err := credentials.NewRedisStore(queueClient.GetRedis(), keyHash[:])
+		if err != nil {
+			zapLogger.Warn("Failed to initialize Redis credential store", zap.Error(err))
+			break
+		}
+		credentialStore = redisStore
+	case "file":
+		fileCredStore, err := credentials.NewFileStore(getEnv("CREDENTIAL_STORE_DIR", "/app/credentials"))
+		if err != nil {
+			zapLogger.Warn("Failed to initialize file credential store", zap.Error(err))
+			break
+		}
+		credentialStore = fileCredStore
+	default:
+		zapLogger.Warn("Invalid CREDENTIAL_STORE_BACKEND, credential store disabled", zap.String("credential_store_backend", credentialBackend))
+	}
+	detectionHandler.SetCredentialStore(credentialStore)
+
+	// Credential rotation: evicts cookie jars nobody has refreshed in
+	// CREDENTIAL_MAX_AGE, so a jar that went stale (see
+	// PlatformExtractor.extractInstagram) doesn't sit around forever
+	// reporting cookies_expired instead of prompting a re-upload.
+	credentialMaxAge := getEnvDuration("CREDENTIAL_MAX_AGE", 0)
+	if credentialStore != nil && credentialMaxAge > 0 {
+		credentialRotator := credentials.NewRotator(credentialStore, credentials.RotatorConfig{
+			MaxAge:   credentialMaxAge,
+			Interval: getEnvDuration("CREDENTIAL_ROTATION_INTERVAL", time.Hour),
+		}, zapLogger)
+		credentialRotator.Start(context.Background())
+		defer credentialRotator.Stop()
+	}
+
+	// Webhook dispatcher: durable, HMAC-signed delivery of job lifecycle
+	// events with retries that survive restarts (see internal/webhooks)
+	webhookDispatcher := webhooks.NewDispatcher(queueClient.GetRedis(), zapLogger)
+	webhookDispatcher.Start(context.Background())
+	defer webhookDispatcher.Stop()
+	queueClient.SetWebhookDispatcher(webhookDispatcher)
+	webhookHandler := handlers.NewWebhookHandler(webhookDispatcher, zapLogger)
+
 	// Start temp file cleanup service (prevents disk space issues)
 	tempDir := getEnv("TEMP_DIR", os.TempDir())
 	cleanupService := cleanup.NewTempFileCleanup(
@@ -84,6 +301,16 @@ func main() {
 		30*time.Minute, // Check every 30 minutes
 		zapLogger,
 	)
+	cleanupService.SetActiveJobLister(queueClient)
+	diskLowWatermark := uint64(1 * 1024 * 1024 * 1024) // 1GB free triggers an emergency sweep
+	if v := getEnv("CLEANUP_DISK_LOW_WATERMARK_BYTES", ""); v != "" {
+		if parsed, err := strconv.ParseUint(v, 10, 64); err == nil {
+			diskLowWatermark = parsed
+		} else {
+			zapLogger.Warn("Invalid CLEANUP_DISK_LOW_WATERMARK_BYTES, using default", zap.String("value", v))
+		}
+	}
+	cleanupService.SetDiskWatermarks(diskLowWatermark, 0)
 	cleanupService.Start(context.Background())
 	defer cleanupService.Stop()
 	zapLogger.Info("Temp file cleanup service started",
@@ -91,6 +318,36 @@ func main() {
 		zap.Duration("max_age", 1*time.Hour),
 	)
 
+	// Health handler: real yt-dlp/FFmpeg version probes, a Redis PING,
+	// free-space on the temp/output directory, and Asynq's critical-queue
+	// depth.
+	diskMinFreeBytes := int64(500 * 1024 * 1024)
+	if v := getEnv("HEALTH_DISK_MIN_FREE_BYTES", ""); v != "" {
+		if parsed, err := strconv.ParseInt(v, 10, 64); err == nil {
+			diskMinFreeBytes = parsed
+		} else {
+			zapLogger.Warn("Invalid HEALTH_DISK_MIN_FREE_BYTES, using default", zap.String("value", v))
+		}
+	}
+	criticalQueueMaxSize := 500
+	if v := getEnv("HEALTH_CRITICAL_QUEUE_MAX_SIZE", ""); v != "" {
+		if parsed, err := strconv.Atoi(v); err == nil {
+			criticalQueueMaxSize = parsed
+		} else {
+			zapLogger.Warn("Invalid HEALTH_CRITICAL_QUEUE_MAX_SIZE, using default", zap.String("value", v))
+		}
+	}
+	healthHandler := handlers.NewHealthHandler(queueClient, distCache, handlers.HealthHandlerConfig{
+		YtdlpPath:            ytdlpBinary,
+		FFmpegPath:           getEnv("FFMPEG_PATH", "ffmpeg"),
+		DiskPath:             tempDir,
+		DiskMinFreeBytes:     uint64(diskMinFreeBytes),
+		RedisAddr:            redisAddr,
+		CriticalQueueName:    "critical",
+		CriticalQueueMaxSize: criticalQueueMaxSize,
+	}, zapLogger)
+	defer healthHandler.Close()
+
 	// API key is mandatory for security in production
 	apiKey := getEnv("API_KEY", "")
 	if apiKey == "" {
@@ -129,6 +386,14 @@ func main() {
 		Format: "[${time}] ${status} - ${latency} ${method} ${path}\n",
 	}))
 
+	// Structured access log: one zap entry per request with method, path,
+	// status, latency, and byte counts. Body capture stays off by default
+	// (enable via ACCESS_LOG_BODIES) since most routes here pass through
+	// media job payloads, not anything worth buffering twice.
+	accessLogConfig := middleware.DefaultAccessLogConfig()
+	accessLogConfig.LogBodies = getEnvBool("ACCESS_LOG_BODIES", false)
+	app.Use(middleware.AccessLogMiddleware(zapLogger, accessLogConfig))
+
 	// CORS security: Only allow trusted origins (from environment)
 	app.Use(cors.New(cors.Config{
 		AllowOrigins:     getEnv("CORS_ORIGINS", "http://localhost:3000"),
@@ -137,9 +402,15 @@ func main() {
 		AllowCredentials: true,
 	}))
 
-	// Rate limiting on proxy endpoints (100 req/min per IP)
-	rateLimiter := middleware.NewRateLimiter(100, time.Minute)
-	defer rateLimiter.Close()
+	// Rate limiting: a Redis-backed token bucket shared across every API
+	// replica, so the limit doesn't get multiplied per-process. Anonymous
+	// proxy traffic and authenticated /api/v1 traffic get independent
+	// tiers; /proxy/jobs gets its own tighter policy since job IDs are
+	// enumerable.
+	rateLimiter := middleware.NewRateLimiter(queueClient.GetRedis(), zapLogger)
+	anonymousRatePolicy := middleware.Policy{Requests: 100, Window: time.Minute}
+	jobStatusRatePolicy := middleware.Policy{Requests: 30, Window: time.Minute}
+	apiKeyRatePolicy := middleware.Policy{Requests: 300, Window: time.Minute}
 
 	// Metrics middleware
 	metricsInstance := metrics.GetMetrics()
@@ -148,13 +419,12 @@ func main() {
 		return c.Next()
 	})
 
-	// Health check - basic (for load balancer)
-	app.Get("/health", func(c *fiber.Ctx) error {
-		return c.JSON(fiber.Map{
-			"status": "healthy",
-			"time":   time.Now().Unix(),
-		})
-	})
+	// Health checks - basic for the load balancer, detailed/ready/live for
+	// operators and k8s probes
+	app.Get("/health", healthHandler.BasicHealth)
+	app.Get("/health/detailed", healthHandler.DetailedHealth)
+	app.Get("/health/ready", healthHandler.Readiness)
+	app.Get("/health/live", healthHandler.Liveness)
 
 	// Metrics endpoint (public, read-only)
 	// Cache for 30 seconds to reduce computational load
@@ -181,25 +451,66 @@ func main() {
 		// Add deduplication stats
 		snapshot["deduplication"] = deduplicator.Stats()
 
+		// Add read cache stats if the signed-download backend is S3 with
+		// ranged-read caching enabled
+		if s3Store, ok := fileStore.(*storage.S3Storage); ok {
+			if readCacheStats := s3Store.CacheStats(); readCacheStats != nil {
+				snapshot["read_cache"] = readCacheStats
+			}
+		}
+
+		// Add local cache tier stats when running the tiered storage backend
+		if tieredStore, ok := fileStore.(*storage.TieredStorage); ok {
+			snapshot["tiered_cache"] = tieredStore.CacheStats()
+		}
+
+		// Add log ring buffer stats
+		if ringBuffer != nil {
+			snapshot["log_buffer"] = ringBuffer.Stats()
+		}
+
+		// Add sync.Pool utilization (gets/puts/news/hit-rate per pool), so
+		// operators can tell a buffer pool is undersized from this endpoint
+		// instead of only from adaptive-sizing log warnings
+		snapshot["pool_stats"] = pool.GetStats()
+
 		return c.JSON(snapshot)
 	})
 
+	// Prometheus/OpenMetrics exposition of the same counters, plus the
+	// distribution histograms the JSON snapshot above only summarizes as
+	// p50/p95/p99 (ytdlp_exec_seconds{platform}, ffmpeg_exec_seconds{operation}).
+	app.Get("/metrics/prometheus", func(c *fiber.Ctx) error {
+		c.Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		return c.SendString(metricsInstance.RenderPrometheus())
+	})
+
 	// Performance profiling endpoints (for debugging)
 	if getEnv("ENABLE_PPROF", "false") == "true" {
 		handlers.RegisterPprofRoutes(app)
 		zapLogger.Info("pprof profiling endpoints enabled at /debug/pprof")
 	}
 
+	// Log ring buffer endpoints: tail recent history (optionally long-poll
+	// with follow=1) or download the whole buffer as a .zst file
+	if ringBuffer != nil {
+		handlers.RegisterLogBufferRoutes(app, ringBuffer)
+	}
+
 	// Public proxy routes (for GitHub Pages frontend - no API key required)
 	proxy := app.Group("/proxy")
 
 	// Apply rate limiting to proxy routes
-	proxy.Use(rateLimiter.Middleware())
+	proxy.Use(rateLimiter.Middleware(anonymousRatePolicy))
 
 	// Smart URL detection endpoint - Analyzes URL and returns platform info + available options
 	// Frontend calls this first to show appropriate UI controls
 	proxy.Post("/detect", detectionHandler.DetectURL)
 
+	// Lists every format kkdai/youtube/v2 reports for a YouTube URL,
+	// without invoking yt-dlp - useful when yt-dlp is down/blocked.
+	proxy.Get("/formats", detectionHandler.ListFormats)
+
 	// Proxy extract endpoint - Frontend uses this without exposing API key
 	proxy.Post("/extract", func(c *fiber.Ctx) error {
 		var req types.ExtractionRequest
@@ -216,18 +527,19 @@ func main() {
 			})
 		}
 
-		// Quick duration check using metadata cache (3-8s → 50ms)
-		// This prevents unnecessary yt-dlp calls for every request
+		// Quick duration check using metadata cache (3-8s → 50ms).
+		// GetOrLoad checks L1 then L2 before falling back to yt-dlp, and
+		// coalesces concurrent lookups for the same URL onto one yt-dlp
+		// call instead of every one of them running it.
 		var duration int
 		if distCache != nil {
-			ctx := c.Context()
-			if cachedMeta, err := distCache.GetMetadata(ctx, req.URL); err == nil && cachedMeta != nil {
-				duration = cachedMeta.Duration
+			meta, err := distCache.GetOrLoad(c.Context(), req.URL, func(loadCtx context.Context) (*cache.URLMetadata, error) {
+				return fetchURLMetadata(loadCtx, ytdlp, req.URL)
+			})
+			if err == nil && meta != nil {
+				duration = meta.Duration
 			}
-		}
-
-		// Only fetch metadata if not cached
-		if duration == 0 {
+		} else {
 			durationCtx, durationCancel := context.WithTimeout(c.Context(), 15*time.Second)
 			metadata, _ := ytdlp.ExtractMetadata(durationCtx, req.URL)
 			durationCancel()
@@ -245,10 +557,22 @@ func main() {
 			})
 		}
 
-		// Use deduplication to coalesce identical URL requests
-		// Create a unique key based on URL and format settings
-		dedupKey := fmt.Sprintf("%s:%s:%v:%s:%s",
-			req.URL, req.Format, req.ExtractAudio, req.AudioFormat, req.Quality)
+		// Before coalescing in-flight requests, check whether an identical
+		// request already completed recently - no need to even enqueue.
+		if cachedJob, ok, err := queueClient.LookupDedupedResult(c.Context(), req); err == nil && ok {
+			metricsInstance.RecordDedupHit(dedup.PlatformFromURL(req.URL))
+			return c.Status(202).JSON(fiber.Map{
+				"job_id":       cachedJob.ID,
+				"status":       "completed",
+				"message":      "Extraction job queued successfully",
+				"deduplicated": true,
+			})
+		}
+
+		// Use deduplication to coalesce identical in-flight URL requests.
+		// dedup.Key canonicalizes the URL and covers every content-affecting
+		// field, matching the key LookupDedupedResult checks above.
+		dedupKey := dedup.Key(req)
 
 		result := deduplicator.DoContext(c.Context(), dedupKey, func() (interface{}, error) {
 			// This function only runs once per unique request
@@ -279,7 +603,7 @@ func main() {
 	// Proxy job status endpoint - Check job progress without API key
 	// Rate limited to prevent job ID enumeration attacks
 	// Cache completed jobs for 5 minutes to reduce load
-	proxy.Get("/jobs/:id", rateLimiter.Middleware(), middleware.ConditionalCacheMiddleware(
+	proxy.Get("/jobs/:id", rateLimiter.Middleware(jobStatusRatePolicy), middleware.ConditionalCacheMiddleware(
 		func(c *fiber.Ctx) bool {
 			// Only cache completed jobs
 			jobID := c.Params("id")
@@ -328,10 +652,30 @@ func main() {
 			})
 		}
 
-		// Redirect to S3 presigned URL or local download
+		if job.Result.ChecksumSHA256 != "" {
+			c.Set("X-Checksum-SHA256", job.Result.ChecksumSHA256)
+		}
+
+		// Mint a short-lived, single-use download token instead of handing
+		// out the long-lived presigned URL / static download path directly.
+		if fileStore != nil && job.Result.Key != "" {
+			token, err := fileStore.IssueDownloadToken(job.Result.Key, 5*time.Minute, c.IP())
+			if err != nil {
+				zapLogger.Error("Failed to issue download token", zap.Error(err))
+				return c.Status(500).JSON(fiber.Map{
+					"error": "Failed to prepare download",
+				})
+			}
+			return c.Redirect("/proxy/d/"+token, 302)
+		}
+
+		// Signed downloads unavailable - redirect straight to the served file
 		return c.Redirect(job.Result.DownloadURL, 302)
 	})
 
+	// Proxy download redemption endpoint - serves the object a signed token grants access to, once
+	proxy.Get("/d/:token", downloadHandler.Redeem)
+
 	// Serve downloaded files (for local storage)
 	// Use * wildcard to capture full path including subdirectories
 	app.Get("/downloads/*", func(c *fiber.Ctx) error {
@@ -362,12 +706,50 @@ func main() {
 		return c.SendFile(fullPath, false) // false = no compression
 	})
 
+	// Serve HLS playlists/segments produced by the "hls" OutputFormat (for
+	// local storage). Mirrors the /downloads/* route above, but with
+	// inline disposition and HLS-specific content types so browsers and
+	// players like hls.js can fetch master.m3u8 and its segments directly
+	// instead of being prompted to download them.
+	app.Get("/hls/*", func(c *fiber.Ctx) error {
+		filePath := c.Params("*")
+		fullPath := filepath.Join(getEnv("LOCAL_STORAGE_DIR", "/app/downloads"), filepath.Clean(filePath))
+
+		// Security check - prevent directory traversal
+		if !strings.HasPrefix(fullPath, getEnv("LOCAL_STORAGE_DIR", "/app/downloads")) {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid file path",
+			})
+		}
+
+		if _, err := os.Stat(fullPath); os.IsNotExist(err) {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "File not found",
+			})
+		}
+
+		switch strings.ToLower(filepath.Ext(fullPath)) {
+		case ".m3u8":
+			c.Set("Content-Type", "application/vnd.apple.mpegurl")
+			c.Set("Cache-Control", "no-cache") // playlists mutate until VOD packaging finishes
+		case ".ts":
+			c.Set("Content-Type", "video/mp2t")
+			c.Set("Cache-Control", "public, max-age=31536000, immutable") // segments are content-addressed by sequence
+		}
+
+		return c.SendFile(fullPath, false)
+	})
+
 	// API routes (protected with API key - for backend-to-backend or authorized clients)
 	api := app.Group("/api/v1")
 
 	// Apply API key auth to protected endpoints
 	api.Use(middleware.APIKeyAuth(apiKey))
 
+	// Authenticated callers get their own, higher-tier bucket keyed by API
+	// key instead of sharing the anonymous /proxy tier.
+	api.Use(rateLimiter.MiddlewareWithAPIKeyTier(anonymousRatePolicy, apiKeyRatePolicy))
+
 	// Extract endpoint
 	api.Post("/extract", func(c *fiber.Ctx) error {
 		var req types.ExtractionRequest
@@ -384,17 +766,18 @@ func main() {
 			})
 		}
 
-		// Quick duration check using metadata cache (3-8s → 50ms)
+		// Quick duration check using metadata cache (3-8s → 50ms). See the
+		// proxy /extract handler above for why this goes through
+		// GetOrLoad rather than a bare GetMetadata check.
 		var duration int
 		if distCache != nil {
-			ctx := c.Context()
-			if cachedMeta, err := distCache.GetMetadata(ctx, req.URL); err == nil && cachedMeta != nil {
-				duration = cachedMeta.Duration
+			meta, err := distCache.GetOrLoad(c.Context(), req.URL, func(loadCtx context.Context) (*cache.URLMetadata, error) {
+				return fetchURLMetadata(loadCtx, ytdlp, req.URL)
+			})
+			if err == nil && meta != nil {
+				duration = meta.Duration
 			}
-		}
-
-		// Only fetch metadata if not cached
-		if duration == 0 {
+		} else {
 			durationCtx, durationCancel := context.WithTimeout(c.Context(), 15*time.Second)
 			metadata, _ := ytdlp.ExtractMetadata(durationCtx, req.URL)
 			durationCancel()
@@ -412,8 +795,21 @@ func main() {
 			})
 		}
 
-		// Enqueue job
-		jobID, err := queueClient.EnqueueExtractionJob(context.Background(), req)
+		// An identical request that already completed recently can be
+		// served from cache instead of re-enqueued (see internal/dedup).
+		if cachedJob, ok, err := queueClient.LookupDedupedResult(c.Context(), req); err == nil && ok {
+			metricsInstance.RecordDedupHit(dedup.PlatformFromURL(req.URL))
+			return c.Status(202).JSON(fiber.Map{
+				"job_id":       cachedJob.ID,
+				"status":       "completed",
+				"message":      "Extraction job queued successfully",
+				"deduplicated": true,
+			})
+		}
+
+		// Enqueue job, partitioned by requesting hostname so one noisy
+		// embed site can't monopolize the worker pool (see FairQueue).
+		jobID, err := queueClient.EnqueueWithPriority(context.Background(), req, c.Hostname(), 0)
 		if err != nil {
 			zapLogger.Error("Failed to enqueue job", zap.Error(err))
 			return c.Status(500).JSON(fiber.Map{
@@ -431,8 +827,9 @@ func main() {
 	// Batch extract endpoint
 	api.Post("/batch", func(c *fiber.Ctx) error {
 		var req struct {
-			URLs     []string                `json:"urls"`
-			Template types.ExtractionRequest `json:"template"`
+			URLs        []string                `json:"urls"`
+			Template    types.ExtractionRequest `json:"template"`
+			MaxFailures int                     `json:"max_failures"`
 		}
 
 		if err := c.BodyParser(&req); err != nil {
@@ -447,19 +844,37 @@ func main() {
 			})
 		}
 
-		// Enqueue batch
-		jobIDs, err := queueClient.EnqueueBatchJob(context.Background(), req.URLs, req.Template)
+		// Enqueue batch - a worker fans it out into one child job per URL
+		// and aggregates their completions under this batch ID.
+		batchID, err := queueClient.EnqueueBatch(context.Background(), req.URLs, req.Template, req.MaxFailures)
 		if err != nil {
 			zapLogger.Error("Failed to enqueue batch", zap.Error(err))
 			return c.Status(500).JSON(fiber.Map{
-				"error": "Failed to enqueue batch jobs",
+				"error": "Failed to enqueue batch",
 			})
 		}
 
 		return c.Status(202).JSON(fiber.Map{
-			"job_ids": jobIDs,
-			"count":   len(jobIDs),
-			"message": "Batch extraction jobs queued successfully",
+			"batch_id": batchID,
+			"count":    len(req.URLs),
+			"message":  "Batch extraction queued successfully",
+		})
+	})
+
+	// Get batch status endpoint
+	api.Get("/batch/:id", func(c *fiber.Ctx) error {
+		batchID := c.Params("id")
+
+		batch, children, err := queueClient.GetBatchStatus(context.Background(), batchID)
+		if err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Batch not found",
+			})
+		}
+
+		return c.JSON(fiber.Map{
+			"batch":    batch,
+			"children": children,
 		})
 	})
 
@@ -477,6 +892,134 @@ func main() {
 		return c.JSON(job)
 	})
 
+	// Stream job progress as SSE, so the frontend can drop polling and
+	// render pipeline stage transitions instead of a single integer
+	api.Get("/jobs/:id/stream", handlers.JobProgressStream(queueClient))
+
+	// Inspect and tune the fair queue's per-tenant weights at runtime.
+	adminQueue := api.Group("/admin/queue")
+	adminQueue.Get("/tenants", func(c *fiber.Ctx) error {
+		return c.JSON(fiber.Map{
+			"tenants": queueClient.TenantQueueStats(),
+		})
+	})
+	adminQueue.Post("/weight", func(c *fiber.Ctx) error {
+		var body struct {
+			Tenant string  `json:"tenant"`
+			Weight float64 `json:"weight"`
+		}
+		if err := c.BodyParser(&body); err != nil || body.Tenant == "" || body.Weight <= 0 {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "tenant and a positive weight are required",
+			})
+		}
+
+		queueClient.SetTenantWeight(body.Tenant, body.Weight)
+		return c.JSON(fiber.Map{
+			"tenant": body.Tenant,
+			"weight": body.Weight,
+		})
+	})
+
+	// Manage recurring extraction jobs. The enqueuer loop itself is a
+	// single leader-elected goroutine (see queue.PeriodicScheduler) started
+	// below, so every API instance can serve these routes while at most
+	// one actually fires due policies.
+	periodicScheduler := queue.NewPeriodicScheduler(queueClient, zapLogger)
+	periodicScheduler.Start(context.Background())
+
+	adminPeriodic := api.Group("/admin/periodic")
+	adminPeriodic.Get("/", func(c *fiber.Ctx) error {
+		policies, err := queueClient.ListPeriodicPolicies(c.Context())
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "failed to list periodic policies"})
+		}
+		return c.JSON(fiber.Map{"policies": policies})
+	})
+	adminPeriodic.Post("/", func(c *fiber.Ctx) error {
+		var body struct {
+			CronExpr string                  `json:"cron_expr"`
+			Template types.ExtractionRequest `json:"template"`
+		}
+		if err := c.BodyParser(&body); err != nil || body.CronExpr == "" {
+			return c.Status(400).JSON(fiber.Map{"error": "cron_expr and template are required"})
+		}
+
+		policyID, err := queueClient.SchedulePeriodic(c.Context(), body.CronExpr, body.Template)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(201).JSON(fiber.Map{"policy_id": policyID})
+	})
+	adminPeriodic.Delete("/:id", func(c *fiber.Ctx) error {
+		if err := queueClient.UnschedulePeriodic(c.Context(), c.Params("id")); err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "failed to unschedule periodic policy"})
+		}
+		return c.JSON(fiber.Map{"status": "unscheduled"})
+	})
+
+	// Rotate the cookie jars the Instagram/YouTube extraction fallbacks use
+	// to recover from rate-limit and login-required walls.
+	adminCredentials := api.Group("/admin/credentials")
+	adminCredentials.Get("/", func(c *fiber.Ctx) error {
+		if credentialStore == nil {
+			return c.Status(503).JSON(fiber.Map{"error": "credential store not configured"})
+		}
+		creds, err := credentialStore.List(c.Context())
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "failed to list credentials"})
+		}
+		return c.JSON(fiber.Map{"credentials": creds})
+	})
+	adminCredentials.Put("/:platform", func(c *fiber.Ctx) error {
+		if credentialStore == nil {
+			return c.Status(503).JSON(fiber.Map{"error": "credential store not configured"})
+		}
+		var body struct {
+			CookiesBase64 string `json:"cookies_base64"`
+		}
+		if err := c.BodyParser(&body); err != nil || body.CookiesBase64 == "" {
+			return c.Status(400).JSON(fiber.Map{"error": "cookies_base64 is required"})
+		}
+		platform := c.Params("platform")
+		if err := credentialStore.Set(c.Context(), platform, body.CookiesBase64); err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "failed to store credential"})
+		}
+		return c.JSON(fiber.Map{"platform": platform, "status": "stored"})
+	})
+	adminCredentials.Delete("/:platform", func(c *fiber.Ctx) error {
+		if credentialStore == nil {
+			return c.Status(503).JSON(fiber.Map{"error": "credential store not configured"})
+		}
+		platform := c.Params("platform")
+		if err := credentialStore.Delete(c.Context(), platform); err != nil {
+			if err == credentials.ErrNotFound {
+				return c.Status(404).JSON(fiber.Map{"error": "no credential stored for platform"})
+			}
+			return c.Status(500).JSON(fiber.Map{"error": "failed to delete credential"})
+		}
+		return c.JSON(fiber.Map{"platform": platform, "status": "deleted"})
+	})
+
+	// Resume a failed job under its original ID instead of submitting a
+	// fresh one, so downloadMedia can rehydrate from its puller checkpoint
+	// and yt-dlp's own partial output file.
+	api.Post("/jobs/:id/resume", func(c *fiber.Ctx) error {
+		jobID := c.Params("id")
+
+		if err := queueClient.ResumeJob(context.Background(), jobID); err != nil {
+			zapLogger.Warn("Failed to resume job", zap.String("job_id", jobID), zap.Error(err))
+			return c.Status(400).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		return c.Status(202).JSON(fiber.Map{
+			"job_id":  jobID,
+			"message": "Job resumed",
+		})
+	})
+
 	// Download endpoint (redirect to presigned URL)
 	api.Get("/download/:id", func(c *fiber.Ctx) error {
 		jobID := c.Params("id")
@@ -505,26 +1048,14 @@ func main() {
 		return c.Redirect(job.Result.DownloadURL, 302)
 	})
 
-	// Webhook registration endpoint
-	api.Post("/webhooks/register", func(c *fiber.Ctx) error {
-		var req struct {
-			JobID      string `json:"job_id"`
-			WebhookURL string `json:"webhook_url"`
-		}
-
-		if err := c.BodyParser(&req); err != nil {
-			return c.Status(400).JSON(fiber.Map{
-				"error": "Invalid request body",
-			})
-		}
-
-		// TODO: Implement webhook registration logic
-		// Store webhook URL in Redis associated with job_id
-
-		return c.JSON(fiber.Map{
-			"message": "Webhook registered successfully",
-		})
-	})
+	// Webhook endpoints: register a URL for job lifecycle events, inspect
+	// delivery history, force a replay, and inspect/redeliver anything that
+	// exhausted its retry budget (see internal/webhooks)
+	api.Post("/webhooks/register", webhookHandler.Register)
+	api.Get("/webhooks/:job_id/deliveries", webhookHandler.Deliveries)
+	api.Post("/webhooks/:job_id/replay", webhookHandler.Replay)
+	api.Get("/webhooks/dead-letters", webhookHandler.DeadLetters)
+	api.Post("/webhooks/redeliver/:delivery_id", webhookHandler.Redeliver)
 
 	// History endpoints (public - no API key required, site-specific)
 	history := app.Group("/api/history")
@@ -556,9 +1087,80 @@ func main() {
 	zapLogger.Info("Server stopped")
 }
 
+// fetchURLMetadata runs yt-dlp's metadata extraction and adapts the result
+// to cache.URLMetadata, the shape distCache.GetOrLoad's loader expects. A
+// yt-dlp error propagates as-is; a nil, nil result (no metadata, no error)
+// is what GetOrLoad treats as a negative result worth remembering.
+func fetchURLMetadata(ctx context.Context, ytdlp *extractor.YtDlp, url string) (*cache.URLMetadata, error) {
+	durationCtx, durationCancel := context.WithTimeout(ctx, 15*time.Second)
+	defer durationCancel()
+
+	metadata, err := ytdlp.ExtractMetadata(durationCtx, url)
+	if err != nil {
+		return nil, err
+	}
+	if metadata == nil {
+		return nil, nil
+	}
+	return &cache.URLMetadata{
+		URL:      url,
+		Title:    metadata.Title,
+		Duration: metadata.Duration,
+		Platform: metadata.Platform,
+	}, nil
+}
+
+// workerPoolLoadSampler adapts workerPool and queueClient to
+// queue.LoadSampler for dynamicConcurrency above.
+type workerPoolLoadSampler struct {
+	workerPool  *pool.WorkerPool
+	queueClient *queue.Client
+}
+
+func (s workerPoolLoadSampler) Inflight() int64 {
+	return s.workerPool.ActiveJobs()
+}
+
+func (s workerPoolLoadSampler) QueueDepth(ctx context.Context) (int64, error) {
+	return s.queueClient.QueueDepth(ctx)
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
 	}
 	return defaultValue
 }
+
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		return value == "true" || value == "1" || value == "yes"
+	}
+	return defaultValue
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if duration, err := time.ParseDuration(value); err == nil {
+			return duration
+		}
+	}
+	return defaultValue
+}
+
+// splitEnvList parses a comma-separated env value into a trimmed, non-empty
+// slice. An empty input yields a nil slice.
+func splitEnvList(value string) []string {
+	if value == "" {
+		return nil
+	}
+
+	parts := strings.Split(value, ",")
+	result := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			result = append(result, p)
+		}
+	}
+	return result
+}
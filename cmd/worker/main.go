@@ -7,14 +7,21 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/gofiber/fiber/v2"
 	"go.uber.org/zap"
 
+	"github.com/KeremKalyoncu/MedYan/internal/errclass"
 	"github.com/KeremKalyoncu/MedYan/internal/extractor"
 	"github.com/KeremKalyoncu/MedYan/internal/handlers"
+	"github.com/KeremKalyoncu/MedYan/internal/ippool"
+	"github.com/KeremKalyoncu/MedYan/internal/pool"
 	"github.com/KeremKalyoncu/MedYan/internal/queue"
+	"github.com/KeremKalyoncu/MedYan/internal/webhooks"
 	"github.com/KeremKalyoncu/MedYan/pkg/storage"
 )
 
@@ -26,60 +33,150 @@ func main() {
 	}
 	defer zapLogger.Sync()
 
+	// Wire a logger into the shared buffer pools so a persistently low hit
+	// rate (pool undersized for its workload) gets logged here too
+	pool.SetLogger(zapLogger)
+
 	zapLogger.Info("Starting Media Extraction Worker")
 
 	// Configuration from environment
 	redisAddr := getEnv("REDIS_ADDR", "localhost:6379")
 	ytdlpPath := getEnv("YTDLP_PATH", "yt-dlp")
 	ffmpegPath := getEnv("FFMPEG_PATH", "ffmpeg")
+	ffprobePath := getEnv("FFPROBE_PATH", "ffprobe")
+	hwAccelMode := getEnv("FFMPEG_HWACCEL_MODE", "auto")
 	s3Bucket := getEnv("S3_BUCKET", "media-extraction-output")
 	s3Region := getEnv("S3_REGION", "us-east-1")
 	s3Endpoint := getEnv("S3_ENDPOINT", "")
+	tokenSigningKey := getEnv("DOWNLOAD_TOKEN_SIGNING_KEY", "")
 
 	// Disable localhost:9000 - not valid in production
 	if s3Endpoint == "http://localhost:9000" || s3Endpoint == "localhost:9000" {
 		s3Endpoint = ""
 	}
 
+	// STORAGE_BACKEND explicitly picks the backend; unset falls back to the
+	// historical heuristic of "S3 if an endpoint is configured".
+	storageBackend := getEnv("STORAGE_BACKEND", "")
+	if storageBackend == "" {
+		if s3Endpoint != "" && s3Endpoint != "disabled" {
+			storageBackend = "s3"
+		} else {
+			storageBackend = "local"
+		}
+	}
+
 	// Initialize extractors
 	ytdlp := extractor.NewYtDlp(ytdlpPath, 10*time.Minute, zapLogger)
-	ffmpeg := extractor.NewFFmpeg(ffmpegPath, 30*time.Minute, zapLogger)
+	ffmpeg := extractor.NewFFmpeg(ffmpegPath, ffprobePath, 30*time.Minute, zapLogger)
+	ffmpeg.SetHWAccelMode(extractor.HWAccelFamily(hwAccelMode))
+	ytdlp.SetFFmpeg(ffmpeg)
+
+	// Bound concurrent transcodes/muxing independently of the generic
+	// WorkerPool below, so a burst of extraction requests can't starve it.
+	// Individual ffmpeg invocations (ConvertFormat, PackageHLS, ...) don't
+	// carry a priority today, so this pool has no reservation - it gates
+	// purely on concurrency, same as before reservations existed.
+	ffmpegPool := pool.NewFFmpegPool("transcode", pool.DefaultFFmpegPoolSize(), 32, zapLogger, 0)
+	ffmpeg.SetPool(ffmpegPool)
+
+	// Separate, outer pool gating whole extraction tasks (download *and*
+	// transcode) rather than individual ffmpeg invocations - this must be a
+	// distinct instance from ffmpegPool above, since gating a task through
+	// one pool and then gating its ffmpeg step through the same pool from
+	// inside that task would deadlock once every worker is occupied waiting
+	// on its own inner RunGated call. Server.handleExtractionTask knows each
+	// job's quality, so this pool reserves FFMPEG_LOW_PRIORITY_RESERVED
+	// workers for pool.PriorityLow jobs (low-quality/audio-only requests) so
+	// a flood of 4K transcodes landing on the general queue can't starve them.
+	extractionPool := pool.NewFFmpegPool("extraction", pool.DefaultFFmpegPoolSize(), 32, zapLogger, getEnvInt("FFMPEG_LOW_PRIORITY_RESERVED", 1))
+
+	// Track in-flight yt-dlp/ffmpeg processes so a watchdog can kill ones
+	// that have stopped making progress (separate from their overall
+	// timeouts above), and so the admin endpoints below can list/cancel
+	// individual extractions by task ID.
+	processRegistry := extractor.NewProcessRegistry()
+	ytdlp.SetRegistry(processRegistry)
+	ffmpeg.SetRegistry(processRegistry)
+
+	idleCheckInterval := getEnvDuration("EXTRACTOR_IDLE_CHECK_INTERVAL", 15*time.Second)
+	idleTimeout := getEnvDuration("EXTRACTOR_IDLE_TIMEOUT", 2*time.Minute)
 
-	// Initialize storage
-	// Use local file storage for now (S3 can be enabled via environment variables)
+	watchdogCtx, watchdogCancel := context.WithCancel(context.Background())
+	defer watchdogCancel()
+	processRegistry.StartWatchdog(watchdogCtx, idleCheckInterval, idleTimeout, zapLogger)
+
+	// Initialize storage backend, selected by STORAGE_BACKEND
 	var fileStorage interface {
-		Upload(ctx context.Context, filePath, key string) error
-		UploadStream(ctx context.Context, reader io.Reader, key string) error
+		Upload(ctx context.Context, filePath, key string) (string, error)
+		UploadStream(ctx context.Context, reader io.Reader, key string) (string, error)
+		UploadMultipart(ctx context.Context, key string, partSize int64, reader io.Reader) (string, error)
 		GetPresignedURL(ctx context.Context, key string) (string, error)
+		PutPresignedURL(ctx context.Context, key string) (string, error)
 	}
 
-	if s3Endpoint != "" && s3Endpoint != "disabled" {
-		// Use S3 if endpoint is configured
+	switch storageBackend {
+	case "s3":
 		s3Stor, err := storage.NewS3Storage(context.Background(), storage.Config{
 			Region:               s3Region,
 			Bucket:               s3Bucket,
 			Endpoint:             s3Endpoint,
 			PresignedURLExpiry:   24 * time.Hour,
 			StreamThresholdBytes: 500 * 1024 * 1024,
+			TokenSigningKey:      tokenSigningKey,
 			Logger:               zapLogger,
 		})
 		if err != nil {
 			zapLogger.Fatal("Failed to initialize S3 storage", zap.Error(err))
 		}
 		fileStorage = s3Stor
-	} else {
-		// Use local file storage (default)
-		localStor, err := storage.NewLocalStorage("/app/downloads", zapLogger)
+	case "local":
+		localStor, err := storage.NewLocalStorage(getEnv("LOCAL_STORAGE_DIR", "/app/downloads"), tokenSigningKey, zapLogger)
 		if err != nil {
 			zapLogger.Fatal("Failed to initialize local storage", zap.Error(err))
 		}
 		fileStorage = localStor
+	case "tiered":
+		localStor, err := storage.NewLocalStorage(getEnv("LOCAL_STORAGE_DIR", "/app/downloads"), tokenSigningKey, zapLogger)
+		if err != nil {
+			zapLogger.Fatal("Failed to initialize local cache tier", zap.Error(err))
+		}
+		s3Stor, err := storage.NewS3Storage(context.Background(), storage.Config{
+			Region:               s3Region,
+			Bucket:               s3Bucket,
+			Endpoint:             s3Endpoint,
+			PresignedURLExpiry:   24 * time.Hour,
+			StreamThresholdBytes: 500 * 1024 * 1024,
+			TokenSigningKey:      tokenSigningKey,
+			Logger:               zapLogger,
+		})
+		if err != nil {
+			zapLogger.Fatal("Failed to initialize S3 origin for tiered storage", zap.Error(err))
+		}
+		tieredCacheMaxBytes := getEnvInt64("TIERED_CACHE_MAX_BYTES", 5*1024*1024*1024)
+		fileStorage = storage.NewTieredStorage(localStor, s3Stor, zapLogger, storage.TieredConfig{
+			MaxCacheBytes: tieredCacheMaxBytes,
+		})
+	default:
+		zapLogger.Fatal("Invalid STORAGE_BACKEND", zap.String("storage_backend", storageBackend))
 	}
 
 	// Initialize queue client (for updating job status)
 	queueClient := queue.NewClient(redisAddr, zapLogger)
 	defer queueClient.Close()
 
+	// Drains queueClient's FairQueue in weighted-fair order and submits to
+	// Asynq - handleBatchTask's per-URL fan-out enqueues through this same
+	// Client, so it needs a dispatcher too (see queue.FairDispatcher).
+	queue.NewFairDispatcher(queueClient, zapLogger).Start(context.Background())
+
+	// Webhook dispatcher: the worker is where job state transitions actually
+	// happen, so it runs the delivery loop rather than just publishing to it
+	webhookDispatcher := webhooks.NewDispatcher(queueClient.GetRedis(), zapLogger)
+	webhookDispatcher.Start(context.Background())
+	defer webhookDispatcher.Stop()
+	queueClient.SetWebhookDispatcher(webhookDispatcher)
+
 	// Initialize extraction handler
 	extractionHandler := handlers.NewExtractionHandler(
 		ytdlp,
@@ -88,6 +185,32 @@ func main() {
 		queueClient,
 		zapLogger,
 	)
+	extractionHandler.SetHLSConfig(handlers.HLSConfig{
+		SegmentSeconds:     getEnvInt("HLS_SEGMENT_SECONDS", 6),
+		Renditions:         extractor.RenditionsFromNames(getEnvStringSlice("HLS_VARIANTS", []string{"1080p", "720p", "480p"})),
+		CriticalRenditions: extractor.RenditionsFromNames(getEnvStringSlice("HLS_CRITICAL_VARIANTS", []string{"4k", "1080p", "720p", "480p"})),
+	})
+
+	// Egress pool: rotates yt-dlp downloads through YTDLP_SOURCE_IPS/
+	// YTDLP_PROXIES and cools one down per-platform once it looks throttled
+	// or bot-blocked. Empty config (the default) makes this a no-op.
+	egressPool := ippool.New(ippool.Config{
+		SourceIPs: getEnvStringSlice("YTDLP_SOURCE_IPS", nil),
+		ProxyURLs: getEnvStringSlice("YTDLP_PROXIES", nil),
+		Cooldown:  10 * time.Minute,
+	}, zapLogger)
+	extractionHandler.SetIPPool(egressPool)
+
+	// Post-download verification: off by default, "warn" just logs a
+	// mismatch/muxing problem (attempting a remux repair either way),
+	// "strict" also fails the attempt so the retry loop re-downloads.
+	extractionHandler.SetVerifyMode(extractor.VerifyMode(getEnv("DOWNLOAD_VERIFY_MODE", "")))
+
+	// Native YouTube extractor: a yt-dlp-independent fallback used when
+	// yt-dlp's circuit breaker trips or it keeps hitting rate limits on a
+	// YouTube URL.
+	nativeYouTube := extractor.NewNativeYouTube(zapLogger)
+	extractionHandler.SetFallback(extractor.NewFallbackExtractor(ytdlp, nativeYouTube, zapLogger))
 
 	// Initialize queue server (worker)
 	workerServer := queue.NewServer(queue.ServerConfig{
@@ -101,15 +224,37 @@ func main() {
 		ShutdownTimeout: 30,
 		Logger:          zapLogger,
 		Handler:         extractionHandler,
+		RetryPolicy: errclass.Policy{
+			MaxRetries:     getEnvInt("JOB_MAX_RETRIES", 3),
+			RetryDelayBase: getEnvDuration("JOB_RETRY_DELAY_BASE", 5*time.Second),
+		},
 	})
+	workerServer.SetFFmpegPool(extractionPool)
+	workerServer.SetClient(queueClient)
 
 	zapLogger.Info("Worker configuration",
 		zap.String("redis", redisAddr),
 		zap.String("ytdlp", ytdlpPath),
 		zap.String("ffmpeg", ffmpegPath),
+		zap.String("storage_backend", storageBackend),
 		zap.String("s3_bucket", s3Bucket),
 	)
 
+	// Admin server: lists and forcibly cancels in-flight extractions by task
+	// ID. This lives on the worker (not the API process) because the tracked
+	// *exec.Cmd handles only exist in the process that started them.
+	adminHandler := handlers.NewAdminHandler(processRegistry, zapLogger)
+	adminApp := fiber.New(fiber.Config{DisableStartupMessage: true})
+	adminApp.Get("/admin/processes", adminHandler.ListProcesses)
+	adminApp.Post("/admin/processes/:task_id/cancel", adminHandler.CancelProcess)
+
+	adminPort := getEnv("ADMIN_PORT", "9090")
+	go func() {
+		if err := adminApp.Listen(":" + adminPort); err != nil {
+			zapLogger.Error("Admin server error", zap.Error(err))
+		}
+	}()
+
 	// Start worker in goroutine
 	go func() {
 		if err := workerServer.Start(); err != nil {
@@ -124,6 +269,16 @@ func main() {
 
 	zapLogger.Info("Shutting down worker...")
 	workerServer.Shutdown()
+	adminApp.ShutdownWithTimeout(5 * time.Second)
+
+	ffmpegShutdownCtx, ffmpegShutdownCancel := context.WithTimeout(context.Background(), 15*time.Second)
+	if err := extractionPool.Shutdown(ffmpegShutdownCtx); err != nil {
+		zapLogger.Warn("Extraction pool shutdown did not complete cleanly", zap.Error(err))
+	}
+	if err := ffmpegPool.Shutdown(ffmpegShutdownCtx); err != nil {
+		zapLogger.Warn("FFmpeg pool shutdown did not complete cleanly", zap.Error(err))
+	}
+	ffmpegShutdownCancel()
 
 	zapLogger.Info("Worker stopped")
 }
@@ -145,3 +300,40 @@ func getEnvInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+func getEnvInt64(key string, defaultValue int64) int64 {
+	if value := os.Getenv(key); value != "" {
+		if result, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return result
+		}
+	}
+	return defaultValue
+}
+
+func getEnvStringSlice(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	parts := strings.Split(value, ",")
+	result := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if trimmed := strings.TrimSpace(p); trimmed != "" {
+			result = append(result, trimmed)
+		}
+	}
+	if len(result) == 0 {
+		return defaultValue
+	}
+	return result
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if duration, err := time.ParseDuration(value); err == nil {
+			return duration
+		}
+	}
+	return defaultValue
+}
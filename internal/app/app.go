@@ -21,9 +21,13 @@ type Container struct {
 	QueueClient       *queue.Client
 	ExtractorYtdlp    *extractor.YtDlp
 	ExtractorFFmpeg   *extractor.FFmpeg
+	ProcessRegistry   *extractor.ProcessRegistry
+	AdminHandler      *handlers.AdminHandler
 	Storage           storage.Storage
 	ExtractionHandler *handlers.ExtractionHandler
 	WorkerServer      *queue.Server
+
+	watchdogCancel context.CancelFunc
 }
 
 // NewContainer creates and initializes a new application container
@@ -51,27 +55,32 @@ func NewContainer(logger *zap.Logger) (*Container, error) {
 
 	ffmpegExtractor := extractor.NewFFmpeg(
 		cfg.Extractor.FFmpegPath,
+		cfg.Extractor.FfprobePath,
 		cfg.Extractor.FFmpegTimeout,
 		logger,
 	)
+	ffmpegExtractor.SetHWAccelMode(extractor.HWAccelFamily(cfg.Extractor.HWAccelMode))
 
-	// Initialize storage
-	s3Storage, err := storage.NewS3Storage(
-		context.Background(),
-		storage.Config{
-			Region:               cfg.Storage.Region,
-			Bucket:               cfg.Storage.Bucket,
-			Endpoint:             cfg.Storage.Endpoint,
-			PresignedURLExpiry:   cfg.Storage.PresignedURLExpiry,
-			StreamThresholdBytes: cfg.Storage.StreamThresholdBytes,
-			Logger:               logger,
-		},
-	)
+	// Track in-flight yt-dlp/ffmpeg processes so a watchdog can kill ones
+	// that have stopped making progress, and so the admin handler can list
+	// and forcibly cancel them by task ID.
+	processRegistry := extractor.NewProcessRegistry()
+	ytdlp.SetRegistry(processRegistry)
+	ffmpegExtractor.SetRegistry(processRegistry)
+
+	watchdogCtx, watchdogCancel := context.WithCancel(context.Background())
+	processRegistry.StartWatchdog(watchdogCtx, cfg.Extractor.IdleCheckInterval, cfg.Extractor.IdleTimeout, logger)
+
+	adminHandler := handlers.NewAdminHandler(processRegistry, logger)
+
+	// Initialize storage backend, selected by cfg.Storage.Backend
+	fileStore, err := newStorageBackend(cfg, logger)
 	if err != nil {
-		return nil, fmt.Errorf("failed to initialize S3 storage: %w", err)
+		return nil, fmt.Errorf("failed to initialize storage: %w", err)
 	}
 
 	logger.Info("Storage initialized successfully",
+		zap.String("backend", cfg.Storage.Backend),
 		zap.String("bucket", cfg.Storage.Bucket),
 		zap.String("endpoint", cfg.Storage.Endpoint),
 	)
@@ -80,10 +89,15 @@ func NewContainer(logger *zap.Logger) (*Container, error) {
 	extractionHandler := handlers.NewExtractionHandler(
 		ytdlp,
 		ffmpegExtractor,
-		s3Storage,
+		fileStore,
 		queueClient,
 		logger,
 	)
+	extractionHandler.SetHLSConfig(handlers.HLSConfig{
+		SegmentSeconds:     cfg.Extractor.HLSSegmentSeconds,
+		Renditions:         extractor.RenditionsFromNames(cfg.Extractor.HLSVariants),
+		CriticalRenditions: extractor.RenditionsFromNames(cfg.Extractor.HLSCriticalVariants),
+	})
 
 	// Initialize worker server
 	workerServer := queue.NewServer(queue.ServerConfig{
@@ -105,16 +119,76 @@ func NewContainer(logger *zap.Logger) (*Container, error) {
 		QueueClient:       queueClient,
 		ExtractorYtdlp:    ytdlp,
 		ExtractorFFmpeg:   ffmpegExtractor,
-		Storage:           s3Storage,
+		ProcessRegistry:   processRegistry,
+		AdminHandler:      adminHandler,
+		Storage:           fileStore,
 		ExtractionHandler: extractionHandler,
 		WorkerServer:      workerServer,
+		watchdogCancel:    watchdogCancel,
 	}, nil
 }
 
+// newStorageBackend constructs the storage.Storage implementation selected
+// by cfg.Storage.Backend. "tiered" layers a local LRU cache in front of the
+// same S3 origin the "s3" backend talks to directly.
+func newStorageBackend(cfg *config.Config, logger *zap.Logger) (storage.Storage, error) {
+	switch cfg.Storage.Backend {
+	case "local":
+		return storage.NewLocalStorage(cfg.Storage.LocalDir, cfg.Storage.TokenSigningKey, logger)
+
+	case "tiered":
+		local, err := storage.NewLocalStorage(cfg.Storage.LocalDir, cfg.Storage.TokenSigningKey, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize local cache tier: %w", err)
+		}
+
+		origin, err := storage.NewS3Storage(context.Background(), storage.Config{
+			Region:               cfg.Storage.Region,
+			Bucket:               cfg.Storage.Bucket,
+			Endpoint:             cfg.Storage.Endpoint,
+			PresignedURLExpiry:   cfg.Storage.PresignedURLExpiry,
+			StreamThresholdBytes: cfg.Storage.StreamThresholdBytes,
+			SSEMode:              storage.SSEMode(cfg.Storage.SSEMode),
+			KMSKeyID:             cfg.Storage.KMSKeyID,
+			SSECustomerKey:       cfg.Storage.SSECustomerKey,
+			TokenSigningKey:      cfg.Storage.TokenSigningKey,
+			Logger:               logger,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize S3 origin: %w", err)
+		}
+
+		return storage.NewTieredStorage(local, origin, logger, storage.TieredConfig{
+			MaxCacheBytes: cfg.Storage.TieredCacheMaxBytes,
+		}), nil
+
+	case "s3":
+		return storage.NewS3Storage(context.Background(), storage.Config{
+			Region:               cfg.Storage.Region,
+			Bucket:               cfg.Storage.Bucket,
+			Endpoint:             cfg.Storage.Endpoint,
+			PresignedURLExpiry:   cfg.Storage.PresignedURLExpiry,
+			StreamThresholdBytes: cfg.Storage.StreamThresholdBytes,
+			SSEMode:              storage.SSEMode(cfg.Storage.SSEMode),
+			KMSKeyID:             cfg.Storage.KMSKeyID,
+			SSECustomerKey:       cfg.Storage.SSECustomerKey,
+			TokenSigningKey:      cfg.Storage.TokenSigningKey,
+			Logger:               logger,
+		})
+
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.Storage.Backend)
+	}
+}
+
 // Close closes all resources
 func (c *Container) Close() error {
 	c.Logger.Info("Closing application container")
 
+	if c.watchdogCancel != nil {
+		c.watchdogCancel()
+	}
+
 	if c.QueueClient != nil {
 		c.QueueClient.Close()
 	}
@@ -8,37 +8,38 @@ import (
 
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
+
+	"github.com/KeremKalyoncu/MedYan/internal/redisconn"
 )
 
 // CacheManager provides caching functionality using Redis
 type CacheManager struct {
 	client *redis.Client
+	conn   *redisconn.Conn
 	logger *zap.Logger
 	prefix string
 }
 
-// NewCacheManager creates a new cache manager
+// NewCacheManager creates a new cache manager. redisAddr may be a bare
+// host:port or a full redisconn DSN; see internal/redisconn. The
+// underlying *redis.Client is shared (via redisconn.Default) with any
+// other subsystem pointed at the same DSN - e.g. queue.NewClient, since
+// both default to DB 0 when redisAddr doesn't specify one.
 func NewCacheManager(redisAddr string, logger *zap.Logger) (*CacheManager, error) {
-	// Optimize Redis client with connection pooling
-	client := redis.NewClient(&redis.Options{
-		Addr:         redisAddr,
-		PoolSize:     20, // Increased connection pool (default: 10)
-		MinIdleConns: 5,  // Keep minimum idle connections
-		MaxRetries:   3,  // Retry failed commands
-		DialTimeout:  5 * time.Second,
-		ReadTimeout:  3 * time.Second,
-		WriteTimeout: 3 * time.Second,
-		// Enable connection pooling optimizations
-		PoolTimeout: 4 * time.Second,
-	})
+	client, conn, err := redisconn.Default.GetClient(redisAddr, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+	}
 
 	// Test connection
 	if err := client.Ping(context.Background()).Err(); err != nil {
+		_ = conn.Close()
 		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
 	}
 
 	return &CacheManager{
 		client: client,
+		conn:   conn,
 		logger: logger,
 		prefix: "cache:",
 	}, nil
@@ -244,9 +245,18 @@ func (cm *CacheManager) Count(ctx context.Context) (int64, error) {
 
 // Close closes the Redis connection
 func (cm *CacheManager) Close() error {
+	if cm.conn != nil {
+		return cm.conn.Close()
+	}
 	return cm.client.Close()
 }
 
+// GetRedis returns the underlying redis client, for callers (e.g.
+// TieredCache) that need to subscribe to pub/sub channels alongside it.
+func (cm *CacheManager) GetRedis() *redis.Client {
+	return cm.client
+}
+
 // Error definitions
 var (
 	ErrCacheMiss = fmt.Errorf("cache miss")
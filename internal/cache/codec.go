@@ -0,0 +1,207 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// codecID identifies how a cache entry's payload (the bytes following the
+// 2-byte header) is encoded. Legacy entries written before this header
+// existed have neither byte - see decodeEntry.
+type codecID byte
+
+const (
+	codecIDJSON codecID = iota
+	codecIDMsgpack
+	codecIDJSONZstd
+	codecIDMsgpackZstd
+)
+
+// urlMetadataSchemaVersion is bumped whenever URLMetadata's fields change in
+// a way that would otherwise unmarshal into the wrong shape. decodeEntry
+// treats any stored entry whose version doesn't match as a cache miss rather
+// than risk returning a partially-populated or misinterpreted value.
+const urlMetadataSchemaVersion byte = 1
+
+// entryHeaderSize is the {codec_id, schema_version} prefix written before
+// every entry encoded by encodeEntry.
+const entryHeaderSize = 2
+
+// compressEntryThreshold is the encoded (pre-compression) payload size past
+// which encodeEntry zstd-compresses it. Small entries aren't worth the
+// compression overhead or the extra CPU on every Get.
+const compressEntryThreshold = 256
+
+// ErrSchemaVersionMismatch is returned by decodeEntry when a stored entry's
+// schema_version doesn't match urlMetadataSchemaVersion. Callers treat this
+// the same as a cache miss.
+var ErrSchemaVersionMismatch = errors.New("cache: stale schema version")
+
+// Codec marshals and unmarshals a URLMetadata's payload, independent of the
+// entryHeaderSize prefix and any zstd compression encodeEntry/decodeEntry
+// apply around it.
+type Codec interface {
+	id() codecID
+	marshal(v *URLMetadata) ([]byte, error)
+	unmarshal(data []byte, v *URLMetadata) error
+}
+
+// jsonCodec is the default Codec - it's what every entry was encoded with
+// before this header existed, so it's also what decodeEntry falls back to
+// for header-less legacy entries.
+type jsonCodec struct{}
+
+func (jsonCodec) id() codecID { return codecIDJSON }
+
+func (jsonCodec) marshal(v *URLMetadata) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) unmarshal(data []byte, v *URLMetadata) error {
+	return json.Unmarshal(data, v)
+}
+
+// msgpackCodec trades JSON's human-readability for a smaller payload - the
+// URL and title strings that dominate URLMetadata's size still cost their
+// full length, but MessagePack drops JSON's per-field quoting and punctuation.
+type msgpackCodec struct{}
+
+func (msgpackCodec) id() codecID { return codecIDMsgpack }
+
+func (msgpackCodec) marshal(v *URLMetadata) ([]byte, error) {
+	return msgpack.Marshal(v)
+}
+
+func (msgpackCodec) unmarshal(data []byte, v *URLMetadata) error {
+	return msgpack.Unmarshal(data, v)
+}
+
+// zstdVariant maps a base codec's id to the id stored when encodeEntry
+// decided the marshaled payload was worth compressing.
+func zstdVariant(id codecID) (codecID, bool) {
+	switch id {
+	case codecIDJSON:
+		return codecIDJSONZstd, true
+	case codecIDMsgpack:
+		return codecIDMsgpackZstd, true
+	default:
+		return 0, false
+	}
+}
+
+// baseCodecFor returns the Codec whose marshal/unmarshal applies to id,
+// treating the zstd variants as the same payload shape as their base codec.
+func baseCodecFor(id codecID) (Codec, bool) {
+	switch id {
+	case codecIDJSON, codecIDJSONZstd:
+		return jsonCodec{}, true
+	case codecIDMsgpack, codecIDMsgpackZstd:
+		return msgpackCodec{}, true
+	default:
+		return nil, false
+	}
+}
+
+func isCompressed(id codecID) bool {
+	return id == codecIDJSONZstd || id == codecIDMsgpackZstd
+}
+
+// encodeEntry marshals v with codec, zstd-compressing the result (and
+// switching to that codec's compressed id) when it's larger than
+// compressEntryThreshold, and prepends the {codec_id, schema_version}
+// header decodeEntry expects.
+func encodeEntry(codec Codec, v *URLMetadata) ([]byte, error) {
+	payload, err := codec.marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	id := codec.id()
+	if len(payload) > compressEntryThreshold {
+		if zid, ok := zstdVariant(id); ok {
+			compressed, err := zstdCompress(payload)
+			if err == nil {
+				id = zid
+				payload = compressed
+			}
+			// A compression failure isn't fatal - fall back to storing the
+			// uncompressed payload under the base codec id.
+		}
+	}
+
+	out := make([]byte, entryHeaderSize, entryHeaderSize+len(payload))
+	out[0] = byte(id)
+	out[1] = urlMetadataSchemaVersion
+	return append(out, payload...), nil
+}
+
+// decodeEntry reverses encodeEntry. data with no recognized header byte is
+// assumed to be a legacy entry written before this header existed - always
+// plain JSON with no compression - since none of the header's codecID
+// values collide with the leading byte of valid JSON text (a '{', a digit,
+// a quote, or whitespace).
+func decodeEntry(data []byte) (*URLMetadata, error) {
+	if len(data) < entryHeaderSize || !isKnownCodecID(data[0]) {
+		var v URLMetadata
+		if err := json.Unmarshal(data, &v); err != nil {
+			return nil, err
+		}
+		return &v, nil
+	}
+
+	id := codecID(data[0])
+	version := data[1]
+	if version != urlMetadataSchemaVersion {
+		return nil, ErrSchemaVersionMismatch
+	}
+
+	codec, ok := baseCodecFor(id)
+	if !ok {
+		return nil, errors.New("cache: unknown codec id")
+	}
+
+	payload := data[entryHeaderSize:]
+	if isCompressed(id) {
+		decompressed, err := zstdDecompress(payload)
+		if err != nil {
+			return nil, err
+		}
+		payload = decompressed
+	}
+
+	var v URLMetadata
+	if err := codec.unmarshal(payload, &v); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func isKnownCodecID(b byte) bool {
+	switch codecID(b) {
+	case codecIDJSON, codecIDMsgpack, codecIDJSONZstd, codecIDMsgpackZstd:
+		return true
+	default:
+		return false
+	}
+}
+
+func zstdCompress(data []byte) ([]byte, error) {
+	enc, err := zstd.NewWriter(nil)
+	if err != nil {
+		return nil, err
+	}
+	defer enc.Close()
+	return enc.EncodeAll(data, nil), nil
+}
+
+func zstdDecompress(data []byte) ([]byte, error) {
+	dec, err := zstd.NewReader(nil)
+	if err != nil {
+		return nil, err
+	}
+	defer dec.Close()
+	return dec.DecodeAll(data, nil)
+}
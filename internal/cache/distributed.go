@@ -4,14 +4,34 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
-	"encoding/json"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
+
+	"github.com/KeremKalyoncu/MedYan/internal/dedup"
+	"github.com/KeremKalyoncu/MedYan/internal/redisconn"
 )
 
+// distributedL1MaxEntries bounds the in-process L1 layer GetOrLoad checks
+// before falling back to Redis. URL metadata is small and this cache is
+// per-process, so a generous cap costs little memory.
+const distributedL1MaxEntries = 4096
+
+// distributedL1TTL is how long GetOrLoad trusts its in-process L1 copy
+// before re-checking Redis. Kept well under ttl (the L2 TTL) since L1 isn't
+// invalidated across instances the way TieredCache's is.
+const distributedL1TTL = 5 * time.Minute
+
+// distributedNegativeTTL is how long GetOrLoad remembers that a URL's
+// loader resolved to "no metadata" (nil, nil) before trying again. Shorter
+// than the L2 positive TTL (ttl), since a 404 today is more likely to
+// change than a video's title is; it is longer than distributedL1TTL, and
+// negative results are kept in L1 for this same duration.
+const distributedNegativeTTL = 10 * time.Minute
+
 // URLMetadata stores cached metadata for a URL
 type URLMetadata struct {
 	URL         string    `json:"url"`
@@ -27,45 +47,90 @@ type URLMetadata struct {
 // DistributedCache provides distributed caching for URL metadata
 type DistributedCache struct {
 	client *redis.Client
+	conn   *redisconn.Conn
 	logger *zap.Logger
 	ttl    time.Duration
+
+	// l1 is an in-process layer GetOrLoad consults before Redis, and sf
+	// coalesces concurrent GetOrLoad calls for the same URL onto a single
+	// L2 fetch (and, on a full miss, a single loader call). Neither is
+	// touched by the older GetMetadata/SetMetadata pair - those still go
+	// straight to Redis, same as before GetOrLoad existed.
+	l1 *LRUCache
+	sf *dedup.Singleflight
+
+	// codec encodes every entry SetMetadata(Batch) writes; see codec.go.
+	// GetMetadata(Batch) decodes with whatever codec the stored entry's
+	// header names, regardless of this field, so switching codec only
+	// affects newly-written entries.
+	codec Codec
+
+	stats distributedCacheStats
+}
+
+// distributedCacheStats are GetOrLoad's per-tier hit/miss counters,
+// surfaced via Stats(). sf.Stats()'s in_flight_calls shows GetOrLoad
+// fetches currently running; its coalesced_calls counter is only
+// incremented by DoWithCache, which GetOrLoad doesn't use, so it doesn't
+// count GetOrLoad callers that shared another call's result.
+type distributedCacheStats struct {
+	l1Hits       atomic.Uint64
+	l1Misses     atomic.Uint64
+	l2Hits       atomic.Uint64
+	l2Misses     atomic.Uint64
+	negativeHits atomic.Uint64
+	loaderCalls  atomic.Uint64
+	loaderErrors atomic.Uint64
 }
 
-// NewDistributedCache creates a new distributed cache instance
+// NewDistributedCache creates a new distributed cache instance. redisAddr
+// may be a bare host:port (DB 1 is assumed, as before this used
+// redisconn) or a full redisconn DSN with its own explicit db segment; see
+// internal/redisconn. The underlying *redis.Client is shared (via
+// redisconn.Default) with any other subsystem pointed at the same DSN.
 func NewDistributedCache(redisAddr string, logger *zap.Logger) (*DistributedCache, error) {
-	// Optimize Redis client with connection pooling
-	client := redis.NewClient(&redis.Options{
-		Addr:         redisAddr,
-		DB:           1,  // Use DB 1 for cache (DB 0 is for job queue)
-		PoolSize:     20, // Increased from default 10
-		MinIdleConns: 5,
-		MaxRetries:   3,
-		DialTimeout:  5 * time.Second,
-		ReadTimeout:  3 * time.Second,
-		WriteTimeout: 3 * time.Second,
-		PoolTimeout:  4 * time.Second,
-	})
+	opts, err := redisconn.ParseWithDefaultDB(redisAddr, 1)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis address: %w", err)
+	}
+
+	client, conn, err := redisconn.Default.GetClientWithOptions(opts, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+	}
 
 	// Test connection
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	if err := client.Ping(ctx).Err(); err != nil {
+		_ = conn.Close()
 		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
 	}
 
 	logger.Info("Distributed cache initialized with optimized connection pool",
 		zap.String("redis_addr", redisAddr),
-		zap.Int("pool_size", 20),
+		zap.Int("pool_size", opts.PoolSize),
 	)
 
 	return &DistributedCache{
 		client: client,
+		conn:   conn,
 		logger: logger,
 		ttl:    24 * time.Hour, // Cache for 24 hours
+		l1:     NewLRUCache(distributedL1MaxEntries),
+		sf:     dedup.NewSingleflight(),
+		codec:  jsonCodec{},
 	}, nil
 }
 
+// SetCodec changes the Codec used to encode entries written after this
+// call (GetMetadata decodes by the stored entry's own header regardless of
+// this setting, so existing entries remain readable either way).
+func (dc *DistributedCache) SetCodec(codec Codec) {
+	dc.codec = codec
+}
+
 // hashURL creates a SHA256 hash of the URL for use as cache key
 func (dc *DistributedCache) hashURL(url string) string {
 	hash := sha256.Sum256([]byte(url))
@@ -88,8 +153,11 @@ func (dc *DistributedCache) GetMetadata(ctx context.Context, url string) (*URLMe
 		return nil, err
 	}
 
-	var metadata URLMetadata
-	if err := json.Unmarshal(data, &metadata); err != nil {
+	metadata, err := decodeEntry(data)
+	if err != nil {
+		if err == ErrSchemaVersionMismatch {
+			return nil, nil // Stale schema - treat like a cache miss
+		}
 		dc.logger.Error("Failed to unmarshal cached metadata",
 			zap.String("url", url),
 			zap.Error(err),
@@ -97,7 +165,7 @@ func (dc *DistributedCache) GetMetadata(ctx context.Context, url string) (*URLMe
 		return nil, err
 	}
 
-	return &metadata, nil
+	return metadata, nil
 }
 
 // SetMetadata stores metadata for a URL in cache
@@ -106,7 +174,7 @@ func (dc *DistributedCache) SetMetadata(ctx context.Context, metadata *URLMetada
 
 	metadata.CachedAt = time.Now()
 
-	data, err := json.Marshal(metadata)
+	data, err := encodeEntry(dc.codec, metadata)
 	if err != nil {
 		dc.logger.Error("Failed to marshal metadata",
 			zap.String("url", metadata.URL),
@@ -138,71 +206,15 @@ func (dc *DistributedCache) InvalidateMetadata(ctx context.Context, url string)
 	return dc.client.Del(ctx, key).Err()
 }
 
-// IncrementDownloadCount tracks download counts per URL
-func (dc *DistributedCache) IncrementDownloadCount(ctx context.Context, url string) (int64, error) {
-	key := "url:count:" + dc.hashURL(url)
-	count, err := dc.client.Incr(ctx, key).Result()
-	if err != nil {
-		return 0, err
-	}
-
-	// Set expiry on first increment
-	if count == 1 {
-		dc.client.Expire(ctx, key, 30*24*time.Hour) // 30 days
-	}
-
-	return count, nil
-}
-
-// GetPopularURLs returns the most frequently downloaded URLs
-// Uses SCAN instead of KEYS for production safety (non-blocking)
-func (dc *DistributedCache) GetPopularURLs(ctx context.Context, limit int64) (map[string]int64, error) {
-	// This is a simplified version - in production, you'd use a sorted set
-	pattern := "url:count:*"
-
-	// Use SCAN instead of KEYS to avoid blocking Redis
-	var cursor uint64
-	var keys []string
-	for {
-		var batch []string
-		var err error
-		batch, cursor, err = dc.client.Scan(ctx, cursor, pattern, 100).Result()
-		if err != nil {
-			return nil, err
-		}
-		keys = append(keys, batch...)
-		if cursor == 0 {
-			break
-		}
-	}
-
-	result := make(map[string]int64)
-
-	// Use pipeline for efficient batch operations
-	pipe := dc.client.Pipeline()
-	cmds := make(map[string]*redis.StringCmd)
-
-	for _, key := range keys {
-		cmds[key] = pipe.Get(ctx, key)
-	}
-
-	_, err := pipe.Exec(ctx)
-	if err != nil && err != redis.Nil {
-		return nil, err
-	}
-
-	for key, cmd := range cmds {
-		count, err := cmd.Int64()
-		if err == nil {
-			result[key] = count
-		}
-	}
-
-	return result, nil
-}
-
-// Close closes the Redis connection
+// Close closes the Redis connection and stops GetOrLoad's singleflight
+// cleanup goroutine. The Redis connection itself is only actually closed
+// once every other holder of the shared client (e.g. a queue.Client on the
+// same DSN) has released its own reference.
 func (dc *DistributedCache) Close() error {
+	dc.sf.Close()
+	if dc.conn != nil {
+		return dc.conn.Close()
+	}
 	return dc.client.Close()
 }
 
@@ -243,8 +255,11 @@ func (dc *DistributedCache) GetMetadataBatch(ctx context.Context, urls []string)
 			continue
 		}
 
-		var metadata URLMetadata
-		if err := json.Unmarshal(data, &metadata); err != nil {
+		metadata, err := decodeEntry(data)
+		if err != nil {
+			if err == ErrSchemaVersionMismatch {
+				continue // Stale schema - treat like a cache miss
+			}
 			dc.logger.Error("Failed to unmarshal cached metadata",
 				zap.String("url", url),
 				zap.Error(err),
@@ -252,7 +267,7 @@ func (dc *DistributedCache) GetMetadataBatch(ctx context.Context, urls []string)
 			continue
 		}
 
-		result[url] = &metadata
+		result[url] = metadata
 	}
 
 	dc.logger.Debug("Batch metadata fetch completed",
@@ -276,7 +291,7 @@ func (dc *DistributedCache) SetMetadataBatch(ctx context.Context, metadataList [
 		key := "url:meta:" + dc.hashURL(metadata.URL)
 		metadata.CachedAt = time.Now()
 
-		data, err := json.Marshal(metadata)
+		data, err := encodeEntry(dc.codec, metadata)
 		if err != nil {
 			dc.logger.Error("Failed to marshal metadata",
 				zap.String("url", metadata.URL),
@@ -338,5 +353,17 @@ func (dc *DistributedCache) Stats(ctx context.Context) (map[string]interface{},
 		"total_conns":   poolStats.TotalConns,
 		"idle_conns":    poolStats.IdleConns,
 		"stale_conns":   poolStats.StaleConns,
+
+		// GetOrLoad's per-tier counters. sf.Stats()'s coalesced_calls
+		// counts DoWithCache callers only, not GetOrLoad's (see
+		// distributedCacheStats).
+		"getorload_l1_hits":       dc.stats.l1Hits.Load(),
+		"getorload_l1_misses":     dc.stats.l1Misses.Load(),
+		"getorload_l2_hits":       dc.stats.l2Hits.Load(),
+		"getorload_l2_misses":     dc.stats.l2Misses.Load(),
+		"getorload_negative_hits": dc.stats.negativeHits.Load(),
+		"getorload_loader_calls":  dc.stats.loaderCalls.Load(),
+		"getorload_loader_errors": dc.stats.loaderErrors.Load(),
+		"getorload_singleflight":  dc.sf.Stats(),
 	}, nil
 }
@@ -0,0 +1,96 @@
+package cache
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+// negativeMarker is what GetOrLoad stores in L1/L2 to remember a URL whose
+// loader resolved to "no metadata" (nil, nil) rather than an error -
+// distinct from a cache miss, which means "we haven't looked yet".
+type negativeMarker struct {
+	Negative bool `json:"negative"`
+}
+
+func negativeCacheKey(hash string) string {
+	return "url:negmeta:" + hash
+}
+
+// GetOrLoad returns metadata for url, checking the in-process L1 layer and
+// then Redis (L2) before calling loader on a full miss. Concurrent
+// GetOrLoad calls for the same url are coalesced via an internal
+// Singleflight so a burst only costs one L2 fetch and, on a miss, one
+// loader call - the other callers block and share that result instead of
+// repeating it.
+//
+// A loader that returns (nil, nil) means the URL was resolved but has no
+// metadata (e.g. it 404s); GetOrLoad remembers that as a negative result
+// for distributedNegativeTTL so repeated lookups of the same unresolvable
+// URL don't keep re-invoking loader. A loader error is never cached and
+// propagates to every caller coalesced onto that call.
+func (dc *DistributedCache) GetOrLoad(ctx context.Context, url string, loader func(ctx context.Context) (*URLMetadata, error)) (*URLMetadata, error) {
+	hash := dc.hashURL(url)
+
+	var fromL1 URLMetadata
+	if err := dc.l1.Get(ctx, hash, &fromL1); err == nil {
+		dc.stats.l1Hits.Add(1)
+		return &fromL1, nil
+	}
+	dc.stats.l1Misses.Add(1)
+
+	var neg negativeMarker
+	if err := dc.l1.Get(ctx, negativeCacheKey(hash), &neg); err == nil {
+		dc.stats.negativeHits.Add(1)
+		return nil, nil
+	}
+
+	result := dc.sf.DoContext(ctx, hash, func() (interface{}, error) {
+		return dc.loadThroughL2(ctx, url, hash, loader)
+	})
+	if result.Err != nil {
+		return nil, result.Err
+	}
+	if result.Val == nil {
+		return nil, nil
+	}
+	return result.Val.(*URLMetadata), nil
+}
+
+// loadThroughL2 is the body of GetOrLoad's singleflight call: check L2,
+// fall back to loader on an L2 miss, and backfill both layers with
+// whatever was found (including a negative result) before returning.
+func (dc *DistributedCache) loadThroughL2(ctx context.Context, url, hash string, loader func(ctx context.Context) (*URLMetadata, error)) (interface{}, error) {
+	if meta, err := dc.GetMetadata(ctx, url); err == nil && meta != nil {
+		dc.stats.l2Hits.Add(1)
+		_ = dc.l1.Set(ctx, hash, meta, distributedL1TTL)
+		return meta, nil
+	}
+	dc.stats.l2Misses.Add(1)
+
+	if exists, err := dc.client.Exists(ctx, negativeCacheKey(hash)).Result(); err == nil && exists > 0 {
+		dc.stats.negativeHits.Add(1)
+		_ = dc.l1.Set(ctx, negativeCacheKey(hash), negativeMarker{Negative: true}, distributedNegativeTTL)
+		return nil, nil
+	}
+
+	dc.stats.loaderCalls.Add(1)
+	meta, err := loader(ctx)
+	if err != nil {
+		dc.stats.loaderErrors.Add(1)
+		return nil, err
+	}
+	if meta == nil {
+		if err := dc.client.Set(ctx, negativeCacheKey(hash), true, distributedNegativeTTL).Err(); err != nil {
+			dc.logger.Warn("Failed to store negative metadata cache entry", zap.String("url", url), zap.Error(err))
+		}
+		_ = dc.l1.Set(ctx, negativeCacheKey(hash), negativeMarker{Negative: true}, distributedNegativeTTL)
+		return nil, nil
+	}
+
+	if err := dc.SetMetadata(ctx, meta); err != nil {
+		dc.logger.Warn("Failed to backfill L2 metadata cache after load", zap.String("url", url), zap.Error(err))
+	}
+	_ = dc.l1.Set(ctx, hash, meta, distributedL1TTL)
+	return meta, nil
+}
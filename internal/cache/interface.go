@@ -0,0 +1,38 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Cache is implemented by every backend in this package - CacheManager
+// (Redis), LRUCache (in-process), and TieredCache (L1 LRU + L2 Redis with
+// pub/sub invalidation). Depend on this instead of a concrete type when a
+// caller doesn't need a specific backend's extras, so swapping backends
+// (e.g. single-host deployments skipping Redis entirely) doesn't require
+// touching call sites.
+type Cache interface {
+	Get(ctx context.Context, key string, dest interface{}) error
+	Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+	Exists(ctx context.Context, key string) (bool, error)
+
+	// SetWithTags stores value like Set, additionally recording key under
+	// each of tags so InvalidateTag can drop every dependent entry at
+	// once without scanning the keyspace.
+	SetWithTags(ctx context.Context, key string, value interface{}, ttl time.Duration, tags ...string) error
+	// InvalidateTag deletes every key ever stored under tag via SetWithTags.
+	InvalidateTag(ctx context.Context, tag string) error
+
+	// GetOrLoad returns the cached value for key into dest, calling loader
+	// to populate the cache on a miss. Backends may serve a stale value
+	// while refreshing in the background instead of blocking the caller;
+	// see CacheManager.GetOrLoad for the concrete staleness policy.
+	GetOrLoad(ctx context.Context, key string, ttl time.Duration, dest interface{}, loader func(ctx context.Context) (interface{}, error)) error
+}
+
+var (
+	_ Cache = (*CacheManager)(nil)
+	_ Cache = (*LRUCache)(nil)
+	_ Cache = (*TieredCache)(nil)
+)
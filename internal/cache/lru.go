@@ -0,0 +1,177 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// LRUCache is an in-process Cache backend - no network hop, but entries
+// don't survive a restart and aren't shared across instances. Suited to
+// single-host deployments, or as the L1 layer inside TieredCache.
+type LRUCache struct {
+	maxEntries int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+
+	tagMu sync.Mutex
+	tags  map[string]map[string]struct{} // tag -> set of keys
+}
+
+type lruEntry struct {
+	key      string
+	value    json.RawMessage
+	expireAt time.Time
+}
+
+// NewLRUCache creates an LRUCache holding at most maxEntries.
+func NewLRUCache(maxEntries int) *LRUCache {
+	return &LRUCache{
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+		tags:       make(map[string]map[string]struct{}),
+	}
+}
+
+// Get returns the cached value for key into dest, or ErrCacheMiss if
+// absent or expired.
+func (c *LRUCache) Get(ctx context.Context, key string, dest interface{}) error {
+	c.mu.Lock()
+	elem, ok := c.entries[key]
+	if !ok {
+		c.mu.Unlock()
+		return ErrCacheMiss
+	}
+	entry := elem.Value.(*lruEntry)
+	if time.Now().After(entry.expireAt) {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+		c.mu.Unlock()
+		return ErrCacheMiss
+	}
+	c.order.MoveToFront(elem)
+	value := entry.value
+	c.mu.Unlock()
+
+	return json.Unmarshal(value, dest)
+}
+
+// Set stores (or overwrites) value for key, evicting the least-recently-used
+// entry once maxEntries is exceeded.
+func (c *LRUCache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal value: %w", err)
+	}
+	if ttl <= 0 {
+		ttl = time.Hour
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		c.order.MoveToFront(elem)
+		entry := elem.Value.(*lruEntry)
+		entry.value = data
+		entry.expireAt = time.Now().Add(ttl)
+		return nil
+	}
+
+	elem := c.order.PushFront(&lruEntry{key: key, value: data, expireAt: time.Now().Add(ttl)})
+	c.entries[key] = elem
+
+	for c.order.Len() > c.maxEntries {
+		back := c.order.Back()
+		if back == nil {
+			break
+		}
+		c.order.Remove(back)
+		delete(c.entries, back.Value.(*lruEntry).key)
+	}
+	return nil
+}
+
+// Delete removes key, if present.
+func (c *LRUCache) Delete(ctx context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+	}
+	return nil
+}
+
+// Exists reports whether key is present and unexpired.
+func (c *LRUCache) Exists(ctx context.Context, key string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		return false, nil
+	}
+	return time.Now().Before(elem.Value.(*lruEntry).expireAt), nil
+}
+
+// SetWithTags stores value like Set, additionally recording key under
+// each of tags so InvalidateTag can drop them all at once.
+func (c *LRUCache) SetWithTags(ctx context.Context, key string, value interface{}, ttl time.Duration, tags ...string) error {
+	if err := c.Set(ctx, key, value, ttl); err != nil {
+		return err
+	}
+
+	c.tagMu.Lock()
+	defer c.tagMu.Unlock()
+	for _, tag := range tags {
+		if c.tags[tag] == nil {
+			c.tags[tag] = make(map[string]struct{})
+		}
+		c.tags[tag][key] = struct{}{}
+	}
+	return nil
+}
+
+// InvalidateTag deletes every key ever stored under tag via SetWithTags.
+func (c *LRUCache) InvalidateTag(ctx context.Context, tag string) error {
+	c.tagMu.Lock()
+	keys := c.tags[tag]
+	delete(c.tags, tag)
+	c.tagMu.Unlock()
+
+	for key := range keys {
+		_ = c.Delete(ctx, key)
+	}
+	return nil
+}
+
+// GetOrLoad returns the cached value for key, loading and caching it via
+// loader on a miss. LRUCache doesn't implement stale-while-revalidate -
+// there's no point backgrounding a refresh for a process-local cache that
+// a concurrent request is about to hit again in-process anyway - so a
+// miss always loads synchronously.
+func (c *LRUCache) GetOrLoad(ctx context.Context, key string, ttl time.Duration, dest interface{}, loader func(ctx context.Context) (interface{}, error)) error {
+	if err := c.Get(ctx, key, dest); err == nil {
+		return nil
+	}
+
+	value, err := loader(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load value for %s: %w", key, err)
+	}
+	if err := c.Set(ctx, key, value, ttl); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dest)
+}
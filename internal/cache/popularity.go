@@ -0,0 +1,259 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// popularSetKey is the ZSET tracking every URL ever passed to
+// IncrementDownloadCount, scored by a time-decayed download count rather
+// than a raw total - see popularityIncrScript.
+const popularSetKey = "url:popular"
+
+// popularMetaKey is a hash of member -> unix seconds of its last
+// popularSetKey update, so popularityIncrScript and DecayPopularity know
+// how much time has elapsed since a member's score was last decayed.
+const popularMetaKey = "url:popular:meta"
+
+// popularHourlyPrefix namespaces GetTrending's rolling per-hour buckets,
+// e.g. "url:popular:hour:2024010115" for 2024-01-01 15:00 UTC.
+const popularHourlyPrefix = "url:popular:hour:"
+
+// popularHourlyTTL bounds how long an hourly bucket survives - long enough
+// to cover any window GetTrending is realistically asked for.
+const popularHourlyTTL = 7 * 24 * time.Hour
+
+// popularDecayHalfLife is how long it takes a download count's
+// contribution to popularSetKey's score to fall to half its value, absent
+// further downloads. Chosen so a URL popular a week ago has mostly faded
+// by now, letting newly-trending URLs overtake it without waiting a month.
+const popularDecayHalfLife = 7 * 24 * time.Hour
+
+// popularDecayLambda is the exponential decay constant derived from
+// popularDecayHalfLife: score(t) = score(0) * exp(-lambda * t).
+var popularDecayLambda = math.Ln2 / popularDecayHalfLife.Seconds()
+
+// popularityIncrScript atomically decays a member's existing popularSetKey
+// score to account for elapsed time since its last update, adds weight,
+// and records the new score and timestamp - all in one round trip so
+// concurrent IncrementDownloadCount calls for different URLs never race
+// on read-decay-write.
+//
+// now_s and lambda come from Go (ARGV) rather than Redis's own clock,
+// matching internal/middleware.tokenBucketScript's convention of passing
+// time in explicitly rather than trusting TIME inside the script.
+var popularityIncrScript = redis.NewScript(`
+local zkey = KEYS[1]
+local metakey = KEYS[2]
+local member = ARGV[1]
+local lambda = tonumber(ARGV[2])
+local now_s = tonumber(ARGV[3])
+local weight = tonumber(ARGV[4])
+
+local old_score = tonumber(redis.call("ZSCORE", zkey, member))
+local last_ts = tonumber(redis.call("HGET", metakey, member))
+
+local decayed = 0
+if old_score ~= nil and last_ts ~= nil then
+	local dt = now_s - last_ts
+	if dt < 0 then dt = 0 end
+	decayed = old_score * math.exp(-lambda * dt)
+end
+
+local new_score = decayed + weight
+redis.call("ZADD", zkey, new_score, member)
+redis.call("HSET", metakey, member, now_s)
+return tostring(new_score)
+`)
+
+// hourBucketKey returns the rolling hourly bucket GetTrending sums over
+// for t.
+func hourBucketKey(t time.Time) string {
+	return popularHourlyPrefix + t.UTC().Format("2006010215")
+}
+
+// IncrementDownloadCount records a download of url, both in the
+// time-decayed popularSetKey ranking (see GetPopularURLs) and in the
+// current rolling hourly bucket (see GetTrending). Returns the URL's new
+// decayed popularity score, which has replaced the plain running total
+// this used to return - GetPopularURLs no longer keeps a separate raw
+// counter to read it from.
+func (dc *DistributedCache) IncrementDownloadCount(ctx context.Context, url string) (float64, error) {
+	now := time.Now()
+
+	res, err := popularityIncrScript.Run(ctx, dc.client,
+		[]string{popularSetKey, popularMetaKey},
+		url, popularDecayLambda, float64(now.Unix()), 1,
+	).Text()
+	if err != nil {
+		return 0, fmt.Errorf("failed to update popularity score: %w", err)
+	}
+	score, err := parseScore(res)
+	if err != nil {
+		return 0, err
+	}
+
+	bucketKey := hourBucketKey(now)
+	pipe := dc.client.Pipeline()
+	pipe.ZIncrBy(ctx, bucketKey, 1, url)
+	pipe.Expire(ctx, bucketKey, popularHourlyTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		dc.logger.Warn("Failed to update trending bucket", zap.String("url", url), zap.Error(err))
+	}
+
+	return score, nil
+}
+
+// GetPopularURLs returns the limit highest time-decayed popularity scores
+// via a single ZREVRANGE WITHSCORES call - O(log N + limit) rather than
+// the SCAN-and-pipeline-GET sweep this used to do over one key per URL.
+func (dc *DistributedCache) GetPopularURLs(ctx context.Context, limit int64) (map[string]float64, error) {
+	entries, err := dc.client.ZRevRangeWithScores(ctx, popularSetKey, 0, limit-1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read popularity ranking: %w", err)
+	}
+
+	result := make(map[string]float64, len(entries))
+	for _, z := range entries {
+		url, ok := z.Member.(string)
+		if !ok {
+			continue
+		}
+		result[url] = z.Score
+	}
+	return result, nil
+}
+
+// GetTrending returns the limit URLs with the most downloads within the
+// last window, read from a ZUNIONSTORE of window's rolling hourly buckets
+// rather than popularSetKey's all-time decayed ranking - so a URL that
+// just started spiking outranks one that was popular last month but has
+// gone quiet, even before popularSetKey's own decay has caught up.
+func (dc *DistributedCache) GetTrending(ctx context.Context, window time.Duration, limit int) (map[string]float64, error) {
+	hours := int(window / time.Hour)
+	if window%time.Hour != 0 {
+		hours++
+	}
+	if hours < 1 {
+		hours = 1
+	}
+
+	now := time.Now()
+	buckets := make([]string, hours)
+	for i := 0; i < hours; i++ {
+		buckets[i] = hourBucketKey(now.Add(-time.Duration(i) * time.Hour))
+	}
+
+	unionKey := fmt.Sprintf("url:popular:trending:%d", now.Unix())
+	if err := dc.client.ZUnionStore(ctx, unionKey, &redis.ZStore{Keys: buckets}).Err(); err != nil {
+		return nil, fmt.Errorf("failed to union trending buckets: %w", err)
+	}
+	// unionKey is scratch space for this one call - drop it once read
+	// rather than leaving it to expire on its own.
+	defer dc.client.Del(context.Background(), unionKey)
+	dc.client.Expire(ctx, unionKey, time.Minute)
+
+	entries, err := dc.client.ZRevRangeWithScores(ctx, unionKey, 0, int64(limit-1)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trending ranking: %w", err)
+	}
+
+	result := make(map[string]float64, len(entries))
+	for _, z := range entries {
+		url, ok := z.Member.(string)
+		if !ok {
+			continue
+		}
+		result[url] = z.Score
+	}
+	return result, nil
+}
+
+// DecayPopularity rewrites every popularSetKey member's score to account
+// for elapsed time since it was last touched, via ZRANGEBYSCORE + ZADD as
+// described in the request this implements - unlike
+// popularityIncrScript's lazy per-member decay on write, this is what
+// keeps a URL that was popular once but has had zero downloads since from
+// sitting at an inflated score forever. Members that decay below
+// popularPruneThreshold are dropped outright to keep the ZSET from
+// growing unbounded with long-dead URLs. Intended to run periodically
+// (e.g. hourly) from a scheduled job, the same way
+// cleanup.TempFileCleanup's ticker drives its own periodic pass.
+func (dc *DistributedCache) DecayPopularity(ctx context.Context) (int, error) {
+	const popularPruneThreshold = 0.01
+
+	entries, err := dc.client.ZRangeByScoreWithScores(ctx, popularSetKey, &redis.ZRangeBy{
+		Min: "-inf", Max: "+inf",
+	}).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read popularity set for decay: %w", err)
+	}
+	if len(entries) == 0 {
+		return 0, nil
+	}
+
+	now := time.Now().Unix()
+	members := make([]string, 0, len(entries))
+	for _, z := range entries {
+		if url, ok := z.Member.(string); ok {
+			members = append(members, url)
+		}
+	}
+	lastTimestamps, err := dc.client.HMGet(ctx, popularMetaKey, members...).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read popularity timestamps for decay: %w", err)
+	}
+
+	pipe := dc.client.Pipeline()
+	pruned := 0
+	for i, z := range entries {
+		url, ok := z.Member.(string)
+		if !ok {
+			continue
+		}
+		lastTs := now
+		if raw, ok := lastTimestamps[i].(string); ok {
+			if parsed, err := parseScore(raw); err == nil {
+				lastTs = int64(parsed)
+			}
+		}
+		dt := float64(now - lastTs)
+		if dt < 0 {
+			dt = 0
+		}
+		decayed := z.Score * math.Exp(-popularDecayLambda*dt)
+
+		if decayed < popularPruneThreshold {
+			pipe.ZRem(ctx, popularSetKey, url)
+			pipe.HDel(ctx, popularMetaKey, url)
+			pruned++
+			continue
+		}
+		pipe.ZAdd(ctx, popularSetKey, redis.Z{Score: decayed, Member: url})
+		pipe.HSet(ctx, popularMetaKey, url, now)
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("failed to apply popularity decay: %w", err)
+	}
+
+	dc.logger.Info("Decayed popularity ranking",
+		zap.Int("members", len(entries)),
+		zap.Int("pruned", pruned),
+	)
+	return pruned, nil
+}
+
+func parseScore(s string) (float64, error) {
+	var f float64
+	_, err := fmt.Sscanf(s, "%g", &f)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse score %q: %w", s, err)
+	}
+	return f, nil
+}
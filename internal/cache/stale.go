@@ -0,0 +1,103 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// cacheEnvelope wraps a GetOrLoad value with enough metadata to decide
+// when it's stale-but-servable vs. needing a synchronous reload.
+type cacheEnvelope struct {
+	Value    json.RawMessage `json:"value"`
+	StoredAt time.Time       `json:"stored_at"`
+	TTL      time.Duration   `json:"ttl"`
+}
+
+// staleFraction is how much of an envelope's TTL must remain before it's
+// considered stale - e.g. 0.2 means a background refresh starts once 80%
+// of the TTL has elapsed, while the old value keeps serving in the
+// meantime.
+const staleFraction = 0.2
+
+func (e cacheEnvelope) isStale() bool {
+	if e.TTL <= 0 {
+		return false
+	}
+	remaining := e.TTL - time.Since(e.StoredAt)
+	return remaining < time.Duration(float64(e.TTL)*staleFraction)
+}
+
+func envelopeKey(prefix, key string) string {
+	return prefix + "envelope:" + key
+}
+
+// GetOrLoad returns the cached value for key into dest, calling loader to
+// populate the cache on a miss. Once a cached value crosses into its
+// stale window (the last staleFraction of its TTL), GetOrLoad still
+// returns it immediately and kicks off a background refresh via loader
+// instead of blocking the caller - this fits extraction metadata well,
+// where a slightly-stale title/duration beats a synchronous yt-dlp re-run
+// on every request.
+func (cm *CacheManager) GetOrLoad(ctx context.Context, key string, ttl time.Duration, dest interface{}, loader func(ctx context.Context) (interface{}, error)) error {
+	raw, err := cm.client.Get(ctx, envelopeKey(cm.prefix, key)).Bytes()
+	if err == nil {
+		var envelope cacheEnvelope
+		if jsonErr := json.Unmarshal(raw, &envelope); jsonErr == nil {
+			if jsonErr := json.Unmarshal(envelope.Value, dest); jsonErr == nil {
+				if envelope.isStale() {
+					go cm.refresh(key, ttl, loader)
+				}
+				return nil
+			}
+		}
+	}
+
+	// Miss (or an unreadable entry) - load synchronously.
+	value, err := loader(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load value for %s: %w", key, err)
+	}
+	if err := cm.storeEnvelope(ctx, key, ttl, value); err != nil {
+		cm.logger.Warn("Failed to store GetOrLoad envelope", zap.String("key", key), zap.Error(err))
+	}
+
+	valueJSON, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal loaded value for %s: %w", key, err)
+	}
+	return json.Unmarshal(valueJSON, dest)
+}
+
+// refresh reloads key in the background after GetOrLoad served a stale
+// value. It uses a fresh context rather than the original request's,
+// since that one may already be canceled by the time this goroutine runs.
+func (cm *CacheManager) refresh(key string, ttl time.Duration, loader func(ctx context.Context) (interface{}, error)) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	value, err := loader(ctx)
+	if err != nil {
+		cm.logger.Warn("Background cache refresh failed, keeping stale value", zap.String("key", key), zap.Error(err))
+		return
+	}
+	if err := cm.storeEnvelope(ctx, key, ttl, value); err != nil {
+		cm.logger.Warn("Failed to store refreshed cache envelope", zap.String("key", key), zap.Error(err))
+	}
+}
+
+func (cm *CacheManager) storeEnvelope(ctx context.Context, key string, ttl time.Duration, value interface{}) error {
+	valueJSON, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	envelope := cacheEnvelope{Value: valueJSON, StoredAt: time.Now(), TTL: ttl}
+	data, err := json.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+	return cm.client.Set(ctx, envelopeKey(cm.prefix, key), data, ttl).Err()
+}
@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// tagSetKey returns the Redis key of the set tracking every key stored
+// under tag via SetWithTags.
+func tagSetKey(tag string) string {
+	return "tag:" + tag
+}
+
+// SetWithTags stores value like Set, additionally recording key under
+// each of tags' member sets so InvalidateTag can drop every dependent
+// entry in one pipelined UNLINK instead of a KEYS/SCAN sweep.
+func (cm *CacheManager) SetWithTags(ctx context.Context, key string, value interface{}, ttl time.Duration, tags ...string) error {
+	if err := cm.Set(ctx, key, value, ttl); err != nil {
+		return err
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+
+	fullKey := cm.prefix + key
+	pipe := cm.client.Pipeline()
+	for _, tag := range tags {
+		pipe.SAdd(ctx, cm.prefix+tagSetKey(tag), fullKey)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		cm.logger.Warn("Failed to record cache tags", zap.String("key", key), zap.Error(err))
+		return fmt.Errorf("failed to record cache tags: %w", err)
+	}
+	return nil
+}
+
+// InvalidateTag drops every key ever stored under tag via SetWithTags, in
+// one pipelined UNLINK (non-blocking delete) rather than scanning the
+// keyspace.
+func (cm *CacheManager) InvalidateTag(ctx context.Context, tag string) error {
+	tagKey := cm.prefix + tagSetKey(tag)
+
+	members, err := cm.client.SMembers(ctx, tagKey).Result()
+	if err != nil {
+		return fmt.Errorf("failed to list tag members: %w", err)
+	}
+	if len(members) == 0 {
+		return cm.client.Unlink(ctx, tagKey).Err()
+	}
+
+	pipe := cm.client.Pipeline()
+	pipe.Unlink(ctx, members...)
+	pipe.Unlink(ctx, tagKey)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to invalidate tag %s: %w", tag, err)
+	}
+
+	cm.logger.Debug("Invalidated cache tag", zap.String("tag", tag), zap.Int("keys", len(members)))
+	return nil
+}
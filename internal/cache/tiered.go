@@ -0,0 +1,155 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// invalidationChannel is the Redis pub/sub channel TieredCache instances use
+// to tell each other's L1 to drop a key that one of them just wrote or
+// deleted, the same way internal/queue publishes per-job progress events.
+const invalidationChannel = "cache:invalidate"
+
+// TieredCache layers an in-process LRUCache (L1) in front of a Redis-backed
+// CacheManager (L2). Reads check L1 first to skip the network hop on a hit;
+// writes go to both, and fan out a pub/sub invalidation so sibling instances
+// drop their own stale L1 copy instead of serving it until its TTL expires.
+type TieredCache struct {
+	l1     *LRUCache
+	l2     *CacheManager
+	redis  *redis.Client
+	logger *zap.Logger
+}
+
+// NewTieredCache creates a TieredCache with an L1 of up to l1MaxEntries
+// backed by l2. It spawns a goroutine listening for invalidations published
+// by other instances; callers don't need to stop it explicitly - it exits
+// when the subscription's connection is closed along with l2.
+func NewTieredCache(l1MaxEntries int, l2 *CacheManager, logger *zap.Logger) *TieredCache {
+	tc := &TieredCache{
+		l1:     NewLRUCache(l1MaxEntries),
+		l2:     l2,
+		redis:  l2.GetRedis(),
+		logger: logger,
+	}
+	go tc.listenForInvalidations()
+	return tc
+}
+
+func (tc *TieredCache) listenForInvalidations() {
+	ctx := context.Background()
+	pubsub := tc.redis.Subscribe(ctx, invalidationChannel)
+	defer pubsub.Close()
+
+	if _, err := pubsub.Receive(ctx); err != nil {
+		tc.logger.Warn("Failed to subscribe to cache invalidation channel", zap.Error(err))
+		return
+	}
+
+	for msg := range pubsub.Channel() {
+		_ = tc.l1.Delete(ctx, msg.Payload)
+	}
+}
+
+func (tc *TieredCache) publishInvalidation(ctx context.Context, key string) {
+	if err := tc.redis.Publish(ctx, invalidationChannel, key).Err(); err != nil {
+		tc.logger.Warn("Failed to publish cache invalidation", zap.String("key", key), zap.Error(err))
+	}
+}
+
+// Get checks L1 first, falling back to L2 on a miss and populating L1 with
+// whatever L2 returns so the next Get for key is local.
+func (tc *TieredCache) Get(ctx context.Context, key string, dest interface{}) error {
+	if err := tc.l1.Get(ctx, key, dest); err == nil {
+		return nil
+	}
+
+	if err := tc.l2.Get(ctx, key, dest); err != nil {
+		return err
+	}
+
+	ttl, err := tc.l2.GetTTL(ctx, key)
+	if err != nil || ttl <= 0 {
+		ttl = time.Minute
+	}
+	_ = tc.l1.Set(ctx, key, dest, ttl)
+	return nil
+}
+
+// Set writes to both L1 and L2, then tells sibling instances to drop their
+// own L1 copy of key rather than serving it until its TTL expires.
+func (tc *TieredCache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	if err := tc.l2.Set(ctx, key, value, ttl); err != nil {
+		return err
+	}
+	if err := tc.l1.Set(ctx, key, value, ttl); err != nil {
+		return err
+	}
+	tc.publishInvalidation(ctx, key)
+	return nil
+}
+
+// Delete removes key from both layers and notifies siblings.
+func (tc *TieredCache) Delete(ctx context.Context, key string) error {
+	if err := tc.l2.Delete(ctx, key); err != nil {
+		return err
+	}
+	_ = tc.l1.Delete(ctx, key)
+	tc.publishInvalidation(ctx, key)
+	return nil
+}
+
+// Exists checks L1 first, then falls back to L2.
+func (tc *TieredCache) Exists(ctx context.Context, key string) (bool, error) {
+	if ok, err := tc.l1.Exists(ctx, key); err == nil && ok {
+		return true, nil
+	}
+	return tc.l2.Exists(ctx, key)
+}
+
+// SetWithTags stores value in both layers under tags, so InvalidateTag can
+// be called against either layer's own tag bookkeeping. L1's tag set only
+// knows about this instance's writes, so tag invalidation is only complete
+// when called on the TieredCache as a whole (see InvalidateTag) rather than
+// reaching into a single layer directly.
+func (tc *TieredCache) SetWithTags(ctx context.Context, key string, value interface{}, ttl time.Duration, tags ...string) error {
+	if err := tc.l2.SetWithTags(ctx, key, value, ttl, tags...); err != nil {
+		return err
+	}
+	if err := tc.l1.SetWithTags(ctx, key, value, ttl, tags...); err != nil {
+		return err
+	}
+	tc.publishInvalidation(ctx, key)
+	return nil
+}
+
+// InvalidateTag drops every key stored under tag from L2 (the authoritative
+// set of tagged members across all instances) and from this instance's own
+// L1. Other instances' L1 copies age out via their own TTL; keys are
+// content-addressed by whatever the caller uses as the cache key, so a
+// short-lived staleness window here is the same tradeoff the repo already
+// accepts for L1 hits in Get.
+func (tc *TieredCache) InvalidateTag(ctx context.Context, tag string) error {
+	if err := tc.l2.InvalidateTag(ctx, tag); err != nil {
+		return err
+	}
+	return tc.l1.InvalidateTag(ctx, tag)
+}
+
+// GetOrLoad checks L1, then L2 (which applies its own stale-while-revalidate
+// policy), populating L1 on either path so the next call for key is local.
+func (tc *TieredCache) GetOrLoad(ctx context.Context, key string, ttl time.Duration, dest interface{}, loader func(ctx context.Context) (interface{}, error)) error {
+	if err := tc.l1.Get(ctx, key, dest); err == nil {
+		return nil
+	}
+
+	if err := tc.l2.GetOrLoad(ctx, key, ttl, dest, loader); err != nil {
+		return fmt.Errorf("tiered cache load failed for %s: %w", key, err)
+	}
+	_ = tc.l1.Set(ctx, key, dest, ttl)
+	return nil
+}
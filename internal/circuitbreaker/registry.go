@@ -0,0 +1,317 @@
+package circuitbreaker
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/KeremKalyoncu/MedYan/internal/metrics"
+)
+
+// BreakerConfig controls every Breaker a Registry creates.
+type BreakerConfig struct {
+	// BucketCount and BucketWidth size the sliding window error rate is
+	// computed over, e.g. 10 * time.Second = a 10-second window.
+	BucketCount int
+	BucketWidth time.Duration
+	// MinRequests is the minimum requests observed in the window before
+	// ErrorRateThreshold is even considered - otherwise a single failed
+	// request on a cold breaker would trip it.
+	MinRequests int
+	// ErrorRateThreshold is the fraction of failed requests (per IsFailure)
+	// in the window that trips the breaker open.
+	ErrorRateThreshold float64
+	// OpenTimeout is how long a breaker stays open before allowing a
+	// half-open probe.
+	OpenTimeout time.Duration
+	// HalfOpenMaxRequests is both how many probe requests a half-open
+	// breaker allows concurrently and how many consecutive successes close
+	// it again.
+	HalfOpenMaxRequests uint32
+	// IsFailure classifies fn's returned error as a breaker-relevant
+	// failure or not - e.g. DefaultIsFailure treats a cancelled context or
+	// a plain 404 as not the upstream's fault. Defaults to DefaultIsFailure
+	// if nil.
+	IsFailure func(err error) bool
+}
+
+// DefaultBreakerConfig returns the settings used when a Registry isn't
+// given an explicit BreakerConfig: a 10x1s sliding window, tripping at 50%
+// errors with 10+ requests observed, 30s open before probing, 3 successful
+// half-open probes to close again.
+func DefaultBreakerConfig() BreakerConfig {
+	return BreakerConfig{
+		BucketCount:         10,
+		BucketWidth:         time.Second,
+		MinRequests:         10,
+		ErrorRateThreshold:  0.5,
+		OpenTimeout:         30 * time.Second,
+		HalfOpenMaxRequests: 3,
+		IsFailure:           DefaultIsFailure,
+	}
+}
+
+// Breaker is one (host, operation) circuit, created lazily by a Registry.
+// Unlike CircuitBreaker's Counts (consecutive streak or since-inception
+// ratio), it trips on error rate over a slidingWindow, so a host that was
+// unhealthy a minute ago but has since recovered isn't punished forever by
+// an old burst of failures.
+type Breaker struct {
+	name   string
+	config BreakerConfig
+	logger *zap.Logger
+	window *slidingWindow
+
+	mu                sync.Mutex
+	state             State
+	expiry            time.Time
+	halfOpenInFlight  uint32
+	halfOpenSuccesses uint32
+}
+
+func newBreaker(name string, config BreakerConfig, logger *zap.Logger) *Breaker {
+	return &Breaker{
+		name:   name,
+		config: config,
+		logger: logger,
+		window: newSlidingWindow(config.BucketCount, config.BucketWidth),
+	}
+}
+
+// Name returns the breaker's registry key, e.g. "youtube.com:extract".
+func (b *Breaker) Name() string {
+	return b.name
+}
+
+// State returns the breaker's current state.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// Execute runs fn if the breaker currently allows it, classifying fn's
+// returned error via config.IsFailure to decide whether this counts as a
+// window failure. Returns ErrCircuitOpen or ErrTooManyRequests (fn is never
+// called) when the breaker is rejecting requests.
+func (b *Breaker) Execute(ctx context.Context, fn func() error) error {
+	if err := b.beforeRequest(); err != nil {
+		metrics.GetMetrics().RecordCircuitRejected(b.name)
+		return err
+	}
+
+	select {
+	case <-ctx.Done():
+		b.afterRequest(!b.isFailure(ctx.Err()), time.Now())
+		return ctx.Err()
+	default:
+	}
+
+	err := fn()
+	b.afterRequest(!b.isFailure(err), time.Now())
+	return err
+}
+
+func (b *Breaker) isFailure(err error) bool {
+	if b.config.IsFailure != nil {
+		return b.config.IsFailure(err)
+	}
+	return DefaultIsFailure(err)
+}
+
+func (b *Breaker) beforeRequest() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	if b.state == StateOpen {
+		if now.Before(b.expiry) {
+			return ErrCircuitOpen
+		}
+		b.setState(StateHalfOpen, now)
+	}
+
+	if b.state == StateHalfOpen {
+		if b.halfOpenInFlight >= b.config.HalfOpenMaxRequests {
+			return ErrTooManyRequests
+		}
+		b.halfOpenInFlight++
+	}
+	return nil
+}
+
+func (b *Breaker) afterRequest(success bool, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == StateHalfOpen {
+		if b.halfOpenInFlight > 0 {
+			b.halfOpenInFlight--
+		}
+		if !success {
+			b.setState(StateOpen, now)
+			return
+		}
+		b.halfOpenSuccesses++
+		if b.halfOpenSuccesses >= b.config.HalfOpenMaxRequests {
+			b.window.reset()
+			b.setState(StateClosed, now)
+		}
+		return
+	}
+
+	b.window.record(success, now)
+	if b.state == StateClosed {
+		total, fail := b.window.snapshot(now)
+		if total >= uint64(b.config.MinRequests) && float64(fail)/float64(total) >= b.config.ErrorRateThreshold {
+			b.setState(StateOpen, now)
+		}
+	}
+}
+
+// setState must be called with b.mu held.
+func (b *Breaker) setState(state State, now time.Time) {
+	if b.state == state {
+		return
+	}
+	prev := b.state
+	b.state = state
+	b.halfOpenInFlight = 0
+	b.halfOpenSuccesses = 0
+
+	switch state {
+	case StateOpen:
+		b.expiry = now.Add(b.config.OpenTimeout)
+		metrics.GetMetrics().RecordCircuitTrip(b.name)
+	}
+
+	metrics.GetMetrics().SetCircuitState(b.name, float64(state))
+	if b.logger != nil {
+		b.logger.Warn("Circuit breaker state changed",
+			zap.String("name", b.name),
+			zap.String("from", prev.String()),
+			zap.String("to", state.String()),
+		)
+	}
+}
+
+// Registry lazily creates and reaps Breakers keyed by an arbitrary string -
+// typically "host:operation" via GetFor - so a flaky platform trips only
+// its own breaker instead of every host sharing one CircuitBreaker.
+type Registry struct {
+	mu       sync.Mutex
+	breakers map[string]*Breaker
+	lastUsed map[string]time.Time
+	config   BreakerConfig
+	logger   *zap.Logger
+	idleTTL  time.Duration
+	closeCh  chan struct{}
+}
+
+// NewRegistry creates a Registry. Breakers not used for idleTTL (fixed at
+// 10 minutes) while Closed are reaped by Start's background loop,
+// so a process that talks to many short-lived hosts doesn't accumulate one
+// Breaker per hostname forever.
+func NewRegistry(config BreakerConfig, logger *zap.Logger) *Registry {
+	if config.BucketCount == 0 {
+		config = DefaultBreakerConfig()
+	}
+	if config.IsFailure == nil {
+		config.IsFailure = DefaultIsFailure
+	}
+	return &Registry{
+		breakers: make(map[string]*Breaker),
+		lastUsed: make(map[string]time.Time),
+		config:   config,
+		logger:   logger,
+		idleTTL:  10 * time.Minute,
+		closeCh:  make(chan struct{}),
+	}
+}
+
+// Get lazily creates (on first call) and returns the Breaker for key.
+func (r *Registry) Get(key string) *Breaker {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	b, ok := r.breakers[key]
+	if !ok {
+		b = newBreaker(key, r.config, r.logger)
+		r.breakers[key] = b
+	}
+	r.lastUsed[key] = time.Now()
+	return b
+}
+
+// GetFor is Get keyed by host and operation, e.g.
+// GetFor("youtube.com", "extract") - the shape the request this
+// implements names explicitly.
+func (r *Registry) GetFor(host, operation string) *Breaker {
+	return r.Get(host + ":" + operation)
+}
+
+// Start begins the background reaper. Safe to skip if a Registry is used
+// only for a short-lived process where unbounded breaker growth isn't a
+// concern.
+func (r *Registry) Start() {
+	go r.reapLoop()
+}
+
+// Stop halts the background reaper. Existing Breakers remain usable.
+func (r *Registry) Stop() {
+	close(r.closeCh)
+}
+
+func (r *Registry) reapLoop() {
+	interval := r.idleTTL / 2
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.reap()
+		case <-r.closeCh:
+			return
+		}
+	}
+}
+
+// reap drops every Closed breaker untouched for idleTTL. Open/half-open
+// breakers are left alone even if idle - reaping one mid-recovery would
+// just mean re-tripping on the next burst of requests instead of honoring
+// the probe it's already in.
+func (r *Registry) reap() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for key, b := range r.breakers {
+		if b.State() != StateClosed {
+			continue
+		}
+		if now.Sub(r.lastUsed[key]) < r.idleTTL {
+			continue
+		}
+		delete(r.breakers, key)
+		delete(r.lastUsed, key)
+	}
+}
+
+// Snapshot returns every currently-tracked breaker's state, for a debug/
+// stats endpoint.
+func (r *Registry) Snapshot() map[string]State {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := make(map[string]State, len(r.breakers))
+	for key, b := range r.breakers {
+		out[key] = b.State()
+	}
+	return out
+}
@@ -0,0 +1,89 @@
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// HTTPStatusError represents a non-2xx HTTP response surfaced to a
+// Breaker's IsFailure classifier - constructing it from a status code
+// rather than passing the *http.Response itself keeps IsFailure's
+// signature a plain func(error) bool, usable outside an HTTP context too.
+type HTTPStatusError struct {
+	StatusCode int
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("http status %d", e.StatusCode)
+}
+
+// DefaultIsFailure is the BreakerConfig.IsFailure used when none is given.
+// A cancelled context means the caller gave up, not that the upstream is
+// unhealthy, so it isn't counted. A 429 or 5xx genuinely indicates the
+// upstream is struggling; any other 4xx is this side's problem (bad URL,
+// auth, etc.) and shouldn't trip a breaker meant to track upstream health.
+// Everything else (DNS failures, connection resets, timeouts) is presumed
+// a real failure.
+func DefaultIsFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var statusErr *HTTPStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode >= 500
+	}
+
+	return true
+}
+
+// roundTripper protects next with a per-(host, operation) Breaker from
+// registry.
+type roundTripper struct {
+	registry  *Registry
+	operation string
+	next      http.RoundTripper
+}
+
+// WrapRoundTripper returns an http.RoundTripper that runs every request
+// through registry's Breaker for (request host, operation) before handing
+// it to next, rejecting with ErrCircuitOpen/ErrTooManyRequests when that
+// host's breaker is tripped. A non-2xx response is classified via
+// registry's IsFailure (as an HTTPStatusError) but is still returned to the
+// caller - only genuine transport errors or an open breaker produce a nil
+// response.
+func WrapRoundTripper(registry *Registry, operation string, next http.RoundTripper) http.RoundTripper {
+	return &roundTripper{registry: registry, operation: operation, next: next}
+}
+
+func (rt *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	b := rt.registry.GetFor(req.URL.Hostname(), rt.operation)
+
+	var resp *http.Response
+	execErr := b.Execute(req.Context(), func() error {
+		var rtErr error
+		resp, rtErr = rt.next.RoundTrip(req)
+		if rtErr != nil {
+			return rtErr
+		}
+		if resp.StatusCode >= 400 {
+			return &HTTPStatusError{StatusCode: resp.StatusCode}
+		}
+		return nil
+	})
+
+	if errors.Is(execErr, ErrCircuitOpen) || errors.Is(execErr, ErrTooManyRequests) {
+		return nil, execErr
+	}
+	if resp != nil {
+		// A real response was obtained - status-derived classification
+		// only feeds the breaker, it isn't a RoundTrip-level error.
+		return resp, nil
+	}
+	return nil, execErr
+}
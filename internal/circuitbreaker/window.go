@@ -0,0 +1,95 @@
+package circuitbreaker
+
+import (
+	"sync"
+	"time"
+)
+
+// bucket holds one slidingWindow time slice's request/failure counts.
+type bucket struct {
+	total uint64
+	fail  uint64
+}
+
+// slidingWindow is a ring of fixed-width time buckets used to compute an
+// error rate over the last (bucketCount * bucketWidth) of requests, rather
+// than since-inception counters (the original CircuitBreaker's Counts) or a
+// single consecutive-failure streak. This is what lets a breaker recover as
+// soon as a host's error rate actually improves, instead of waiting for an
+// Interval-based full reset.
+type slidingWindow struct {
+	mu          sync.Mutex
+	buckets     []bucket
+	bucketWidth time.Duration
+	current     int
+	windowStart time.Time
+}
+
+func newSlidingWindow(bucketCount int, bucketWidth time.Duration) *slidingWindow {
+	if bucketCount < 1 {
+		bucketCount = 1
+	}
+	return &slidingWindow{
+		buckets:     make([]bucket, bucketCount),
+		bucketWidth: bucketWidth,
+		windowStart: time.Now(),
+	}
+}
+
+// rotate advances the ring to now, zeroing any buckets the elapsed time has
+// moved past - the caller must hold w.mu.
+func (w *slidingWindow) rotate(now time.Time) {
+	elapsed := now.Sub(w.windowStart)
+	steps := int(elapsed / w.bucketWidth)
+	if steps <= 0 {
+		return
+	}
+	if steps > len(w.buckets) {
+		steps = len(w.buckets)
+	}
+	for i := 0; i < steps; i++ {
+		w.current = (w.current + 1) % len(w.buckets)
+		w.buckets[w.current] = bucket{}
+	}
+	w.windowStart = w.windowStart.Add(time.Duration(steps) * w.bucketWidth)
+}
+
+// record tallies one request's outcome into the current bucket.
+func (w *slidingWindow) record(success bool, now time.Time) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.rotate(now)
+	b := &w.buckets[w.current]
+	b.total++
+	if !success {
+		b.fail++
+	}
+}
+
+// snapshot returns the total requests and failures across every live
+// bucket as of now.
+func (w *slidingWindow) snapshot(now time.Time) (total, fail uint64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.rotate(now)
+	for _, b := range w.buckets {
+		total += b.total
+		fail += b.fail
+	}
+	return total, fail
+}
+
+// reset clears every bucket, e.g. when a breaker closes again after a
+// successful half-open probe - stale failures from before the trip
+// shouldn't count against the freshly-closed breaker.
+func (w *slidingWindow) reset() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for i := range w.buckets {
+		w.buckets[i] = bucket{}
+	}
+	w.windowStart = time.Now()
+}
@@ -1,9 +1,11 @@
 package cleanup
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 
 	"go.uber.org/zap"
@@ -12,6 +14,10 @@ import (
 // Manager handles file cleanup operations
 type Manager struct {
 	logger *zap.Logger
+
+	cachesMu  sync.Mutex
+	caches    map[string]*usageCache
+	freshness time.Duration
 }
 
 // NewManager creates a new cleanup manager
@@ -21,6 +27,37 @@ func NewManager(logger *zap.Logger) *Manager {
 	}
 }
 
+// SetUsageFreshness overrides how long a cached subdirectory's totals are
+// trusted before being rescanned even without an mtime change. Optional -
+// zero (the default) falls back to defaultFreshness.
+func (m *Manager) SetUsageFreshness(d time.Duration) {
+	m.freshness = d
+}
+
+// usageCacheFor returns (creating if necessary) the usageCache backing root.
+func (m *Manager) usageCacheFor(root string) *usageCache {
+	m.cachesMu.Lock()
+	defer m.cachesMu.Unlock()
+
+	if m.caches == nil {
+		m.caches = make(map[string]*usageCache)
+	}
+	if c, ok := m.caches[root]; ok {
+		return c
+	}
+
+	c := newUsageCache(root, m.freshness, m.logger)
+	m.caches[root] = c
+	return c
+}
+
+// Usage returns a snapshot of root's disk usage, scanning only the
+// subdirectories that changed since their last scan instead of walking the
+// whole tree - cheap enough for health/stats endpoints to call frequently.
+func (m *Manager) Usage(root string) (*UsageSnapshot, error) {
+	return m.usageCacheFor(root).scan()
+}
+
 // CleanupOptions configures cleanup behavior
 type CleanupOptions struct {
 	// Age of files to delete (delete older than this)
@@ -107,6 +144,13 @@ func (m *Manager) CleanTempFiles(tempDir string, opts CleanupOptions) *CleanupRe
 		zap.Int("errors", len(result.Errors)),
 	)
 
+	if result.FilesDeleted > 0 && !opts.DryRun {
+		// Deletions don't reliably bump every affected subdirectory's mtime
+		// on every filesystem, so force a full rescan rather than risk
+		// Usage() serving stale totals until something else invalidates it.
+		m.usageCacheFor(tempDir).invalidate()
+	}
+
 	return result
 }
 
@@ -201,21 +245,14 @@ func (m *Manager) RemoveDirectory(dirPath string, dryRun bool) error {
 	return nil
 }
 
-// GetDirectorySize calculates total directory size in bytes
+// GetDirectorySize calculates total directory size in bytes, backed by the
+// same incremental usage cache as Usage instead of a fresh filepath.Walk.
 func (m *Manager) GetDirectorySize(dirPath string) (int64, error) {
-	var totalSize int64
-
-	err := filepath.Walk(dirPath, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if !info.IsDir() {
-			totalSize += info.Size()
-		}
-		return nil
-	})
-
-	return totalSize, err
+	snapshot, err := m.Usage(dirPath)
+	if err != nil {
+		return 0, err
+	}
+	return snapshot.TotalSize, nil
 }
 
 // GetDirectoryStats returns detailed directory statistics
@@ -227,42 +264,25 @@ type DirectoryStats struct {
 	AverageSize int64
 }
 
-// GetDirectoryStats calculates directory statistics
+// GetDirectoryStats calculates directory statistics, backed by the same
+// incremental usage cache as Usage instead of a fresh filepath.Walk.
 func (m *Manager) GetDirectoryStats(dirPath string) (*DirectoryStats, error) {
-	stats := &DirectoryStats{
-		OldestFile: time.Now(),
+	snapshot, err := m.Usage(dirPath)
+	if err != nil {
+		return nil, err
 	}
 
-	var firstFile = true
-
-	err := filepath.Walk(dirPath, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-
-		if !info.IsDir() {
-			stats.TotalSize += info.Size()
-			stats.FileCount++
-
-			modTime := info.ModTime()
-			if modTime.Before(stats.OldestFile) {
-				stats.OldestFile = modTime
-			}
-
-			if firstFile || modTime.After(stats.NewestFile) {
-				stats.NewestFile = modTime
-				firstFile = false
-			}
-		}
-
-		return nil
-	})
-
+	stats := &DirectoryStats{
+		TotalSize:  snapshot.TotalSize,
+		FileCount:  snapshot.TotalFiles,
+		OldestFile: snapshot.OldestMod,
+		NewestFile: snapshot.NewestMod,
+	}
 	if stats.FileCount > 0 {
 		stats.AverageSize = stats.TotalSize / stats.FileCount
 	}
 
-	return stats, err
+	return stats, nil
 }
 
 // CleanupStrategy defines automatic cleanup behavior
@@ -281,14 +301,27 @@ type CleanupStrategy struct {
 
 	// Directories to clean
 	Directories []string
+
+	// DryRun reports what would be deleted without deleting anything, for
+	// both the local Directories sweep and the RemotePrefixes sweep below.
+	DryRun bool
+
+	// RemotePrefixes are object-store key prefixes (e.g. "jobs/") swept for
+	// finished-job output the same way Directories sweeps local temp files.
+	RemotePrefixes []string
+
+	// RemoteMaxAge deletes remote objects older than this. Defaults to
+	// MaxAge when zero.
+	RemoteMaxAge time.Duration
 }
 
 // Worker performs scheduled cleanup operations
 type Worker struct {
-	strategy *CleanupStrategy
-	manager  *Manager
-	logger   *zap.Logger
-	stopCh   chan struct{}
+	strategy       *CleanupStrategy
+	manager        *Manager
+	logger         *zap.Logger
+	stopCh         chan struct{}
+	storageCleaner StorageCleaner
 }
 
 // NewWorker creates a new cleanup worker
@@ -301,6 +334,13 @@ func NewWorker(strategy *CleanupStrategy, manager *Manager, logger *zap.Logger)
 	}
 }
 
+// SetStorageCleaner wires a StorageCleaner so performCleanup also sweeps
+// strategy.RemotePrefixes. Optional - a nil cleaner (the default) skips the
+// remote sweep and only cleans strategy.Directories on local disk.
+func (w *Worker) SetStorageCleaner(c StorageCleaner) {
+	w.storageCleaner = c
+}
+
 // Start begins the periodic cleanup process
 func (w *Worker) Start() {
 	if !w.strategy.Enabled {
@@ -341,6 +381,7 @@ func (w *Worker) performCleanup() {
 	opts := CleanupOptions{
 		DeleteOlderThan: w.strategy.MaxAge,
 		Recursive:       true,
+		DryRun:          w.strategy.DryRun,
 	}
 
 	for _, dir := range w.strategy.Directories {
@@ -357,5 +398,66 @@ func (w *Worker) performCleanup() {
 		}
 	}
 
+	w.sweepRemote()
+
 	w.logger.Info("Cleanup cycle completed")
 }
+
+// sweepRemote deletes finished-job output under strategy.RemotePrefixes the
+// same way performCleanup reaps strategy.Directories on local disk. No-op
+// when no StorageCleaner has been wired via SetStorageCleaner.
+func (w *Worker) sweepRemote() {
+	if w.storageCleaner == nil || len(w.strategy.RemotePrefixes) == 0 {
+		return
+	}
+
+	maxAge := w.strategy.RemoteMaxAge
+	if maxAge == 0 {
+		maxAge = w.strategy.MaxAge
+	}
+
+	ctx := context.Background()
+	result := &CleanupResult{Errors: make([]error, 0)}
+
+	for _, prefix := range w.strategy.RemotePrefixes {
+		objects, err := w.storageCleaner.ListOlderThan(ctx, prefix, maxAge)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Errorf("failed to list %s: %w", prefix, err))
+			continue
+		}
+		if len(objects) == 0 {
+			continue
+		}
+
+		keys := make([]string, len(objects))
+		var bytesFreed int64
+		for i, obj := range objects {
+			keys[i] = obj.Key
+			bytesFreed += obj.SizeBytes
+		}
+
+		if !w.strategy.DryRun {
+			if err := w.storageCleaner.DeleteBatch(ctx, keys); err != nil {
+				result.Errors = append(result.Errors, fmt.Errorf("failed to delete under %s: %w", prefix, err))
+				continue
+			}
+		}
+
+		result.FilesDeleted += int64(len(keys))
+		result.BytesFreed += bytesFreed
+
+		w.logger.Info("Remote cleanup completed",
+			zap.String("prefix", prefix),
+			zap.Int("objects_deleted", len(keys)),
+			zap.Int64("bytes_freed", bytesFreed),
+			zap.Bool("dry_run", w.strategy.DryRun),
+		)
+	}
+
+	if len(result.Errors) > 0 {
+		w.logger.Error("Remote cleanup errors occurred", zap.Int("error_count", len(result.Errors)))
+		for _, err := range result.Errors {
+			w.logger.Error("Remote cleanup error", zap.Error(err))
+		}
+	}
+}
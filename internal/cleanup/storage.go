@@ -0,0 +1,137 @@
+package cleanup
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/KeremKalyoncu/MedYan/pkg/storage"
+)
+
+// ObjectRef identifies one object a StorageCleaner found during a sweep.
+type ObjectRef struct {
+	Key          string
+	SizeBytes    int64
+	LastModified time.Time
+}
+
+// StorageCleaner sweeps a remote object store the same way Manager sweeps
+// local temp files, so completed-job output (jobs/{date}/{job_id}/... keys,
+// see storage.GenerateKey) doesn't outlive its presigned URL
+// (ExtractionResult.ExpiresAt) without relying on an external bucket
+// lifecycle policy.
+type StorageCleaner interface {
+	ListOlderThan(ctx context.Context, prefix string, age time.Duration) ([]ObjectRef, error)
+	DeleteBatch(ctx context.Context, keys []string) error
+}
+
+// LocalCleaner implements StorageCleaner against a local-disk store rooted
+// at root, for deployments running with STORAGE_BACKEND=local.
+type LocalCleaner struct {
+	root string
+}
+
+// NewLocalCleaner creates a LocalCleaner rooted at root (the same base path
+// passed to storage.NewLocalStorage).
+func NewLocalCleaner(root string) *LocalCleaner {
+	return &LocalCleaner{root: root}
+}
+
+// ListOlderThan walks root/prefix and returns every file last modified
+// before now-age.
+func (l *LocalCleaner) ListOlderThan(ctx context.Context, prefix string, age time.Duration) ([]ObjectRef, error) {
+	dir := filepath.Join(l.root, prefix)
+	cutoff := time.Now().Add(-age)
+
+	var refs []ObjectRef
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil // prefix doesn't exist (yet) - nothing to sweep
+			}
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		if info.ModTime().After(cutoff) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(l.root, path)
+		if err != nil {
+			return err
+		}
+
+		refs = append(refs, ObjectRef{
+			Key:          filepath.ToSlash(rel),
+			SizeBytes:    info.Size(),
+			LastModified: info.ModTime(),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", dir, err)
+	}
+
+	return refs, nil
+}
+
+// DeleteBatch removes each key under root, tolerating ones already gone.
+func (l *LocalCleaner) DeleteBatch(ctx context.Context, keys []string) error {
+	var firstErr error
+	for _, key := range keys {
+		if err := os.Remove(filepath.Join(l.root, key)); err != nil && !os.IsNotExist(err) && firstErr == nil {
+			firstErr = fmt.Errorf("failed to delete %s: %w", key, err)
+		}
+	}
+	return firstErr
+}
+
+// S3Cleaner implements StorageCleaner against any storage.FileStore backend
+// (S3Storage, TieredStorage) via ListByPrefix/Delete.
+type S3Cleaner struct {
+	store storage.FileStore
+}
+
+// NewS3Cleaner creates an S3Cleaner backed by store.
+func NewS3Cleaner(store storage.FileStore) *S3Cleaner {
+	return &S3Cleaner{store: store}
+}
+
+// ListOlderThan lists prefix and filters to objects last modified before
+// now-age.
+func (s *S3Cleaner) ListOlderThan(ctx context.Context, prefix string, age time.Duration) ([]ObjectRef, error) {
+	objects, err := s.store.ListByPrefix(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := time.Now().Add(-age)
+	refs := make([]ObjectRef, 0, len(objects))
+	for _, obj := range objects {
+		if obj.LastModified.Before(cutoff) {
+			refs = append(refs, ObjectRef{
+				Key:          obj.Key,
+				SizeBytes:    obj.SizeBytes,
+				LastModified: obj.LastModified,
+			})
+		}
+	}
+
+	return refs, nil
+}
+
+// DeleteBatch deletes each key, continuing past individual failures and
+// returning the first error encountered (if any).
+func (s *S3Cleaner) DeleteBatch(ctx context.Context, keys []string) error {
+	var firstErr error
+	for _, key := range keys {
+		if err := s.store.Delete(ctx, key); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("failed to delete %s: %w", key, err)
+		}
+	}
+	return firstErr
+}
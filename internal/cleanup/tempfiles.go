@@ -5,11 +5,35 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
 	"time"
 
+	"github.com/google/uuid"
 	"go.uber.org/zap"
+
+	"github.com/KeremKalyoncu/MedYan/internal/metrics"
 )
 
+// ActiveJobLister reports which job IDs are currently pending or
+// processing, so TempFileCleanup can skip files a slow job is still writing
+// to instead of judging them by age alone. Satisfied by *queue.Client's
+// ActiveJobIDs - an interface here (rather than importing internal/queue
+// directly) so cleanup doesn't have to know about Redis, Asynq, or any
+// other queue backend.
+type ActiveJobLister interface {
+	ActiveJobIDs(ctx context.Context) (map[string]struct{}, error)
+}
+
+// tempFileCandidate is one file under tempDir that looks like it belongs to
+// an extraction job, discovered by a cleanup pass.
+type tempFileCandidate struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
 // TempFileCleanup periodically removes old temporary files
 // This prevents disk space issues from failed downloads
 type TempFileCleanup struct {
@@ -19,6 +43,18 @@ type TempFileCleanup struct {
 	logger    *zap.Logger
 	closeCh   chan struct{}
 	stoppedCh chan struct{}
+
+	activeJobs ActiveJobLister
+
+	// diskLowWatermarkBytes triggers an emergency sweep - delete the
+	// oldest job-owned files first, ignoring maxAge - once tempDir's
+	// filesystem free space drops below it. Zero (the default) disables
+	// the emergency sweep entirely.
+	diskLowWatermarkBytes uint64
+	// diskHighWatermarkBytes is the free-space target an emergency sweep
+	// stops at once reached. Defaults to 2x diskLowWatermarkBytes if left
+	// zero while diskLowWatermarkBytes is set.
+	diskHighWatermarkBytes uint64
 }
 
 // NewTempFileCleanup creates a new temp file cleanup service
@@ -35,6 +71,27 @@ func NewTempFileCleanup(tempDir string, maxAge, interval time.Duration, logger *
 	}
 }
 
+// SetActiveJobLister wires a lister so cleanup can spare a still-processing
+// job's temp files regardless of how old they've gotten. Optional - a nil
+// lister (the default) falls back to age alone, same as before this was
+// introduced.
+func (tfc *TempFileCleanup) SetActiveJobLister(lister ActiveJobLister) {
+	tfc.activeJobs = lister
+}
+
+// SetDiskWatermarks enables the emergency sweep: once tempDir's filesystem
+// free space drops below low, cleanup deletes job-owned files oldest-first
+// (ignoring maxAge, but still sparing active jobs) until free space climbs
+// back above high. high defaults to 2x low when zero. Pass low=0 to
+// disable the emergency sweep.
+func (tfc *TempFileCleanup) SetDiskWatermarks(low, high uint64) {
+	tfc.diskLowWatermarkBytes = low
+	if high == 0 {
+		high = low * 2
+	}
+	tfc.diskHighWatermarkBytes = high
+}
+
 // Start begins the cleanup goroutine
 func (tfc *TempFileCleanup) Start(ctx context.Context) {
 	go tfc.run(ctx)
@@ -67,72 +124,203 @@ func (tfc *TempFileCleanup) run(ctx context.Context) {
 	}
 }
 
+// jobIDFromTempFile extracts the job ID prefix from a tempDir entry named
+// by downloadMedia/ExtractionHandler.extractClip ("{jobID}.ext",
+// "{jobID}.ext.part", "{jobID}_clip.ext"), so cleanup can check it against
+// ActiveJobLister before deleting. Returns "" for anything that isn't
+// UUID-prefixed (e.g. a cookie jar or something unrelated), which callers
+// treat as "not spared by job ID" rather than "always safe to delete".
+func jobIDFromTempFile(name string) string {
+	base := name
+	if i := strings.IndexAny(base, "._"); i > 0 {
+		base = base[:i]
+	}
+	if _, err := uuid.Parse(base); err != nil {
+		return ""
+	}
+	return base
+}
+
+// isCleanupCandidate reports whether name is a file type this service
+// should ever consider deleting: a job-owned output/residue (see
+// jobIDFromTempFile), a yt-dlp partial-download sidecar (.part/.ytdl), or an
+// orphaned cookie jar (extractor.WriteCookiesFile's "cookies-{unix}.txt").
+// Anything else - including the usage cache this package itself writes to
+// tempDir - is left alone.
+func isCleanupCandidate(name string) bool {
+	if jobIDFromTempFile(name) != "" {
+		return true
+	}
+	if strings.HasPrefix(name, "cookies-") {
+		return true
+	}
+	lower := strings.ToLower(name)
+	return strings.HasSuffix(lower, ".part") || strings.HasSuffix(lower, ".ytdl")
+}
+
+// diskFreeBytes reports free space on the filesystem backing path.
+func diskFreeBytes(path string) (uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+	return stat.Bavail * uint64(stat.Bsize), nil
+}
+
 func (tfc *TempFileCleanup) cleanup(ctx context.Context) {
 	now := time.Now()
 	cutoff := now.Add(-tfc.maxAge)
 
-	var deletedCount int
-	var deletedSize int64
-	var errorCount int
+	var active map[string]struct{}
+	if tfc.activeJobs != nil {
+		m, err := tfc.activeJobs.ActiveJobIDs(ctx)
+		if err != nil {
+			tfc.logger.Warn("Failed to list active jobs, proceeding without sparing them", zap.Error(err))
+		} else {
+			active = m
+		}
+	}
 
 	tfc.logger.Info("Starting temp file cleanup",
 		zap.String("dir", tfc.tempDir),
 		zap.Duration("max_age", tfc.maxAge),
 	)
 
-	// Walk temp directory
+	var survivors []tempFileCandidate // not deleted by the age-based pass below, still eligible for the emergency sweep
+	var sparedJobIDs []string
+	var deletedCount int
+	var deletedSize int64
+	var errorCount int
+	var totalFiles int
+
 	err := filepath.Walk(tfc.tempDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return nil // Skip files we can't access
 		}
-
-		// Skip directories
 		if info.IsDir() {
 			return nil
 		}
+		totalFiles++
 
-		// Check if file is old enough to delete
-		if info.ModTime().Before(cutoff) {
-			// Check for our temp file patterns (optional: add pattern matching)
-			// e.g., files starting with "ytdlp-", "medyan-", etc.
+		name := info.Name()
+		if !isCleanupCandidate(name) {
+			return nil
+		}
 
-			size := info.Size()
-			if err := os.Remove(path); err != nil {
+		if jobID := jobIDFromTempFile(name); jobID != "" && active != nil {
+			if _, stillActive := active[jobID]; stillActive {
+				sparedJobIDs = append(sparedJobIDs, jobID)
+				return nil
+			}
+		}
+
+		if info.ModTime().Before(cutoff) {
+			if delErr := os.Remove(path); delErr != nil {
 				tfc.logger.Warn("Failed to delete old temp file",
 					zap.String("file", path),
 					zap.Duration("age", now.Sub(info.ModTime())),
-					zap.Error(err),
+					zap.Error(delErr),
 				)
 				errorCount++
 			} else {
 				tfc.logger.Debug("Deleted old temp file",
 					zap.String("file", path),
 					zap.Duration("age", now.Sub(info.ModTime())),
-					zap.Int64("size", size),
+					zap.Int64("size", info.Size()),
 				)
 				deletedCount++
-				deletedSize += size
+				deletedSize += info.Size()
 			}
+			return nil
 		}
 
+		survivors = append(survivors, tempFileCandidate{path: path, size: info.Size(), modTime: info.ModTime()})
 		return nil
 	})
 
 	if err != nil {
-		tfc.logger.Error("Temp file cleanup failed",
-			zap.Error(err),
-		)
+		tfc.logger.Error("Temp file cleanup failed", zap.Error(err))
 		return
 	}
 
+	if len(sparedJobIDs) > 0 {
+		tfc.logger.Info("Spared temp files for active jobs",
+			zap.Strings("job_ids", sparedJobIDs),
+			zap.Int("count", len(sparedJobIDs)),
+		)
+	}
+
+	freeBytes, statErr := diskFreeBytes(tfc.tempDir)
+	if statErr != nil {
+		tfc.logger.Warn("Failed to stat temp directory free space", zap.Error(statErr))
+	} else if tfc.diskLowWatermarkBytes > 0 && freeBytes < tfc.diskLowWatermarkBytes {
+		swept, sweptBytes := tfc.emergencySweep(survivors, freeBytes)
+		deletedCount += swept
+		deletedSize += sweptBytes
+		if updated, statErr2 := diskFreeBytes(tfc.tempDir); statErr2 == nil {
+			freeBytes = updated
+		}
+	}
+
+	remainingFiles := int64(totalFiles - deletedCount)
+	if remainingFiles < 0 {
+		remainingFiles = 0
+	}
+	metrics.GetMetrics().RecordTempCleanupStats(int64(freeBytes), remainingFiles)
+
 	tfc.logger.Info("Temp file cleanup completed",
 		zap.Int("deleted_count", deletedCount),
 		zap.Int64("freed_bytes", deletedSize),
 		zap.String("freed_mb", formatBytes(deletedSize)),
 		zap.Int("errors", errorCount),
+		zap.Uint64("disk_free_bytes", freeBytes),
 	)
 }
 
+// emergencySweep deletes candidates oldest-first, ignoring maxAge, until
+// tempDir's free space climbs back above diskHighWatermarkBytes or
+// candidates run out. candidates is cleanup's list of files that survived
+// the age-based pass (active-job files were already excluded there).
+// Returns the count and total bytes actually freed.
+func (tfc *TempFileCleanup) emergencySweep(candidates []tempFileCandidate, freeBytes uint64) (int, int64) {
+	metrics.GetMetrics().RecordEmergencySweep()
+	tfc.logger.Warn("Disk free space below low watermark, starting emergency temp file sweep",
+		zap.Uint64("free_bytes", freeBytes),
+		zap.Uint64("low_watermark_bytes", tfc.diskLowWatermarkBytes),
+		zap.Uint64("high_watermark_bytes", tfc.diskHighWatermarkBytes),
+	)
+
+	sorted := append([]tempFileCandidate(nil), candidates...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].modTime.Before(sorted[j].modTime) })
+
+	var deletedCount int
+	var deletedSize int64
+	for _, c := range sorted {
+		if freeBytes >= tfc.diskHighWatermarkBytes {
+			break
+		}
+		if err := os.Remove(c.path); err != nil {
+			tfc.logger.Warn("Emergency sweep failed to delete temp file", zap.String("file", c.path), zap.Error(err))
+			continue
+		}
+		deletedCount++
+		deletedSize += c.size
+		freeBytes += uint64(c.size)
+		tfc.logger.Info("Emergency sweep deleted temp file",
+			zap.String("file", c.path),
+			zap.Int64("size", c.size),
+			zap.Time("mod_time", c.modTime),
+		)
+	}
+
+	tfc.logger.Warn("Emergency temp file sweep completed",
+		zap.Int("deleted_count", deletedCount),
+		zap.Int64("freed_bytes", deletedSize),
+	)
+
+	return deletedCount, deletedSize
+}
+
 func formatBytes(bytes int64) string {
 	const unit = 1024
 	if bytes < unit {
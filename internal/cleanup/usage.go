@@ -0,0 +1,296 @@
+package cleanup
+
+import (
+	"encoding/gob"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// usageCacheFileName is the on-disk cache written under a scanned root,
+// mirroring MinIO's data-usage crawler: walk only what changed, reuse
+// everything else.
+const usageCacheFileName = ".usage.cache"
+
+// defaultFreshness bounds how long a subdirectory's cached totals are
+// trusted before they're rescanned even if its mtime hasn't changed -
+// guards against filesystems/operations that don't reliably bump mtime.
+const defaultFreshness = 10 * time.Minute
+
+// dirUsage holds one subdirectory's aggregated stats as of its last scan.
+type dirUsage struct {
+	Size      int64
+	FileCount int64
+	OldestMod time.Time
+	NewestMod time.Time
+	LastScan  time.Time
+	// ParentModAtScan is the subdirectory's own mtime as observed at
+	// LastScan; a later scan reuses this entry only if the mtime hasn't
+	// changed since, since that's how a removed/added child is detected
+	// without re-walking the whole subtree.
+	ParentModAtScan time.Time
+}
+
+// usageCacheFile is the gob-encoded persisted form of a usageCache.
+type usageCacheFile struct {
+	TopChecksum string
+	Dirs        map[string]dirUsage
+}
+
+// UsageSnapshot is the O(1) summary returned by Manager.Usage, suitable for
+// health/stats endpoints that can't afford a full directory walk per call.
+type UsageSnapshot struct {
+	Root        string
+	TotalSize   int64
+	TotalFiles  int64
+	OldestMod   time.Time
+	NewestMod   time.Time
+	GeneratedAt time.Time
+}
+
+// usageCache tracks per-subdirectory usage for a single root directory,
+// persisted to <root>/.usage.cache so it survives process restarts.
+type usageCache struct {
+	mu          sync.Mutex
+	root        string
+	freshness   time.Duration
+	topChecksum string
+	dirs        map[string]dirUsage
+	logger      *zap.Logger
+}
+
+func newUsageCache(root string, freshness time.Duration, logger *zap.Logger) *usageCache {
+	if freshness <= 0 {
+		freshness = defaultFreshness
+	}
+	c := &usageCache{
+		root:      root,
+		freshness: freshness,
+		dirs:      make(map[string]dirUsage),
+		logger:    logger,
+	}
+	c.load()
+	return c
+}
+
+func (c *usageCache) cachePath() string {
+	return filepath.Join(c.root, usageCacheFileName)
+}
+
+// load reads the persisted cache from disk. A missing or corrupt cache file
+// just starts empty - the next scan rebuilds it from scratch.
+func (c *usageCache) load() {
+	f, err := os.Open(c.cachePath())
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	var persisted usageCacheFile
+	if err := gob.NewDecoder(f).Decode(&persisted); err != nil {
+		if c.logger != nil {
+			c.logger.Warn("Discarding corrupt usage cache",
+				zap.String("root", c.root),
+				zap.Error(err),
+			)
+		}
+		return
+	}
+
+	c.topChecksum = persisted.TopChecksum
+	c.dirs = persisted.Dirs
+	if c.dirs == nil {
+		c.dirs = make(map[string]dirUsage)
+	}
+}
+
+// save persists the cache to disk. Best-effort: a failure to write is
+// logged but doesn't fail the scan that triggered it.
+func (c *usageCache) save() {
+	tmp := c.cachePath() + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		if c.logger != nil {
+			c.logger.Warn("Failed to persist usage cache", zap.String("root", c.root), zap.Error(err))
+		}
+		return
+	}
+
+	persisted := usageCacheFile{TopChecksum: c.topChecksum, Dirs: c.dirs}
+	if err := gob.NewEncoder(f).Encode(persisted); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		if c.logger != nil {
+			c.logger.Warn("Failed to encode usage cache", zap.String("root", c.root), zap.Error(err))
+		}
+		return
+	}
+	f.Close()
+
+	if err := os.Rename(tmp, c.cachePath()); err != nil && c.logger != nil {
+		c.logger.Warn("Failed to install usage cache", zap.String("root", c.root), zap.Error(err))
+	}
+}
+
+// invalidate drops every cached entry, forcing the next scan to rebuild
+// from scratch. Used after CleanTempFiles deletes files, since deletions
+// don't reliably bump every affected directory's mtime on every filesystem.
+func (c *usageCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.dirs = make(map[string]dirUsage)
+	c.topChecksum = ""
+}
+
+// topLevelChecksum hashes the sorted names of root's immediate children, so
+// a top-level directory being added or removed forces a full rebuild rather
+// than silently leaving a stale/missing entry in the incremental cache.
+func topLevelChecksum(entries []os.DirEntry) string {
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+	return strings.Join(names, "\x00")
+}
+
+// scan refreshes the cache and returns the resulting snapshot. It walks only
+// the subdirectories whose mtime changed since their last scan (or that
+// have no cached entry, or whose cached entry is older than freshness);
+// everything else reuses its cached totals.
+func (c *usageCache) scan() (*UsageSnapshot, error) {
+	entries, err := os.ReadDir(c.root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", c.root, err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	checksum := topLevelChecksum(entries)
+	if checksum != c.topChecksum {
+		c.dirs = make(map[string]dirUsage)
+		c.topChecksum = checksum
+	}
+
+	now := time.Now()
+	seen := make(map[string]struct{}, len(entries))
+
+	snapshot := &UsageSnapshot{Root: c.root, GeneratedAt: now}
+
+	for _, entry := range entries {
+		if entry.Name() == usageCacheFileName || entry.Name() == usageCacheFileName+".tmp" {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue // vanished between ReadDir and Info; skip this cycle
+		}
+
+		seen[entry.Name()] = struct{}{}
+
+		if !entry.IsDir() {
+			// A loose file directly under root: cheap enough to account
+			// for directly, no walk or caching needed.
+			snapshot.TotalSize += info.Size()
+			snapshot.TotalFiles++
+			accumulateRange(snapshot, info.ModTime())
+			continue
+		}
+
+		childPath := filepath.Join(c.root, entry.Name())
+		cached, ok := c.dirs[entry.Name()]
+		stale := !ok ||
+			!info.ModTime().Equal(cached.ParentModAtScan) ||
+			now.Sub(cached.LastScan) > c.freshness
+
+		if stale {
+			usage, err := walkDirUsage(childPath)
+			if err != nil {
+				if c.logger != nil {
+					c.logger.Warn("Failed to scan subdirectory for usage cache",
+						zap.String("dir", childPath),
+						zap.Error(err),
+					)
+				}
+				if ok {
+					usage = cached // fall back to the stale entry rather than dropping it
+				}
+			} else {
+				usage.LastScan = now
+				usage.ParentModAtScan = info.ModTime()
+			}
+			cached = usage
+			c.dirs[entry.Name()] = cached
+		}
+
+		snapshot.TotalSize += cached.Size
+		snapshot.TotalFiles += cached.FileCount
+		if !cached.OldestMod.IsZero() {
+			accumulateRange(snapshot, cached.OldestMod)
+		}
+		if !cached.NewestMod.IsZero() {
+			accumulateRange(snapshot, cached.NewestMod)
+		}
+	}
+
+	// Drop entries for subdirectories that no longer exist.
+	for name := range c.dirs {
+		if _, ok := seen[name]; !ok {
+			delete(c.dirs, name)
+		}
+	}
+
+	c.save()
+
+	return snapshot, nil
+}
+
+func accumulateRange(snapshot *UsageSnapshot, t time.Time) {
+	if snapshot.OldestMod.IsZero() || t.Before(snapshot.OldestMod) {
+		snapshot.OldestMod = t
+	}
+	if t.After(snapshot.NewestMod) {
+		snapshot.NewestMod = t
+	}
+}
+
+// walkDirUsage computes fresh {size, fileCount, oldestMod, newestMod} for a
+// single subdirectory via filepath.Walk - the expensive path, only taken
+// for subdirectories the cache has flagged as stale.
+func walkDirUsage(dir string) (dirUsage, error) {
+	var usage dirUsage
+	first := true
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil // tolerate a file vanishing mid-walk
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		usage.Size += info.Size()
+		usage.FileCount++
+
+		modTime := info.ModTime()
+		if first || modTime.Before(usage.OldestMod) {
+			usage.OldestMod = modTime
+		}
+		if first || modTime.After(usage.NewestMod) {
+			usage.NewestMod = modTime
+		}
+		first = false
+
+		return nil
+	})
+
+	return usage, err
+}
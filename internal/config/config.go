@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -53,6 +54,9 @@ type RedisConfig struct {
 
 // StorageConfig holds S3/MinIO configuration
 type StorageConfig struct {
+	// Backend selects the FileStore implementation: "s3", "local", or
+	// "tiered" (a local LRU cache in front of the S3 origin).
+	Backend              string
 	Endpoint             string
 	Region               string
 	Bucket               string
@@ -60,17 +64,48 @@ type StorageConfig struct {
 	SecretAccessKey      string
 	PresignedURLExpiry   time.Duration
 	StreamThresholdBytes int64
-	UsePathStyle         bool // For MinIO
+	UsePathStyle         bool   // For MinIO
+	SSEMode              string // none, SSE-S3, SSE-KMS, or SSE-C
+	KMSKeyID             string // Required when SSEMode is SSE-KMS
+	SSECustomerKey       string // Raw 256-bit AES key, required when SSEMode is SSE-C
+	TokenSigningKey      string // HMAC key backing signed download tokens
+	LocalDir             string // Base directory for the "local" and "tiered" backends
+	TieredCacheMaxBytes  int64  // Local cache budget for the "tiered" backend
 }
 
 // ExtractorConfig holds extractor tool configuration
 type ExtractorConfig struct {
-	YtdlpPath         string
-	FFmpegPath        string
+	YtdlpPath   string
+	FFmpegPath  string
+	FfprobePath string
+	// HWAccelMode selects the hardware encoder family FFmpeg prefers -
+	// "auto" (default, probes the host lazily), "off", "nvenc", "vaapi",
+	// "qsv", or "videotoolbox". See extractor.HWAccelFamily.
+	HWAccelMode       string
 	YtdlpTimeout      time.Duration
 	FFmpegTimeout     time.Duration
 	MaxConcurrentJobs int
 	TempDir           string
+
+	// IdleCheckInterval controls how often the watchdog scans in-flight
+	// yt-dlp/ffmpeg processes for staleness.
+	IdleCheckInterval time.Duration
+	// IdleTimeout bounds how long a process may go without producing
+	// progress before the watchdog kills it. This is separate from
+	// YtdlpTimeout/FFmpegTimeout, which bound total runtime even when a
+	// process is actively working.
+	IdleTimeout time.Duration
+
+	// HLSSegmentSeconds sets the target duration of each HLS segment for
+	// jobs with OutputFormat "hls".
+	HLSSegmentSeconds int
+	// HLSVariants lists the types.QualityPresets names packaged into the
+	// HLS bitrate ladder, e.g. []string{"1080p", "720p", "480p"}.
+	HLSVariants []string
+	// HLSCriticalVariants is HLSVariants for jobs whose quality maps to the
+	// "critical" queue (see queue.QueueForQuality) - typically the
+	// full 4K->1080p->720p->480p ladder.
+	HLSCriticalVariants []string
 }
 
 // WorkerConfig holds job worker configuration
@@ -123,6 +158,7 @@ func Load() (*Config, error) {
 			PoolSize:   getEnvInt("REDIS_POOL_SIZE", 10),
 		},
 		Storage: StorageConfig{
+			Backend:              getEnv("STORAGE_BACKEND", "s3"),
 			Endpoint:             getEnv("S3_ENDPOINT", ""),
 			Region:               getEnv("S3_REGION", "us-east-1"),
 			Bucket:               getEnv("S3_BUCKET", "media-extraction-output"),
@@ -131,14 +167,27 @@ func Load() (*Config, error) {
 			PresignedURLExpiry:   getEnvDuration("S3_PRESIGNED_EXPIRY", 24*time.Hour),
 			StreamThresholdBytes: getEnvInt64("S3_STREAM_THRESHOLD", 500*1024*1024), // 500MB
 			UsePathStyle:         getEnvBool("S3_USE_PATH_STYLE", true),             // MinIO uses path style
+			SSEMode:              getEnv("S3_SSE_MODE", "none"),
+			KMSKeyID:             getEnv("S3_KMS_KEY_ID", ""),
+			SSECustomerKey:       getEnv("S3_SSE_CUSTOMER_KEY", ""),
+			TokenSigningKey:      getEnv("DOWNLOAD_TOKEN_SIGNING_KEY", ""),
+			LocalDir:             getEnv("LOCAL_STORAGE_DIR", "/app/downloads"),
+			TieredCacheMaxBytes:  getEnvInt64("TIERED_CACHE_MAX_BYTES", 5*1024*1024*1024),
 		},
 		Extractor: ExtractorConfig{
-			YtdlpPath:         getEnv("YTDLP_PATH", "yt-dlp"),
-			FFmpegPath:        getEnv("FFMPEG_PATH", "ffmpeg"),
-			YtdlpTimeout:      getEnvDuration("YTDLP_TIMEOUT", 10*time.Minute),
-			FFmpegTimeout:     getEnvDuration("FFMPEG_TIMEOUT", 30*time.Minute),
-			MaxConcurrentJobs: getEnvInt("MAX_CONCURRENT_JOBS", 8),
-			TempDir:           getEnv("TEMP_DIR", os.TempDir()),
+			YtdlpPath:           getEnv("YTDLP_PATH", "yt-dlp"),
+			FFmpegPath:          getEnv("FFMPEG_PATH", "ffmpeg"),
+			FfprobePath:         getEnv("FFPROBE_PATH", "ffprobe"),
+			HWAccelMode:         getEnv("FFMPEG_HWACCEL_MODE", "auto"),
+			YtdlpTimeout:        getEnvDuration("YTDLP_TIMEOUT", 10*time.Minute),
+			FFmpegTimeout:       getEnvDuration("FFMPEG_TIMEOUT", 30*time.Minute),
+			MaxConcurrentJobs:   getEnvInt("MAX_CONCURRENT_JOBS", 8),
+			TempDir:             getEnv("TEMP_DIR", os.TempDir()),
+			IdleCheckInterval:   getEnvDuration("EXTRACTOR_IDLE_CHECK_INTERVAL", 15*time.Second),
+			IdleTimeout:         getEnvDuration("EXTRACTOR_IDLE_TIMEOUT", 2*time.Minute),
+			HLSSegmentSeconds:   getEnvInt("HLS_SEGMENT_SECONDS", 6),
+			HLSVariants:         getEnvStringSlice("HLS_VARIANTS", []string{"1080p", "720p", "480p"}),
+			HLSCriticalVariants: getEnvStringSlice("HLS_CRITICAL_VARIANTS", []string{"4k", "1080p", "720p", "480p"}),
 		},
 		Worker: WorkerConfig{
 			Concurrency:     getEnvInt("WORKER_CONCURRENCY", 8),
@@ -187,6 +236,30 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("S3_BUCKET is required")
 	}
 
+	switch c.Storage.Backend {
+	case "s3", "local", "tiered":
+	default:
+		return fmt.Errorf("STORAGE_BACKEND must be one of s3, local, tiered")
+	}
+
+	switch c.Storage.SSEMode {
+	case "", "none", "SSE-S3", "SSE-KMS", "SSE-C":
+	default:
+		return fmt.Errorf("S3_SSE_MODE must be one of none, SSE-S3, SSE-KMS, SSE-C")
+	}
+
+	if c.Storage.SSEMode == "SSE-KMS" && c.Storage.KMSKeyID == "" {
+		return fmt.Errorf("S3_KMS_KEY_ID is required when S3_SSE_MODE is SSE-KMS")
+	}
+
+	if c.Storage.SSEMode == "SSE-C" && c.Storage.SSECustomerKey == "" {
+		return fmt.Errorf("S3_SSE_CUSTOMER_KEY is required when S3_SSE_MODE is SSE-C")
+	}
+
+	if c.Storage.TokenSigningKey == "" {
+		return fmt.Errorf("DOWNLOAD_TOKEN_SIGNING_KEY is required")
+	}
+
 	if c.Extractor.YtdlpPath == "" {
 		return fmt.Errorf("YTDLP_PATH is required")
 	}
@@ -239,6 +312,25 @@ func getEnvBool(key string, defaultValue bool) bool {
 	return defaultValue
 }
 
+func getEnvStringSlice(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	parts := strings.Split(value, ",")
+	result := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if trimmed := strings.TrimSpace(p); trimmed != "" {
+			result = append(result, trimmed)
+		}
+	}
+	if len(result) == 0 {
+		return defaultValue
+	}
+	return result
+}
+
 func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
 	if value := os.Getenv(key); value != "" {
 		if duration, err := time.ParseDuration(value); err == nil {
@@ -0,0 +1,41 @@
+// Package credentials stores per-platform authentication material (cookie
+// jars, in future API tokens) for extractor.PlatformExtractor's automated
+// recovery fallbacks, behind a pluggable Store so operators can rotate them
+// at runtime instead of editing code or redeploying.
+package credentials
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrNotFound is returned by Store.Get when no credential is configured for
+// a platform.
+var ErrNotFound = errors.New("credentials: not found")
+
+// Credential is one platform's stored authentication material.
+type Credential struct {
+	Platform      string    `json:"platform"`
+	CookiesBase64 string    `json:"cookies_base64"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	// Stale marks a jar that a recovery fallback already retried and had
+	// rejected as login-required, so callers know to prompt for a fresh
+	// upload instead of retrying with the same cookies again.
+	Stale bool `json:"stale,omitempty"`
+}
+
+// Store is a pluggable backend for per-platform credentials. FileStore and
+// RedisStore are the two backends this package provides.
+type Store interface {
+	Get(ctx context.Context, platform string) (*Credential, error)
+	Set(ctx context.Context, platform, cookiesBase64 string) error
+	List(ctx context.Context) ([]Credential, error)
+	// MarkStale flags platform's jar as stale without discarding it, so an
+	// operator can see which jars need re-uploading before cleanup evicts
+	// them. Returns ErrNotFound if no credential is stored for platform.
+	MarkStale(ctx context.Context, platform string) error
+	// Delete removes platform's jar entirely. Returns ErrNotFound if none
+	// is stored.
+	Delete(ctx context.Context, platform string) error
+}
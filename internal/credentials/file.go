@@ -0,0 +1,134 @@
+package credentials
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileStore persists credentials as one JSON file per platform under a base
+// directory - the simplest backend, for single-host deployments that don't
+// need RedisStore's shared, encrypted storage.
+type FileStore struct {
+	mu  sync.Mutex
+	dir string
+}
+
+// NewFileStore creates a FileStore rooted at dir, creating it (and any
+// missing parents) if necessary.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("credentials: failed to create store dir: %w", err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+func (f *FileStore) path(platform string) string {
+	return filepath.Join(f.dir, platform+".json")
+}
+
+// Get returns the stored credential for platform, or ErrNotFound.
+func (f *FileStore) Get(ctx context.Context, platform string) (*Credential, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	data, err := os.ReadFile(f.path(platform))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	var cred Credential
+	if err := json.Unmarshal(data, &cred); err != nil {
+		return nil, fmt.Errorf("credentials: failed to parse %s: %w", platform, err)
+	}
+	return &cred, nil
+}
+
+// Set stores (or overwrites) the credential for platform.
+func (f *FileStore) Set(ctx context.Context, platform, cookiesBase64 string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	cred := Credential{Platform: platform, CookiesBase64: cookiesBase64, UpdatedAt: time.Now()}
+	data, err := json.Marshal(cred)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(f.path(platform), data, 0o600)
+}
+
+// List returns every stored credential, for the /admin/credentials
+// endpoint's listing.
+func (f *FileStore) List(ctx context.Context) ([]Credential, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	entries, err := os.ReadDir(f.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	creds := make([]Credential, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(f.dir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		var cred Credential
+		if err := json.Unmarshal(data, &cred); err != nil {
+			continue
+		}
+		creds = append(creds, cred)
+	}
+	return creds, nil
+}
+
+// MarkStale flags platform's jar as stale without discarding it.
+func (f *FileStore) MarkStale(ctx context.Context, platform string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	data, err := os.ReadFile(f.path(platform))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return ErrNotFound
+		}
+		return err
+	}
+
+	var cred Credential
+	if err := json.Unmarshal(data, &cred); err != nil {
+		return fmt.Errorf("credentials: failed to parse %s: %w", platform, err)
+	}
+	cred.Stale = true
+
+	data, err = json.Marshal(cred)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(f.path(platform), data, 0o600)
+}
+
+// Delete removes platform's jar entirely.
+func (f *FileStore) Delete(ctx context.Context, platform string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := os.Remove(f.path(platform)); err != nil {
+		if os.IsNotExist(err) {
+			return ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
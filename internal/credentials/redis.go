@@ -0,0 +1,161 @@
+package credentials
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisKeyPrefix namespaces RedisStore's entries from the rest of the Redis
+// keyspace (job:*, batch:*, webhook:*).
+const redisKeyPrefix = "credential:"
+
+// platformsSetKey indexes every platform RedisStore has ever stored, so
+// List doesn't need a Redis KEYS scan.
+const platformsSetKey = "credential:platforms"
+
+// RedisStore persists credentials in Redis, AES-256-GCM encrypted at rest,
+// so operators can share one credential pool across every worker without
+// cookies sitting in plaintext alongside job metadata.
+type RedisStore struct {
+	redis *redis.Client
+	aead  cipher.AEAD
+}
+
+// NewRedisStore creates a RedisStore. key must be exactly 32 bytes (an
+// AES-256 key) - callers typically derive it by hashing an operator secret
+// (e.g. sha256.Sum256) rather than using raw bytes directly.
+func NewRedisStore(redisClient *redis.Client, key []byte) (*RedisStore, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("credentials: invalid encryption key: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("credentials: failed to init GCM: %w", err)
+	}
+	return &RedisStore{redis: redisClient, aead: aead}, nil
+}
+
+// encrypt seals plaintext with a fresh random nonce, prefixed to the
+// returned ciphertext so decrypt doesn't need it stored separately.
+func (r *RedisStore) encrypt(plaintext []byte) (string, error) {
+	nonce := make([]byte, r.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := r.aead.Seal(nonce, nonce, plaintext, nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (r *RedisStore) decrypt(encoded string) ([]byte, error) {
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	nonceSize := r.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return nil, fmt.Errorf("credentials: ciphertext too short")
+	}
+	nonce, ciphertext := sealed[:nonceSize], sealed[nonceSize:]
+	return r.aead.Open(nil, nonce, ciphertext, nil)
+}
+
+// Get returns the stored credential for platform, or ErrNotFound.
+func (r *RedisStore) Get(ctx context.Context, platform string) (*Credential, error) {
+	encoded, err := r.redis.Get(ctx, redisKeyPrefix+platform).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	plaintext, err := r.decrypt(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("credentials: failed to decrypt %s: %w", platform, err)
+	}
+
+	var cred Credential
+	if err := json.Unmarshal(plaintext, &cred); err != nil {
+		return nil, err
+	}
+	return &cred, nil
+}
+
+// Set stores (or overwrites) the credential for platform.
+func (r *RedisStore) Set(ctx context.Context, platform, cookiesBase64 string) error {
+	cred := Credential{Platform: platform, CookiesBase64: cookiesBase64, UpdatedAt: time.Now()}
+	plaintext, err := json.Marshal(cred)
+	if err != nil {
+		return err
+	}
+
+	encoded, err := r.encrypt(plaintext)
+	if err != nil {
+		return fmt.Errorf("credentials: failed to encrypt %s: %w", platform, err)
+	}
+
+	if err := r.redis.Set(ctx, redisKeyPrefix+platform, encoded, 0).Err(); err != nil {
+		return err
+	}
+	return r.redis.SAdd(ctx, platformsSetKey, platform).Err()
+}
+
+// List returns every stored credential, for the /admin/credentials
+// endpoint's listing.
+func (r *RedisStore) List(ctx context.Context) ([]Credential, error) {
+	platforms, err := r.redis.SMembers(ctx, platformsSetKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	creds := make([]Credential, 0, len(platforms))
+	for _, platform := range platforms {
+		cred, err := r.Get(ctx, platform)
+		if err != nil {
+			continue
+		}
+		creds = append(creds, *cred)
+	}
+	return creds, nil
+}
+
+// MarkStale flags platform's jar as stale without discarding it.
+func (r *RedisStore) MarkStale(ctx context.Context, platform string) error {
+	cred, err := r.Get(ctx, platform)
+	if err != nil {
+		return err
+	}
+	cred.Stale = true
+
+	plaintext, err := json.Marshal(cred)
+	if err != nil {
+		return err
+	}
+	encoded, err := r.encrypt(plaintext)
+	if err != nil {
+		return fmt.Errorf("credentials: failed to encrypt %s: %w", platform, err)
+	}
+	return r.redis.Set(ctx, redisKeyPrefix+platform, encoded, 0).Err()
+}
+
+// Delete removes platform's jar entirely.
+func (r *RedisStore) Delete(ctx context.Context, platform string) error {
+	deleted, err := r.redis.Del(ctx, redisKeyPrefix+platform).Result()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrNotFound
+	}
+	return r.redis.SRem(ctx, platformsSetKey, platform).Err()
+}
@@ -0,0 +1,100 @@
+package credentials
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// RotatorConfig controls how aggressively Rotator evicts old jars.
+type RotatorConfig struct {
+	// MaxAge is how long a jar is kept after its last Set before Rotator
+	// evicts it. Zero disables eviction entirely.
+	MaxAge time.Duration
+	// Interval is how often Rotator sweeps the store for expired jars.
+	Interval time.Duration
+}
+
+// Rotator periodically sweeps a Store and evicts jars older than
+// Config.MaxAge, so a cookie upload that's never refreshed doesn't sit
+// around (and keep getting retried against a platform that's long since
+// invalidated it) forever.
+type Rotator struct {
+	store     Store
+	cfg       RotatorConfig
+	logger    *zap.Logger
+	closeCh   chan struct{}
+	stoppedCh chan struct{}
+}
+
+// NewRotator creates a Rotator for store. Start must be called to begin the
+// sweep loop.
+func NewRotator(store Store, cfg RotatorConfig, logger *zap.Logger) *Rotator {
+	return &Rotator{
+		store:     store,
+		cfg:       cfg,
+		logger:    logger,
+		closeCh:   make(chan struct{}),
+		stoppedCh: make(chan struct{}),
+	}
+}
+
+// Start begins the sweep loop in the background. A no-op if cfg.MaxAge is
+// zero.
+func (r *Rotator) Start(ctx context.Context) {
+	if r.cfg.MaxAge <= 0 {
+		close(r.stoppedCh)
+		return
+	}
+	go r.run(ctx)
+}
+
+// Stop halts the sweep loop and waits for the in-flight sweep to finish.
+func (r *Rotator) Stop() {
+	close(r.closeCh)
+	<-r.stoppedCh
+}
+
+func (r *Rotator) run(ctx context.Context) {
+	defer close(r.stoppedCh)
+
+	ticker := time.NewTicker(r.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.closeCh:
+			return
+		case <-ticker.C:
+			r.sweep(ctx)
+		}
+	}
+}
+
+func (r *Rotator) sweep(ctx context.Context) {
+	creds, err := r.store.List(ctx)
+	if err != nil {
+		r.logger.Warn("Credential rotation sweep failed to list jars", zap.Error(err))
+		return
+	}
+
+	for _, cred := range creds {
+		if time.Since(cred.UpdatedAt) < r.cfg.MaxAge {
+			continue
+		}
+		if err := r.store.Delete(ctx, cred.Platform); err != nil {
+			r.logger.Warn("Failed to evict expired credential",
+				zap.String("platform", cred.Platform),
+				zap.Error(err),
+			)
+			continue
+		}
+		r.logger.Info("Evicted expired credential jar",
+			zap.String("platform", cred.Platform),
+			zap.Duration("age", time.Since(cred.UpdatedAt)),
+		)
+	}
+}
@@ -0,0 +1,84 @@
+package dedup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/KeremKalyoncu/MedYan/internal/types"
+)
+
+// CanonicalizeURL normalizes rawURL so trivially-different links to the
+// same content (different casing, a trailing slash, tracking query
+// params) hash to the same Key instead of silently missing the dedup
+// cache. Falls back to a lowercased/trimmed copy of rawURL if it doesn't
+// parse as a URL at all.
+func CanonicalizeURL(rawURL string) string {
+	trimmed := strings.TrimSpace(rawURL)
+	u, err := url.Parse(trimmed)
+	if err != nil || u.Host == "" {
+		return strings.ToLower(strings.TrimSuffix(trimmed, "/"))
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	u.Fragment = ""
+
+	if u.RawQuery != "" {
+		q := u.Query()
+		for _, tracking := range []string{"utm_source", "utm_medium", "utm_campaign", "utm_content", "utm_term", "si", "feature"} {
+			q.Del(tracking)
+		}
+		u.RawQuery = q.Encode()
+	}
+
+	return u.String()
+}
+
+// Key returns a stable identifier for req's content-affecting fields
+// (canonicalized URL, quality, format, extractAudio, audioBitrate,
+// subtitles), for the dedup cache in internal/queue.Client. Two requests
+// for the same content in the same shape hash to the same Key regardless
+// of field order or trivial URL differences.
+func Key(req types.ExtractionRequest) string {
+	subtitles := append([]string(nil), req.Subtitles...)
+	sort.Strings(subtitles)
+
+	parts := fmt.Sprintf("%s|%s|%s|%t|%s|%s",
+		CanonicalizeURL(req.URL),
+		req.Quality,
+		req.Format,
+		req.ExtractAudio,
+		req.AudioBitrate,
+		strings.Join(subtitles, ","),
+	)
+
+	sum := sha256.Sum256([]byte(parts))
+	return hex.EncodeToString(sum[:])
+}
+
+// PlatformFromURL identifies the source platform for the dedup_hits_total
+// metric label, mirroring the detectPlatform helpers already duplicated
+// per-package (internal/extractor, internal/queue, internal/handlers).
+func PlatformFromURL(rawURL string) string {
+	lower := strings.ToLower(rawURL)
+
+	switch {
+	case strings.Contains(lower, "youtube.com"), strings.Contains(lower, "youtu.be"):
+		return "youtube"
+	case strings.Contains(lower, "instagram.com"):
+		return "instagram"
+	case strings.Contains(lower, "tiktok.com"):
+		return "tiktok"
+	case strings.Contains(lower, "twitter.com"), strings.Contains(lower, "x.com"):
+		return "twitter"
+	case strings.Contains(lower, "facebook.com"), strings.Contains(lower, "fb.watch"):
+		return "facebook"
+	default:
+		return "other"
+	}
+}
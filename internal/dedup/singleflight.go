@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -13,6 +14,29 @@ import (
 type Singleflight struct {
 	mu    sync.Mutex
 	calls map[string]*call
+
+	cacheMu sync.RWMutex
+	cache   map[string]cacheEntry
+
+	stats   sfStats
+	closeCh chan struct{}
+	once    sync.Once
+}
+
+// cacheEntry is a DoWithCache result retained past its originating call's
+// completion, for later callers of the same key to reuse without
+// re-invoking fn.
+type cacheEntry struct {
+	result   Result
+	expireAt time.Time
+}
+
+// sfStats are the hit/miss/coalesce counters DoWithCache instruments,
+// surfaced via Stats().
+type sfStats struct {
+	cacheHits   atomic.Uint64
+	cacheMisses atomic.Uint64
+	coalesced   atomic.Uint64
 }
 
 // call represents an in-flight or completed Do call
@@ -35,7 +59,9 @@ type Result struct {
 // NewSingleflight creates a new Singleflight instance
 func NewSingleflight() *Singleflight {
 	sf := &Singleflight{
-		calls: make(map[string]*call),
+		calls:   make(map[string]*call),
+		cache:   make(map[string]cacheEntry),
+		closeCh: make(chan struct{}),
 	}
 
 	// Start cleanup goroutine
@@ -139,40 +165,157 @@ func (sf *Singleflight) DoContext(ctx context.Context, key string, fn func() (in
 	}
 }
 
-// Forget removes a key from the in-flight calls map
-// This is useful if you want to force a retry
+// DoWithCache is like Do, but retains the result for ttl (or negTTL, if fn
+// returned an error) after the call completes. Callers for the same key
+// within that window get the retained Result without fn running again at
+// all - not even coalesced onto an in-flight call, since there isn't one.
+// This matters for bursts arriving just after an extraction finishes: Do
+// alone only coalesces callers that overlap in time with the original
+// call, while DoWithCache also covers the callers that arrive microseconds
+// after it returns. negTTL is typically shorter than ttl so a transient
+// extractor failure doesn't get replayed in a hot loop for as long as a
+// real result would be.
+func (sf *Singleflight) DoWithCache(key string, ttl, negTTL time.Duration, fn func() (interface{}, error)) Result {
+	sf.cacheMu.RLock()
+	entry, ok := sf.cache[key]
+	sf.cacheMu.RUnlock()
+	if ok && time.Now().Before(entry.expireAt) {
+		sf.stats.cacheHits.Add(1)
+		return Result{Val: entry.result.Val, Err: entry.result.Err, Shared: true}
+	}
+	sf.stats.cacheMisses.Add(1)
+
+	result := sf.Do(key, fn)
+	if result.Shared {
+		sf.stats.coalesced.Add(1)
+	}
+
+	effectiveTTL := ttl
+	if result.Err != nil {
+		effectiveTTL = negTTL
+	}
+	if effectiveTTL > 0 {
+		sf.cacheMu.Lock()
+		sf.cache[key] = cacheEntry{
+			result:   Result{Val: result.Val, Err: result.Err},
+			expireAt: time.Now().Add(effectiveTTL),
+		}
+		sf.cacheMu.Unlock()
+	}
+
+	return result
+}
+
+// Forget removes a key from the in-flight calls map and any DoWithCache
+// result retained for it. This is useful if you want to force a retry.
 func (sf *Singleflight) Forget(key string) {
 	sf.mu.Lock()
 	delete(sf.calls, key)
 	sf.mu.Unlock()
+
+	sf.cacheMu.Lock()
+	delete(sf.cache, key)
+	sf.cacheMu.Unlock()
 }
 
-// cleanup periodically removes stale entries (defensive)
+// cleanup periodically removes stale in-flight calls (defensive) and
+// expired DoWithCache entries, until Close is called.
 func (sf *Singleflight) cleanup() {
 	ticker := time.NewTicker(1 * time.Minute)
 	defer ticker.Stop()
 
-	for range ticker.C {
-		now := time.Now()
-		sf.mu.Lock()
-		for key, c := range sf.calls {
-			if now.After(c.deadline) {
-				delete(sf.calls, key)
+	for {
+		select {
+		case <-ticker.C:
+			now := time.Now()
+
+			sf.mu.Lock()
+			for key, c := range sf.calls {
+				if now.After(c.deadline) {
+					delete(sf.calls, key)
+				}
 			}
+			sf.mu.Unlock()
+
+			sf.cacheMu.Lock()
+			for key, entry := range sf.cache {
+				if now.After(entry.expireAt) {
+					delete(sf.cache, key)
+				}
+			}
+			sf.cacheMu.Unlock()
+		case <-sf.closeCh:
+			return
 		}
-		sf.mu.Unlock()
 	}
 }
 
-// Stats returns statistics about in-flight calls
+// Close stops the background cleanup goroutine. Safe to call more than
+// once.
+func (sf *Singleflight) Close() {
+	sf.once.Do(func() {
+		close(sf.closeCh)
+	})
+}
+
+// Stats returns statistics about in-flight calls and DoWithCache's
+// hit/miss/coalesce counters.
 func (sf *Singleflight) Stats() map[string]interface{} {
 	sf.mu.Lock()
-	defer sf.mu.Unlock()
+	inFlight := len(sf.calls)
+	sf.mu.Unlock()
+
+	sf.cacheMu.RLock()
+	cacheSize := len(sf.cache)
+	sf.cacheMu.RUnlock()
 
 	return map[string]interface{}{
-		"in_flight_calls": len(sf.calls),
+		"in_flight_calls": inFlight,
+		"cached_results":  cacheSize,
+		"cache_hits":      sf.stats.cacheHits.Load(),
+		"cache_misses":    sf.stats.cacheMisses.Load(),
+		"coalesced_calls": sf.stats.coalesced.Load(),
 	}
 }
 
 // ErrDuplicate indicates that a call was deduplicated (not an actual error)
 var ErrDuplicate = errors.New("request was deduplicated")
+
+// Group manages multiple independently-keyed Singleflight instances, so
+// different subsystems using the same literal key for different purposes
+// (e.g. metadata lookup vs. actual extraction, both keyed by URL) don't
+// collide in the same dedup/cache keyspace.
+type Group struct {
+	mu     sync.Mutex
+	groups map[string]*Singleflight
+}
+
+// NewGroup creates an empty Group. Named Singleflights are created lazily
+// on first use via For.
+func NewGroup() *Group {
+	return &Group{groups: make(map[string]*Singleflight)}
+}
+
+// For returns the Singleflight registered under name, creating it on first
+// use.
+func (g *Group) For(name string) *Singleflight {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	sf, ok := g.groups[name]
+	if !ok {
+		sf = NewSingleflight()
+		g.groups[name] = sf
+	}
+	return sf
+}
+
+// Close stops every Singleflight created via For.
+func (g *Group) Close() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for _, sf := range g.groups {
+		sf.Close()
+	}
+}
@@ -0,0 +1,175 @@
+// Package errclass classifies yt-dlp/ffmpeg failures by stderr text (and,
+// where available, exit code) so the queue layer can decide whether a job
+// is worth retrying instead of treating every failure the same way.
+//
+// The taxonomy comes from the same hard lesson every long-running scraper
+// learns eventually: some errors ("video unavailable", "removed for
+// violating") are permanent and burn retry budget for nothing, some
+// ("HTTP Error 429", "rate-limited") mean back off and try again from a
+// different egress, some ("Sign in to confirm your age", "private video",
+// "members only", cookie errors) mean the job needs fresh auth material
+// rather than another attempt, and
+// everything else is presumed transient and retryable.
+package errclass
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Class is the outcome of classifying a failed extraction.
+type Class string
+
+const (
+	// Retryable is the default: the failure looks transient (network
+	// reset, temporary 5xx, timeout) and a plain retry is likely to
+	// succeed.
+	Retryable Class = "retryable"
+	// Permanent means retrying is pointless - the content itself is
+	// unavailable, private, or removed.
+	Permanent Class = "permanent"
+	// RateLimited means the platform throttled this egress; retrying
+	// makes sense only after backing off and rotating to a fresh IP.
+	RateLimited Class = "rate_limited"
+	// AuthRequired means the job needs a fresh (or no longer stale)
+	// cookie jar before it can succeed, not another attempt with the
+	// same credentials.
+	AuthRequired Class = "auth_required"
+)
+
+// String implements fmt.Stringer so Class reads naturally in logs.
+func (c Class) String() string {
+	if c == "" {
+		return string(Retryable)
+	}
+	return string(c)
+}
+
+// Pattern maps a single case-insensitive regex to the Class it indicates.
+type Pattern struct {
+	Class   Class  `json:"class"`
+	Pattern string `json:"pattern"`
+}
+
+// defaultPatterns are the built-in rules, checked first-match-wins in the
+// order below (most specific/dangerous classes first, so e.g. a "private
+// video" message can't be shadowed by a looser retryable-looking pattern).
+var defaultPatterns = []Pattern{
+	{Class: AuthRequired, Pattern: `sign in to confirm`},
+	{Class: AuthRequired, Pattern: `login required`},
+	{Class: AuthRequired, Pattern: `cookies.*(expired|invalid|rejected)`},
+	{Class: AuthRequired, Pattern: `private video`},
+	{Class: AuthRequired, Pattern: `members[- ]only`},
+
+	{Class: Permanent, Pattern: `video unavailable`},
+	{Class: Permanent, Pattern: `this video has been removed`},
+	{Class: Permanent, Pattern: `account terminated`},
+	{Class: Permanent, Pattern: `copyright (claim|strike)`},
+	{Class: Permanent, Pattern: `removed for violating`},
+	{Class: Permanent, Pattern: `no video formats found`},
+	{Class: Permanent, Pattern: `unsupported url`},
+
+	{Class: RateLimited, Pattern: `http error 429`},
+	{Class: RateLimited, Pattern: `rate.?limit`},
+	{Class: RateLimited, Pattern: `too many requests`},
+	{Class: RateLimited, Pattern: `temporarily blocked`},
+}
+
+// compiledPattern is a Pattern with its regex pre-compiled, so Classify
+// doesn't recompile the table on every call.
+type compiledPattern struct {
+	class Class
+	re    *regexp.Regexp
+}
+
+// Classifier classifies stderr output against a compiled pattern table.
+type Classifier struct {
+	patterns []compiledPattern
+}
+
+// NewClassifier compiles defaultPatterns followed by extra (so operator
+// overrides in extra are checked after, and therefore never shadow, the
+// built-ins - extra is meant to add platform-specific patterns, not
+// override safety-critical ones).
+func NewClassifier(extra []Pattern) (*Classifier, error) {
+	all := make([]Pattern, 0, len(defaultPatterns)+len(extra))
+	all = append(all, defaultPatterns...)
+	all = append(all, extra...)
+
+	compiled := make([]compiledPattern, 0, len(all))
+	for _, p := range all {
+		re, err := regexp.Compile("(?i)" + p.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("errclass: invalid pattern %q: %w", p.Pattern, err)
+		}
+		compiled = append(compiled, compiledPattern{class: p.Class, re: re})
+	}
+
+	return &Classifier{patterns: compiled}, nil
+}
+
+// Classify returns the first matching Class for stderr, or Retryable if
+// nothing matches. exitCode is currently informational only (kept for
+// future exit-code-driven rules); pass -1 when it isn't available.
+func (c *Classifier) Classify(stderr string, exitCode int) Class {
+	for _, p := range c.patterns {
+		if p.re.MatchString(stderr) {
+			return p.class
+		}
+	}
+	return Retryable
+}
+
+// DefaultClassifier is the package-level Classifier built from
+// defaultPatterns alone, for callers that don't need operator-supplied
+// extensions.
+var DefaultClassifier = mustNewClassifier()
+
+func mustNewClassifier() *Classifier {
+	c, err := NewClassifier(nil)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// Classify classifies stderr/exitCode using DefaultClassifier.
+func Classify(stderr string, exitCode int) Class {
+	return DefaultClassifier.Classify(stderr, exitCode)
+}
+
+// LoadPatternsFile reads operator-supplied extra patterns from a file, one
+// "class pattern" pair per line (separated by the first space - not a tab -
+// and the pattern may itself contain spaces), so error classification can be extended without a rebuild.
+// Blank lines and lines starting with '#' are ignored.
+func LoadPatternsFile(path string) ([]Pattern, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("errclass: failed to open patterns file: %w", err)
+	}
+	defer f.Close()
+
+	var patterns []Pattern
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.SplitN(line, " ", 2)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("errclass: malformed patterns line %q (want \"class pattern\")", line)
+		}
+
+		patterns = append(patterns, Pattern{Class: Class(fields[0]), Pattern: strings.TrimSpace(fields[1])})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("errclass: failed to read patterns file: %w", err)
+	}
+
+	return patterns, nil
+}
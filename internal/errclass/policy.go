@@ -0,0 +1,98 @@
+package errclass
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Policy turns a Class and an attempt number into a Decision, driven by
+// WorkerConfig.MaxRetries/RetryDelayBase (see internal/config). It mirrors
+// internal/retry's exponential-backoff-with-jitter shape rather than
+// introducing a second scheduler.
+type Policy struct {
+	// MaxRetries is the maximum number of retries (not counting the
+	// initial attempt) for Retryable/RateLimited failures.
+	MaxRetries int
+	// RetryDelayBase is the base delay for exponential backoff; doubled
+	// per attempt and capped at maxRetryDelay.
+	RetryDelayBase time.Duration
+}
+
+// maxRetryDelay caps the exponential backoff the same way internal/retry's
+// DefaultConfig caps at 10s, just scaled up for whole extraction jobs
+// rather than single HTTP calls.
+const maxRetryDelay = 5 * time.Minute
+
+// retryJitter is the fraction of the computed delay randomized, matching
+// internal/retry.DefaultConfig's 30%.
+const retryJitter = 0.3
+
+// Decision is what a Policy recommends doing about one failed attempt.
+type Decision struct {
+	// SkipRetry means the caller should stop retrying this job
+	// (wrap the error with asynq.SkipRetry).
+	SkipRetry bool
+	// RotateEgress means the next attempt, if any, should run from a
+	// different IP/proxy than this one.
+	RotateEgress bool
+	// NeedsCookies means the job should be routed to
+	// types.StatusNeedsCookies rather than retried.
+	NeedsCookies bool
+	// Delay is how long to wait before the next attempt. Meaningless
+	// when SkipRetry is true.
+	Delay time.Duration
+}
+
+// Decide returns the Decision for class on the given attempt (1-indexed:
+// 1 is the first failure, so attempt-1 retries have already been made).
+// Retries stop once MaxRetries retries have been made.
+func (p Policy) Decide(class Class, attempt int) Decision {
+	switch class {
+	case Permanent:
+		return Decision{SkipRetry: true}
+
+	case AuthRequired:
+		return Decision{SkipRetry: true, NeedsCookies: true}
+
+	case RateLimited:
+		if attempt > p.maxRetries() {
+			return Decision{SkipRetry: true, RotateEgress: true}
+		}
+		return Decision{RotateEgress: true, Delay: p.backoff(attempt)}
+
+	default: // Retryable
+		if attempt > p.maxRetries() {
+			return Decision{SkipRetry: true}
+		}
+		return Decision{Delay: p.backoff(attempt)}
+	}
+}
+
+func (p Policy) maxRetries() int {
+	if p.MaxRetries <= 0 {
+		return 3
+	}
+	return p.MaxRetries
+}
+
+func (p Policy) retryDelayBase() time.Duration {
+	if p.RetryDelayBase <= 0 {
+		return 2 * time.Second
+	}
+	return p.RetryDelayBase
+}
+
+// backoff computes exponential backoff randomized by +/-retryJitter, the same
+// calculation internal/retry.calculateDelay uses.
+func (p Policy) backoff(attempt int) time.Duration {
+	backoff := float64(p.retryDelayBase()) * math.Pow(2, float64(attempt-1))
+	if backoff > float64(maxRetryDelay) {
+		backoff = float64(maxRetryDelay)
+	}
+
+	jitterAmount := backoff * retryJitter
+	backoff = backoff - jitterAmount + (rand.Float64() * jitterAmount * 2)
+
+	return time.Duration(backoff)
+}
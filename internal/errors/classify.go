@@ -0,0 +1,35 @@
+package errors
+
+import "regexp"
+
+// classifyRule maps a yt-dlp/ffmpeg stderr signature to the CustomError
+// Classify should report for it. Checked first-match-wins, most specific
+// first, mirroring internal/errclass's pattern table.
+type classifyRule struct {
+	re  *regexp.Regexp
+	err *CustomError
+}
+
+var classifyRules = []classifyRule{
+	{regexp.MustCompile(`(?i)sign in to confirm|login required|this video is only available to registered users|cookies.*(expired|invalid|rejected)`), ErrLoginRequired},
+	{regexp.MustCompile(`(?i)private video|this is a private video`), ErrPrivateVideo},
+	{regexp.MustCompile(`(?i)not available in your country|geo.?restrict|not available in your location`), ErrGeoBlocked},
+	{regexp.MustCompile(`(?i)premieres in|this live event will begin|live event has not (started|ended)`), ErrLiveNotEnded},
+	{regexp.MustCompile(`(?i)copyright (claim|strike)|blocked it on copyright grounds|removed for violating`), ErrCopyrightBlocked},
+	{regexp.MustCompile(`(?i)requested format is not available|no video formats found`), ErrFormatUnavailable},
+	{regexp.MustCompile(`(?i)http error 429|rate.?limit|too many requests|temporarily blocked`), ErrThrottled},
+}
+
+// Classify inspects stderr - yt-dlp/ffmpeg's combined output for a failed
+// extraction - for a known failure signature and returns the matching
+// typed CustomError with err attached as Cause. Falls back to
+// ErrExtractionFailed (not retryable) when nothing matches, since an
+// unrecognized failure shouldn't be assumed safe to retry indefinitely.
+func Classify(err error, stderr string) *CustomError {
+	for _, rule := range classifyRules {
+		if rule.re.MatchString(stderr) {
+			return rule.err.WithCause(err)
+		}
+	}
+	return ErrExtractionFailed.WithCause(err)
+}
@@ -3,6 +3,7 @@ package errors
 import (
 	"errors"
 	"fmt"
+	"time"
 )
 
 // CustomError represents an application error with metadata
@@ -12,6 +13,23 @@ type CustomError struct {
 	StatusCode int         // HTTP status code
 	Cause      error       // Underlying error
 	Details    interface{} // Additional error details
+
+	// Retryable reports whether the caller is expected to succeed by
+	// retrying as-is (or after RetryAfter). Set on the pre-defined errors
+	// below; defaults to false for anything constructed directly with
+	// NewCustomError, since an unclassified error shouldn't be assumed
+	// safe to retry.
+	Retryable bool
+	// RetryAfter is how long a retry should wait, for errors where that's
+	// known (e.g. a platform-supplied backoff). Zero means "no specific
+	// guidance" - callers fall back to their own backoff policy.
+	RetryAfter time.Duration
+	// Platform is the source platform (e.g. "youtube", "instagram") this
+	// error was classified for, when known. Set via WithPlatform rather
+	// than at construction, since the pre-defined errors below are
+	// platform-agnostic until a caller attaches one (Classify itself
+	// doesn't; ExtractionHandler calls WithPlatform on its result).
+	Platform string
 }
 
 // Error implements the error interface
@@ -57,6 +75,29 @@ func (e *CustomError) WithDetails(details interface{}) *CustomError {
 	return e
 }
 
+// WithPlatform records which source platform this error was classified
+// for.
+func (e *CustomError) WithPlatform(platform string) *CustomError {
+	e.Platform = platform
+	return e
+}
+
+// WithRetryAfter records a platform-supplied (or otherwise known) backoff
+// duration for this error, and implies Retryable.
+func (e *CustomError) WithRetryAfter(d time.Duration) *CustomError {
+	e.RetryAfter = d
+	e.Retryable = true
+	return e
+}
+
+// WithRetryable overrides whether this error is worth retrying, for
+// callers that know better than the pre-defined default (e.g. a caller
+// deciding a normally-permanent error was actually transient this time).
+func (e *CustomError) WithRetryable(retryable bool) *CustomError {
+	e.Retryable = retryable
+	return e
+}
+
 // Pre-defined errors
 var (
 	// Validation errors (400)
@@ -99,11 +140,83 @@ var (
 	)
 
 	// Rate limiting (429)
-	ErrRateLimited = NewCustomError(
-		"RATE_LIMITED",
-		"Too many requests. Please try again later",
-		429,
-	)
+	ErrRateLimited = &CustomError{
+		Code:       "RATE_LIMITED",
+		Message:    "Too many requests. Please try again later",
+		StatusCode: 429,
+		Retryable:  true,
+	}
+
+	// Busy (503): the ffmpeg worker pool's bounded queue is full (see
+	// internal/pool.FFmpegPool, pool.ErrPoolSaturated). Distinct from
+	// ErrRateLimited - this isn't about the caller's request rate, it's this
+	// host being out of transcoding capacity right now.
+	ErrBusy = &CustomError{
+		Code:       "BUSY",
+		Message:    "The server is at capacity, please retry shortly",
+		StatusCode: 503,
+		Retryable:  true,
+	}
+
+	// Platform-classified extraction failures (see Classify). Each mirrors
+	// a yt-dlp/ffmpeg stderr signature that's common enough, and distinct
+	// enough in what the caller should do about it, to warrant its own
+	// code rather than falling back to the generic ErrExtractionFailed.
+	ErrGeoBlocked = &CustomError{
+		Code:       "GEO_BLOCKED",
+		Message:    "This content is not available in the extractor's region",
+		StatusCode: 403,
+		Retryable:  false,
+	}
+
+	ErrLoginRequired = &CustomError{
+		Code:       "LOGIN_REQUIRED",
+		Message:    "This content requires an authenticated session to access",
+		StatusCode: 401,
+		Retryable:  false,
+	}
+
+	ErrPrivateVideo = &CustomError{
+		Code:       "PRIVATE_VIDEO",
+		Message:    "This content is private",
+		StatusCode: 403,
+		Retryable:  false,
+	}
+
+	// ErrLiveNotEnded is retryable - the stream just hasn't finished yet,
+	// so a later attempt against the same URL is expected to work.
+	ErrLiveNotEnded = &CustomError{
+		Code:       "LIVE_NOT_ENDED",
+		Message:    "This live stream has not started or finished yet",
+		StatusCode: 425,
+		Retryable:  true,
+		RetryAfter: 5 * time.Minute,
+	}
+
+	ErrCopyrightBlocked = &CustomError{
+		Code:       "COPYRIGHT_BLOCKED",
+		Message:    "This content has been blocked on copyright grounds",
+		StatusCode: 403,
+		Retryable:  false,
+	}
+
+	ErrFormatUnavailable = &CustomError{
+		Code:       "FORMAT_UNAVAILABLE",
+		Message:    "The requested format or quality is not available for this content",
+		StatusCode: 422,
+		Retryable:  false,
+	}
+
+	// ErrThrottled mirrors errclass.RateLimited but as a CustomError, for
+	// callers (ExtractionHandler.handleError) working in that domain
+	// rather than errclass's.
+	ErrThrottled = &CustomError{
+		Code:       "THROTTLED",
+		Message:    "The platform is rate-limiting this request",
+		StatusCode: 429,
+		Retryable:  true,
+		RetryAfter: 30 * time.Second,
+	}
 
 	// Server errors (500)
 	ErrInternal = NewCustomError(
@@ -0,0 +1,15 @@
+package extractor
+
+import (
+	"context"
+
+	"github.com/KeremKalyoncu/MedYan/internal/types"
+)
+
+// Extractor is the common surface YtDlp, NativeYouTube, and
+// FallbackExtractor all implement, so a caller that only needs metadata and
+// downloads can depend on it instead of a concrete *YtDlp.
+type Extractor interface {
+	ExtractMetadata(ctx context.Context, url string) (*types.MediaMetadata, error)
+	Download(ctx context.Context, url, outputPath string, opts DownloadOptions) (*types.MediaMetadata, error)
+}
@@ -0,0 +1,75 @@
+package extractor
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/KeremKalyoncu/MedYan/internal/circuitbreaker"
+	"github.com/KeremKalyoncu/MedYan/internal/types"
+)
+
+// FallbackExtractor tries primary first and, when it fails with an error
+// that looks like yt-dlp being blocked rather than the video itself being
+// unavailable (an open circuit breaker, or a rate-limit error that
+// survived every retry), falls back to fallback - but only for URLs
+// fallback actually supports (see its CanHandle-less platform guard:
+// youtube-only for NativeYouTube today, so this checks that directly
+// rather than adding a CanHandle method neither Extractor needs yet).
+type FallbackExtractor struct {
+	primary  Extractor
+	fallback Extractor
+	logger   *zap.Logger
+}
+
+// NewFallbackExtractor creates a FallbackExtractor that tries primary, then
+// fallback for YouTube URLs when primary's error looks like it's being
+// blocked rather than the video being genuinely unavailable.
+func NewFallbackExtractor(primary, fallback Extractor, logger *zap.Logger) *FallbackExtractor {
+	return &FallbackExtractor{primary: primary, fallback: fallback, logger: logger}
+}
+
+func (f *FallbackExtractor) ExtractMetadata(ctx context.Context, url string) (*types.MediaMetadata, error) {
+	metadata, err := f.primary.ExtractMetadata(ctx, url)
+	if err == nil || !f.shouldFallback(url, err) {
+		return metadata, err
+	}
+
+	f.logger.Warn("Primary extractor blocked, falling back",
+		zap.String("url", url),
+		zap.Error(err),
+	)
+	return f.fallback.ExtractMetadata(ctx, url)
+}
+
+func (f *FallbackExtractor) Download(ctx context.Context, url, outputPath string, opts DownloadOptions) (*types.MediaMetadata, error) {
+	metadata, err := f.primary.Download(ctx, url, outputPath, opts)
+	if err == nil || !f.shouldFallback(url, err) {
+		return metadata, err
+	}
+
+	f.logger.Warn("Primary extractor blocked, falling back",
+		zap.String("url", url),
+		zap.Error(err),
+	)
+	return f.fallback.Download(ctx, url, outputPath, opts)
+}
+
+// shouldFallback reports whether err from primary on url warrants trying
+// fallback: url must be a YouTube URL (the only platform NativeYouTube
+// supports), and err must be an open circuit breaker or a rate-limit error
+// that isRetryableError already tried and exhausted every retry attempt on.
+func (f *FallbackExtractor) shouldFallback(url string, err error) bool {
+	if detectPlatform(url) != "youtube" {
+		return false
+	}
+
+	if errors.Is(err, circuitbreaker.ErrCircuitOpen) || errors.Is(err, circuitbreaker.ErrTooManyRequests) {
+		return true
+	}
+
+	errStr := strings.ToLower(err.Error())
+	return strings.Contains(errStr, "429") || strings.Contains(errStr, "rate")
+}
@@ -1,35 +1,187 @@
 package extractor
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	ffmpeg "github.com/u2takey/ffmpeg-go"
 	"go.uber.org/zap"
+
+	"github.com/KeremKalyoncu/MedYan/internal/metrics"
+	"github.com/KeremKalyoncu/MedYan/internal/pool"
+	"github.com/KeremKalyoncu/MedYan/internal/types"
 )
 
+// hwFallbackLogOnce caps "falling back to software encoding" logging to
+// once per process, across every FFmpeg instance and every hardware
+// failure - the failure itself still falls back per-call, this only avoids
+// repeating the same warning for every subsequent job on a host whose GPU
+// just isn't usable.
+var hwFallbackLogOnce sync.Once
+
 // FFmpeg wraps ffmpeg-go for media transcoding
 type FFmpeg struct {
-	binaryPath string
-	timeout    time.Duration
-	logger     *zap.Logger
+	binaryPath  string
+	ffprobePath string
+	timeout     time.Duration
+	logger      *zap.Logger
+	registry    *ProcessRegistry
+	pool        *pool.FFmpegPool
+
+	hwAccelMode     HWAccelFamily
+	hwAccelOnce     sync.Once
+	hwAccelDetected HWAccelFamily
 }
 
-// NewFFmpeg creates a new FFmpeg wrapper
-func NewFFmpeg(binaryPath string, timeout time.Duration, logger *zap.Logger) *FFmpeg {
+// NewFFmpeg creates a new FFmpeg wrapper. ffprobePath is used by
+// GetMediaInfo; timeout bounds both ffmpeg transcodes and ffprobe calls.
+func NewFFmpeg(binaryPath, ffprobePath string, timeout time.Duration, logger *zap.Logger) *FFmpeg {
 	return &FFmpeg{
-		binaryPath: binaryPath,
-		timeout:    timeout,
-		logger:     logger,
+		binaryPath:  binaryPath,
+		ffprobePath: ffprobePath,
+		timeout:     timeout,
+		logger:      logger,
+	}
+}
+
+// SetRegistry wires a ProcessRegistry so transcodes are tracked for the idle
+// watchdog and the admin cancel endpoint. Optional - a nil registry (the
+// default) disables tracking entirely. Tracked ffmpeg processes are the
+// *exec.Cmd that ffmpeg-go's Compile returns, registered with a no-op
+// cancel func, so canceling one kills its process directly.
+func (f *FFmpeg) SetRegistry(registry *ProcessRegistry) {
+	f.registry = registry
+}
+
+// SetPool wires an internal/pool.FFmpegPool so every tracked transcode runs
+// under its concurrency cap rather than unboundedly alongside HTTP request
+// handling. Optional - a nil pool (the default) runs transcodes inline,
+// same as before this was introduced.
+func (f *FFmpeg) SetPool(p *pool.FFmpegPool) {
+	f.pool = p
+}
+
+// SetHWAccelMode configures which hardware encoder family ConvertFormat,
+// DownscaleVideo, and CompressVideo should prefer. HWAccelAuto (the
+// default, used when this is never called) probes the host lazily on first
+// use via DetectHWAccel and caches the result; HWAccelOff always encodes in
+// software. Any accelerated run that fails with one of ffmpeg's own
+// device-initialization errors (see isHWFallbackError) falls back to
+// software automatically regardless of mode.
+func (f *FFmpeg) SetHWAccelMode(mode HWAccelFamily) {
+	f.hwAccelMode = mode
+}
+
+// resolvedHWAccel returns the hwaccel family to actually use: hwAccelMode
+// verbatim unless it's HWAccelAuto or unset, in which case DetectHWAccel's
+// result is cached (via hwAccelOnce) for the life of this FFmpeg instance.
+func (f *FFmpeg) resolvedHWAccel() HWAccelFamily {
+	mode := f.hwAccelMode
+	if mode == "" {
+		mode = HWAccelAuto
+	}
+	if mode != HWAccelAuto {
+		return mode
+	}
+
+	f.hwAccelOnce.Do(func() {
+		f.hwAccelDetected = DetectHWAccel(f.binaryPath)
+		f.logger.Info("Hardware acceleration autodetected",
+			zap.String("family", string(f.hwAccelDetected)),
+		)
+	})
+	return f.hwAccelDetected
+}
+
+// isHWFallbackError reports whether msg - an accelerated ffmpeg run's
+// captured stderr - contains one of ffmpeg's own hardware-initialization
+// failure markers (a missing/unusable GPU or driver), as opposed to a real
+// encoding error that should be surfaced as-is.
+func isHWFallbackError(msg string) bool {
+	lower := strings.ToLower(msg)
+	return strings.Contains(lower, "no capable devices") || strings.Contains(lower, "device creation failed")
+}
+
+// softwareScaleFilter is the "-vf" value every hw family falls back to when
+// it has no scaling filter of its own (see hwEncodeParams.filter) and what
+// ConvertFormat/CompressVideo/DownscaleVideo use in pure software mode.
+// targetHeight <= 0 means no scaling is requested at all.
+func softwareScaleFilter(targetHeight int) string {
+	if targetHeight <= 0 {
+		return ""
 	}
+	return fmt.Sprintf("scale=-2:%d", targetHeight)
 }
 
-// ExtractAudio extracts audio from video file
-func (f *FFmpeg) ExtractAudio(ctx context.Context, inputPath, format, bitrate string) (string, error) {
+// hwStreamBuilder builds the ffmpeg-go Stream for one encode attempt: codec
+// and vf are the resolved "-c:v"/"-vf" values (already hw-mapped, or the
+// plain software ones), inputArgs/extraOut are merged into the Input/Output
+// kwargs respectively (both empty for the software path), captureErr, when
+// non-nil, captures ffmpeg's stderr instead of the usual ErrorToStdOut() so
+// runHWEncode can inspect it for a fallback marker, and withProgress, when
+// true, adds the `-progress pipe:1` kwargs (see withProgressKwargs) since
+// runHWEncode has a ProgressCallback to feed.
+type hwStreamBuilder func(codec, vf string, inputArgs, extraOut ffmpeg.KwArgs, captureErr io.Writer, withProgress bool) *ffmpeg.Stream
+
+// runHWEncode runs build under this FFmpeg's resolved hardware acceleration
+// family, falling back to softCodec in software when either there's no hw
+// mapping for softCodec/the configured family, or the accelerated attempt
+// fails with isHWFallbackError. targetHeight is the scaling target (0 for
+// callers, like ConvertFormat/CompressVideo, that don't scale). durationSecs
+// and callback are forwarded to runTrackedWithProgress unchanged - callback
+// may be nil, in which case no progress instrumentation is added.
+func (f *FFmpeg) runHWEncode(ctx context.Context, operation, taskID, softCodec string, targetHeight int, durationSecs float64, callback ProgressCallback, build hwStreamBuilder) error {
+	family := f.resolvedHWAccel()
+	params := hwVideoParams(family, softCodec)
+	withProgress := callback != nil
+
+	runSoftware := func() error {
+		stream := build(softCodec, softwareScaleFilter(targetHeight), nil, nil, nil, withProgress)
+		return f.runTrackedWithProgress(operation, taskID, stream, durationSecs, callback)
+	}
+
+	if params.videoCodec == "" {
+		return runSoftware()
+	}
+
+	vf := softwareScaleFilter(targetHeight)
+	if params.filter != nil {
+		vf = params.filter(targetHeight)
+	}
+
+	var stderr bytes.Buffer
+	hwStream := build(params.videoCodec, vf, params.inputArgs, params.extraOut, &stderr, withProgress)
+	err := f.runTrackedWithProgress(operation, taskID, hwStream, durationSecs, callback)
+	if err == nil {
+		return nil
+	}
+	if !isHWFallbackError(stderr.String()) {
+		return err
+	}
+
+	hwFallbackLogOnce.Do(func() {
+		f.logger.Warn("Hardware-accelerated ffmpeg run failed, falling back to software encoding",
+			zap.String("family", string(family)),
+			zap.String("operation", operation),
+			zap.Error(err),
+		)
+	})
+
+	return runSoftware()
+}
+
+// ExtractAudio extracts audio from video file. progressCallback, when
+// non-nil, receives one FFmpegProgress per `-progress` tick ffmpeg reports
+// (see ProgressCallback) - pass nil for callers that don't need it.
+func (f *FFmpeg) ExtractAudio(ctx context.Context, inputPath, format, bitrate string, progressCallback ProgressCallback) (string, error) {
 	outputPath := f.generateOutputPath(inputPath, format)
 
 	f.logger.Info("Extracting audio",
@@ -47,23 +199,29 @@ func (f *FFmpeg) ExtractAudio(ctx context.Context, inputPath, format, bitrate st
 	if bitrate != "" {
 		kwargs["audio_bitrate"] = bitrate
 	}
+	if progressCallback != nil {
+		withProgressKwargs(kwargs)
+	}
 
-	err := ffmpeg.Input(inputPath).
+	stream := ffmpeg.Input(inputPath).
 		Output(outputPath, kwargs).
 		OverWriteOutput().
 		ErrorToStdOut().
-		SetFfmpegPath(f.binaryPath).
-		Run()
+		SetFfmpegPath(f.binaryPath)
 
-	if err != nil {
+	duration := f.durationForProgress(ctx, inputPath, progressCallback)
+	if err := f.runTrackedWithProgress("extract_audio", "", stream, duration, progressCallback); err != nil {
 		return "", fmt.Errorf("audio extraction failed: %w", err)
 	}
 
 	return outputPath, nil
 }
 
-// ConvertFormat converts video between formats
-func (f *FFmpeg) ConvertFormat(ctx context.Context, inputPath, outputFormat, codec, bitrate string) (string, error) {
+// ConvertFormat converts video between formats. taskID, when set, registers
+// the ffmpeg process with the extractor process registry so the idle
+// watchdog and admin cancel endpoint can see and stop it. progressCallback,
+// when non-nil, receives one FFmpegProgress per `-progress` tick.
+func (f *FFmpeg) ConvertFormat(ctx context.Context, inputPath, outputFormat, codec, bitrate, taskID string, progressCallback ProgressCallback) (string, error) {
 	if strings.TrimSpace(outputFormat) == "" {
 		return "", fmt.Errorf("output format is required")
 	}
@@ -95,33 +253,520 @@ func (f *FFmpeg) ConvertFormat(ctx context.Context, inputPath, outputFormat, cod
 		zap.String("codec", codec),
 	)
 
+	build := func(videoCodec, vf string, inputArgs, extraOut ffmpeg.KwArgs, captureErr io.Writer, withProgress bool) *ffmpeg.Stream {
+		kwargs := ffmpeg.KwArgs{
+			"c:v": videoCodec,
+			"c:a": "copy", // Copy audio stream if possible
+		}
+		if bitrate != "" {
+			kwargs["b:v"] = bitrate
+		}
+		if vf != "" {
+			kwargs["vf"] = vf
+		}
+		for k, v := range extraOut {
+			kwargs[k] = v
+		}
+		if withProgress {
+			withProgressKwargs(kwargs)
+		}
+
+		var input *ffmpeg.Stream
+		if len(inputArgs) > 0 {
+			input = ffmpeg.Input(inputPath, inputArgs)
+		} else {
+			input = ffmpeg.Input(inputPath)
+		}
+
+		out := input.Output(outputPath, kwargs).
+			OverWriteOutput().
+			SetFfmpegPath(f.binaryPath)
+		if captureErr != nil {
+			return out.WithErrorOutput(captureErr)
+		}
+		return out.ErrorToStdOut()
+	}
+
+	duration := f.durationForProgress(ctx, inputPath, progressCallback)
+	if err := f.runHWEncode(ctx, "convert", taskID, codec, 0, duration, progressCallback, build); err != nil {
+		return "", fmt.Errorf("format conversion failed: %w", err)
+	}
+
+	return outputPath, nil
+}
+
+// Remux stream-copies inputPath into a sibling file with the same
+// container, adding -movflags +faststart so the moov atom moves to the
+// front. Used by the post-download verification pass to repair a file
+// whose streams are fine but whose muxing is broken (e.g. a fragmented MP4
+// with no leading moov atom) without re-encoding.
+func (f *FFmpeg) Remux(ctx context.Context, inputPath string) (string, error) {
+	outputPath := f.appendSuffix(inputPath, "_remuxed")
+
+	stream := ffmpeg.Input(inputPath).
+		Output(outputPath, ffmpeg.KwArgs{
+			"c":        "copy",
+			"movflags": "+faststart",
+		}).
+		OverWriteOutput().
+		SetFfmpegPath(f.binaryPath).
+		ErrorToStdOut()
+
+	if err := f.runTracked("remux", "", stream); err != nil {
+		return "", fmt.Errorf("remux failed: %w", err)
+	}
+
+	return outputPath, nil
+}
+
+// ConvertFormatStream is ConvertFormat's diskless counterpart: instead of
+// writing to a named output file, it pipes ffmpeg's stdout to the returned
+// io.ReadCloser so a caller (see ExtractionHandler's streaming upload path)
+// can forward the converted bytes straight into a storage.Storage.
+// UploadMultipart call without the result ever touching local disk. Only
+// containers whose muxer doesn't need to seek back and rewrite a header
+// once writing finishes can be piped this way - the caller is responsible
+// for only requesting one of those (mp4/mov need -movflags faststart,
+// which this method doesn't attempt). taskID is tracked the same way
+// ConvertFormat's is.
+func (f *FFmpeg) ConvertFormatStream(ctx context.Context, inputPath, outputFormat, codec, taskID string) (io.ReadCloser, error) {
+	if strings.TrimSpace(outputFormat) == "" {
+		return nil, fmt.Errorf("output format is required")
+	}
+	container := strings.TrimPrefix(strings.ToLower(outputFormat), ".")
+
+	f.logger.Info("Converting format (streaming)",
+		zap.String("input", inputPath),
+		zap.String("container", container),
+		zap.String("codec", codec),
+	)
+
 	kwargs := ffmpeg.KwArgs{
 		"c:v": codec,
+		"c:a": "copy",
+		"f":   container, // piped output has no filename to infer the container from
 	}
 
-	if bitrate != "" {
-		kwargs["b:v"] = bitrate
+	pr, pw := io.Pipe()
+
+	stream := ffmpeg.Input(inputPath).
+		Output("pipe:1", kwargs).
+		WithOutput(pw).
+		OverWriteOutput().
+		ErrorToStdOut().
+		SetFfmpegPath(f.binaryPath)
+
+	go func() {
+		err := f.runTracked("convert_stream", taskID, stream)
+		if err != nil {
+			err = fmt.Errorf("format conversion failed: %w", err)
+		}
+		pw.CloseWithError(err)
+	}()
+
+	return pr, nil
+}
+
+// runTracked runs a compiled ffmpeg stream to completion, enforcing f.timeout
+// and - when taskID is set and a registry is configured - registering the
+// underlying *exec.Cmd so it can be killed by the idle watchdog or the admin
+// cancel endpoint. operation (e.g. "convert", "hls_package") labels the
+// ffmpeg_exec_seconds histogram this run is recorded against.
+func (f *FFmpeg) runTracked(operation, taskID string, stream *ffmpeg.Stream) error {
+	start := time.Now()
+	defer func() {
+		metrics.GetMetrics().RecordFFmpegExec(operation, time.Since(start))
+	}()
+
+	if f.pool != nil {
+		return f.pool.RunGated(context.Background(), func() error {
+			return f.runTrackedDirect(taskID, stream)
+		})
 	}
+	return f.runTrackedDirect(taskID, stream)
+}
 
-	// Copy audio stream if possible
-	kwargs["c:a"] = "copy"
+// runTrackedDirect is runTracked's actual body, split out so it can run
+// either inline or gated by an FFmpegPool's concurrency limit.
+func (f *FFmpeg) runTrackedDirect(taskID string, stream *ffmpeg.Stream) error {
+	cmd := stream.Compile()
 
-	err := ffmpeg.Input(inputPath).
-		Output(outputPath, kwargs).
+	if f.registry != nil && taskID != "" {
+		// ffmpeg-go builds the command without a context, so there's no
+		// cancel func to derive; Cancel/killIdle fall back to killing
+		// cmd.Process directly, which this no-op satisfies.
+		f.registry.Track(taskID, "ffmpeg", cmd, func() {})
+		defer f.registry.Untrack(taskID)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start ffmpeg: %w", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(f.timeout):
+		if cmd.Process != nil {
+			cmd.Process.Kill()
+		}
+		<-done
+		return fmt.Errorf("ffmpeg timed out after %s", f.timeout)
+	}
+}
+
+// Rendition describes one output in an adaptive-bitrate HLS/DASH ladder,
+// such as the "1080p"/"720p"/"480p" entries in types.QualityPresets. Unlike
+// the single-variant-per-process design this replaces, every Rendition in
+// a PackageHLS/PackageDASH call is produced by one ffmpeg invocation.
+type Rendition struct {
+	Name         string // label only - used for logging, not file naming
+	Height       int    // target output height; width is derived via scale=-2:H, so 0 leaves the source resolution untouched
+	VideoBitrate string // ffmpeg bitrate string, e.g. "5M"
+	AudioBitrate string // e.g. "192k"
+	VideoCodec   string // defaults to "libx264" if empty
+	AudioCodec   string // defaults to "aac" if empty
+}
+
+// RenditionsFromNames resolves a list of quality preset names (e.g.
+// "1080p", "720p") to Renditions using types.QualityPresets, skipping any
+// name that isn't a known preset. VideoCodec/AudioCodec are left at their
+// defaults since QualityPresets doesn't specify a codec.
+func RenditionsFromNames(names []string) []Rendition {
+	renditions := make([]Rendition, 0, len(names))
+	for _, name := range names {
+		preset, ok := types.QualityPresets[name]
+		if !ok {
+			continue
+		}
+		renditions = append(renditions, Rendition{
+			Name:         preset.Name,
+			Height:       preset.MaxHeight,
+			VideoBitrate: preset.VideoBitrate,
+			AudioBitrate: preset.AudioBitrate,
+		})
+	}
+	return renditions
+}
+
+// PackageOptions controls PackageHLS/PackageDASH's segmenting, output
+// location, and process tracking.
+type PackageOptions struct {
+	OutputDir      string
+	SegmentSeconds int    // defaults to 6 if <= 0
+	TaskID         string // tracked the same way ConvertFormat's taskID is
+	// ProgressCallback, when non-nil, receives one FFmpegProgress per
+	// `-progress` tick PackageHLS's underlying ffmpeg process reports.
+	ProgressCallback ProgressCallback
+}
+
+// PackageResult lists every file PackageHLS/PackageDASH produced - read
+// back from OutputDir after the run completes rather than predicted ahead
+// of time, since the exact segment count is ffmpeg's decision - so the
+// storage layer can upload the master manifest, the per-rendition
+// manifests, and every segment together.
+type PackageResult struct {
+	Dir            string
+	MasterPlaylist string
+	Files          []string
+}
+
+// hlsVideoCodecTags/hlsAudioCodecTags map ffmpeg encoder names to the
+// RFC 6381 codec strings HLS clients use to decide whether they can even
+// attempt a variant before downloading it. These are the commonly-used
+// defaults for each encoder's typical profile/level, not computed from the
+// actual encoded stream - GetMediaInfo doesn't (yet) probe that deeply.
+var hlsVideoCodecTags = map[string]string{
+	"libx264":    "avc1.640028",
+	"h264":       "avc1.640028",
+	"libx265":    "hvc1.1.6.L93.B0",
+	"hevc":       "hvc1.1.6.L93.B0",
+	"libvpx-vp9": "vp09.00.10.08",
+}
+
+var hlsAudioCodecTags = map[string]string{
+	"aac":        "mp4a.40.2",
+	"libmp3lame": "mp4a.40.34",
+	"libopus":    "opus",
+	"opus":       "opus",
+}
+
+// hlsCodecString builds an EXT-X-STREAM-INF CODECS attribute value from a
+// rendition's encoders, omitting either half that isn't in the tag tables
+// above rather than guessing.
+func hlsCodecString(videoCodec, audioCodec string) string {
+	vTag, vOK := hlsVideoCodecTags[strings.ToLower(videoCodec)]
+	aTag, aOK := hlsAudioCodecTags[strings.ToLower(audioCodec)]
+	switch {
+	case vOK && aOK:
+		return vTag + "," + aTag
+	case vOK:
+		return vTag
+	case aOK:
+		return aTag
+	default:
+		return ""
+	}
+}
+
+// renditionCodecs fills in Rendition's codec defaults, matching what the
+// kwargs built for it actually use.
+func renditionCodecs(r Rendition) (videoCodec, audioCodec string) {
+	videoCodec = r.VideoCodec
+	if videoCodec == "" {
+		videoCodec = "libx264"
+	}
+	audioCodec = r.AudioCodec
+	if audioCodec == "" {
+		audioCodec = "aac"
+	}
+	return videoCodec, audioCodec
+}
+
+// listProducedFiles lists every regular file directly under dir, for
+// PackageResult.Files.
+func listProducedFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list packaged output files: %w", err)
+	}
+	files := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		files = append(files, filepath.Join(dir, e.Name()))
+	}
+	return files, nil
+}
+
+// PackageHLS segments inputPath into a multi-bitrate HLS bundle in one
+// ffmpeg process: -var_stream_map fans renditions out into
+// stream_0.m3u8/stream_1.m3u8/... rendition playlists (and their
+// stream_N_%03d.ts segments) from a single invocation, rather than the one
+// ffmpeg-process-per-rendition this previously ran. A hand-written
+// index.m3u8 master then lists every variant via EXT-X-STREAM-INF with
+// BANDWIDTH, CODECS and (when Name is set) NAME attributes; RESOLUTION is
+// not written - ffmpeg's own -master_pl_name output doesn't carry CODECS,
+// so this isn't asked to generate one. opts.TaskID, when set, registers the
+// process the same way ConvertFormat does.
+func (f *FFmpeg) PackageHLS(ctx context.Context, inputPath string, renditions []Rendition, opts PackageOptions) (*PackageResult, error) {
+	if len(renditions) == 0 {
+		return nil, fmt.Errorf("at least one rendition is required")
+	}
+	if opts.OutputDir == "" {
+		return nil, fmt.Errorf("output directory is required")
+	}
+	segmentSeconds := opts.SegmentSeconds
+	if segmentSeconds <= 0 {
+		segmentSeconds = 6
+	}
+
+	if err := os.MkdirAll(opts.OutputDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create HLS output directory: %w", err)
+	}
+
+	mapArgs := make([]string, 0, len(renditions)*2)
+	varStreamParts := make([]string, 0, len(renditions))
+	kwargs := ffmpeg.KwArgs{
+		"hls_time":             segmentSeconds,
+		"hls_playlist_type":    "vod",
+		"hls_segment_filename": filepath.Join(opts.OutputDir, "stream_%v_%03d.ts"),
+		"f":                    "hls",
+	}
+
+	for i, r := range renditions {
+		videoCodec, audioCodec := renditionCodecs(r)
+
+		mapArgs = append(mapArgs, "0:v:0", "0:a:0")
+		varStreamParts = append(varStreamParts, fmt.Sprintf("v:%d,a:%d", i, i))
+
+		kwargs[fmt.Sprintf("c:v:%d", i)] = videoCodec
+		kwargs[fmt.Sprintf("c:a:%d", i)] = audioCodec
+		if r.Height > 0 {
+			kwargs[fmt.Sprintf("filter:v:%d", i)] = fmt.Sprintf("scale=-2:%d", r.Height)
+		}
+		if r.VideoBitrate != "" {
+			kwargs[fmt.Sprintf("b:v:%d", i)] = r.VideoBitrate
+		}
+		if r.AudioBitrate != "" {
+			kwargs[fmt.Sprintf("b:a:%d", i)] = r.AudioBitrate
+		}
+	}
+	kwargs["map"] = mapArgs
+	kwargs["var_stream_map"] = strings.Join(varStreamParts, " ")
+	if opts.ProgressCallback != nil {
+		withProgressKwargs(kwargs)
+	}
+
+	f.logger.Info("Packaging adaptive HLS",
+		zap.String("input", inputPath),
+		zap.Int("renditions", len(renditions)),
+		zap.Int("segment_seconds", segmentSeconds),
+	)
+
+	streamPattern := filepath.Join(opts.OutputDir, "stream_%v.m3u8")
+	stream := ffmpeg.Input(inputPath).
+		Output(streamPattern, kwargs).
 		OverWriteOutput().
 		ErrorToStdOut().
-		SetFfmpegPath(f.binaryPath).
-		Run()
+		SetFfmpegPath(f.binaryPath)
+
+	duration := f.durationForProgress(ctx, inputPath, opts.ProgressCallback)
+	if err := f.runTrackedWithProgress("hls_package", opts.TaskID, stream, duration, opts.ProgressCallback); err != nil {
+		return nil, fmt.Errorf("HLS packaging failed: %w", err)
+	}
+
+	masterLines := []string{"#EXTM3U"}
+	for i, r := range renditions {
+		videoCodec, audioCodec := renditionCodecs(r)
+		bandwidth := parseBitrate(r.VideoBitrate) + parseBitrate(r.AudioBitrate)
+
+		// RESOLUTION is omitted: scale=-2:H preserves the source aspect
+		// ratio, so the actual output width isn't known without a
+		// GetMediaInfo probe of the source before this loop runs - not done
+		// today. Every HLS client treats BANDWIDTH as authoritative
+		// regardless.
+		attrs := []string{fmt.Sprintf("BANDWIDTH=%d", bandwidth)}
+		if codecs := hlsCodecString(videoCodec, audioCodec); codecs != "" {
+			attrs = append(attrs, fmt.Sprintf("CODECS=%q", codecs))
+		}
+		if r.Name != "" {
+			attrs = append(attrs, fmt.Sprintf("NAME=%q", r.Name))
+		}
 
+		masterLines = append(masterLines, "#EXT-X-STREAM-INF:"+strings.Join(attrs, ","))
+		masterLines = append(masterLines, fmt.Sprintf("stream_%d.m3u8", i))
+	}
+
+	masterPath := filepath.Join(opts.OutputDir, "index.m3u8")
+	if err := os.WriteFile(masterPath, []byte(strings.Join(masterLines, "\n")+"\n"), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write master playlist: %w", err)
+	}
+
+	files, err := listProducedFiles(opts.OutputDir)
 	if err != nil {
-		return "", fmt.Errorf("format conversion failed: %w", err)
+		return nil, err
 	}
+	return &PackageResult{Dir: opts.OutputDir, MasterPlaylist: masterPath, Files: files}, nil
+}
 
-	return outputPath, nil
+// PackageDASH behaves like PackageHLS but produces an MPEG-DASH bundle
+// (manifest.mpd plus fMP4 init/media segments) in one ffmpeg process. The
+// dash muxer takes every rendition as a -map pair within a single output
+// rather than HLS's var_stream_map trick, so per-rendition scaling happens
+// in a filter_complex split/scale graph instead of per-output -vf options.
+func (f *FFmpeg) PackageDASH(ctx context.Context, inputPath string, renditions []Rendition, opts PackageOptions) (*PackageResult, error) {
+	if len(renditions) == 0 {
+		return nil, fmt.Errorf("at least one rendition is required")
+	}
+	if opts.OutputDir == "" {
+		return nil, fmt.Errorf("output directory is required")
+	}
+	segmentSeconds := opts.SegmentSeconds
+	if segmentSeconds <= 0 {
+		segmentSeconds = 6
+	}
+
+	if err := os.MkdirAll(opts.OutputDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create DASH output directory: %w", err)
+	}
+
+	splitLabels := make([]string, 0, len(renditions))
+	for i := range renditions {
+		splitLabels = append(splitLabels, fmt.Sprintf("[v%d]", i))
+	}
+	filterParts := []string{fmt.Sprintf("[0:v]split=%d%s", len(renditions), strings.Join(splitLabels, ""))}
+
+	mapArgs := make([]string, 0, len(renditions)*2)
+	kwargs := ffmpeg.KwArgs{
+		"f":            "dash",
+		"use_timeline": 1,
+		"use_template": 1,
+		"seg_duration": segmentSeconds,
+	}
+
+	for i, r := range renditions {
+		videoCodec, audioCodec := renditionCodecs(r)
+
+		vIn := fmt.Sprintf("v%d", i)
+		vOut := fmt.Sprintf("vout%d", i)
+		if r.Height > 0 {
+			filterParts = append(filterParts, fmt.Sprintf("[%s]scale=-2:%d[%s]", vIn, r.Height, vOut))
+		} else {
+			filterParts = append(filterParts, fmt.Sprintf("[%s]null[%s]", vIn, vOut))
+		}
+		mapArgs = append(mapArgs, "["+vOut+"]", "0:a:0")
+
+		kwargs[fmt.Sprintf("c:v:%d", i)] = videoCodec
+		kwargs[fmt.Sprintf("c:a:%d", i)] = audioCodec
+		if r.VideoBitrate != "" {
+			kwargs[fmt.Sprintf("b:v:%d", i)] = r.VideoBitrate
+		}
+		if r.AudioBitrate != "" {
+			kwargs[fmt.Sprintf("b:a:%d", i)] = r.AudioBitrate
+		}
+	}
+	kwargs["filter_complex"] = strings.Join(filterParts, "; ")
+	kwargs["map"] = mapArgs
+
+	f.logger.Info("Packaging adaptive DASH",
+		zap.String("input", inputPath),
+		zap.Int("renditions", len(renditions)),
+		zap.Int("segment_seconds", segmentSeconds),
+	)
+
+	manifestPath := filepath.Join(opts.OutputDir, "manifest.mpd")
+	stream := ffmpeg.Input(inputPath).
+		Output(manifestPath, kwargs).
+		OverWriteOutput().
+		ErrorToStdOut().
+		SetFfmpegPath(f.binaryPath)
+
+	if err := f.runTracked("dash_package", opts.TaskID, stream); err != nil {
+		return nil, fmt.Errorf("DASH packaging failed: %w", err)
+	}
+
+	files, err := listProducedFiles(opts.OutputDir)
+	if err != nil {
+		return nil, err
+	}
+	return &PackageResult{Dir: opts.OutputDir, MasterPlaylist: manifestPath, Files: files}, nil
 }
 
-// DownscaleVideo reduces video resolution
-func (f *FFmpeg) DownscaleVideo(ctx context.Context, inputPath string, maxHeight int, codec, bitrate string) (string, error) {
+// parseBitrate converts an ffmpeg-style bitrate string ("5M", "192k") to a
+// bits-per-second estimate for the master playlist's BANDWIDTH attribute.
+// Unparseable or empty input yields 0 rather than an error, since a missing
+// BANDWIDTH contribution from one variant shouldn't fail the whole job.
+func parseBitrate(s string) int {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0
+	}
+
+	mult := 1
+	switch {
+	case strings.HasSuffix(s, "M") || strings.HasSuffix(s, "m"):
+		mult = 1_000_000
+		s = s[:len(s)-1]
+	case strings.HasSuffix(s, "K") || strings.HasSuffix(s, "k"):
+		mult = 1_000
+		s = s[:len(s)-1]
+	}
+
+	val, err := strconv.Atoi(s)
+	if err != nil {
+		return 0
+	}
+	return val * mult
+}
+
+// DownscaleVideo reduces video resolution. progressCallback, when non-nil,
+// receives one FFmpegProgress per `-progress` tick.
+func (f *FFmpeg) DownscaleVideo(ctx context.Context, inputPath string, maxHeight int, codec, bitrate string, progressCallback ProgressCallback) (string, error) {
 	outputPath := f.appendSuffix(inputPath, fmt.Sprintf("_%dp", maxHeight))
 
 	f.logger.Info("Downscaling video",
@@ -130,50 +775,225 @@ func (f *FFmpeg) DownscaleVideo(ctx context.Context, inputPath string, maxHeight
 		zap.Int("max_height", maxHeight),
 	)
 
-	// Scale filter: scale=-2:HEIGHT (maintains aspect ratio)
-	scaleFilter := fmt.Sprintf("scale=-2:%d", maxHeight)
+	build := func(videoCodec, vf string, inputArgs, extraOut ffmpeg.KwArgs, captureErr io.Writer, withProgress bool) *ffmpeg.Stream {
+		kwargs := ffmpeg.KwArgs{
+			"c:v": videoCodec,
+			"c:a": "copy",
+			"vf":  vf, // Use vf parameter instead of Filter()
+		}
+		if bitrate != "" {
+			kwargs["b:v"] = bitrate
+		}
+		for k, v := range extraOut {
+			kwargs[k] = v
+		}
+		if withProgress {
+			withProgressKwargs(kwargs)
+		}
 
-	kwargs := ffmpeg.KwArgs{
-		"c:v": codec,
-		"c:a": "copy",
-		"vf":  scaleFilter, // Use vf parameter instead of Filter()
+		var input *ffmpeg.Stream
+		if len(inputArgs) > 0 {
+			input = ffmpeg.Input(inputPath, inputArgs)
+		} else {
+			input = ffmpeg.Input(inputPath)
+		}
+
+		out := input.Output(outputPath, kwargs).
+			OverWriteOutput().
+			SetFfmpegPath(f.binaryPath)
+		if captureErr != nil {
+			return out.WithErrorOutput(captureErr)
+		}
+		return out.ErrorToStdOut()
 	}
 
-	if bitrate != "" {
-		kwargs["b:v"] = bitrate
+	duration := f.durationForProgress(ctx, inputPath, progressCallback)
+	if err := f.runHWEncode(ctx, "downscale", "", codec, maxHeight, duration, progressCallback, build); err != nil {
+		return "", fmt.Errorf("downscaling failed: %w", err)
+	}
+
+	f.logger.Info("Video downscaled",
+		zap.Int("max_height", maxHeight),
+	)
+
+	return outputPath, nil
+}
+
+// ExtractClip cuts inputPath down to spec's range(s) and writes the result
+// to outputPath. A single Start/End pair seeks with -ss before -i (fast,
+// since it skips straight to the nearest keyframe instead of decoding from
+// the start) and tries -c copy first; if that stream copy fails - the usual
+// symptom of a cut point that doesn't land on a keyframe - it falls back to
+// re-encoding with codec, which can cut at any point. Multiple Ranges
+// entries are each cut to a temp file the same way, then joined into a
+// single outputPath with ffmpeg's concat demuxer.
+func (f *FFmpeg) ExtractClip(ctx context.Context, inputPath, outputPath string, spec types.ClipSpec, codec string) error {
+	if len(spec.Ranges) > 0 {
+		return f.extractMultiClip(inputPath, outputPath, spec.Ranges, codec)
+	}
+	return f.extractSingleClip(inputPath, outputPath, spec.Start, spec.End, codec)
+}
+
+// extractSingleClip is ExtractClip's single-range case, also used by
+// extractMultiClip to cut each range before concatenation.
+func (f *FFmpeg) extractSingleClip(inputPath, outputPath, start, end, codec string) error {
+	if err := f.runClipCut(inputPath, outputPath, start, end, "copy"); err == nil {
+		return nil
+	}
+
+	f.logger.Info("Clip stream copy failed, falling back to re-encode",
+		zap.String("input", inputPath),
+		zap.String("start", start),
+		zap.String("end", end),
+	)
+	if err := f.runClipCut(inputPath, outputPath, start, end, codec); err != nil {
+		return fmt.Errorf("clip extraction failed: %w", err)
+	}
+	return nil
+}
+
+// runClipCut runs one -ss/-i/-t ffmpeg invocation, copying both streams
+// when codec is "copy" or re-encoding video (audio stays AAC) otherwise.
+func (f *FFmpeg) runClipCut(inputPath, outputPath, start, end, codec string) error {
+	startSeconds, err := ParseClipSeconds(start)
+	if err != nil {
+		return fmt.Errorf("invalid clip start %q: %w", start, err)
+	}
+
+	kwargs := ffmpeg.KwArgs{}
+	if codec == "copy" {
+		kwargs["c"] = "copy"
+	} else {
+		kwargs["c:v"] = codec
+		kwargs["c:a"] = "aac"
 	}
 
-	err := ffmpeg.Input(inputPath).
+	if end != "" {
+		endSeconds, err := ParseClipSeconds(end)
+		if err != nil {
+			return fmt.Errorf("invalid clip end %q: %w", end, err)
+		}
+		kwargs["t"] = endSeconds - startSeconds
+	}
+
+	return ffmpeg.Input(inputPath, ffmpeg.KwArgs{"ss": startSeconds}).
 		Output(outputPath, kwargs).
 		OverWriteOutput().
 		ErrorToStdOut().
 		SetFfmpegPath(f.binaryPath).
 		Run()
+}
 
+// extractMultiClip is ExtractClip's multi-range case: cut each range to its
+// own temp file, list them for ffmpeg's concat demuxer, then stream-copy the
+// concatenation into outputPath.
+func (f *FFmpeg) extractMultiClip(inputPath, outputPath string, ranges []types.ClipSegment, codec string) error {
+	segmentDir, err := os.MkdirTemp(filepath.Dir(outputPath), "clip_segments_*")
 	if err != nil {
-		return "", fmt.Errorf("downscaling failed: %w", err)
+		return fmt.Errorf("failed to create clip segment directory: %w", err)
 	}
+	defer os.RemoveAll(segmentDir)
 
-	f.logger.Info("Video downscaled",
-		zap.String("scale_filter", scaleFilter),
+	ext := filepath.Ext(inputPath)
+	var listLines []string
+	for i, r := range ranges {
+		segmentPath := filepath.Join(segmentDir, fmt.Sprintf("segment_%03d%s", i, ext))
+		if err := f.extractSingleClip(inputPath, segmentPath, r.Start, r.End, codec); err != nil {
+			return fmt.Errorf("clip range %d: %w", i, err)
+		}
+		// Temp segment paths are generated above and never contain a single
+		// quote, so the concat demuxer's minimal escaping rules don't apply.
+		listLines = append(listLines, fmt.Sprintf("file '%s'", segmentPath))
+	}
+
+	listPath := filepath.Join(segmentDir, "concat_list.txt")
+	if err := os.WriteFile(listPath, []byte(strings.Join(listLines, "\n")+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write concat list: %w", err)
+	}
+
+	f.logger.Info("Concatenating clip ranges",
+		zap.String("output", outputPath),
+		zap.Int("ranges", len(ranges)),
 	)
 
-	return outputPath, nil
+	err = ffmpeg.Input(listPath, ffmpeg.KwArgs{"f": "concat", "safe": "0"}).
+		Output(outputPath, ffmpeg.KwArgs{"c": "copy"}).
+		OverWriteOutput().
+		ErrorToStdOut().
+		SetFfmpegPath(f.binaryPath).
+		Run()
+	if err != nil {
+		return fmt.Errorf("clip concat failed: %w", err)
+	}
+	return nil
+}
+
+// ParseClipSeconds parses a ClipSegment Start/End value - either a plain
+// seconds duration ("90.5") or a colon-separated timestamp ("01:30.5",
+// "00:01:30.5") - into seconds. Shared with handlers.validateClipSpec so
+// both the ffmpeg invocation and the pre-flight duration check agree on
+// what a bound means.
+func ParseClipSeconds(value string) (float64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, fmt.Errorf("empty clip bound")
+	}
+	if !strings.Contains(value, ":") {
+		seconds, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid clip bound %q: %w", value, err)
+		}
+		return seconds, nil
+	}
+
+	parts := strings.Split(value, ":")
+	if len(parts) > 3 {
+		return 0, fmt.Errorf("invalid clip timestamp %q", value)
+	}
+
+	var seconds float64
+	for _, part := range parts {
+		unit, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid clip timestamp %q: %w", value, err)
+		}
+		seconds = seconds*60 + unit
+	}
+	return seconds, nil
 }
 
-// ExtractSubtitles extracts subtitle streams from video
+// ExtractSubtitles extracts subtitle streams from video. The stream picked
+// is the one matching language (falling back to the stream flagged default,
+// then to the first subtitle stream) per GetMediaInfo's probe of inputPath,
+// rather than always assuming subtitle stream 0.
 func (f *FFmpeg) ExtractSubtitles(ctx context.Context, inputPath string, language string) ([]string, error) {
 	outputPath := f.changeExtension(inputPath, "."+language+".srt")
 
+	streamIndex := 0
+	info, err := f.GetMediaInfo(ctx, inputPath)
+	if err != nil {
+		f.logger.Warn("GetMediaInfo failed before subtitle extraction, defaulting to subtitle stream 0",
+			zap.String("input", inputPath),
+			zap.Error(err),
+		)
+	} else {
+		idx, ok := info.SubtitleStreamIndex(language)
+		if !ok {
+			return nil, fmt.Errorf("no subtitle streams found in %s", inputPath)
+		}
+		streamIndex = idx
+	}
+
 	f.logger.Info("Extracting subtitles",
 		zap.String("input", inputPath),
 		zap.String("language", language),
+		zap.Int("stream_index", streamIndex),
 	)
 
-	err := ffmpeg.Input(inputPath).
+	err = ffmpeg.Input(inputPath).
 		Output(outputPath, ffmpeg.KwArgs{
 			"c:s": "srt",
-			"map": "0:s:0", // First subtitle stream
+			"map": fmt.Sprintf("0:s:%d", streamIndex),
 		}).
 		OverWriteOutput().
 		ErrorToStdOut().
@@ -187,25 +1007,8 @@ func (f *FFmpeg) ExtractSubtitles(ctx context.Context, inputPath string, languag
 	return []string{outputPath}, nil
 }
 
-// GetMediaInfo retrieves information about a media file
-func (f *FFmpeg) GetMediaInfo(inputPath string) (map[string]interface{}, error) {
-	// Use ffprobe to get media information
-	// This is a simplified version - in production, use ffprobe directly
-	info := make(map[string]interface{})
-
-	stat, err := os.Stat(inputPath)
-	if err != nil {
-		return nil, err
-	}
-
-	info["size"] = stat.Size()
-	info["path"] = inputPath
-
-	return info, nil
-}
-
 // CompressVideo compresses video with quality vs speed preset
-func (f *FFmpeg) CompressVideo(ctx context.Context, inputPath, preset string) (string, error) {
+func (f *FFmpeg) CompressVideo(ctx context.Context, inputPath, preset string, progressCallback ProgressCallback) (string, error) {
 	outputPath := f.appendSuffix(inputPath, "_compressed")
 
 	f.logger.Info("Compressing video",
@@ -213,20 +1016,47 @@ func (f *FFmpeg) CompressVideo(ctx context.Context, inputPath, preset string) (s
 		zap.String("preset", preset),
 	)
 
-	err := ffmpeg.Input(inputPath).
-		Output(outputPath, ffmpeg.KwArgs{
-			"c:v":    "libx264",
-			"preset": preset, // ultrafast, fast, medium, slow
-			"crf":    "23",   // Quality (0-51, lower = better)
-			"c:a":    "aac",
-			"b:a":    "128k",
-		}).
-		OverWriteOutput().
-		ErrorToStdOut().
-		SetFfmpegPath(f.binaryPath).
-		Run()
+	build := func(videoCodec, vf string, inputArgs, extraOut ffmpeg.KwArgs, captureErr io.Writer, withProgress bool) *ffmpeg.Stream {
+		kwargs := ffmpeg.KwArgs{
+			"c:v": videoCodec,
+			"c:a": "aac",
+			"b:a": "128k",
+		}
+		if len(extraOut) > 0 {
+			// Hardware families bring their own rate-control flags in place
+			// of libx264's preset/crf (see hwVideoParams).
+			for k, v := range extraOut {
+				kwargs[k] = v
+			}
+		} else {
+			kwargs["preset"] = preset // ultrafast, fast, medium, slow
+			kwargs["crf"] = "23"      // Quality (0-51, lower = better)
+		}
+		if vf != "" {
+			kwargs["vf"] = vf
+		}
+		if withProgress {
+			withProgressKwargs(kwargs)
+		}
 
-	if err != nil {
+		var input *ffmpeg.Stream
+		if len(inputArgs) > 0 {
+			input = ffmpeg.Input(inputPath, inputArgs)
+		} else {
+			input = ffmpeg.Input(inputPath)
+		}
+
+		out := input.Output(outputPath, kwargs).
+			OverWriteOutput().
+			SetFfmpegPath(f.binaryPath)
+		if captureErr != nil {
+			return out.WithErrorOutput(captureErr)
+		}
+		return out.ErrorToStdOut()
+	}
+
+	duration := f.durationForProgress(ctx, inputPath, progressCallback)
+	if err := f.runHWEncode(ctx, "compress", "", "libx264", 0, duration, progressCallback, build); err != nil {
 		return "", fmt.Errorf("compression failed: %w", err)
 	}
 
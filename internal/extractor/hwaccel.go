@@ -0,0 +1,198 @@
+package extractor
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	ffmpeg "github.com/u2takey/ffmpeg-go"
+)
+
+// HWAccelFamily names a hardware-accelerated encoder family FFmpeg can
+// target, or a mode controlling how one is chosen.
+type HWAccelFamily string
+
+const (
+	// HWAccelAuto probes the host lazily on first use (see DetectHWAccel)
+	// and caches the result for the life of the FFmpeg instance. The
+	// default when SetHWAccelMode is never called.
+	HWAccelAuto HWAccelFamily = "auto"
+	// HWAccelOff always uses software encoding (libx264/libx265/etc).
+	HWAccelOff HWAccelFamily = "off"
+	// HWAccelNVENC targets NVIDIA's NVENC encoders.
+	HWAccelNVENC HWAccelFamily = "nvenc"
+	// HWAccelVAAPI targets Linux VA-API (Intel/AMD) encoders.
+	HWAccelVAAPI HWAccelFamily = "vaapi"
+	// HWAccelQSV targets Intel Quick Sync Video encoders.
+	HWAccelQSV HWAccelFamily = "qsv"
+	// HWAccelVideoToolbox targets macOS VideoToolbox encoders.
+	HWAccelVideoToolbox HWAccelFamily = "videotoolbox"
+)
+
+// vaapiRenderNode is the render node VA-API (and, on this host's best
+// guess, Quick Sync) devices show up as. Checked for existence rather than
+// opened - actually exercising it is left to ffmpeg itself, whose failure
+// this package's hw-fallback handling already covers.
+const vaapiRenderNode = "/dev/dri/renderD128"
+
+// DetectHWAccel probes the host for the best available hardware encoder
+// family, preferring (in order) VideoToolbox on macOS, NVENC when
+// nvidia-smi and ffmpeg's own "cuda" hwaccel are both present, then QSV or
+// VAAPI when a DRI render node exists alongside the matching ffmpeg
+// hwaccel. Returns HWAccelOff if nothing usable is found, including when
+// ffmpegPath itself can't be run (e.g. not installed) - callers should
+// always get software encoding as a safe result.
+func DetectHWAccel(ffmpegPath string) HWAccelFamily {
+	accels := ffmpegHWAccels(ffmpegPath)
+
+	if runtime.GOOS == "darwin" {
+		if accels["videotoolbox"] {
+			return HWAccelVideoToolbox
+		}
+		return HWAccelOff
+	}
+
+	if accels["cuda"] && nvidiaSMIAvailable() {
+		return HWAccelNVENC
+	}
+
+	hasRenderNode := renderNodeExists()
+	if accels["qsv"] && hasRenderNode {
+		return HWAccelQSV
+	}
+	if accels["vaapi"] && hasRenderNode {
+		return HWAccelVAAPI
+	}
+
+	return HWAccelOff
+}
+
+// ffmpegHWAccels runs `ffmpeg -hwaccels` and returns the set of methods it
+// reports (e.g. "cuda", "vaapi", "qsv", "videotoolbox"), lowercased. Returns
+// an empty (non-nil) set on any error running ffmpeg.
+func ffmpegHWAccels(ffmpegPath string) map[string]bool {
+	accels := make(map[string]bool)
+
+	out, err := exec.Command(ffmpegPath, "-hide_banner", "-hwaccels").Output()
+	if err != nil {
+		return accels
+	}
+
+	for _, line := range strings.Split(string(out), "\n") {
+		line = strings.ToLower(strings.TrimSpace(line))
+		if line == "" || strings.HasPrefix(line, "hardware acceleration methods") {
+			continue
+		}
+		accels[line] = true
+	}
+	return accels
+}
+
+// nvidiaSMIAvailable reports whether nvidia-smi is on PATH, the simplest
+// signal that an NVIDIA GPU and its driver are actually installed (ffmpeg
+// reporting "cuda" as a build-time capability doesn't mean a device exists).
+func nvidiaSMIAvailable() bool {
+	_, err := exec.LookPath("nvidia-smi")
+	return err == nil
+}
+
+// renderNodeExists reports whether this host exposes a DRI render node,
+// the device VA-API (and, on Intel hosts, Quick Sync) encodes through.
+func renderNodeExists() bool {
+	_, err := os.Stat(vaapiRenderNode)
+	return err == nil
+}
+
+// hwEncodeParams carries the kwargs/filter adjustments a HWAccelFamily
+// needs on top of the software baseline an FFmpeg method would otherwise
+// use. A zero value means "no hardware mapping for this codec/family -
+// encode in software unchanged".
+type hwEncodeParams struct {
+	// inputArgs is merged into ffmpeg.Input's kwargs, e.g. the cuda decoder
+	// offload or the VA-API device init.
+	inputArgs ffmpeg.KwArgs
+	// videoCodec overrides the software codec (e.g. "libx264" ->
+	// "h264_nvenc"). Empty means this family has no mapping for the
+	// requested software codec, so the caller should stay in software.
+	videoCodec string
+	// extraOut is merged into the Output kwargs, e.g. NVENC's rate-control
+	// flags in place of libx264's preset/crf.
+	extraOut ffmpeg.KwArgs
+	// filter, when non-nil, builds this family's "-vf" value for scaling to
+	// targetHeight (targetHeight <= 0 means no scaling is needed). A nil
+	// filter means this family doesn't need a different filter than the
+	// plain software "scale=-2:H" every caller already falls back to.
+	filter func(targetHeight int) string
+}
+
+// hwVideoParams derives hwEncodeParams for softCodec (as ConvertFormat,
+// DownscaleVideo, and CompressVideo already accept it - "libx264" or
+// "libx265") under family. Returns a zero value for HWAccelOff/HWAccelAuto
+// (resolvedHWAccel never leaves a method with HWAccelAuto, but treating it
+// as "no mapping" here is the safe default) and for any softCodec this repo
+// doesn't already use.
+func hwVideoParams(family HWAccelFamily, softCodec string) hwEncodeParams {
+	var codecFamily string
+	switch softCodec {
+	case "libx264", "h264":
+		codecFamily = "h264"
+	case "libx265", "hevc":
+		codecFamily = "hevc"
+	default:
+		return hwEncodeParams{}
+	}
+
+	switch family {
+	case HWAccelNVENC:
+		codec := "h264_nvenc"
+		if codecFamily == "hevc" {
+			codec = "hevc_nvenc"
+		}
+		return hwEncodeParams{
+			inputArgs:  ffmpeg.KwArgs{"hwaccel": "cuda", "hwaccel_output_format": "cuda"},
+			videoCodec: codec,
+			extraOut:   ffmpeg.KwArgs{"preset": "p4", "tune": "hq", "rc": "vbr", "cq": "23"},
+			filter: func(targetHeight int) string {
+				if targetHeight <= 0 {
+					return ""
+				}
+				return fmt.Sprintf("scale_npp=-2:%d:format=yuv420p", targetHeight)
+			},
+		}
+
+	case HWAccelVAAPI:
+		codec := "h264_vaapi"
+		if codecFamily == "hevc" {
+			codec = "hevc_vaapi"
+		}
+		return hwEncodeParams{
+			inputArgs:  ffmpeg.KwArgs{"vaapi_device": vaapiRenderNode},
+			videoCodec: codec,
+			filter: func(targetHeight int) string {
+				if targetHeight <= 0 {
+					return "format=nv12,hwupload"
+				}
+				return fmt.Sprintf("format=nv12,hwupload,scale_vaapi=-2:%d", targetHeight)
+			},
+		}
+
+	case HWAccelQSV:
+		codec := "h264_qsv"
+		if codecFamily == "hevc" {
+			codec = "hevc_qsv"
+		}
+		return hwEncodeParams{videoCodec: codec}
+
+	case HWAccelVideoToolbox:
+		codec := "h264_videotoolbox"
+		if codecFamily == "hevc" {
+			codec = "hevc_videotoolbox"
+		}
+		return hwEncodeParams{videoCodec: codec}
+
+	default:
+		return hwEncodeParams{}
+	}
+}
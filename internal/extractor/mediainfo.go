@@ -0,0 +1,317 @@
+package extractor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// MediaInfo is ffprobe's view of a media file on disk, as returned by
+// FFmpeg.GetMediaInfo. Unlike types.MediaMetadata - yt-dlp's pre-download
+// metadata for a remote URL - this reflects the actual file produced by a
+// download or transcode, which is what downstream steps (picking a scale
+// ladder, detecting audio-only input, choosing a subtitle stream) need.
+type MediaInfo struct {
+	Format    MediaFormat
+	Video     []VideoStream
+	Audio     []AudioStream
+	Subtitles []SubtitleStream
+	Chapters  []Chapter
+}
+
+// MediaFormat is ffprobe's top-level "format" section.
+type MediaFormat struct {
+	Duration   float64 // seconds
+	BitRate    int64   // bits/sec, 0 if ffprobe didn't report one
+	FormatName string  // e.g. "mov,mp4,m4a,3gp,3g2,mj2"
+	Tags       map[string]string
+}
+
+// VideoStream describes one ffprobe stream with codec_type "video".
+type VideoStream struct {
+	Index      int // ffmpeg's global stream index, e.g. for -map 0:<Index>
+	Codec      string
+	Width      int
+	Height     int
+	FPS        float64 // decoded from r_frame_rate, 0 if ffprobe reported "0/0"
+	BitRate    int64
+	PixFmt     string
+	ColorSpace string
+	HDR        bool // true for PQ (smpte2084) or HLG (arib-std-b67) transfer functions
+}
+
+// AudioStream describes one ffprobe stream with codec_type "audio".
+type AudioStream struct {
+	Index      int
+	Codec      string
+	Channels   int
+	SampleRate int
+	Language   string // from the stream's "language" tag, empty if unset
+}
+
+// SubtitleStream describes one ffprobe stream with codec_type "subtitle".
+// RelativeIndex is the stream's position among subtitle streams only - the
+// N ffmpeg's "0:s:N" specifier expects, as opposed to Index, which is the
+// file-wide stream index ffprobe reports.
+type SubtitleStream struct {
+	Index         int
+	RelativeIndex int
+	Codec         string
+	Language      string
+	Forced        bool
+	Default       bool
+}
+
+// Chapter is one ffprobe "chapters" entry.
+type Chapter struct {
+	Start float64 // seconds
+	End   float64 // seconds
+	Title string
+}
+
+// HasVideo reports whether inputPath has at least one video stream.
+func (m *MediaInfo) HasVideo() bool {
+	return len(m.Video) > 0
+}
+
+// HasAudio reports whether inputPath has at least one audio stream. False
+// for video files with no audio track; an audio-only file is
+// !HasVideo() && HasAudio().
+func (m *MediaInfo) HasAudio() bool {
+	return len(m.Audio) > 0
+}
+
+// BestVideoStream returns the highest-resolution video stream (by pixel
+// count), or nil if HasVideo() is false. Ties keep the first stream ffprobe
+// listed.
+func (m *MediaInfo) BestVideoStream() *VideoStream {
+	if len(m.Video) == 0 {
+		return nil
+	}
+	best := &m.Video[0]
+	for i := 1; i < len(m.Video); i++ {
+		if m.Video[i].Width*m.Video[i].Height > best.Width*best.Height {
+			best = &m.Video[i]
+		}
+	}
+	return best
+}
+
+// AspectRatio returns BestVideoStream's width/height, or 0 if there is no
+// video stream or its height is 0.
+func (m *MediaInfo) AspectRatio() float64 {
+	v := m.BestVideoStream()
+	if v == nil || v.Height == 0 {
+		return 0
+	}
+	return float64(v.Width) / float64(v.Height)
+}
+
+// IsHDR reports whether BestVideoStream is HDR. False when there is no
+// video stream.
+func (m *MediaInfo) IsHDR() bool {
+	v := m.BestVideoStream()
+	return v != nil && v.HDR
+}
+
+// SubtitleStreamIndex picks the RelativeIndex ExtractSubtitles should pass
+// to ffmpeg's "0:s:N" map specifier for the given language (an exact,
+// case-insensitive match against SubtitleStream.Language). When language is
+// empty or no stream matches, it falls back to the first stream flagged
+// Default, then to subtitle stream 0. ok is false only when there are no
+// subtitle streams at all.
+func (m *MediaInfo) SubtitleStreamIndex(language string) (index int, ok bool) {
+	if len(m.Subtitles) == 0 {
+		return 0, false
+	}
+
+	if language != "" {
+		for _, s := range m.Subtitles {
+			if strings.EqualFold(s.Language, language) {
+				return s.RelativeIndex, true
+			}
+		}
+	}
+
+	for _, s := range m.Subtitles {
+		if s.Default {
+			return s.RelativeIndex, true
+		}
+	}
+
+	return m.Subtitles[0].RelativeIndex, true
+}
+
+// GetMediaInfo runs ffprobe against inputPath and parses its JSON output
+// into a MediaInfo. The call is bounded by f.timeout, the same deadline
+// ffmpeg transcodes are held to.
+func (f *FFmpeg) GetMediaInfo(ctx context.Context, inputPath string) (*MediaInfo, error) {
+	ctx, cancel := context.WithTimeout(ctx, f.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, f.ffprobePath,
+		"-v", "error",
+		"-print_format", "json",
+		"-show_format",
+		"-show_streams",
+		"-show_chapters",
+		inputPath,
+	)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("ffprobe failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	var raw ffprobeOutput
+	if err := json.Unmarshal(stdout.Bytes(), &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse ffprobe output: %w", err)
+	}
+
+	return raw.toMediaInfo(), nil
+}
+
+// ffprobeOutput mirrors the JSON `ffprobe -show_format -show_streams
+// -show_chapters -print_format json` produces. ffprobe reports most numeric
+// fields as JSON strings (duration, bit_rate, r_frame_rate's numerator and
+// denominator), so these are parsed by toMediaInfo rather than unmarshaled
+// directly into numeric fields.
+type ffprobeOutput struct {
+	Streams  []ffprobeStream  `json:"streams"`
+	Format   ffprobeFormat    `json:"format"`
+	Chapters []ffprobeChapter `json:"chapters"`
+}
+
+type ffprobeFormat struct {
+	Duration   string            `json:"duration"`
+	BitRate    string            `json:"bit_rate"`
+	FormatName string            `json:"format_name"`
+	Tags       map[string]string `json:"tags"`
+}
+
+type ffprobeStream struct {
+	Index         int               `json:"index"`
+	CodecType     string            `json:"codec_type"`
+	CodecName     string            `json:"codec_name"`
+	Width         int               `json:"width"`
+	Height        int               `json:"height"`
+	RFrameRate    string            `json:"r_frame_rate"`
+	BitRate       string            `json:"bit_rate"`
+	PixFmt        string            `json:"pix_fmt"`
+	ColorSpace    string            `json:"color_space"`
+	ColorTransfer string            `json:"color_transfer"`
+	ColorPrimary  string            `json:"color_primaries"`
+	Channels      int               `json:"channels"`
+	SampleRate    string            `json:"sample_rate"`
+	Disposition   map[string]int    `json:"disposition"`
+	Tags          map[string]string `json:"tags"`
+}
+
+type ffprobeChapter struct {
+	StartTime string            `json:"start_time"`
+	EndTime   string            `json:"end_time"`
+	Tags      map[string]string `json:"tags"`
+}
+
+// toMediaInfo converts the raw ffprobe JSON shape into MediaInfo's typed,
+// already-parsed fields.
+func (o *ffprobeOutput) toMediaInfo() *MediaInfo {
+	info := &MediaInfo{
+		Format: MediaFormat{
+			Duration:   parseFfprobeFloat(o.Format.Duration),
+			BitRate:    parseFfprobeInt(o.Format.BitRate),
+			FormatName: o.Format.FormatName,
+			Tags:       o.Format.Tags,
+		},
+	}
+
+	subtitleOrdinal := 0
+	for _, s := range o.Streams {
+		switch s.CodecType {
+		case "video":
+			info.Video = append(info.Video, VideoStream{
+				Index:      s.Index,
+				Codec:      s.CodecName,
+				Width:      s.Width,
+				Height:     s.Height,
+				FPS:        parseFfprobeRate(s.RFrameRate),
+				BitRate:    parseFfprobeInt(s.BitRate),
+				PixFmt:     s.PixFmt,
+				ColorSpace: s.ColorSpace,
+				HDR:        s.ColorTransfer == "smpte2084" || s.ColorTransfer == "arib-std-b67",
+			})
+		case "audio":
+			info.Audio = append(info.Audio, AudioStream{
+				Index:      s.Index,
+				Codec:      s.CodecName,
+				Channels:   s.Channels,
+				SampleRate: int(parseFfprobeInt(s.SampleRate)),
+				Language:   s.Tags["language"],
+			})
+		case "subtitle":
+			info.Subtitles = append(info.Subtitles, SubtitleStream{
+				Index:         s.Index,
+				RelativeIndex: subtitleOrdinal,
+				Codec:         s.CodecName,
+				Language:      s.Tags["language"],
+				Forced:        s.Disposition["forced"] != 0,
+				Default:       s.Disposition["default"] != 0,
+			})
+			subtitleOrdinal++
+		}
+	}
+
+	for _, c := range o.Chapters {
+		info.Chapters = append(info.Chapters, Chapter{
+			Start: parseFfprobeFloat(c.StartTime),
+			End:   parseFfprobeFloat(c.EndTime),
+			Title: c.Tags["title"],
+		})
+	}
+
+	return info
+}
+
+// parseFfprobeFloat parses an ffprobe numeric string field, returning 0 for
+// empty or unparseable values (ffprobe uses "N/A" for fields it can't
+// determine, e.g. an unknown duration).
+func parseFfprobeFloat(s string) float64 {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0
+	}
+	return v
+}
+
+// parseFfprobeInt is parseFfprobeFloat for integer fields (bit_rate,
+// sample_rate), which ffprobe also emits as strings.
+func parseFfprobeInt(s string) int64 {
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return v
+}
+
+// parseFfprobeRate parses r_frame_rate's "num/den" form into a float,
+// returning 0 for "0/0" (ffprobe's way of saying the frame rate couldn't be
+// determined, e.g. for a still-image input).
+func parseFfprobeRate(s string) float64 {
+	num, den, ok := strings.Cut(s, "/")
+	if !ok {
+		return parseFfprobeFloat(s)
+	}
+	n := parseFfprobeFloat(num)
+	d := parseFfprobeFloat(den)
+	if d == 0 {
+		return 0
+	}
+	return n / d
+}
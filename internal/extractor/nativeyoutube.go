@@ -0,0 +1,191 @@
+package extractor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	youtubev2 "github.com/kkdai/youtube/v2"
+	"go.uber.org/zap"
+
+	"github.com/KeremKalyoncu/MedYan/internal/types"
+)
+
+// qualityHeightCaps maps the quality strings buildFormatString understands
+// to a max height, so NativeYouTube picks comparable formats to what
+// YtDlp would for the same DownloadOptions.Quality.
+var qualityHeightCaps = map[string]int{
+	"4k":    2160,
+	"1080p": 1080,
+	"720p":  720,
+	"480p":  480,
+}
+
+// NativeYouTube is a yt-dlp-independent extractor for YouTube URLs, built
+// on github.com/kkdai/youtube/v2. It exists as a fallback for when the
+// yt-dlp binary is missing or being rate-limited/blocked - see
+// FallbackExtractor - so it deliberately avoids any dependency on yt-dlp or
+// even ffmpeg: Download only ever picks a single progressive (combined
+// video+audio) stream rather than merging separate adaptive streams.
+type NativeYouTube struct {
+	client youtubev2.Client
+	logger *zap.Logger
+}
+
+// NewNativeYouTube creates a NativeYouTube extractor.
+func NewNativeYouTube(logger *zap.Logger) *NativeYouTube {
+	return &NativeYouTube{logger: logger}
+}
+
+// ExtractMetadata fetches a YouTube video's metadata and available formats
+// without downloading anything.
+func (n *NativeYouTube) ExtractMetadata(ctx context.Context, url string) (*types.MediaMetadata, error) {
+	video, err := n.client.GetVideoContext(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("native youtube: failed to fetch video info: %w", err)
+	}
+
+	return n.toMediaMetadata(video), nil
+}
+
+// Download fetches url's best available progressive (combined video+audio)
+// stream and writes it to outputPath. opts.Quality steers
+// selectProgressiveFormat's height cap; opts.Format is not used, so the
+// stream is written in whatever container YouTube serves it in.
+func (n *NativeYouTube) Download(ctx context.Context, url, outputPath string, opts DownloadOptions) (*types.MediaMetadata, error) {
+	video, err := n.client.GetVideoContext(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("native youtube: failed to fetch video info: %w", err)
+	}
+
+	format, err := selectProgressiveFormat(video.Formats, opts.Quality)
+	if err != nil {
+		return nil, fmt.Errorf("native youtube: %w", err)
+	}
+
+	stream, _, err := n.client.GetStreamContext(ctx, video, format)
+	if err != nil {
+		return nil, fmt.Errorf("native youtube: failed to open stream: %w", err)
+	}
+	defer stream.Close()
+
+	out, err := os.Create(outputPath)
+	if err != nil {
+		return nil, fmt.Errorf("native youtube: failed to create output file: %w", err)
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, stream); err != nil {
+		return nil, fmt.Errorf("native youtube: failed to write stream: %w", err)
+	}
+
+	n.logger.Info("Native YouTube download complete",
+		zap.String("url", url),
+		zap.Int("itag", format.ItagNo),
+		zap.String("output", outputPath),
+	)
+
+	return n.toMediaMetadata(video), nil
+}
+
+// selectProgressiveFormat picks the best combined video+audio stream from
+// formats, preferring avc1 video / mp4a audio at quality's height cap (see
+// qualityHeightCaps), and otherwise falling back to the single best
+// progressive format available - mirroring YtDlp.buildFormatString's
+// "prefer avc1+mp4a, fall back to best" preference without needing a
+// second merge pass, since a progressive stream already has both.
+func selectProgressiveFormat(formats youtubev2.FormatList, quality string) (*youtubev2.Format, error) {
+	var progressive []youtubev2.Format
+	for _, f := range formats {
+		if f.AudioChannels > 0 && strings.Contains(f.MimeType, "video/") {
+			progressive = append(progressive, f)
+		}
+	}
+	if len(progressive) == 0 {
+		return nil, fmt.Errorf("no progressive (combined video+audio) format available")
+	}
+
+	heightCap, hasCap := qualityHeightCaps[quality]
+
+	preferred := make([]youtubev2.Format, 0, len(progressive))
+	for _, f := range progressive {
+		if hasCap && f.Height > heightCap {
+			continue
+		}
+		preferred = append(preferred, f)
+	}
+	if len(preferred) == 0 {
+		preferred = progressive
+	}
+
+	sort.SliceStable(preferred, func(i, j int) bool {
+		iAVC := strings.Contains(preferred[i].MimeType, "avc1")
+		jAVC := strings.Contains(preferred[j].MimeType, "avc1")
+		if iAVC != jAVC {
+			return iAVC
+		}
+		if preferred[i].Height != preferred[j].Height {
+			return preferred[i].Height > preferred[j].Height
+		}
+		return preferred[i].Bitrate > preferred[j].Bitrate
+	})
+
+	best := preferred[0]
+	return &best, nil
+}
+
+// toMediaMetadata converts a youtubev2.Video into our MediaMetadata,
+// populating Formats with every available stream (progressive and
+// adaptive) so GET /formats can list them without yt-dlp.
+func (n *NativeYouTube) toMediaMetadata(video *youtubev2.Video) *types.MediaMetadata {
+	metadata := &types.MediaMetadata{
+		Title:       video.Title,
+		Description: video.Description,
+		Duration:    int(video.Duration.Seconds()),
+		Uploader:    video.Author,
+		Platform:    "youtube",
+	}
+	if len(video.Thumbnails) > 0 {
+		metadata.Thumbnail = video.Thumbnails[len(video.Thumbnails)-1].URL
+	}
+
+	metadata.Formats = make([]types.FormatEntry, 0, len(video.Formats))
+	for _, f := range video.Formats {
+		entry := types.FormatEntry{
+			FormatID:   fmt.Sprintf("%d", f.ItagNo),
+			Quality:    f.Quality,
+			Resolution: f.QualityLabel,
+			Width:      f.Width,
+			Height:     f.Height,
+			Filesize:   f.ContentLength,
+			Bitrate:    f.Bitrate,
+			URL:        f.URL,
+			Protocol:   "https",
+		}
+		if mimeType := f.MimeType; mimeType != "" {
+			parts := strings.SplitN(mimeType, ";", 2)
+			entry.Ext = strings.TrimPrefix(parts[0], "video/")
+			switch {
+			case strings.Contains(mimeType, "avc1"):
+				entry.VideoCodec = "avc1"
+			case strings.Contains(mimeType, "vp9"):
+				entry.VideoCodec = "vp9"
+			}
+			switch {
+			case strings.Contains(mimeType, "mp4a"):
+				entry.AudioCodec = "mp4a"
+			case strings.Contains(mimeType, "opus"):
+				entry.AudioCodec = "opus"
+			}
+		}
+		if f.AudioChannels == 0 {
+			entry.AudioCodec = ""
+		}
+		metadata.Formats = append(metadata.Formats, entry)
+	}
+
+	return metadata
+}
@@ -8,13 +8,18 @@ import (
 
 	"go.uber.org/zap"
 
+	"github.com/KeremKalyoncu/MedYan/internal/credentials"
+	"github.com/KeremKalyoncu/MedYan/internal/pool"
 	"github.com/KeremKalyoncu/MedYan/internal/types"
 )
 
 // PlatformExtractor provides platform-specific extraction strategies
 type PlatformExtractor struct {
-	ytdlp  *YtDlp
-	logger *zap.Logger
+	ytdlp       *YtDlp
+	logger      *zap.Logger
+	pool        *pool.FFmpegPool
+	credentials credentials.Store
+	proxies     *ProxyRotator
 }
 
 // NewPlatformExtractor creates a new platform-specific extractor
@@ -25,6 +30,49 @@ func NewPlatformExtractor(ytdlp *YtDlp, logger *zap.Logger) *PlatformExtractor {
 	}
 }
 
+// SetPool wires an internal/pool.FFmpegPool so DownloadWithFallback's
+// downloads run under its concurrency cap instead of alongside it
+// unbounded, matching how FFmpeg.SetPool gates transcodes. Optional - a nil
+// pool (the default) downloads inline, same as before this was introduced.
+func (p *PlatformExtractor) SetPool(fp *pool.FFmpegPool) {
+	p.pool = fp
+}
+
+// SetCredentialStore wires a credentials.Store so extractInstagram's
+// rate-limit/login-required fallback can retry with an operator-supplied
+// cookie jar instead of just returning a helpful error string. Optional - a
+// nil store (the default) skips straight to the proxy-rotation fallback.
+func (p *PlatformExtractor) SetCredentialStore(store credentials.Store) {
+	p.credentials = store
+}
+
+// SetProxyRotator wires a ProxyRotator so extractInstagram's fallback can
+// retry through a different egress IP after cookies alone don't clear a
+// rate-limit wall. Optional - a nil rotator (the default) skips this step.
+func (p *PlatformExtractor) SetProxyRotator(rotator *ProxyRotator) {
+	p.proxies = rotator
+}
+
+// download runs the actual yt-dlp download, gated by p.pool when one is
+// set. Returns pool.ErrQueueFull, unwrapped, when the pool's bounded queue
+// is full, so callers can retry with backoff instead of piling on.
+func (p *PlatformExtractor) download(ctx context.Context, url, outputPath string, opts DownloadOptions) (*types.MediaMetadata, error) {
+	if p.pool == nil {
+		return p.ytdlp.Download(ctx, url, outputPath, opts)
+	}
+
+	var metadata *types.MediaMetadata
+	err := p.pool.RunGated(ctx, func() error {
+		var downloadErr error
+		metadata, downloadErr = p.ytdlp.Download(ctx, url, outputPath, opts)
+		return downloadErr
+	})
+	if err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
+
 // ExtractWithFallback attempts extraction with platform-specific fallbacks
 func (p *PlatformExtractor) ExtractWithFallback(ctx context.Context, url string) (*types.MediaMetadata, error) {
 	platform := detectPlatform(url)
@@ -53,20 +101,72 @@ func (p *PlatformExtractor) extractInstagram(ctx context.Context, url string) (*
 	}
 
 	errStr := strings.ToLower(err.Error())
+	if !strings.Contains(errStr, "rate") && !strings.Contains(errStr, "login required") {
+		// Not a wall we have a recovery strategy for - return as-is.
+		return nil, err
+	}
 
-	// Check if error is rate-limit related
-	if strings.Contains(errStr, "rate") || strings.Contains(errStr, "login required") {
-		p.logger.Warn("Instagram rate-limit or authentication required",
-			zap.Error(err),
-			zap.String("fallback", "user should retry later or provide cookies"),
-		)
+	p.logger.Warn("Instagram rate-limit or authentication required, attempting automated recovery",
+		zap.Error(err),
+	)
 
-		// Return helpful error message
-		return nil, fmt.Errorf("Instagram rate-limit reached or login required. Please: 1) Wait 5-10 minutes before retrying, 2) Try a different Instagram URL, or 3) Contact support for authentication options. Original error: %w", err)
+	// Strategy 2: retry with a stored cookie jar, if one is configured.
+	cookiesFile, cookieErr := p.instagramCookiesFile(ctx)
+	if cookieErr != nil {
+		p.logger.Warn("No Instagram cookie jar available for retry", zap.Error(cookieErr))
+	} else {
+		metadata, err = p.ytdlp.ExtractMetadataWithAuth(ctx, url, cookiesFile, "")
+		if err == nil {
+			p.logger.Info("Instagram extraction succeeded after cookie-jar retry")
+			return metadata, nil
+		}
+		if strings.Contains(strings.ToLower(err.Error()), "login required") && p.credentials != nil {
+			p.logger.Warn("Stored Instagram cookie jar was rejected as login-required, marking stale")
+			if markErr := p.credentials.MarkStale(ctx, "instagram"); markErr != nil {
+				p.logger.Warn("Failed to mark Instagram cookie jar stale", zap.Error(markErr))
+			}
+		}
 	}
 
-	// For other errors, return as-is
-	return nil, err
+	// Strategy 3: rotate through a proxy pool, still presenting the cookie
+	// jar (if any) on each attempt, stopping once every proxy is either
+	// exhausted or cooling down from a prior 429.
+	if p.proxies == nil || p.proxies.Len() == 0 {
+		return nil, fmt.Errorf("Instagram rate-limit reached or login required, and no proxy pool is configured for automated recovery. Please: 1) Wait 5-10 minutes before retrying, 2) Configure INSTAGRAM_PROXIES, or 3) Contact support for authentication options. Original error: %w", err)
+	}
+
+	for attempt := 0; attempt < p.proxies.Len(); attempt++ {
+		proxyURL := p.proxies.Next()
+		if proxyURL == "" {
+			break // every proxy is currently cooling down
+		}
+
+		metadata, err = p.ytdlp.ExtractMetadataWithAuth(ctx, url, cookiesFile, proxyURL)
+		if err == nil {
+			p.logger.Info("Instagram extraction succeeded via rotated proxy", zap.String("proxy", proxyURL))
+			return metadata, nil
+		}
+
+		if strings.Contains(err.Error(), "429") {
+			p.proxies.MarkRateLimited(proxyURL)
+		}
+	}
+
+	return nil, fmt.Errorf("Instagram rate-limit reached or login required even after cookie-jar and proxy-rotation retries. Please: 1) Wait 5-10 minutes before retrying, 2) Try a different Instagram URL, or 3) Contact support for authentication options. Original error: %w", err)
+}
+
+// instagramCookiesFile fetches the configured Instagram cookie jar from
+// p.credentials and writes it to a temp file in the format yt-dlp's
+// --cookies flag expects, the same helper used for per-request cookies.
+func (p *PlatformExtractor) instagramCookiesFile(ctx context.Context) (string, error) {
+	if p.credentials == nil {
+		return "", fmt.Errorf("no credential store configured")
+	}
+	cred, err := p.credentials.Get(ctx, "instagram")
+	if err != nil {
+		return "", err
+	}
+	return WriteCookiesFile(cred.CookiesBase64)
 }
 
 // extractTikTok handles TikTok with fallback strategies
@@ -144,14 +244,14 @@ func (p *PlatformExtractor) DownloadWithFallback(ctx context.Context, url, outpu
 		defer cancel()
 
 		p.logger.Info("Instagram download with extended timeout")
-		return p.ytdlp.Download(ctx, url, outputPath, opts)
+		return p.download(ctx, url, outputPath, opts)
 
 	case "tiktok":
 		// TikTok: Sometimes needs multiple attempts
 		p.logger.Info("TikTok download")
-		return p.ytdlp.Download(ctx, url, outputPath, opts)
+		return p.download(ctx, url, outputPath, opts)
 
 	default:
-		return p.ytdlp.Download(ctx, url, outputPath, opts)
+		return p.download(ctx, url, outputPath, opts)
 	}
 }
@@ -0,0 +1,241 @@
+package extractor
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	ffmpeg "github.com/u2takey/ffmpeg-go"
+	"go.uber.org/zap"
+
+	"github.com/KeremKalyoncu/MedYan/internal/metrics"
+)
+
+// stderrTailSize bounds how much of a failed ffmpeg run's stderr
+// runTrackedWithProgress keeps around to report back - enough to see the
+// actual error, not so much that a multi-hour transcode's chatter is held
+// in memory for nothing.
+const stderrTailSize = 64 * 1024
+
+// stderrTail is an io.Writer that keeps only the most recently written
+// stderrTailSize bytes, so a failed ffmpeg run's diagnostic output can be
+// returned without buffering the entire (sometimes multi-MB) stream.
+type stderrTail struct {
+	mu      sync.Mutex
+	buf     []byte
+	maxSize int
+}
+
+func newStderrTail(maxSize int) *stderrTail {
+	return &stderrTail{maxSize: maxSize}
+}
+
+func (t *stderrTail) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.buf = append(t.buf, p...)
+	if len(t.buf) > t.maxSize {
+		t.buf = t.buf[len(t.buf)-t.maxSize:]
+	}
+	return len(p), nil
+}
+
+func (t *stderrTail) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return string(t.buf)
+}
+
+// FFmpegProgress is one update parsed from a running ffmpeg process's
+// `-progress pipe:1` key=value stream - richer than a bare percent so a
+// caller (see ExtractionHandler's forwarding to queue.Client.PublishProgress)
+// can surface transfer rate and ETA, the same way DownloadProgress does for
+// yt-dlp downloads.
+type FFmpegProgress struct {
+	// PercentComplete is out_time_us divided by the ffprobe-derived source
+	// duration, clamped to [0, 100]. Always 0 when the duration is unknown.
+	PercentComplete float64
+	Frame           int64
+	FPS             float64
+	// Speed is ffmpeg's own "speed=1.23x" reported as a plain multiplier of
+	// realtime (1.0 = realtime, 2.0 = twice as fast as playback).
+	Speed        float64
+	BytesWritten int64
+	ETASeconds   int
+	// Done is true on ffmpeg's final "progress=end" line.
+	Done bool
+}
+
+// ProgressCallback receives one FFmpegProgress per `-progress` batch ffmpeg
+// emits (roughly once a second), far more often than any consumer needs to
+// redraw at - coalesce before forwarding to Redis or an SSE client.
+type ProgressCallback func(FFmpegProgress)
+
+// parseFFmpegProgress scans r - ffmpeg's `-progress pipe:1` output, a
+// stream of "key=value" lines with a "progress=continue"/"progress=end"
+// line terminating each batch - and invokes callback once per batch.
+// durationSecs is the source's ffprobe-derived duration, used to turn
+// out_time_us into a percentage; <= 0 leaves PercentComplete at 0.
+func parseFFmpegProgress(r io.Reader, durationSecs float64, callback ProgressCallback) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 4*1024), 64*1024)
+
+	event := FFmpegProgress{}
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), "=")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "frame":
+			event.Frame, _ = strconv.ParseInt(value, 10, 64)
+		case "fps":
+			event.FPS, _ = strconv.ParseFloat(value, 64)
+		case "total_size":
+			event.BytesWritten, _ = strconv.ParseInt(value, 10, 64)
+		case "speed":
+			event.Speed, _ = strconv.ParseFloat(strings.TrimSuffix(value, "x"), 64)
+		case "out_time_us":
+			outTimeUs, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || durationSecs <= 0 {
+				continue
+			}
+			outTimeSecs := float64(outTimeUs) / 1_000_000
+			pct := outTimeSecs / durationSecs * 100
+			if pct < 0 {
+				pct = 0
+			}
+			if pct > 100 {
+				pct = 100
+			}
+			event.PercentComplete = pct
+			if event.Speed > 0 {
+				if remaining := durationSecs - outTimeSecs; remaining > 0 {
+					event.ETASeconds = int(remaining / event.Speed)
+				}
+			}
+		case "progress":
+			event.Done = value == "end"
+			callback(event)
+			if event.Done {
+				return
+			}
+			event = FFmpegProgress{}
+		}
+	}
+}
+
+// durationForProgress probes inputPath's duration via GetMediaInfo, but
+// only when callback is non-nil - callers that don't want progress
+// instrumentation skip the extra ffprobe invocation entirely.
+func (f *FFmpeg) durationForProgress(ctx context.Context, inputPath string, callback ProgressCallback) float64 {
+	if callback == nil {
+		return 0
+	}
+	info, err := f.GetMediaInfo(ctx, inputPath)
+	if err != nil {
+		f.logger.Warn("Failed to probe duration for progress reporting", zap.Error(err))
+		return 0
+	}
+	return info.Format.Duration
+}
+
+// withProgressKwargs adds the `-progress pipe:1 -nostats` flags to kwargs
+// that make ffmpeg emit a parseable progress stream on stdout instead of
+// its usual human-readable stats line, for callers that pass a
+// ProgressCallback.
+func withProgressKwargs(kwargs ffmpeg.KwArgs) {
+	kwargs["progress"] = "pipe:1"
+	kwargs["nostats"] = ""
+}
+
+// runTrackedWithProgress is runTracked's progress-instrumented counterpart:
+// stream's Output kwargs must already carry the pipe:1 progress flags (see
+// withProgressKwargs) whenever callback is non-nil. Stderr is always
+// captured into a bounded tail buffer, appended to the returned error
+// instead of runTracked's ErrorToStdOut() firehose.
+func (f *FFmpeg) runTrackedWithProgress(operation, taskID string, stream *ffmpeg.Stream, durationSecs float64, callback ProgressCallback) error {
+	start := time.Now()
+	defer func() {
+		metrics.GetMetrics().RecordFFmpegExec(operation, time.Since(start))
+	}()
+
+	if f.pool != nil {
+		return f.pool.RunGated(context.Background(), func() error {
+			return f.runTrackedDirectProgress(taskID, stream, durationSecs, callback)
+		})
+	}
+	return f.runTrackedDirectProgress(taskID, stream, durationSecs, callback)
+}
+
+// runTrackedDirectProgress is runTrackedWithProgress's actual body, split
+// out the same way runTrackedDirect is so it can run either inline or
+// gated by an FFmpegPool's concurrency limit.
+func (f *FFmpeg) runTrackedDirectProgress(taskID string, stream *ffmpeg.Stream, durationSecs float64, callback ProgressCallback) error {
+	cmd := stream.Compile()
+
+	// Preserve whatever the stream's own ErrorToStdOut()/WithErrorOutput()
+	// already wired cmd.Stderr to (e.g. runHWEncode's fallback-detection
+	// buffer) alongside the tail capture, instead of replacing it.
+	tail := newStderrTail(stderrTailSize)
+	if cmd.Stderr != nil {
+		cmd.Stderr = io.MultiWriter(cmd.Stderr, tail)
+	} else {
+		cmd.Stderr = tail
+	}
+
+	var progressDone chan struct{}
+	if callback != nil {
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return err
+		}
+		progressDone = make(chan struct{})
+		go func() {
+			defer close(progressDone)
+			parseFFmpegProgress(stdout, durationSecs, callback)
+		}()
+	}
+
+	if f.registry != nil && taskID != "" {
+		f.registry.Track(taskID, "ffmpeg", cmd, func() {})
+		defer f.registry.Untrack(taskID)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start ffmpeg: %w", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	var runErr error
+	select {
+	case runErr = <-done:
+	case <-time.After(f.timeout):
+		if cmd.Process != nil {
+			cmd.Process.Kill()
+		}
+		<-done
+		runErr = fmt.Errorf("ffmpeg timed out after %s", f.timeout)
+	}
+
+	if progressDone != nil {
+		<-progressDone
+	}
+
+	if runErr != nil {
+		if tailText := strings.TrimSpace(tail.String()); tailText != "" {
+			return fmt.Errorf("%w (stderr: %s)", runErr, tailText)
+		}
+		return runErr
+	}
+	return nil
+}
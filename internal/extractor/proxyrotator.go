@@ -0,0 +1,66 @@
+package extractor
+
+import (
+	"sync"
+	"time"
+)
+
+// defaultProxyCooldown is how long a proxy sits out after MarkRateLimited
+// before ProxyRotator.Next hands it out again.
+const defaultProxyCooldown = 5 * time.Minute
+
+// ProxyRotator round-robins through a fixed pool of HTTP/SOCKS proxy URLs,
+// skipping any currently cooling down after a 429.
+type ProxyRotator struct {
+	mu       sync.Mutex
+	proxies  []string
+	next     int
+	cooldown map[string]time.Time
+}
+
+// NewProxyRotator creates a rotator over proxies (entries as accepted by
+// yt-dlp's --proxy flag, e.g. "http://host:port" or "socks5://host:port").
+func NewProxyRotator(proxies []string) *ProxyRotator {
+	return &ProxyRotator{
+		proxies:  proxies,
+		cooldown: make(map[string]time.Time),
+	}
+}
+
+// Len returns the number of proxies in the pool, for callers bounding how
+// many rotation attempts to make.
+func (r *ProxyRotator) Len() int {
+	return len(r.proxies)
+}
+
+// Next returns the next proxy not currently in cooldown, or "" if the pool
+// is empty or every proxy is cooling down.
+func (r *ProxyRotator) Next() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.proxies) == 0 {
+		return ""
+	}
+
+	now := time.Now()
+	for i := 0; i < len(r.proxies); i++ {
+		proxy := r.proxies[r.next]
+		r.next = (r.next + 1) % len(r.proxies)
+
+		if until, cooling := r.cooldown[proxy]; cooling && now.Before(until) {
+			continue
+		}
+		return proxy
+	}
+
+	return ""
+}
+
+// MarkRateLimited puts proxy in cooldown after it returns a 429, so Next
+// skips it until the cooldown expires.
+func (r *ProxyRotator) MarkRateLimited(proxy string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cooldown[proxy] = time.Now().Add(defaultProxyCooldown)
+}
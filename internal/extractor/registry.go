@@ -0,0 +1,168 @@
+package extractor
+
+import (
+	"context"
+	"os/exec"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// trackedProcess describes a single in-flight extractor subprocess, keyed by
+// the caller's task/job ID. cmd is nil for a caller with no direct handle on
+// its *exec.Cmd - those rely on cancel alone, which must cancel the context
+// the command was started with. ffmpeg processes are tracked by the
+// *exec.Cmd ffmpeg-go's Compile returns, with a no-op cancel.
+type trackedProcess struct {
+	taskID       string
+	kind         string
+	startedAt    time.Time
+	lastProgress time.Time
+	cmd          *exec.Cmd
+	cancel       context.CancelFunc
+}
+
+// ProcessSnapshot describes a tracked process for the admin listing endpoint.
+type ProcessSnapshot struct {
+	TaskID       string        `json:"task_id"`
+	Kind         string        `json:"kind"`
+	StartedAt    time.Time     `json:"started_at"`
+	LastProgress time.Time     `json:"last_progress"`
+	IdleFor      time.Duration `json:"idle_for"`
+}
+
+// ProcessRegistry tracks running extractor subprocesses (yt-dlp, ffmpeg) so a
+// watchdog can kill ones that have stopped making progress, and so an admin
+// endpoint can list and forcibly cancel in-flight extractions by task ID.
+type ProcessRegistry struct {
+	mu        sync.RWMutex
+	processes map[string]*trackedProcess
+}
+
+// NewProcessRegistry creates an empty process registry.
+func NewProcessRegistry() *ProcessRegistry {
+	return &ProcessRegistry{
+		processes: make(map[string]*trackedProcess),
+	}
+}
+
+// Track registers a running command under taskID. cmd may be nil when the
+// caller has no direct handle on the OS process; cancel must then cancel
+// the context the command was started with.
+func (r *ProcessRegistry) Track(taskID, kind string, cmd *exec.Cmd, cancel context.CancelFunc) {
+	now := time.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.processes[taskID] = &trackedProcess{
+		taskID:       taskID,
+		kind:         kind,
+		startedAt:    now,
+		lastProgress: now,
+		cmd:          cmd,
+		cancel:       cancel,
+	}
+}
+
+// Touch records that taskID has produced progress output, resetting its idle
+// clock. It is a no-op if taskID isn't tracked (already finished, or never
+// registered because no registry was configured).
+func (r *ProcessRegistry) Touch(taskID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if p, ok := r.processes[taskID]; ok {
+		p.lastProgress = time.Now()
+	}
+}
+
+// Untrack removes taskID once its command has finished, successfully or not.
+func (r *ProcessRegistry) Untrack(taskID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.processes, taskID)
+}
+
+// List returns a snapshot of all currently tracked processes.
+func (r *ProcessRegistry) List() []ProcessSnapshot {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	now := time.Now()
+	out := make([]ProcessSnapshot, 0, len(r.processes))
+	for _, p := range r.processes {
+		out = append(out, ProcessSnapshot{
+			TaskID:       p.taskID,
+			Kind:         p.kind,
+			StartedAt:    p.startedAt,
+			LastProgress: p.lastProgress,
+			IdleFor:      now.Sub(p.lastProgress),
+		})
+	}
+	return out
+}
+
+// Cancel forcibly kills the process tracked under taskID and cancels its
+// context, reporting false if no such task is currently tracked.
+func (r *ProcessRegistry) Cancel(taskID string) bool {
+	r.mu.RLock()
+	p, ok := r.processes[taskID]
+	r.mu.RUnlock()
+	if !ok {
+		return false
+	}
+
+	p.cancel()
+	if p.cmd != nil && p.cmd.Process != nil {
+		p.cmd.Process.Kill()
+	}
+	return true
+}
+
+// killIdle cancels and kills every tracked process whose last progress is
+// older than idleTimeout, returning the task IDs it acted on.
+func (r *ProcessRegistry) killIdle(idleTimeout time.Duration) []string {
+	r.mu.RLock()
+	now := time.Now()
+	var stale []*trackedProcess
+	for _, p := range r.processes {
+		if now.Sub(p.lastProgress) > idleTimeout {
+			stale = append(stale, p)
+		}
+	}
+	r.mu.RUnlock()
+
+	killed := make([]string, 0, len(stale))
+	for _, p := range stale {
+		p.cancel()
+		if p.cmd != nil && p.cmd.Process != nil {
+			p.cmd.Process.Kill()
+		}
+		killed = append(killed, p.taskID)
+	}
+	return killed
+}
+
+// StartWatchdog launches a goroutine that periodically kills tracked
+// processes that have produced no progress for idleTimeout. This is separate
+// from each command's overall timeout, which bounds total runtime even when
+// a process is still actively working. The goroutine exits when ctx is done.
+func (r *ProcessRegistry) StartWatchdog(ctx context.Context, checkInterval, idleTimeout time.Duration, logger *zap.Logger) {
+	go func() {
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				for _, taskID := range r.killIdle(idleTimeout) {
+					logger.Warn("Killed idle extractor process",
+						zap.String("task_id", taskID),
+						zap.Duration("idle_timeout", idleTimeout),
+					)
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,384 @@
+package extractor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	ffmpeg "github.com/u2takey/ffmpeg-go"
+	"go.uber.org/zap"
+)
+
+// defaultThumbnailCount is how many samples GenerateThumbnails takes across
+// the source's duration when neither ThumbnailOptions.Count nor .Interval
+// is set.
+const defaultThumbnailCount = 100
+
+// defaultThumbnailWidth is each thumbnail's output width in pixels when
+// ThumbnailOptions.Width isn't set.
+const defaultThumbnailWidth = 160
+
+// smartThumbnailWindow is how many source frames ffmpeg's "thumbnail"
+// filter scans to pick the most representative one per sample, when
+// ThumbnailOptions.Smart is set.
+const smartThumbnailWindow = 30
+
+// ThumbnailOptions configures FFmpeg.GenerateThumbnails.
+type ThumbnailOptions struct {
+	OutputDir string
+
+	// Count is how many samples to take across the whole duration. Ignored
+	// when Interval is set. Defaults to defaultThumbnailCount.
+	Count int
+	// Interval, when > 0, takes precedence over Count: one sample every
+	// Interval seconds.
+	Interval float64
+
+	// Width is each thumbnail's output width in pixels; height is derived
+	// via scale=W:-1 to preserve the source aspect ratio. Defaults to
+	// defaultThumbnailWidth.
+	Width int
+
+	// Cols/Rows size the sprite sheet grid in tiled mode (the default -
+	// see Frames). Zero means GenerateThumbnails picks a roughly square
+	// grid sized to fit every sample.
+	Cols, Rows int
+
+	// Frames, when true, emits individual numbered JPEGs (%04d.jpg)
+	// instead of a single tiled sprite.jpg + sprite.vtt.
+	Frames bool
+	// Smart uses ffmpeg's "thumbnail" filter to pick the most
+	// representative frame within each sampling window instead of a plain
+	// equidistant fps sample. Ignored when Keyframes is set.
+	Smart bool
+	// Keyframes samples at the source's actual I-frames instead of
+	// equidistant ticks: ffprobe reads each keyframe's PTS first, so the
+	// sprite/VTT cues land exactly on GOP boundaries, and ffmpeg decodes
+	// only those frames (-skip_frame nokey), which is far cheaper than a
+	// full decode for a long source. Takes precedence over Smart/Interval/
+	// Count, which all assume equidistant sampling.
+	Keyframes bool
+
+	// ProgressCallback, when non-nil, receives one FFmpegProgress per
+	// `-progress` tick the underlying ffmpeg process reports, the same way
+	// FFmpeg.PackageHLS's does - lets a caller show a distinct
+	// "generating previews" phase instead of looking stuck.
+	ProgressCallback ProgressCallback
+
+	// TaskID is tracked the same way ConvertFormat's taskID is.
+	TaskID string
+}
+
+// ThumbnailResult lists what GenerateThumbnails produced. SpritePath/VTTPath
+// are empty in Frames mode, where every sampled frame is listed in Files
+// instead. Cols/Rows/TileWidth/TileHeight are zero in Frames mode, since
+// there's no single tiled sprite to lay out.
+type ThumbnailResult struct {
+	Dir        string
+	SpritePath string
+	VTTPath    string
+	Files      []string
+
+	Cols, Rows            int
+	TileWidth, TileHeight int
+}
+
+// GenerateThumbnails samples inputPath and produces either a tiled sprite
+// sheet with a WebVTT cue sidecar pointing at each tile
+// (sprite.jpg#xywh=x,y,w,h, for player scrubbing previews) or, with
+// opts.Frames set, individual numbered JPEG stills. With opts.Keyframes,
+// samples are the source's actual I-frames (read via ffprobe); otherwise
+// sampling is equidistant, one tick every opts.Interval seconds, or every
+// duration/opts.Count if Interval isn't set.
+func (f *FFmpeg) GenerateThumbnails(ctx context.Context, inputPath string, opts ThumbnailOptions) (*ThumbnailResult, error) {
+	if opts.OutputDir == "" {
+		return nil, fmt.Errorf("output directory is required")
+	}
+	if err := os.MkdirAll(opts.OutputDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create thumbnail output directory: %w", err)
+	}
+
+	info, err := f.GetMediaInfo(ctx, inputPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to probe source: %w", err)
+	}
+	duration := info.Format.Duration
+	if duration <= 0 {
+		return nil, fmt.Errorf("source duration is unknown or zero")
+	}
+
+	width := opts.Width
+	if width <= 0 {
+		width = defaultThumbnailWidth
+	}
+
+	var sampling string
+	var keyframeTimes []float64
+	var interval float64
+	if opts.Keyframes {
+		keyframeTimes, err = f.keyframeTimestamps(ctx, inputPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read keyframe timestamps: %w", err)
+		}
+		if len(keyframeTimes) == 0 {
+			return nil, fmt.Errorf("source has no keyframes to sample")
+		}
+		// -skip_frame nokey (set on the input below) means ffmpeg only
+		// decodes what select is looking for, so this is cheap even on a
+		// long source.
+		sampling = `select=eq(pict_type\,I)`
+	} else {
+		interval = opts.Interval
+		if interval <= 0 {
+			count := opts.Count
+			if count <= 0 {
+				count = defaultThumbnailCount
+			}
+			interval = duration / float64(count)
+		}
+		if interval <= 0 {
+			interval = 1
+		}
+
+		sampling = fmt.Sprintf("fps=1/%s", strconv.FormatFloat(interval, 'f', -1, 64))
+		if opts.Smart {
+			// thumbnail=N picks the most representative of every N decoded
+			// frames; chaining fps after it still thins that down to our
+			// target interval, now choosing among "good" frames rather than
+			// whatever lands on the timestamp.
+			sampling = fmt.Sprintf("thumbnail=%d,%s", smartThumbnailWindow, sampling)
+		}
+	}
+
+	f.logger.Info("Generating thumbnails",
+		zap.String("input", inputPath),
+		zap.Float64("interval_seconds", interval),
+		zap.Bool("tiled", !opts.Frames),
+		zap.Bool("smart", opts.Smart),
+		zap.Bool("keyframes", opts.Keyframes),
+	)
+
+	if opts.Frames {
+		return f.generateThumbnailFrames(inputPath, opts, sampling, width, duration)
+	}
+	return f.generateSpriteSheet(inputPath, opts, sampling, width, duration, interval, keyframeTimes, info)
+}
+
+// thumbnailInput builds the ffmpeg.Input for a thumbnail/sprite run,
+// applying -skip_frame nokey when opts.Keyframes is set so ffmpeg only
+// decodes the frames sampling's select filter is going to keep.
+func thumbnailInput(inputPath string, opts ThumbnailOptions) *ffmpeg.Stream {
+	if opts.Keyframes {
+		return ffmpeg.Input(inputPath, ffmpeg.KwArgs{"skip_frame": "nokey"})
+	}
+	return ffmpeg.Input(inputPath)
+}
+
+func (f *FFmpeg) generateThumbnailFrames(inputPath string, opts ThumbnailOptions, sampling string, width int, duration float64) (*ThumbnailResult, error) {
+	outputPattern := filepath.Join(opts.OutputDir, "%04d.jpg")
+	vf := fmt.Sprintf("%s,scale=%d:-1", sampling, width)
+	kwargs := ffmpeg.KwArgs{"vf": vf, "vsync": "vfr"}
+	if opts.ProgressCallback != nil {
+		withProgressKwargs(kwargs)
+	}
+
+	stream := thumbnailInput(inputPath, opts).
+		Output(outputPattern, kwargs).
+		OverWriteOutput().
+		ErrorToStdOut().
+		SetFfmpegPath(f.binaryPath)
+
+	if err := f.runTrackedWithProgress("thumbnails", opts.TaskID, stream, duration, opts.ProgressCallback); err != nil {
+		return nil, fmt.Errorf("thumbnail extraction failed: %w", err)
+	}
+
+	files, err := listProducedFiles(opts.OutputDir)
+	if err != nil {
+		return nil, err
+	}
+	return &ThumbnailResult{Dir: opts.OutputDir, Files: files}, nil
+}
+
+func (f *FFmpeg) generateSpriteSheet(inputPath string, opts ThumbnailOptions, sampling string, width int, duration, interval float64, keyframeTimes []float64, info *MediaInfo) (*ThumbnailResult, error) {
+	cols, rows := opts.Cols, opts.Rows
+	sampleCount := len(keyframeTimes)
+	if sampleCount == 0 {
+		sampleCount = int(duration/interval) + 1
+	}
+	if cols <= 0 || rows <= 0 {
+		cols, rows = spriteGrid(sampleCount)
+	}
+	// ffmpeg's tile filter needs exactly cols*rows frames; drop any excess
+	// samples the grid has no room for rather than erroring out.
+	if tileCount := cols * rows; sampleCount > tileCount {
+		sampleCount = tileCount
+	}
+	if len(keyframeTimes) > sampleCount {
+		keyframeTimes = keyframeTimes[:sampleCount]
+	}
+
+	tileHeight := width * 9 / 16
+	if vs := info.BestVideoStream(); vs != nil && vs.Width > 0 && vs.Height > 0 {
+		tileHeight = int(math.Round(float64(width) * float64(vs.Height) / float64(vs.Width)))
+	}
+
+	spritePath := filepath.Join(opts.OutputDir, "sprite.jpg")
+	vf := fmt.Sprintf("%s,scale=%d:-1,tile=%dx%d", sampling, width, cols, rows)
+	kwargs := ffmpeg.KwArgs{"vf": vf, "vframes": 1}
+	if opts.ProgressCallback != nil {
+		withProgressKwargs(kwargs)
+	}
+
+	stream := thumbnailInput(inputPath, opts).
+		Output(spritePath, kwargs).
+		OverWriteOutput().
+		ErrorToStdOut().
+		SetFfmpegPath(f.binaryPath)
+
+	if err := f.runTrackedWithProgress("thumbnails", opts.TaskID, stream, duration, opts.ProgressCallback); err != nil {
+		return nil, fmt.Errorf("sprite sheet generation failed: %w", err)
+	}
+
+	vttPath := filepath.Join(opts.OutputDir, "sprite.vtt")
+	var vttErr error
+	if len(keyframeTimes) > 0 {
+		vttErr = writeSpriteVTTFromTimestamps(vttPath, "sprite.jpg", keyframeTimes, cols, width, tileHeight, duration)
+	} else {
+		vttErr = writeSpriteVTT(vttPath, "sprite.jpg", sampleCount, cols, width, tileHeight, interval, duration)
+	}
+	if vttErr != nil {
+		return nil, fmt.Errorf("failed to write sprite VTT: %w", vttErr)
+	}
+
+	return &ThumbnailResult{
+		Dir:        opts.OutputDir,
+		SpritePath: spritePath,
+		VTTPath:    vttPath,
+		Files:      []string{spritePath, vttPath},
+		Cols:       cols,
+		Rows:       rows,
+		TileWidth:  width,
+		TileHeight: tileHeight,
+	}, nil
+}
+
+// keyframeTimestamps runs ffprobe against inputPath and returns every video
+// keyframe's presentation timestamp in seconds, in order. It reads packet
+// flags rather than decoding frames, so it's cheap even on a long source.
+func (f *FFmpeg) keyframeTimestamps(ctx context.Context, inputPath string) ([]float64, error) {
+	ctx, cancel := context.WithTimeout(ctx, f.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, f.ffprobePath,
+		"-v", "error",
+		"-select_streams", "v:0",
+		"-show_entries", "packet=pts_time,flags",
+		"-of", "csv=p=0",
+		inputPath,
+	)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("ffprobe keyframe scan failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	var timestamps []float64
+	for _, line := range strings.Split(stdout.String(), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		pts, flags, ok := strings.Cut(line, ",")
+		if !ok || !strings.Contains(flags, "K") {
+			continue
+		}
+		seconds, err := strconv.ParseFloat(pts, 64)
+		if err != nil {
+			continue
+		}
+		timestamps = append(timestamps, seconds)
+	}
+	return timestamps, nil
+}
+
+// spriteGrid picks a roughly square cols x rows grid that holds at least
+// sampleCount tiles.
+func spriteGrid(sampleCount int) (cols, rows int) {
+	if sampleCount <= 0 {
+		return 1, 1
+	}
+	cols = int(math.Ceil(math.Sqrt(float64(sampleCount))))
+	rows = int(math.Ceil(float64(sampleCount) / float64(cols)))
+	return cols, rows
+}
+
+// writeSpriteVTT writes a WebVTT sidecar with one cue per sprite tile, in
+// row-major order matching ffmpeg's tile filter, pointing back at
+// spriteFilename via the player-scrubbing-preview xywh media fragment
+// convention (https://www.w3.org/TR/media-frags/).
+func writeSpriteVTT(path, spriteFilename string, sampleCount, cols, tileWidth, tileHeight int, interval, duration float64) error {
+	var b strings.Builder
+	b.WriteString("WEBVTT\n\n")
+
+	for i := 0; i < sampleCount; i++ {
+		start := float64(i) * interval
+		end := start + interval
+		if end > duration {
+			end = duration
+		}
+		x := (i % cols) * tileWidth
+		y := (i / cols) * tileHeight
+
+		fmt.Fprintf(&b, "%s --> %s\n%s#xywh=%d,%d,%d,%d\n\n",
+			vttTimestamp(start), vttTimestamp(end), spriteFilename, x, y, tileWidth, tileHeight)
+	}
+
+	return os.WriteFile(path, []byte(b.String()), 0644)
+}
+
+// writeSpriteVTTFromTimestamps is writeSpriteVTT for Keyframes mode, where
+// tiles aren't evenly spaced: each cue runs from its keyframe's own PTS to
+// the next keyframe's (or duration, for the last tile).
+func writeSpriteVTTFromTimestamps(path, spriteFilename string, timestamps []float64, cols, tileWidth, tileHeight int, duration float64) error {
+	var b strings.Builder
+	b.WriteString("WEBVTT\n\n")
+
+	for i, start := range timestamps {
+		end := duration
+		if i+1 < len(timestamps) {
+			end = timestamps[i+1]
+		}
+		x := (i % cols) * tileWidth
+		y := (i / cols) * tileHeight
+
+		fmt.Fprintf(&b, "%s --> %s\n%s#xywh=%d,%d,%d,%d\n\n",
+			vttTimestamp(start), vttTimestamp(end), spriteFilename, x, y, tileWidth, tileHeight)
+	}
+
+	return os.WriteFile(path, []byte(b.String()), 0644)
+}
+
+// vttTimestamp formats seconds as a WebVTT cue timestamp (HH:MM:SS.mmm).
+func vttTimestamp(seconds float64) string {
+	if seconds < 0 {
+		seconds = 0
+	}
+	total := time.Duration(seconds * float64(time.Second))
+	h := total / time.Hour
+	total -= h * time.Hour
+	m := total / time.Minute
+	total -= m * time.Minute
+	s := total / time.Second
+	total -= s * time.Second
+	ms := total / time.Millisecond
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", h, m, s, ms)
+}
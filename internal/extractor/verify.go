@@ -0,0 +1,196 @@
+package extractor
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/KeremKalyoncu/MedYan/internal/types"
+)
+
+// VerifyMode controls how Download's post-download verification pass
+// reacts to a file that doesn't match the metadata yt-dlp reported before
+// downloading it.
+type VerifyMode string
+
+const (
+	// VerifyOff skips verification entirely - Download's behavior before
+	// this was introduced, and still the default (the zero value).
+	VerifyOff VerifyMode = ""
+	// VerifyWarn runs verification, attempts the remux repair on failure,
+	// and logs an unrepaired failure, but never fails the download.
+	VerifyWarn VerifyMode = "warn"
+	// VerifyStrict is VerifyWarn, except an unrepaired failure is returned
+	// as an error - which isRetryableError treats as retryable by default,
+	// so Download's retry loop re-downloads the file.
+	VerifyStrict VerifyMode = "strict"
+)
+
+// durationToleranceSeconds is how far a downloaded file's container
+// duration may drift from yt-dlp's pre-download metadata before
+// verifyDownload flags it.
+const durationToleranceSeconds = 2.0
+
+// VerificationReport is the result of running verifyDownload against a
+// downloaded file.
+type VerificationReport struct {
+	// Passed is true when no issues were found, or every issue found was
+	// resolved by a remux repair.
+	Passed bool
+	// Repaired is true when a muxing problem was fixed by remuxing into a
+	// sibling file - callers should use RepairedPath instead of the
+	// original output.
+	Repaired     bool
+	RepairedPath string
+	// Issues lists every check that failed, even when Repaired made the
+	// file usable again.
+	Issues []string
+}
+
+// verifyDownload runs ffprobe against outputTemplate's resolved file and
+// cross-checks it against expected (yt-dlp's pre-download metadata),
+// attempting a stream-copy remux when the result looks broken. It returns a
+// non-nil error only when mode is VerifyStrict and the file is still
+// unusable after the repair attempt; VerifyWarn reports the same failure
+// without erroring.
+func verifyDownload(ctx context.Context, ffmpeg *FFmpeg, logger *zap.Logger, outputTemplate string, expected *types.MediaMetadata, extractAudio bool, mode VerifyMode) (*VerificationReport, error) {
+	report := &VerificationReport{Passed: true}
+
+	actualPath := resolveDownloadedFile(outputTemplate)
+	if actualPath == "" {
+		report.Passed = false
+		report.Issues = append(report.Issues, "downloaded file not found on disk")
+		return report, nil
+	}
+
+	info, err := ffmpeg.GetMediaInfo(ctx, actualPath)
+	if err != nil {
+		report.Passed = false
+		report.Issues = append(report.Issues, fmt.Sprintf("ffprobe failed: %v", err))
+		return finishVerification(ctx, ffmpeg, logger, actualPath, expected, extractAudio, report, mode)
+	}
+
+	report.Issues = mediaIssues(info, expected, extractAudio, actualPath)
+	if len(report.Issues) == 0 {
+		return report, nil
+	}
+	report.Passed = false
+	return finishVerification(ctx, ffmpeg, logger, actualPath, expected, extractAudio, report, mode)
+}
+
+// mediaIssues is the set of checks verifyDownload runs against a probed
+// file - pulled out so finishVerification can re-run the exact same checks
+// against a remux repair's output instead of judging it by a looser
+// standard (see finishVerification).
+func mediaIssues(info *MediaInfo, expected *types.MediaMetadata, extractAudio bool, path string) []string {
+	var issues []string
+
+	if expected != nil && expected.Duration > 0 && info.Format.Duration > 0 {
+		if math.Abs(info.Format.Duration-float64(expected.Duration)) > durationToleranceSeconds {
+			issues = append(issues, fmt.Sprintf("duration mismatch: expected %ds, got %.1fs", expected.Duration, info.Format.Duration))
+		}
+	}
+
+	if !extractAudio && info.BestVideoStream() == nil {
+		issues = append(issues, "no video stream with non-zero dimensions")
+	}
+
+	if !info.HasAudio() {
+		issues = append(issues, "no audio stream")
+	}
+
+	// ffprobe reports no format duration at all for a fragmented MP4 with
+	// no leading moov atom - not a precise check, but catches the common
+	// "fragmented without init" failure this was written for.
+	if strings.EqualFold(filepath.Ext(path), ".mp4") && info.Format.Duration == 0 {
+		issues = append(issues, "mp4 has no moov atom (ffprobe reported no duration)")
+	}
+
+	return issues
+}
+
+// finishVerification logs report's issues, attempts a remux repair, and
+// decides whether to return an error based on mode and whether the repair
+// worked. A repair only counts as working when repairedInfo clears every
+// check in mediaIssues, not merely when it has a non-zero duration -
+// ffmpeg.Remux is a stream copy, so a file missing its audio track (or any
+// other mediaIssues failure besides the moov-atom case) "repairs" into an
+// otherwise-unchanged file still missing that stream.
+func finishVerification(ctx context.Context, ffmpeg *FFmpeg, logger *zap.Logger, actualPath string, expected *types.MediaMetadata, extractAudio bool, report *VerificationReport, mode VerifyMode) (*VerificationReport, error) {
+	logger.Warn("Download verification failed",
+		zap.String("path", actualPath),
+		zap.Strings("issues", report.Issues),
+	)
+
+	repairedPath, remuxErr := ffmpeg.Remux(ctx, actualPath)
+	if remuxErr != nil {
+		logger.Warn("Remux repair attempt failed", zap.String("path", actualPath), zap.Error(remuxErr))
+	} else if repairedInfo, infoErr := ffmpeg.GetMediaInfo(ctx, repairedPath); infoErr != nil {
+		os.Remove(repairedPath)
+	} else if remaining := mediaIssues(repairedInfo, expected, extractAudio, repairedPath); len(remaining) == 0 {
+		report.Passed = true
+		report.Repaired = true
+		report.RepairedPath = repairedPath
+		logger.Info("Remuxed download to repair verification failure",
+			zap.String("original", actualPath),
+			zap.String("repaired", repairedPath),
+		)
+		return report, nil
+	} else {
+		logger.Warn("Remux repair did not resolve verification issues",
+			zap.String("path", repairedPath),
+			zap.Strings("issues", remaining),
+		)
+		os.Remove(repairedPath)
+	}
+
+	if mode == VerifyStrict {
+		return report, fmt.Errorf("download verification failed: %s", strings.Join(report.Issues, "; "))
+	}
+	return report, nil
+}
+
+// resolveDownloadedFile locates the file yt-dlp actually produced from an
+// -o template of the form "<stem>.%(ext)s" - yt-dlp replaces %(ext)s with
+// the real extension, and merges/remuxes can change it from what was
+// requested. Mirrors ExtractionHandler.findDownloadedFile's glob-and-pick-
+// largest strategy without needing the job ID, since the stem is already
+// the template's basename up to its first dot.
+func resolveDownloadedFile(outputTemplate string) string {
+	dir := filepath.Dir(outputTemplate)
+	stem := strings.SplitN(filepath.Base(outputTemplate), ".", 2)[0]
+
+	matches, err := filepath.Glob(filepath.Join(dir, stem+".*"))
+	if err != nil || len(matches) == 0 {
+		return ""
+	}
+
+	var bestPath string
+	var bestSize int64 = -1
+	for _, candidate := range matches {
+		name := strings.ToLower(filepath.Base(candidate))
+		if strings.HasSuffix(name, ".part") || strings.HasSuffix(name, ".ytdl") {
+			continue
+		}
+		switch filepath.Ext(name) {
+		case ".json", ".srt", ".vtt", ".ass", ".lrc":
+			continue
+		}
+
+		info, statErr := os.Stat(candidate)
+		if statErr != nil || info.IsDir() {
+			continue
+		}
+		if info.Size() > bestSize {
+			bestSize = info.Size()
+			bestPath = candidate
+		}
+	}
+
+	return bestPath
+}
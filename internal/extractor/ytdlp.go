@@ -5,7 +5,9 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -18,41 +20,73 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/KeremKalyoncu/MedYan/internal/circuitbreaker"
+	"github.com/KeremKalyoncu/MedYan/internal/ippool"
+	"github.com/KeremKalyoncu/MedYan/internal/metrics"
 	"github.com/KeremKalyoncu/MedYan/internal/retry"
 	"github.com/KeremKalyoncu/MedYan/internal/types"
 )
 
 // YtDlp wraps yt-dlp for media extraction with resilience patterns
 type YtDlp struct {
-	binaryPath     string
-	timeout        time.Duration
-	logger         *zap.Logger
-	circuitBreaker *circuitbreaker.CircuitBreaker
-	retryConfig    retry.Config
+	binaryPath  string
+	timeout     time.Duration
+	logger      *zap.Logger
+	breakers    *circuitbreaker.Registry
+	retryConfig retry.Config
+	registry    *ProcessRegistry
+	ippool      *ippool.Pool
+	ffmpeg      *FFmpeg
+}
+
+// SetIPPool wires an egress pool so ExtractMetadata and Download rotate
+// through its source IPs/proxies on each retry attempt when the caller
+// didn't already pin an explicit proxy or source address. A nil pool (the
+// default) disables rotation entirely.
+func (y *YtDlp) SetIPPool(pool *ippool.Pool) {
+	y.ippool = pool
+}
+
+// SetFFmpeg wires an FFmpeg wrapper so Download can run its post-download
+// verification pass (see DownloadOptions.Verify) via ffprobe, and repair a
+// muxing failure with a stream-copy remux. Optional - a nil ffmpeg (the
+// default) skips verification even when Verify is set.
+func (y *YtDlp) SetFFmpeg(ffmpeg *FFmpeg) {
+	y.ffmpeg = ffmpeg
+}
+
+// SetRegistry wires a ProcessRegistry so downloads are tracked for the idle
+// watchdog and the admin cancel endpoint. Optional - a nil registry (the
+// default) disables tracking entirely.
+func (y *YtDlp) SetRegistry(registry *ProcessRegistry) {
+	y.registry = registry
+}
+
+// Timeout reports the per-invocation timeout this wrapper was constructed
+// with, so callers (e.g. DetectionHandler) can warn that a live recording
+// will be cut off at this duration rather than running indefinitely.
+func (y *YtDlp) Timeout() time.Duration {
+	return y.timeout
 }
 
 // NewYtDlp creates a new yt-dlp wrapper with circuit breaker and retry logic
 func NewYtDlp(binaryPath string, timeout time.Duration, logger *zap.Logger) *YtDlp {
-	// Circuit breaker configuration
-	cbConfig := circuitbreaker.Config{
-		MaxRequests: 3,
-		Interval:    60 * time.Second,
-		Timeout:     30 * time.Second,
-		ReadyToTrip: func(counts circuitbreaker.Counts) bool {
-			// Trip if 5+ consecutive failures OR 60%+ failure rate with 10+ requests
-			return counts.ConsecutiveFailures >= 5 ||
-				(counts.Requests >= 10 && float64(counts.TotalFailures)/float64(counts.Requests) >= 0.6)
-		},
-		OnStateChange: func(name string, from circuitbreaker.State, to circuitbreaker.State) {
-			logger.Warn("Circuit breaker state changed",
-				zap.String("name", name),
-				zap.String("from", from.String()),
-				zap.String("to", to.String()),
-			)
-		},
+	// One breaker per platform (see detectPlatform) rather than one for the
+	// whole client - YouTube throttling shouldn't trip downloads from a
+	// platform that's perfectly healthy.
+	breakerConfig := circuitbreaker.DefaultBreakerConfig()
+	breakerConfig.MinRequests = 5
+	breakerConfig.ErrorRateThreshold = 0.6
+	// The pool running out of healthy egresses is a local resource problem,
+	// not evidence that the platform itself is unhealthy - don't let it
+	// trip the breaker and start rejecting requests that might otherwise
+	// succeed without a proxy at all.
+	breakerConfig.IsFailure = func(err error) bool {
+		if errors.Is(err, ippool.ErrNoProxyAvailable) || errors.Is(err, ErrLiveStream) {
+			return false
+		}
+		return circuitbreaker.DefaultIsFailure(err)
 	}
-
-	cb := circuitbreaker.NewCircuitBreaker("yt-dlp", cbConfig)
+	breakers := circuitbreaker.NewRegistry(breakerConfig, logger)
 
 	// Retry configuration with exponential backoff
 	retryConfig := retry.Config{
@@ -72,14 +106,22 @@ func NewYtDlp(binaryPath string, timeout time.Duration, logger *zap.Logger) *YtD
 	}
 
 	return &YtDlp{
-		binaryPath:     binaryPath,
-		timeout:        timeout,
-		logger:         logger,
-		circuitBreaker: cb,
-		retryConfig:    retryConfig,
+		binaryPath:  binaryPath,
+		timeout:     timeout,
+		logger:      logger,
+		breakers:    breakers,
+		retryConfig: retryConfig,
 	}
 }
 
+// retryConfigFor returns y.retryConfig with Breaker set to url's platform
+// breaker, so a tripped breaker for one platform doesn't affect another.
+func (y *YtDlp) retryConfigFor(url string) retry.Config {
+	cfg := y.retryConfig
+	cfg.Breaker = y.breakers.GetFor(detectPlatform(url), "ytdlp")
+	return cfg
+}
+
 // isRetryableError determines if error should trigger retry
 func isRetryableError(err error) bool {
 	if err == nil {
@@ -142,44 +184,119 @@ func isRetryableError(err error) bool {
 	return true
 }
 
+// acquireEgress leases a rotating source IP or proxy from y.ippool for one
+// retry attempt, when a pool is configured and the caller didn't already
+// pin an explicit proxy or source address - ExtractMetadataWithEgress and
+// an explicit DownloadOptions.ProxyURL/SourceIP always bypass the pool. It
+// returns a nil lease (not an error) when rotation doesn't apply.
+func (y *YtDlp) acquireEgress(ctx context.Context, url, sourceIP, proxyURL string) (*ippool.Lease, error) {
+	if y.ippool == nil || sourceIP != "" || proxyURL != "" {
+		return nil, nil
+	}
+	lease, err := y.ippool.Acquire(ctx, detectPlatform(url))
+	if err != nil {
+		return nil, err
+	}
+	metrics.GetMetrics().RecordIPPoolLease()
+	return lease, nil
+}
+
+// releaseEgress releases lease (a no-op if lease is nil), classifying
+// attemptErr to decide whether the egress should cool down.
+func releaseEgress(lease *ippool.Lease, attemptErr error) {
+	if lease == nil {
+		return
+	}
+	outcome := ippool.OutcomeOK
+	if attemptErr != nil {
+		outcome = ippool.ClassifyOutcome(attemptErr.Error())
+		if outcome == ippool.OutcomeThrottled {
+			metrics.GetMetrics().RecordIPPoolCooldown()
+		}
+	}
+	lease.Release(outcome)
+}
+
 // ExtractMetadata extracts metadata from a URL without downloading
 // Uses circuit breaker and retry logic for resilience
 func (y *YtDlp) ExtractMetadata(ctx context.Context, url string) (*types.MediaMetadata, error) {
+	return y.extractMetadata(ctx, url, "", "", "")
+}
+
+// ExtractMetadataWithAuth extracts metadata for url like ExtractMetadata,
+// additionally passing cookiesFile and/or proxyURL through to yt-dlp when
+// set. Used by PlatformExtractor's Instagram fallback once a plain
+// ExtractMetadata attempt hits a login-required or rate-limit wall.
+func (y *YtDlp) ExtractMetadataWithAuth(ctx context.Context, url, cookiesFile, proxyURL string) (*types.MediaMetadata, error) {
+	return y.extractMetadata(ctx, url, cookiesFile, "", proxyURL)
+}
+
+// ExtractMetadataWithEgress is like ExtractMetadataWithAuth, but additionally
+// accepts a sourceIP (from an internal/ippool.Lease) applied via
+// --source-address. sourceIP and proxyURL are mutually exclusive, matching
+// what a single ippool.Lease ever hands out.
+func (y *YtDlp) ExtractMetadataWithEgress(ctx context.Context, url, cookiesFile, sourceIP, proxyURL string) (*types.MediaMetadata, error) {
+	return y.extractMetadata(ctx, url, cookiesFile, sourceIP, proxyURL)
+}
+
+func (y *YtDlp) extractMetadata(ctx context.Context, url, cookiesFile, sourceIP, proxyURL string) (*types.MediaMetadata, error) {
 	var metadata *types.MediaMetadata
 
-	// Wrap with circuit breaker and retry logic
-	err := y.circuitBreaker.Execute(ctx, func() error {
-		return retry.Retry(ctx, y.retryConfig, func() error {
-			args := []string{
-				"--no-playlist", // Single video only
-				"--no-warnings",
-				"--skip-download", // Metadata only
-				"--print-json",    // One JSON object per item
-			}
+	start := time.Now()
+	defer func() {
+		metrics.GetMetrics().RecordYtdlpExec(detectPlatform(url), time.Since(start))
+	}()
 
-			// Add YouTube-specific headers to bypass bot detection
-			if strings.Contains(url, "youtube.com") || strings.Contains(url, "youtu.be") {
-				args = append(args,
-					"--user-agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36",
-					"--extractor-args", "youtube:player_client=android_vr,web",
-				)
-			}
+	// Wrap with per-platform circuit breaker and retry logic
+	err := retry.Retry(ctx, y.retryConfigFor(url), func() error {
+		lease, leaseErr := y.acquireEgress(ctx, url, sourceIP, proxyURL)
+		if leaseErr != nil {
+			return leaseErr
+		}
+		attemptSourceIP, attemptProxyURL := sourceIP, proxyURL
+		if lease != nil {
+			attemptSourceIP, attemptProxyURL = lease.Egress.SourceIP, lease.Egress.ProxyURL
+		}
 
-			args = append(args, url)
+		args := []string{
+			"--no-playlist", // Single video only
+			"--no-warnings",
+			"--skip-download", // Metadata only
+			"--print-json",    // One JSON object per item
+		}
 
-			output, err := y.execute(ctx, args)
-			if err != nil {
-				return fmt.Errorf("failed to extract metadata: %w", err)
-			}
+		// Add YouTube-specific headers to bypass bot detection
+		if strings.Contains(url, "youtube.com") || strings.Contains(url, "youtu.be") {
+			args = append(args,
+				"--user-agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36",
+				"--extractor-args", "youtube:player_client=android_vr,web",
+			)
+		}
 
-			rawData, err := extractJSONObjectFromOutput(output)
-			if err != nil {
-				return fmt.Errorf("failed to parse metadata: %w", err)
-			}
+		if cookiesFile != "" {
+			args = append(args, "--cookies", cookiesFile)
+		}
+		if attemptProxyURL != "" {
+			args = append(args, "--proxy", attemptProxyURL)
+		} else if attemptSourceIP != "" {
+			args = append(args, "--source-address", attemptSourceIP)
+		}
 
-			metadata = y.parseMetadata(rawData)
-			return nil
-		})
+		args = append(args, url)
+
+		output, err := y.execute(ctx, args)
+		releaseEgress(lease, err)
+		if err != nil {
+			return fmt.Errorf("failed to extract metadata: %w", err)
+		}
+
+		rawData, err := extractJSONObjectFromOutput(output)
+		if err != nil {
+			return fmt.Errorf("failed to parse metadata: %w", err)
+		}
+
+		metadata = y.parseMetadata(rawData)
+		return nil
 	})
 
 	if err != nil {
@@ -213,26 +330,76 @@ func extractJSONObjectFromOutput(output string) (map[string]interface{}, error)
 func (y *YtDlp) Download(ctx context.Context, url, outputPath string, opts DownloadOptions) (*types.MediaMetadata, error) {
 	var metadata *types.MediaMetadata
 
-	// Wrap with circuit breaker and retry logic
-	err := y.circuitBreaker.Execute(ctx, func() error {
-		return retry.Retry(ctx, y.retryConfig, func() error {
-			args := y.buildDownloadArgs(url, outputPath, opts)
+	start := time.Now()
+	defer func() {
+		metrics.GetMetrics().RecordYtdlpExec(detectPlatform(url), time.Since(start))
+	}()
 
-			y.logger.Info("Starting download",
-				zap.String("url", url),
-				zap.String("output", outputPath),
-				zap.Strings("args", args),
-			)
+	liveMeta := opts.Metadata
+	if liveMeta == nil {
+		if probed, probeErr := y.ExtractMetadata(ctx, url); probeErr == nil {
+			liveMeta = probed
+		}
+	}
+	if err := checkLiveStatus(detectPlatform(url), liveMeta, opts.LiveMode); err != nil {
+		return nil, err
+	}
 
-			// Execute with progress tracking
-			var err error
-			metadata, err = y.downloadWithProgress(ctx, args, opts.ProgressCallback)
-			if err != nil {
-				return fmt.Errorf("download failed: %w", err)
+	// Wrap with per-platform circuit breaker and retry logic
+	err := retry.Retry(ctx, y.retryConfigFor(url), func() error {
+		lease, leaseErr := y.acquireEgress(ctx, url, opts.SourceIP, opts.ProxyURL)
+		if leaseErr != nil {
+			return leaseErr
+		}
+		attemptOpts := opts
+		if lease != nil {
+			attemptOpts.SourceIP, attemptOpts.ProxyURL = lease.Egress.SourceIP, lease.Egress.ProxyURL
+		}
+
+		args := y.buildDownloadArgs(url, outputPath, attemptOpts)
+		args = appendLiveArgs(args, liveMeta, opts.LiveMode)
+
+		y.logger.Info("Starting download",
+			zap.String("url", url),
+			zap.String("output", outputPath),
+			zap.Strings("args", args),
+		)
+
+		// Execute with progress tracking
+		var err error
+		metadata, err = y.downloadWithProgress(ctx, args, opts.ProgressCallback, opts.TaskID)
+		releaseEgress(lease, err)
+		if err != nil {
+			return fmt.Errorf("download failed: %w", err)
+		}
+
+		if opts.Verify != VerifyOff && y.ffmpeg != nil {
+			report, verifyErr := verifyDownload(ctx, y.ffmpeg, y.logger, outputPath, metadata, opts.ExtractAudio, opts.Verify)
+			if opts.OnVerified != nil {
+				opts.OnVerified(report)
+			}
+			if verifyErr != nil {
+				return verifyErr
 			}
+		}
 
-			return nil
-		})
+		if opts.Thumbnails != nil && !opts.ExtractAudio && y.ffmpeg != nil {
+			// buildDownloadArgs doesn't request --print-json, so the real
+			// download path's own metadata return is typically nil - attach
+			// the preview to liveMeta too, since that's the MediaMetadata a
+			// caller like ExtractionHandler actually holds onto (passed in
+			// as opts.Metadata, or probed above for the live-stream check).
+			if preview := y.generatePreview(ctx, outputPath, *opts.Thumbnails); preview != nil {
+				if liveMeta != nil {
+					liveMeta.Thumbnails = preview
+				}
+				if metadata != nil {
+					metadata.Thumbnails = preview
+				}
+			}
+		}
+
+		return nil
 	})
 
 	if err != nil {
@@ -242,18 +409,135 @@ func (y *YtDlp) Download(ctx context.Context, url, outputPath string, opts Downl
 	return metadata, nil
 }
 
+// generatePreview runs FFmpeg.GenerateThumbnails against outputPath for
+// Download's DownloadOptions.Thumbnails, converting its result to a
+// types.ThumbnailSet. A generation failure is logged and returns nil rather
+// than failing the download - the download itself already succeeded.
+func (y *YtDlp) generatePreview(ctx context.Context, outputPath string, opts ThumbnailOptions) *types.ThumbnailSet {
+	result, err := y.ffmpeg.GenerateThumbnails(ctx, outputPath, opts)
+	if err != nil {
+		y.logger.Warn("Preview generation failed", zap.String("output", outputPath), zap.Error(err))
+		return nil
+	}
+	return &types.ThumbnailSet{
+		SpritePath: result.SpritePath,
+		VTTPath:    result.VTTPath,
+		Files:      result.Files,
+		Cols:       result.Cols,
+		Rows:       result.Rows,
+		TileWidth:  result.TileWidth,
+		TileHeight: result.TileHeight,
+	}
+}
+
+// DownloadToSink is Download for callers with no persistent disk to write
+// to: instead of a local outputPath, it streams yt-dlp's output straight
+// into sink (e.g. a StorageSink uploading to S3), by running yt-dlp with
+// "-o -" and piping its stdout through sink's writer. It returns the
+// location Sink.Finalize reports - FileSink's path, or StorageSink's
+// object key.
+//
+// Verification is skipped here: it needs a local file to run ffprobe
+// against, which is exactly what this path doesn't have.
+func (y *YtDlp) DownloadToSink(ctx context.Context, url string, sink Sink, opts DownloadOptions) (string, *types.MediaMetadata, error) {
+	var location string
+	var metadata *types.MediaMetadata
+
+	start := time.Now()
+	defer func() {
+		metrics.GetMetrics().RecordYtdlpExec(detectPlatform(url), time.Since(start))
+	}()
+
+	err := retry.Retry(ctx, y.retryConfigFor(url), func() error {
+		lease, leaseErr := y.acquireEgress(ctx, url, opts.SourceIP, opts.ProxyURL)
+		if leaseErr != nil {
+			return leaseErr
+		}
+		attemptOpts := opts
+		if lease != nil {
+			attemptOpts.SourceIP, attemptOpts.ProxyURL = lease.Egress.SourceIP, lease.Egress.ProxyURL
+		}
+
+		args := y.buildDownloadArgs(url, "-", attemptOpts)
+
+		y.logger.Info("Starting sink download",
+			zap.String("url", url),
+			zap.Strings("args", args),
+		)
+
+		var err error
+		location, metadata, err = y.downloadToSinkWithProgress(ctx, args, sink, opts.ProgressCallback, opts.TaskID)
+		releaseEgress(lease, err)
+		if err != nil {
+			return fmt.Errorf("download failed: %w", err)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return "", nil, err
+	}
+
+	return location, metadata, nil
+}
+
 // DownloadOptions configures the download behavior
 type DownloadOptions struct {
-	Quality          string
-	Format           string
-	ExtractAudio     bool
-	AudioFormat      string
-	AudioBitrate     string
-	Subtitles        []string
-	CookiesFile      string
-	UserAgent        string
-	ProxyURL         string
-	ProgressCallback func(progress int)
+	Quality      string
+	Format       string
+	ExtractAudio bool
+	AudioFormat  string
+	AudioBitrate string
+	Subtitles    []string
+	CookiesFile  string
+	UserAgent    string
+	ProxyURL     string
+	// SourceIP is a local source address to bind to (from an
+	// internal/ippool.Lease), applied via --source-address. Mutually
+	// exclusive with ProxyURL - ProxyURL wins if both are set.
+	SourceIP         string
+	ProgressCallback func(DownloadProgress)
+	// TaskID, when set, registers this download with the extractor process
+	// registry so the idle watchdog and admin cancel endpoint can see it.
+	TaskID string
+	// Verify controls the post-download ffprobe verification pass (see
+	// VerifyMode). Defaults to VerifyOff. Has no effect unless SetFFmpeg
+	// was called on the YtDlp this is passed to.
+	Verify VerifyMode
+	// OnVerified, when set, is called with the verification outcome after
+	// each download attempt that ran one (Verify != VerifyOff and an
+	// FFmpeg is wired up).
+	OnVerified func(*VerificationReport)
+	// Metadata, when set, is used for Download's live-stream check (see
+	// LiveMode) instead of an extra ExtractMetadata probe - pass the
+	// metadata a caller already fetched (e.g. ExtractionHandler's step 1)
+	// to avoid a redundant yt-dlp invocation.
+	Metadata *types.MediaMetadata
+	// LiveMode controls what Download does with a URL whose metadata (see
+	// Metadata) reports a live or upcoming stream. Defaults to
+	// LiveModeRefuse.
+	LiveMode LiveMode
+	// Thumbnails, when set, makes Download generate a scrub-preview sprite
+	// sheet (or frame set, see ThumbnailOptions.Frames) from the downloaded
+	// file afterward (see FFmpeg.GenerateThumbnails), surfaced on
+	// Metadata.Thumbnails (or the probed metadata's, when Metadata is nil)
+	// and on the returned MediaMetadata, which is usually nil, only when
+	// there is one. Skipped when ExtractAudio is set
+	// or no FFmpeg is wired up via SetFFmpeg. Generation failures are
+	// logged but don't fail the download itself.
+	Thumbnails *ThumbnailOptions
+}
+
+// DownloadProgress is one progress tick parsed from a running yt-dlp
+// download's output, richer than a bare percent so callers (like
+// ExtractionHandler.downloadMedia) can publish byte counts and ETA instead
+// of just a 0-100 number.
+type DownloadProgress struct {
+	Percent    int
+	BytesDone  int64
+	BytesTotal int64
+	ETASeconds int
 }
 
 // buildDownloadArgs constructs yt-dlp command arguments
@@ -331,6 +615,8 @@ func (y *YtDlp) buildDownloadArgs(url, outputPath string, opts DownloadOptions)
 
 	if opts.ProxyURL != "" {
 		args = append(args, "--proxy", opts.ProxyURL)
+	} else if opts.SourceIP != "" {
+		args = append(args, "--source-address", opts.SourceIP)
 	}
 
 	// Platform-specific optimizations
@@ -393,8 +679,68 @@ func (y *YtDlp) buildFormatString(quality, format string) string {
 	return formatStr
 }
 
+// parseYtdlpSize converts a yt-dlp progress line's size value+unit (e.g.
+// "10.00", "MiB") into bytes. Returns 0 for an unrecognized unit rather
+// than erroring, since BytesTotal is best-effort.
+func parseYtdlpSize(value, unit string) int64 {
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0
+	}
+	switch unit {
+	case "KiB":
+		f *= 1024
+	case "MiB":
+		f *= 1024 * 1024
+	case "GiB":
+		f *= 1024 * 1024 * 1024
+	case "B":
+	default:
+		return 0
+	}
+	return int64(f)
+}
+
+// progressRegex parses yt-dlp's --newline download lines, e.g.
+// "[download]  42.1% of   10.00MiB at  1.23MiB/s ETA 00:08". Total size and
+// ETA are optional groups - yt-dlp omits them when it doesn't know the
+// final size yet (e.g. a live stream still being captured).
+var progressRegex = regexp.MustCompile(`\[download\]\s+(\d+(?:\.\d+)?)%(?:\s+of\s+~?\s*([\d.]+)(KiB|MiB|GiB|B))?(?:.*ETA\s+(\d+):(\d+))?`)
+
+// parseProgressLine extracts a DownloadProgress from a yt-dlp stderr line
+// and reports it through callback, if the line matches progressRegex.
+func parseProgressLine(line string, registry *ProcessRegistry, taskID string, callback func(DownloadProgress)) {
+	matches := progressRegex.FindStringSubmatch(line)
+	if len(matches) <= 1 {
+		return
+	}
+	if registry != nil && taskID != "" {
+		registry.Touch(taskID)
+	}
+	percent, err := strconv.ParseFloat(matches[1], 64)
+	if err != nil || callback == nil {
+		return
+	}
+	var bytesTotal int64
+	if matches[2] != "" {
+		bytesTotal = parseYtdlpSize(matches[2], matches[3])
+	}
+	etaSeconds := 0
+	if matches[4] != "" {
+		mins, _ := strconv.Atoi(matches[4])
+		secs, _ := strconv.Atoi(matches[5])
+		etaSeconds = mins*60 + secs
+	}
+	callback(DownloadProgress{
+		Percent:    int(percent),
+		BytesDone:  int64(percent / 100 * float64(bytesTotal)),
+		BytesTotal: bytesTotal,
+		ETASeconds: etaSeconds,
+	})
+}
+
 // downloadWithProgress executes download with real-time progress tracking
-func (y *YtDlp) downloadWithProgress(ctx context.Context, args []string, callback func(int)) (*types.MediaMetadata, error) {
+func (y *YtDlp) downloadWithProgress(ctx context.Context, args []string, callback func(DownloadProgress), taskID string) (*types.MediaMetadata, error) {
 	ctx, cancel := context.WithTimeout(ctx, y.timeout)
 	defer cancel()
 
@@ -414,7 +760,11 @@ func (y *YtDlp) downloadWithProgress(ctx context.Context, args []string, callbac
 		return nil, fmt.Errorf("failed to start yt-dlp: %w", err)
 	}
 
-	progressRegex := regexp.MustCompile(`\[download\]\s+(\d+(?:\.\d+)?)%`)
+	if y.registry != nil && taskID != "" {
+		y.registry.Track(taskID, "yt-dlp", cmd, cancel)
+		defer y.registry.Untrack(taskID)
+	}
+
 	var metadataJSON string
 	var stderrBuf bytes.Buffer
 	var mu sync.Mutex
@@ -427,11 +777,7 @@ func (y *YtDlp) downloadWithProgress(ctx context.Context, args []string, callbac
 
 	parseLine := func(line string, captureJSON bool) {
 		// Progress lines typically go to stderr, but we parse both streams.
-		if matches := progressRegex.FindStringSubmatch(line); len(matches) > 1 {
-			if progress, err := strconv.ParseFloat(matches[1], 64); err == nil && callback != nil {
-				callback(int(progress))
-			}
-		}
+		parseProgressLine(line, y.registry, taskID, callback)
 		if captureJSON {
 			trimmed := strings.TrimSpace(line)
 			if strings.HasPrefix(trimmed, "{") && strings.HasSuffix(trimmed, "}") {
@@ -491,6 +837,92 @@ func (y *YtDlp) downloadWithProgress(ctx context.Context, args []string, callbac
 	return nil, nil
 }
 
+// downloadToSinkWithProgress is downloadWithProgress for a Sink instead of
+// a local file: stdout carries raw media bytes (args must already request
+// "-o -"), so it's piped straight into sink's writer rather than scanned
+// for a JSON line, while stderr is parsed for progress exactly as before.
+func (y *YtDlp) downloadToSinkWithProgress(ctx context.Context, args []string, sink Sink, callback func(DownloadProgress), taskID string) (string, *types.MediaMetadata, error) {
+	ctx, cancel := context.WithTimeout(ctx, y.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, y.binaryPath, args...)
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return "", nil, err
+	}
+
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return "", nil, err
+	}
+
+	writer, err := sink.Open(ctx)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to open sink: %w", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		sink.Abort(ctx)
+		return "", nil, fmt.Errorf("failed to start yt-dlp: %w", err)
+	}
+
+	if y.registry != nil && taskID != "" {
+		y.registry.Track(taskID, "yt-dlp", cmd, cancel)
+		defer y.registry.Untrack(taskID)
+	}
+
+	var stderrBuf bytes.Buffer
+	var mu sync.Mutex
+
+	copyDone := make(chan error, 1)
+	go func() {
+		_, copyErr := io.Copy(writer, stdout)
+		closeErr := writer.Close()
+		if copyErr == nil {
+			copyErr = closeErr
+		}
+		copyDone <- copyErr
+	}()
+
+	go func() {
+		scanner := bufio.NewScanner(stderr)
+		scanner.Buffer(make([]byte, 64*1024), 512*1024)
+		for scanner.Scan() {
+			line := scanner.Text()
+			parseProgressLine(line, y.registry, taskID, callback)
+			mu.Lock()
+			stderrBuf.WriteString(line + "\n")
+			mu.Unlock()
+			y.logger.Debug("yt-dlp stderr", zap.String("line", line))
+		}
+	}()
+
+	waitErr := cmd.Wait()
+	copyErr := <-copyDone
+
+	mu.Lock()
+	errMsg := stderrBuf.String()
+	mu.Unlock()
+
+	if waitErr != nil {
+		sink.Abort(ctx)
+		y.logger.Error("yt-dlp failed", zap.Error(waitErr), zap.String("stderr", errMsg))
+		return "", nil, fmt.Errorf("yt-dlp error: %w - %s", waitErr, errMsg)
+	}
+	if copyErr != nil {
+		sink.Abort(ctx)
+		return "", nil, fmt.Errorf("sink write failed: %w - %s", copyErr, errMsg)
+	}
+
+	location, err := sink.Finalize(ctx)
+	if err != nil {
+		return "", nil, fmt.Errorf("sink finalize failed: %w", err)
+	}
+
+	return location, nil, nil
+}
+
 // execute runs yt-dlp and returns stdout
 func (y *YtDlp) execute(ctx context.Context, args []string) (string, error) {
 	ctx, cancel := context.WithTimeout(ctx, y.timeout)
@@ -505,6 +937,35 @@ func (y *YtDlp) execute(ctx context.Context, args []string) (string, error) {
 	return string(output), nil
 }
 
+// parseLiveStatus maps yt-dlp's live_status field ("is_live", "is_upcoming",
+// "was_live", "post_live", "not_live") to our LiveStatus enum, falling back
+// to the older is_live/was_live booleans when live_status is absent.
+func parseLiveStatus(data map[string]interface{}) types.LiveStatus {
+	if raw, ok := data["live_status"].(string); ok {
+		switch raw {
+		case "is_upcoming":
+			return types.LiveStatusUpcoming
+		case "is_live":
+			return types.LiveStatusLive
+		case "post_live":
+			return types.LiveStatusPostLiveDVR
+		case "was_live":
+			return types.LiveStatusWasLive
+		case "not_live":
+			return types.LiveStatusNone
+		}
+	}
+
+	if isLive, ok := data["is_live"].(bool); ok && isLive {
+		return types.LiveStatusLive
+	}
+	if wasLive, ok := data["was_live"].(bool); ok && wasLive {
+		return types.LiveStatusWasLive
+	}
+
+	return types.LiveStatusNone
+}
+
 // parseMetadata converts yt-dlp JSON to MediaMetadata struct
 func (y *YtDlp) parseMetadata(data map[string]interface{}) *types.MediaMetadata {
 	metadata := &types.MediaMetadata{}
@@ -565,6 +1026,12 @@ func (y *YtDlp) parseMetadata(data map[string]interface{}) *types.MediaMetadata
 		metadata.AudioCodec = acodec
 	}
 
+	metadata.LiveStatus = parseLiveStatus(data)
+
+	if releaseTimestamp, ok := data["release_timestamp"].(float64); ok {
+		metadata.ReleaseTimestamp = int64(releaseTimestamp)
+	}
+
 	// Parse formats array from yt-dlp metadata
 	if formatsRaw, ok := data["formats"].([]interface{}); ok {
 		metadata.Formats = make([]types.FormatEntry, 0, len(formatsRaw))
@@ -602,6 +1069,12 @@ func (y *YtDlp) parseMetadata(data map[string]interface{}) *types.MediaMetadata
 				if acodec, ok := formatMap["acodec"].(string); ok {
 					format.AudioCodec = acodec
 				}
+				if url, ok := formatMap["url"].(string); ok {
+					format.URL = url
+				}
+				if protocol, ok := formatMap["protocol"].(string); ok {
+					format.Protocol = protocol
+				}
 
 				// Build quality label from height if not present
 				if format.Quality == "" && format.Height > 0 {
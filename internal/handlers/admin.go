@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"github.com/KeremKalyoncu/MedYan/internal/extractor"
+)
+
+// AdminHandler exposes operational visibility into in-flight extractor
+// subprocesses (yt-dlp, ffmpeg), backed by the same ProcessRegistry the idle
+// watchdog scans.
+type AdminHandler struct {
+	registry *extractor.ProcessRegistry
+	logger   *zap.Logger
+}
+
+// NewAdminHandler creates a new admin handler.
+func NewAdminHandler(registry *extractor.ProcessRegistry, logger *zap.Logger) *AdminHandler {
+	return &AdminHandler{
+		registry: registry,
+		logger:   logger,
+	}
+}
+
+// ListProcesses returns every currently tracked extractor process.
+// GET /admin/processes
+func (h *AdminHandler) ListProcesses(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"processes": h.registry.List(),
+	})
+}
+
+// CancelProcess forcibly kills a single in-flight extraction by task ID.
+// POST /admin/processes/:task_id/cancel
+func (h *AdminHandler) CancelProcess(c *fiber.Ctx) error {
+	taskID := c.Params("task_id")
+
+	if !h.registry.Cancel(taskID) {
+		return c.Status(404).JSON(fiber.Map{"error": "No in-flight process for task_id"})
+	}
+
+	h.logger.Info("Admin canceled extractor process", zap.String("task_id", taskID))
+
+	return c.JSON(fiber.Map{"message": "Process canceled", "task_id": taskID})
+}
@@ -2,20 +2,30 @@ package handlers
 
 import (
 	"context"
+	"fmt"
 	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"go.uber.org/zap"
 
+	"github.com/KeremKalyoncu/MedYan/internal/credentials"
 	"github.com/KeremKalyoncu/MedYan/internal/extractor"
+	"github.com/KeremKalyoncu/MedYan/internal/ippool"
 	"github.com/KeremKalyoncu/MedYan/internal/types"
 )
 
 // DetectionHandler handles smart URL detection and analysis
 type DetectionHandler struct {
-	ytdlp  *extractor.YtDlp
-	logger *zap.Logger
+	ytdlp           *extractor.YtDlp
+	logger          *zap.Logger
+	credentialStore credentials.Store
+	// fallback, when set, replaces ytdlp as DetectURL's metadata source -
+	// normally an extractor.FallbackExtractor wrapping ytdlp itself.
+	fallback extractor.Extractor
+	// native, when set, backs ListFormats so it can answer without
+	// invoking yt-dlp at all.
+	native *extractor.NativeYouTube
 }
 
 // NewDetectionHandler creates a new detection handler
@@ -26,6 +36,64 @@ func NewDetectionHandler(ytdlp *extractor.YtDlp, logger *zap.Logger) *DetectionH
 	}
 }
 
+// SetIPPool wires an egress pool so DetectURL's metadata probe's yt-dlp
+// client rotates through its source IPs/proxies instead of always going
+// out the host's default route. Optional - a nil pool (the default) leaves
+// yt-dlp using its default egress.
+func (h *DetectionHandler) SetIPPool(pool *ippool.Pool) {
+	h.ytdlp.SetIPPool(pool)
+}
+
+// SetCredentialStore wires a credentials.Store so DetectURL can tell
+// clients whether a RequiresAuth platform needs a cookie jar uploaded
+// (cookies_required) or re-uploaded (cookies_expired) instead of just
+// surfacing yt-dlp's raw error. Optional - a nil store (the default) skips
+// this and behaves as before it was introduced.
+func (h *DetectionHandler) SetCredentialStore(store credentials.Store) {
+	h.credentialStore = store
+}
+
+// SetFallback wires a fallback extractor (e.g. an extractor.FallbackExtractor
+// wrapping this handler's YtDlp and an extractor.NativeYouTube) that
+// DetectURL's metadata probe goes through instead of ytdlp directly.
+// Optional - without it, this handler talks to ytdlp exactly as before.
+func (h *DetectionHandler) SetFallback(fallback extractor.Extractor) {
+	h.fallback = fallback
+}
+
+// SetNativeYouTube wires a NativeYouTube extractor for ListFormats to use.
+// Optional - without it, ListFormats returns 503.
+func (h *DetectionHandler) SetNativeYouTube(native *extractor.NativeYouTube) {
+	h.native = native
+}
+
+// metadataSource returns the fallback extractor set via SetFallback, or
+// ytdlp itself when none was set.
+func (h *DetectionHandler) metadataSource() extractor.Extractor {
+	if h.fallback != nil {
+		return h.fallback
+	}
+	return h.ytdlp
+}
+
+// authStatus reports whether platformInfo needs a cookie jar uploaded or
+// re-uploaded, or "" if authentication isn't a concern (RequiresAuth is
+// false, or a usable jar is already on file).
+func (h *DetectionHandler) authStatus(ctx context.Context, platform string, requiresAuth bool) string {
+	if !requiresAuth || h.credentialStore == nil {
+		return ""
+	}
+
+	cred, err := h.credentialStore.Get(ctx, platform)
+	if err != nil {
+		return "cookies_required"
+	}
+	if cred.Stale {
+		return "cookies_expired"
+	}
+	return ""
+}
+
 // PlatformInfo holds platform-specific information
 type PlatformInfo struct {
 	Platform           string   `json:"platform"`
@@ -47,6 +115,25 @@ type VideoInfo struct {
 	Thumbnail         string       `json:"thumbnail,omitempty"`
 	AvailableFormats  []FormatInfo `json:"available_formats"`
 	RecommendedFormat *FormatInfo  `json:"recommended_format"`
+	// AuthStatus is "cookies_required" or "cookies_expired" for a
+	// RequiresAuth platform lacking a usable cookie jar, or "" otherwise.
+	AuthStatus string `json:"auth_status,omitempty"`
+
+	// LiveStatus is "" (a completed VOD) or one of types.LiveStatus's
+	// values for a livestream-backed URL.
+	LiveStatus types.LiveStatus `json:"live_status,omitempty"`
+	// SuggestedDurationCapSeconds is set for a LiveStatusLive URL: the
+	// recording will be cut off around this many seconds in, since
+	// extraction runs under a single yt-dlp invocation bounded by
+	// Extractor.YtdlpTimeout.
+	SuggestedDurationCapSeconds int `json:"suggested_duration_cap_seconds,omitempty"`
+	// StillProcessing is set for a LiveStatusPostLiveDVR URL whose
+	// recording yt-dlp reports is not yet fully assembled (duration
+	// unavailable), so a retry shortly after may return a longer file.
+	StillProcessing bool `json:"still_processing,omitempty"`
+	// Warning surfaces a non-fatal caveat about the returned info, e.g.
+	// that a live recording will be capped by the extractor's timeout.
+	Warning string `json:"warning,omitempty"`
 }
 
 // FormatInfo holds format details
@@ -62,6 +149,11 @@ type FormatInfo struct {
 	Codec      string `json:"codec,omitempty"`
 	HasAudio   bool   `json:"has_audio"`
 	HasVideo   bool   `json:"has_video"`
+	// URL is the format's direct media or manifest URL, populated for
+	// HLS/DASH live formats so clients can consume the stream directly.
+	URL string `json:"url,omitempty"`
+	// Protocol is yt-dlp's format protocol, e.g. "m3u8"/"http_dash_segments".
+	Protocol string `json:"protocol,omitempty"`
 }
 
 // DetectURL analyzes URL and returns platform info + available options
@@ -91,7 +183,19 @@ func (h *DetectionHandler) DetectURL(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 
-	metadata, err := h.ytdlp.ExtractMetadata(ctx, req.URL)
+	metadata, err := h.metadataSource().ExtractMetadata(ctx, req.URL)
+	authStatus := h.authStatus(ctx, platform, platformInfo.RequiresAuth)
+
+	if err == nil && metadata.LiveStatus == types.LiveStatusUpcoming {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error":           "Stream has not started yet",
+			"live_status":     metadata.LiveStatus,
+			"scheduled_start": metadata.ReleaseTimestamp,
+			"url":             req.URL,
+			"platform":        platformInfo,
+		})
+	}
+
 	if err != nil {
 		h.logger.Warn("Failed to extract full metadata, returning basic platform info",
 			zap.Error(err),
@@ -100,19 +204,61 @@ func (h *DetectionHandler) DetectURL(c *fiber.Ctx) error {
 
 		// Return basic platform info without metadata
 		return c.JSON(fiber.Map{
-			"url":      req.URL,
-			"platform": platformInfo,
-			"error":    err.Error(),
-			"message":  "Could not fetch video details, but platform detected",
+			"url":         req.URL,
+			"platform":    platformInfo,
+			"error":       err.Error(),
+			"message":     "Could not fetch video details, but platform detected",
+			"auth_status": authStatus,
 		})
 	}
 
 	// Build video info with metadata
 	videoInfo := h.buildVideoInfo(req.URL, metadata, platformInfo)
+	videoInfo.AuthStatus = authStatus
 
 	return c.JSON(videoInfo)
 }
 
+// ListFormats returns every format github.com/kkdai/youtube/v2 reports for
+// a YouTube URL's query param "url" - itag, bitrate, codec, and direct
+// stream URL - without invoking yt-dlp at all.
+func (h *DetectionHandler) ListFormats(c *fiber.Ctx) error {
+	url := c.Query("url")
+	if url == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "url query parameter is required",
+		})
+	}
+
+	if detectPlatformFromURL(url) != string(types.PlatformYouTube) {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "only YouTube URLs are supported",
+		})
+	}
+
+	if h.native == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error": "native YouTube extractor is not configured",
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	metadata, err := h.native.ExtractMetadata(ctx, url)
+	if err != nil {
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"url":     url,
+		"title":   metadata.Title,
+		"formats": metadata.Formats,
+	})
+}
+
 // buildVideoInfo constructs detailed video information
 func (h *DetectionHandler) buildVideoInfo(url string, metadata *types.MediaMetadata, platformInfo PlatformInfo) *VideoInfo {
 	info := &VideoInfo{
@@ -122,6 +268,21 @@ func (h *DetectionHandler) buildVideoInfo(url string, metadata *types.MediaMetad
 		Platform:    platformInfo,
 		Duration:    metadata.Duration,
 		Thumbnail:   metadata.Thumbnail,
+		LiveStatus:  metadata.LiveStatus,
+	}
+
+	switch metadata.LiveStatus {
+	case types.LiveStatusLive:
+		info.SuggestedDurationCapSeconds = int(h.ytdlp.Timeout().Seconds())
+		info.Warning = fmt.Sprintf(
+			"This is a live stream - recording will be cut off after roughly %d seconds (Extractor.YtdlpTimeout).",
+			info.SuggestedDurationCapSeconds,
+		)
+	case types.LiveStatusPostLiveDVR:
+		if metadata.Duration <= 0 {
+			info.StillProcessing = true
+			info.Warning = "Stream just ended and yt-dlp is still assembling the recording; duration/formats may be incomplete."
+		}
 	}
 
 	// Extract available formats from metadata
@@ -130,7 +291,7 @@ func (h *DetectionHandler) buildVideoInfo(url string, metadata *types.MediaMetad
 
 	// Recommend best format
 	if len(formats) > 0 {
-		info.RecommendedFormat = h.recommendBestFormat(formats)
+		info.RecommendedFormat = h.recommendBestFormat(formats, metadata.LiveStatus)
 	}
 
 	return info
@@ -164,6 +325,8 @@ func (h *DetectionHandler) parseAvailableFormats(metadata *types.MediaMetadata,
 				Codec:      f.Codec,
 				HasAudio:   f.AudioCodec != "",
 				HasVideo:   f.VideoCodec != "",
+				URL:        f.URL,
+				Protocol:   f.Protocol,
 			})
 		}
 	}
@@ -214,8 +377,18 @@ func (h *DetectionHandler) generateStandardFormats(platform PlatformInfo) []Form
 	return formats
 }
 
-// recommendBestFormat selects the best format
-func (h *DetectionHandler) recommendBestFormat(formats []FormatInfo) *FormatInfo {
+// recommendBestFormat selects the best format. VOD (and the zero value,
+// LiveStatusNone) picks the highest-quality mp4 as before; an in-progress
+// live stream instead prefers a low-latency HLS/DASH manifest format, since
+// the highest-quality progressive format either doesn't exist yet or lags
+// further behind the live edge.
+func (h *DetectionHandler) recommendBestFormat(formats []FormatInfo, liveStatus types.LiveStatus) *FormatInfo {
+	if liveStatus == types.LiveStatusLive {
+		if best := recommendLiveFormat(formats); best != nil {
+			return best
+		}
+	}
+
 	var best *FormatInfo
 
 	for i := range formats {
@@ -258,6 +431,39 @@ func (h *DetectionHandler) recommendBestFormat(formats []FormatInfo) *FormatInfo
 	return best
 }
 
+// recommendLiveFormat prefers an HLS manifest format (lowest latency to the
+// live edge) over DASH, and the lowest-height variant among those available
+// so playback starts catching up to the live edge immediately rather than
+// buffering a high-bitrate variant.
+func recommendLiveFormat(formats []FormatInfo) *FormatInfo {
+	var best *FormatInfo
+
+	for i := range formats {
+		format := &formats[i]
+		if format.Protocol == "" || format.URL == "" {
+			continue
+		}
+		if !strings.Contains(format.Protocol, "m3u8") {
+			continue
+		}
+		if best == nil || (format.Height > 0 && format.Height < best.Height) {
+			best = format
+		}
+	}
+	if best != nil {
+		return best
+	}
+
+	for i := range formats {
+		format := &formats[i]
+		if format.URL != "" && strings.Contains(format.Protocol, "dash") {
+			return format
+		}
+	}
+
+	return nil
+}
+
 // detectPlatformFromURL identifies platform from URL
 func detectPlatformFromURL(url string) string {
 	url = strings.ToLower(url)
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"github.com/KeremKalyoncu/MedYan/internal/queue"
+	"github.com/KeremKalyoncu/MedYan/pkg/storage"
+)
+
+// downloadRedemptionTTL bounds how long a redeemed token is remembered, so the
+// one-shot marker doesn't grow unbounded in Redis.
+const downloadRedemptionTTL = 10 * time.Minute
+
+// shortLivedPresignTTL bounds the window a /proxy/d/:token redemption has to
+// actually fetch the object once the token itself has been verified.
+const shortLivedPresignTTL = 60 * time.Second
+
+// DownloadHandler redeems signed download tokens minted for /proxy/download/:id,
+// gating access to the underlying object behind a single-use check. Works
+// against any storage.SignedFileStore backend (S3 or local disk).
+type DownloadHandler struct {
+	storage storage.SignedFileStore
+	queue   *queue.Client
+	logger  *zap.Logger
+}
+
+// NewDownloadHandler creates a new download handler. store may be nil when
+// no signed-download-capable backend is configured, in which case Redeem
+// always 404s.
+func NewDownloadHandler(store storage.SignedFileStore, queueClient *queue.Client, logger *zap.Logger) *DownloadHandler {
+	return &DownloadHandler{
+		storage: store,
+		queue:   queueClient,
+		logger:  logger,
+	}
+}
+
+// Redeem validates a signed download token and serves the object it grants
+// access to, at most once.
+// GET /proxy/d/:token
+func (h *DownloadHandler) Redeem(c *fiber.Ctx) error {
+	if h.storage == nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Download not available"})
+	}
+
+	token := c.Params("token")
+
+	key, err := h.storage.VerifyDownloadToken(token, c.IP())
+	if err != nil {
+		h.logger.Warn("Rejected download token", zap.Error(err))
+		return c.Status(403).JSON(fiber.Map{"error": "Invalid or expired download link"})
+	}
+
+	ctx := context.Background()
+	redemptionKey := "dl:used:" + hashToken(token)
+
+	reserved, err := h.queue.GetRedis().SetNX(ctx, redemptionKey, 1, downloadRedemptionTTL).Result()
+	if err != nil {
+		h.logger.Error("Failed to check download token redemption", zap.Error(err))
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to process download"})
+	}
+	if !reserved {
+		return c.Status(410).JSON(fiber.Map{"error": "Download link already used"})
+	}
+
+	if h.storage.HasInternalEndpoint() {
+		c.Set("Content-Type", "application/octet-stream")
+		c.Set("Accept-Ranges", "bytes")
+
+		if rangeHeader := c.Get("Range"); rangeHeader != "" {
+			if info, err := h.storage.Stat(ctx, key); err == nil {
+				if off, n, ok := parseRange(rangeHeader, info.SizeBytes); ok {
+					body, err := h.storage.GetRange(ctx, key, off, n)
+					if err != nil {
+						h.logger.Error("Failed to stream ranged download", zap.String("key", key), zap.Error(err))
+						return c.Status(500).JSON(fiber.Map{"error": "Failed to stream download"})
+					}
+					defer body.Close()
+
+					c.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", off, off+n-1, info.SizeBytes))
+					c.Status(206)
+					return c.SendStream(body, int(n))
+				}
+			}
+		}
+
+		body, err := h.storage.Get(ctx, key)
+		if err != nil {
+			h.logger.Error("Failed to stream download", zap.String("key", key), zap.Error(err))
+			return c.Status(500).JSON(fiber.Map{"error": "Failed to stream download"})
+		}
+		defer body.Close()
+
+		return c.SendStream(body)
+	}
+
+	presignedURL, err := h.storage.PresignGet(ctx, key, shortLivedPresignTTL)
+	if err != nil {
+		h.logger.Error("Failed to generate short-lived presigned URL", zap.String("key", key), zap.Error(err))
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to generate download link"})
+	}
+
+	return c.Redirect(presignedURL, 302)
+}
+
+// hashToken derives a fixed-size Redis key from an opaque token so the
+// redemption marker doesn't grow unbounded with token length.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// parseRange parses a single-range HTTP Range header (RFC 7233) against an
+// object of the given size, returning the byte offset and length it covers.
+// Multi-range requests and malformed headers report ok=false so the caller
+// can fall back to a full 200 response.
+func parseRange(header string, size int64) (off, n int64, ok bool) {
+	const prefix = "bytes="
+	if !strings.HasPrefix(header, prefix) {
+		return 0, 0, false
+	}
+	spec := strings.TrimPrefix(header, prefix)
+	if strings.Contains(spec, ",") {
+		return 0, 0, false
+	}
+
+	parts := strings.SplitN(spec, "-", 2)
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+	startStr, endStr := parts[0], parts[1]
+
+	if startStr == "" {
+		// Suffix range: the last N bytes of the object.
+		suffixLen, err := strconv.ParseInt(endStr, 10, 64)
+		if err != nil || suffixLen <= 0 {
+			return 0, 0, false
+		}
+		if suffixLen > size {
+			suffixLen = size
+		}
+		return size - suffixLen, suffixLen, true
+	}
+
+	start, err := strconv.ParseInt(startStr, 10, 64)
+	if err != nil || start < 0 || start >= size {
+		return 0, 0, false
+	}
+
+	if endStr == "" {
+		return start, size - start, true
+	}
+
+	end, err := strconv.ParseInt(endStr, 10, 64)
+	if err != nil || end < start {
+		return 0, 0, false
+	}
+	if end >= size {
+		end = size - 1
+	}
+
+	return start, end - start + 1, true
+}
@@ -2,29 +2,66 @@ package handlers
 
 import (
 	"context"
+	goerrors "errors"
 	"fmt"
+	"io"
 	"os"
+	pathpkg "path"
 	"path/filepath"
 	"strings"
 	"time"
 
 	"go.uber.org/zap"
 
+	apperrors "github.com/KeremKalyoncu/MedYan/internal/errors"
 	"github.com/KeremKalyoncu/MedYan/internal/extractor"
+	"github.com/KeremKalyoncu/MedYan/internal/ippool"
 	"github.com/KeremKalyoncu/MedYan/internal/metrics"
+	"github.com/KeremKalyoncu/MedYan/internal/pool"
+	"github.com/KeremKalyoncu/MedYan/internal/puller"
 	"github.com/KeremKalyoncu/MedYan/internal/queue"
 	"github.com/KeremKalyoncu/MedYan/internal/types"
 	"github.com/KeremKalyoncu/MedYan/pkg/storage"
 )
 
+// HLSConfig controls multi-bitrate HLS packaging for jobs whose
+// OutputFormat is types.OutputFormatHLS. Renditions is the ladder used for
+// most jobs; CriticalRenditions is used instead for jobs whose quality
+// maps to the "critical" queue (see queue.QueueForQuality) - a 4K
+// request gets the full 4K->1080p->720p->480p ladder rather than the
+// smaller default one.
+type HLSConfig struct {
+	SegmentSeconds     int
+	Renditions         []extractor.Rendition
+	CriticalRenditions []extractor.Rendition
+}
+
+// DefaultHLSConfig returns the 1080p/720p/480p ladder (4k/1080p/720p/480p
+// for critical-quality jobs) at 6-second segments.
+func DefaultHLSConfig() HLSConfig {
+	return HLSConfig{
+		SegmentSeconds:     6,
+		Renditions:         extractor.RenditionsFromNames([]string{"1080p", "720p", "480p"}),
+		CriticalRenditions: extractor.RenditionsFromNames([]string{"4k", "1080p", "720p", "480p"}),
+	}
+}
+
 // ExtractionHandler handles media extraction jobs
 type ExtractionHandler struct {
-	ytdlp   *extractor.YtDlp
-	ffmpeg  *extractor.FFmpeg
-	storage storage.Storage
-	queue   *queue.Client
-	logger  *zap.Logger
-	tempDir string
+	ytdlp       *extractor.YtDlp
+	ffmpeg      *extractor.FFmpeg
+	storage     storage.Storage
+	queue       *queue.Client
+	logger      *zap.Logger
+	tempDir     string
+	hlsConfig   HLSConfig
+	pullerStore *puller.Store
+	verifyMode  extractor.VerifyMode
+	// fallback, when set, replaces ytdlp as the ExtractMetadata/Download
+	// source - normally an extractor.FallbackExtractor wrapping ytdlp
+	// itself, so SetIPPool/SetFFmpeg/verification keep working exactly as
+	// before and only the failure path changes.
+	fallback extractor.Extractor
 }
 
 // NewExtractionHandler creates a new extraction handler
@@ -36,15 +73,58 @@ func NewExtractionHandler(
 	logger *zap.Logger,
 ) *ExtractionHandler {
 	return &ExtractionHandler{
-		ytdlp:   ytdlp,
-		ffmpeg:  ffmpeg,
-		storage: s3Storage,
-		queue:   queueClient,
-		logger:  logger,
-		tempDir: os.TempDir(),
+		ytdlp:       ytdlp,
+		ffmpeg:      ffmpeg,
+		storage:     s3Storage,
+		queue:       queueClient,
+		logger:      logger,
+		tempDir:     os.TempDir(),
+		hlsConfig:   DefaultHLSConfig(),
+		pullerStore: puller.NewStore(queueClient.GetRedis()),
 	}
 }
 
+// SetHLSConfig overrides the default HLS packaging configuration (segment
+// duration and bitrate ladder). Optional - callers that don't need a custom
+// ladder can leave the constructor's default in place.
+func (h *ExtractionHandler) SetHLSConfig(cfg HLSConfig) {
+	h.hlsConfig = cfg
+}
+
+// SetVerifyMode controls downloadMedia's post-download ffprobe verification
+// pass (see extractor.VerifyMode). Defaults to extractor.VerifyOff, which
+// matches behavior before this was introduced. Has no effect unless the
+// YtDlp this handler was constructed with also has an FFmpeg wired up via
+// extractor.YtDlp.SetFFmpeg.
+func (h *ExtractionHandler) SetVerifyMode(mode extractor.VerifyMode) {
+	h.verifyMode = mode
+}
+
+// SetIPPool wires an egress pool so downloadMedia's yt-dlp client rotates
+// through its source IPs/proxies on each retry attempt instead of always
+// going out the host's default route. Optional - a nil pool (the default)
+// leaves yt-dlp using its default egress.
+func (h *ExtractionHandler) SetIPPool(pool *ippool.Pool) {
+	h.ytdlp.SetIPPool(pool)
+}
+
+// SetFallback wires a fallback extractor (e.g. an extractor.FallbackExtractor
+// wrapping this handler's YtDlp and an extractor.NativeYouTube) that
+// ExtractMetadata/Download calls go through instead of ytdlp directly.
+// Optional - without it, this handler talks to ytdlp exactly as before.
+func (h *ExtractionHandler) SetFallback(fallback extractor.Extractor) {
+	h.fallback = fallback
+}
+
+// extractorSource returns the fallback extractor set via SetFallback, or ytdlp
+// itself when none was set.
+func (h *ExtractionHandler) extractorSource() extractor.Extractor {
+	if h.fallback != nil {
+		return h.fallback
+	}
+	return h.ytdlp
+}
+
 // HandleExtraction processes a media extraction job
 func (h *ExtractionHandler) HandleExtraction(ctx context.Context, job *types.ExtractionJob) error {
 	startTime := time.Now()
@@ -64,11 +144,12 @@ func (h *ExtractionHandler) HandleExtraction(ctx context.Context, job *types.Ext
 	if err := h.queue.UpdateJobStatus(ctx, job.ID, types.StatusProcessing, 10, ""); err != nil {
 		return err
 	}
+	h.queue.PublishProgress(ctx, job.ID, types.StageMetadata, types.StatusProcessing, 10, 0, 0, 0)
 
 	// Step 1: Extract metadata
-	metadata, err := h.ytdlp.ExtractMetadata(ctx, job.Request.URL)
+	metadata, err := h.extractorSource().ExtractMetadata(ctx, job.Request.URL)
 	if err != nil {
-		return h.handleError(ctx, job.ID, fmt.Errorf("metadata extraction failed: %w", err))
+		return h.handleError(ctx, job.ID, platform, fmt.Errorf("metadata extraction failed: %w", err))
 	}
 
 	job.Metadata = metadata
@@ -77,11 +158,16 @@ func (h *ExtractionHandler) HandleExtraction(ctx context.Context, job *types.Ext
 	if err := h.queue.UpdateJobStatus(ctx, job.ID, types.StatusProcessing, 30, ""); err != nil {
 		return err
 	}
+	h.queue.PublishProgress(ctx, job.ID, types.StageDownloading, types.StatusProcessing, 30, 0, 0, 0)
 
 	// Step 2: Download media
 	downloadedFile, err := h.downloadMedia(ctx, job)
 	if err != nil {
-		return h.handleError(ctx, job.ID, fmt.Errorf("download failed: %w", err))
+		var notStarted *extractor.ErrStreamNotStarted
+		if goerrors.As(err, &notStarted) {
+			return h.rescheduleForLiveStart(ctx, job, notStarted.RetryAt)
+		}
+		return h.handleError(ctx, job.ID, platform, fmt.Errorf("download failed: %w", err))
 	}
 	defer storage.CleanupTempFile(downloadedFile, h.logger)
 
@@ -89,40 +175,81 @@ func (h *ExtractionHandler) HandleExtraction(ctx context.Context, job *types.Ext
 	if err := h.queue.UpdateJobStatus(ctx, job.ID, types.StatusProcessing, 70, ""); err != nil {
 		return err
 	}
+	h.queue.PublishProgress(ctx, job.ID, types.StagePostProcessing, types.StatusProcessing, 70, 0, 0, 0)
 
-	// Step 3: Post-process if needed (format conversion, quality adjustment)
-	processedFile := downloadedFile
-	if job.Request.Format != "" || job.Request.Quality != "" {
-		processedFile, err = h.postProcess(ctx, job, downloadedFile)
+	var result *types.ExtractionResult
+
+	if job.Request.OutputFormat == types.OutputFormatHLS {
+		// Step 3/4: segment into a multi-bitrate HLS playlist tree and
+		// upload the whole tree, instead of the single-file path below.
+		result, err = h.packageAndUploadHLS(ctx, job, downloadedFile)
 		if err != nil {
-			return h.handleError(ctx, job.ID, fmt.Errorf("post-processing failed: %w", err))
+			return h.handleError(ctx, job.ID, platform, fmt.Errorf("HLS packaging failed: %w", err))
 		}
-		if processedFile != downloadedFile {
-			defer storage.CleanupTempFile(processedFile, h.logger)
+	} else if job.Request.OutputFormat == types.OutputFormatThumbnails {
+		// Step 3/4: sample the source into a sprite sheet (or frames) and
+		// upload the result set, instead of the downloaded media itself.
+		result, err = h.generateAndUploadThumbnails(ctx, job, downloadedFile)
+		if err != nil {
+			return h.handleError(ctx, job.ID, platform, fmt.Errorf("thumbnail generation failed: %w", err))
 		}
-	}
+	} else if job.Request.Format != "" && !job.Request.ExtractAudio && formatSupportsStreaming(job.Request.Format) {
+		// Step 3/4: pipe ffmpeg's converted output straight into a streaming
+		// multipart upload instead of writing the converted file to disk
+		// first - only safe for containers that don't need to seek back and
+		// rewrite a header once writing finishes (see formatSupportsStreaming).
+		if err := h.queue.UpdateJobStatus(ctx, job.ID, types.StatusProcessing, 85, ""); err != nil {
+			return err
+		}
+		h.queue.PublishProgress(ctx, job.ID, types.StageUploading, types.StatusProcessing, 85, 0, 0, 0)
 
-	// Update progress
-	if err := h.queue.UpdateJobStatus(ctx, job.ID, types.StatusProcessing, 85, ""); err != nil {
-		return err
-	}
+		result, err = h.convertAndUploadStreaming(ctx, job, downloadedFile)
+		if err != nil {
+			return h.handleError(ctx, job.ID, platform, fmt.Errorf("streaming conversion/upload failed: %w", err))
+		}
+	} else {
+		// Step 3: Post-process if needed (format conversion, quality adjustment)
+		processedFile := downloadedFile
+		if job.Request.Format != "" || job.Request.Quality != "" {
+			processedFile, err = h.postProcess(ctx, job, downloadedFile)
+			if err != nil {
+				return h.handleError(ctx, job.ID, platform, fmt.Errorf("post-processing failed: %w", err))
+			}
+			if processedFile != downloadedFile {
+				defer storage.CleanupTempFile(processedFile, h.logger)
+			}
+		}
 
-	// Step 4: Upload to S3
-	result, err := h.uploadResult(ctx, job, processedFile)
-	if err != nil {
-		return h.handleError(ctx, job.ID, fmt.Errorf("upload failed: %w", err))
+		// Update progress
+		if err := h.queue.UpdateJobStatus(ctx, job.ID, types.StatusProcessing, 85, ""); err != nil {
+			return err
+		}
+		h.queue.PublishProgress(ctx, job.ID, types.StageUploading, types.StatusProcessing, 85, 0, 0, 0)
+
+		// Step 4: Upload to S3
+		result, err = h.uploadResult(ctx, job, processedFile)
+		if err != nil {
+			return h.handleError(ctx, job.ID, platform, fmt.Errorf("upload failed: %w", err))
+		}
 	}
 
 	// Step 5: Mark as completed
 	if err := h.queue.UpdateJobResult(ctx, job.ID, result, metadata); err != nil {
 		return err
 	}
+	h.queue.PublishProgress(ctx, job.ID, types.StageCompleted, types.StatusCompleted, 100, 0, 0, 0)
 
 	// Record metrics
 	duration := time.Since(startTime)
 	sizeMB := uint64(result.SizeBytes / (1024 * 1024))
 	metricsInstance.RecordJobSuccess(platform, duration, sizeMB)
 
+	// Feed this job's real wall-clock duration to any DynamicConcurrency
+	// subscribed via queue.Client.ConsumeLatency - the gradient controller
+	// it drives otherwise never sees a sample, since this handler runs in a
+	// separate process (cmd/worker) from whatever owns that controller.
+	h.queue.PublishLatency(ctx, duration)
+
 	h.logger.Info("Extraction completed successfully",
 		zap.String("job_id", job.ID),
 		zap.Duration("duration", duration),
@@ -132,11 +259,41 @@ func (h *ExtractionHandler) HandleExtraction(ctx context.Context, job *types.Ext
 	return nil
 }
 
+// rescheduleForLiveStart persists a fresh copy of job's request to run at
+// retryAt (see extractor.ErrStreamNotStarted) instead of blocking this
+// worker slot until an upcoming stream goes live, then marks the current
+// job scheduled rather than failed - the new job EnqueueExtractionJobAt
+// created is what actually retries the download.
+func (h *ExtractionHandler) rescheduleForLiveStart(ctx context.Context, job *types.ExtractionJob, retryAt time.Time) error {
+	if _, err := h.queue.EnqueueExtractionJobAt(ctx, job.Request, retryAt); err != nil {
+		return h.handleError(ctx, job.ID, h.detectPlatform(job.Request.URL), fmt.Errorf("failed to schedule live-stream retry: %w", err))
+	}
+
+	msg := fmt.Sprintf("stream not live yet, retrying at %s", retryAt.Format(time.RFC3339))
+	if err := h.queue.UpdateJobStatus(ctx, job.ID, types.StatusScheduled, 0, msg); err != nil {
+		h.logger.Warn("Failed to update job status to scheduled", zap.String("job_id", job.ID), zap.Error(err))
+	}
+	h.queue.PublishProgress(ctx, job.ID, types.StageQueued, types.StatusScheduled, 0, 0, 0, 0)
+
+	h.logger.Info("Rescheduled job for live stream start",
+		zap.String("job_id", job.ID),
+		zap.Time("retry_at", retryAt),
+	)
+	return nil
+}
+
+// progressCoalesceInterval bounds how often downloadMedia's ProgressCallback
+// writes to Redis - yt-dlp reports progress ticks far more frequently than
+// any consumer needs to redraw at.
+const progressCoalesceInterval = time.Second
+
 // downloadMedia downloads the media file using yt-dlp
 func (h *ExtractionHandler) downloadMedia(ctx context.Context, job *types.ExtractionJob) (string, error) {
 	// Generate temp file path
 	outputPath := filepath.Join(h.tempDir, fmt.Sprintf("%s.%%(ext)s", job.ID))
 
+	var lastProgressPublish time.Time
+
 	// Prepare download options
 	opts := extractor.DownloadOptions{
 		Quality:      job.Request.Quality,
@@ -147,10 +304,24 @@ func (h *ExtractionHandler) downloadMedia(ctx context.Context, job *types.Extrac
 		Subtitles:    job.Request.Subtitles,
 		UserAgent:    job.Request.UserAgent,
 		ProxyURL:     job.Request.ProxyURL,
-		ProgressCallback: func(progress int) {
-			// Update progress: 30-70% range for download
-			adjustedProgress := 30 + int(float64(progress)*0.4)
+		TaskID:       job.ID,
+		Verify:       h.verifyMode,
+		Metadata:     job.Metadata,
+		LiveMode:     extractor.LiveMode(job.Request.LiveMode),
+		ProgressCallback: func(p extractor.DownloadProgress) {
+			// Update progress: 30-70% range for download. yt-dlp reports
+			// ticks far more often than once a second, so coalesce before
+			// writing to Redis - UpdateJobStatus/PublishProgress on every
+			// tick would otherwise hammer it for no benefit, since no human
+			// or SSE client needs more than one redraw a second anyway.
+			if time.Since(lastProgressPublish) < progressCoalesceInterval {
+				return
+			}
+			lastProgressPublish = time.Now()
+
+			adjustedProgress := 30 + int(float64(p.Percent)*0.4)
 			h.queue.UpdateJobStatus(ctx, job.ID, types.StatusProcessing, adjustedProgress, "")
+			h.queue.PublishProgress(ctx, job.ID, types.StageDownloading, types.StatusProcessing, adjustedProgress, p.BytesDone, p.BytesTotal, p.ETASeconds)
 		},
 	}
 
@@ -164,61 +335,198 @@ func (h *ExtractionHandler) downloadMedia(ctx context.Context, job *types.Extrac
 		defer os.Remove(cookieFile)
 	}
 
+	// If this is a retry of a previously-failed download, yt-dlp resumes
+	// its own partial output by range automatically as long as outputPath
+	// stays the same (it does - it's keyed on job.ID). Surface the
+	// checkpoint in the logs so the bytes-resumed-from isn't a mystery.
+	if job.Resumable {
+		if state, loadErr := h.pullerStore.Load(ctx, job.ID); loadErr == nil && state != nil {
+			h.logger.Info("Resuming download from checkpoint",
+				zap.String("job_id", job.ID),
+				zap.Int64("bytes_written", state.BytesWritten),
+				zap.Int64("expected_size", state.ExpectedSize),
+			)
+		}
+	}
+
+	// Generate a scrub-preview sprite sheet from the downloaded file,
+	// independent of OutputFormat - skipped for audio-only extraction since
+	// there's no video to sample. previewDir is uploaded and cleaned up
+	// below, once Download has populated job.Metadata.Thumbnails.
+	previewDir := filepath.Join(h.tempDir, job.ID+"_preview")
+	if job.Request.Preview != nil && !job.Request.ExtractAudio {
+		spec := *job.Request.Preview
+		opts.Thumbnails = &extractor.ThumbnailOptions{
+			OutputDir: previewDir,
+			Count:     spec.Count,
+			Interval:  spec.Interval,
+			Width:     spec.Width,
+			Cols:      spec.Cols,
+			Rows:      spec.Rows,
+			Frames:    spec.Frames,
+			Smart:     spec.Smart,
+			Keyframes: spec.Keyframes,
+			TaskID:    job.ID,
+			ProgressCallback: func(p extractor.FFmpegProgress) {
+				if time.Since(lastProgressPublish) < progressCoalesceInterval {
+					return
+				}
+				lastProgressPublish = time.Now()
+				h.queue.PublishProgress(ctx, job.ID, types.StagePreview, types.StatusProcessing, 70, p.BytesWritten, 0, p.ETASeconds)
+			},
+		}
+	}
+
 	// Download
-	_, err := h.ytdlp.Download(ctx, job.Request.URL, outputPath, opts)
+	var verification *extractor.VerificationReport
+	opts.OnVerified = func(r *extractor.VerificationReport) { verification = r }
+	_, err := h.extractorSource().Download(ctx, job.Request.URL, outputPath, opts)
 	if err != nil {
+		h.savePullerCheckpoint(ctx, job, outputPath)
 		return "", err
 	}
 
 	// Find the actual downloaded file (yt-dlp replaces %(ext)s)
 	actualFile := h.findDownloadedFile(outputPath, job.ID)
 	if actualFile == "" {
+		h.savePullerCheckpoint(ctx, job, outputPath)
 		return "", fmt.Errorf("downloaded file not found")
 	}
 
+	// Verification repaired a muxing problem by remuxing into a sibling
+	// file - swap it in under actualFile's name so everything downstream
+	// (upload, postProcess) sees the repaired copy.
+	if verification != nil && verification.Repaired {
+		if renameErr := os.Rename(verification.RepairedPath, actualFile); renameErr != nil {
+			h.logger.Warn("Failed to swap in remuxed download",
+				zap.String("job_id", job.ID),
+				zap.Error(renameErr),
+			)
+		} else {
+			h.logger.Info("Swapped in remuxed download after verification repair", zap.String("job_id", job.ID))
+		}
+	}
+
+	// Download succeeded - any earlier checkpoint is stale now.
+	if err := h.pullerStore.Delete(ctx, job.ID); err != nil {
+		h.logger.Warn("Failed to clear puller checkpoint", zap.String("job_id", job.ID), zap.Error(err))
+	}
+
+	if job.Metadata != nil && job.Metadata.Thumbnails != nil {
+		if uploadErr := h.uploadPreview(ctx, job, previewDir); uploadErr != nil {
+			h.logger.Warn("Failed to upload scrub-preview", zap.String("job_id", job.ID), zap.Error(uploadErr))
+			job.Metadata.Thumbnails = nil
+		}
+	}
+	os.RemoveAll(previewDir)
+
 	return actualFile, nil
 }
 
-// postProcess applies additional processing (format conversion, quality adjustment)
-func (h *ExtractionHandler) postProcess(ctx context.Context, job *types.ExtractionJob, inputFile string) (string, error) {
-	// If audio extraction was already done by yt-dlp, skip
-	if job.Request.ExtractAudio {
-		return inputFile, nil
+// uploadPreview uploads every local file job.Metadata.Thumbnails points at
+// (sprite.jpg/%04d.jpg plus sprite.vtt) to storage under a "preview/" key
+// prefix, rewriting the struct's paths from local filesystem paths to
+// those storage keys, mirroring generateAndUploadThumbnails's upload step.
+func (h *ExtractionHandler) uploadPreview(ctx context.Context, job *types.ExtractionJob, previewDir string) error {
+	thumbs := job.Metadata.Thumbnails
+	prefix := storage.GenerateKey(job.ID, "preview")
+
+	uploadOne := func(localPath string) (string, error) {
+		if localPath == "" {
+			return "", nil
+		}
+		rel, err := filepath.Rel(previewDir, localPath)
+		if err != nil {
+			return "", err
+		}
+		key := pathpkg.Join(prefix, filepath.ToSlash(rel))
+		if _, err := h.storage.Upload(ctx, localPath, key); err != nil {
+			return "", fmt.Errorf("failed to upload %s: %w", rel, err)
+		}
+		return key, nil
+	}
+
+	spritePath, err := uploadOne(thumbs.SpritePath)
+	if err != nil {
+		return err
+	}
+	vttPath, err := uploadOne(thumbs.VTTPath)
+	if err != nil {
+		return err
+	}
+
+	files := make([]string, 0, len(thumbs.Files))
+	for _, f := range thumbs.Files {
+		key, err := uploadOne(f)
+		if err != nil {
+			return err
+		}
+		files = append(files, key)
+	}
+
+	thumbs.SpritePath = spritePath
+	thumbs.VTTPath = vttPath
+	thumbs.Files = files
+	return nil
+}
+
+// savePullerCheckpoint records how much of outputPath's partial file yt-dlp
+// had written before the download failed, under job:{id}:puller, so a
+// subsequent /api/jobs/:id/resume has something concrete to report and
+// downloadMedia's next attempt knows what it's resuming from.
+func (h *ExtractionHandler) savePullerCheckpoint(ctx context.Context, job *types.ExtractionJob, outputPath string) {
+	baseDir := filepath.Dir(outputPath)
+	matches, err := filepath.Glob(filepath.Join(baseDir, job.ID+".*part*"))
+	if err != nil || len(matches) == 0 {
+		return
+	}
+
+	var bytesWritten int64
+	for _, m := range matches {
+		if info, statErr := os.Stat(m); statErr == nil {
+			bytesWritten += info.Size()
+		}
+	}
+
+	state := &puller.SharedPullerState{
+		JobID:        job.ID,
+		TempPath:     outputPath,
+		BytesWritten: bytesWritten,
 	}
 
-	// Format conversion if requested
-	if job.Request.Format != "" {
+	if err := h.pullerStore.Save(ctx, state); err != nil {
+		h.logger.Warn("Failed to save puller checkpoint", zap.String("job_id", job.ID), zap.Error(err))
+	}
+}
+
+// postProcess applies additional processing (format conversion, quality
+// adjustment, clip extraction)
+func (h *ExtractionHandler) postProcess(ctx context.Context, job *types.ExtractionJob, inputFile string) (string, error) {
+	currentFile := inputFile
+
+	// Format conversion if requested, unless audio extraction was already
+	// done by yt-dlp.
+	if job.Request.Format != "" && !job.Request.ExtractAudio {
 		h.logger.Info("Converting format",
 			zap.String("job_id", job.ID),
-			zap.String("input", inputFile),
+			zap.String("input", currentFile),
 			zap.String("target_format", job.Request.Format),
 		)
 
-		// Use copy codec if possible (no re-encoding, just remux)
-		// This is 100x faster and uses minimal memory
-		codec := "copy"
-
-		// Only re-encode if format change requires it
-		inputExt := strings.ToLower(filepath.Ext(inputFile))
-		targetExt := strings.ToLower(job.Request.Format)
-		if !strings.HasPrefix(targetExt, ".") {
-			targetExt = "." + targetExt
-		}
-
-		// If extensions are different and codecs are incompatible, re-encode
-		// But use fast, memory-efficient codec
-		if inputExt != targetExt {
-			// Check if we can just remux (container change only)
-			if canRemux(inputExt, targetExt) {
-				codec = "copy" // Just remux, no re-encoding
-			} else {
-				codec = "libx264" // Re-encode only if necessary
+		codec := codecForConversion(currentFile, job.Request.Format)
+
+		var lastProgressPublish time.Time
+		outputFile, err := h.ffmpeg.ConvertFormat(ctx, currentFile, job.Request.Format, codec, "", job.ID, func(p extractor.FFmpegProgress) {
+			if time.Since(lastProgressPublish) < progressCoalesceInterval {
+				return
 			}
-		}
+			lastProgressPublish = time.Now()
 
-		outputFile, err := h.ffmpeg.ConvertFormat(ctx, inputFile, job.Request.Format, codec, "")
+			adjustedProgress := 70 + int(p.PercentComplete*0.15)
+			h.queue.PublishProgress(ctx, job.ID, types.StagePostProcessing, types.StatusProcessing, adjustedProgress, p.BytesWritten, 0, p.ETASeconds)
+		})
 		if err != nil {
-			return "", fmt.Errorf("format conversion failed: %w", err)
+			return "", fmt.Errorf("format conversion failed: %w", wrapFFmpegErr(err))
 		}
 
 		h.logger.Info("Format conversion completed",
@@ -227,13 +535,130 @@ func (h *ExtractionHandler) postProcess(ctx context.Context, job *types.Extracti
 			zap.String("codec", codec),
 		)
 
-		return outputFile, nil
+		currentFile = outputFile
 	}
 
 	// Quality downscaling is handled by yt-dlp during download
 	// (yt-dlp downloads at the requested quality, no post-processing needed)
 
-	return inputFile, nil
+	if job.Request.Clip != nil {
+		clippedFile, err := h.extractClip(ctx, job, currentFile)
+		if err != nil {
+			return "", err
+		}
+		currentFile = clippedFile
+	}
+
+	return currentFile, nil
+}
+
+// extractClip validates job.Request.Clip against the extracted metadata's
+// duration, then runs extractor.FFmpeg.ExtractClip to cut inputFile down to
+// it. Called from postProcess when Clip is set.
+func (h *ExtractionHandler) extractClip(ctx context.Context, job *types.ExtractionJob, inputFile string) (string, error) {
+	spec := *job.Request.Clip
+
+	duration := 0
+	if job.Metadata != nil {
+		duration = job.Metadata.Duration
+	}
+	if err := validateClipSpec(spec, duration); err != nil {
+		return "", err
+	}
+
+	outputFile := filepath.Join(h.tempDir, job.ID+"_clip"+filepath.Ext(inputFile))
+
+	h.logger.Info("Extracting clip",
+		zap.String("job_id", job.ID),
+		zap.String("input", inputFile),
+		zap.String("output", outputFile),
+		zap.Int("ranges", len(spec.Ranges)),
+	)
+
+	// ExtractClip always tries a stream copy first; libx264 is only reached
+	// when a cut point doesn't land on a keyframe and a re-encode is needed.
+	if err := h.ffmpeg.ExtractClip(ctx, inputFile, outputFile, spec, "libx264"); err != nil {
+		return "", fmt.Errorf("clip extraction failed: %w", wrapFFmpegErr(err))
+	}
+
+	return outputFile, nil
+}
+
+// validateClipSpec checks that spec's bound(s) parse, are non-empty ranges,
+// and fit within duration (job.Metadata.Duration, in seconds; 0 means
+// unknown and skips the bounds check), rejecting with ErrInvalidRequest.
+func validateClipSpec(spec types.ClipSpec, duration int) error {
+	ranges := spec.Ranges
+	if len(ranges) == 0 {
+		ranges = []types.ClipSegment{{Start: spec.Start, End: spec.End}}
+	}
+
+	for i, r := range ranges {
+		start, err := extractor.ParseClipSeconds(r.Start)
+		if err != nil {
+			return apperrors.ErrInvalidRequest.WithDetails(fmt.Sprintf("clip range %d: invalid start %q", i, r.Start))
+		}
+		end, err := extractor.ParseClipSeconds(r.End)
+		if err != nil {
+			return apperrors.ErrInvalidRequest.WithDetails(fmt.Sprintf("clip range %d: invalid end %q", i, r.End))
+		}
+		if end <= start {
+			return apperrors.ErrInvalidRequest.WithDetails(fmt.Sprintf("clip range %d: end must be after start", i))
+		}
+		if duration > 0 && end > float64(duration) {
+			return apperrors.ErrInvalidRequest.WithDetails(fmt.Sprintf("clip range %d: end %.2fs exceeds media duration of %ds", i, end, duration))
+		}
+	}
+
+	return nil
+}
+
+// wrapFFmpegErr maps a saturated FFmpegPool's pool.ErrPoolSaturated into
+// apperrors.ErrBusy, so every ffmpeg call site reports the same "server's
+// busy" signal instead of the caller having to know about internal/pool.
+func wrapFFmpegErr(err error) error {
+	if goerrors.Is(err, pool.ErrPoolSaturated) {
+		return apperrors.ErrBusy.WithCause(err)
+	}
+	return err
+}
+
+// codecForConversion picks the video codec ConvertFormat/ConvertFormatStream
+// should use for inputFile -> targetFormat: "copy" (remux, no re-encoding)
+// whenever the container change alone is enough, falling back to libx264
+// only when it isn't.
+func codecForConversion(inputFile, targetFormat string) string {
+	inputExt := strings.ToLower(filepath.Ext(inputFile))
+	targetExt := strings.ToLower(targetFormat)
+	if !strings.HasPrefix(targetExt, ".") {
+		targetExt = "." + targetExt
+	}
+
+	if inputExt == targetExt {
+		return "copy"
+	}
+	if canRemux(inputExt, targetExt) {
+		return "copy"
+	}
+	return "libx264"
+}
+
+// streamableFormats lists output containers whose muxer doesn't need to seek
+// back and rewrite a header once writing finishes (unlike mp4/mov, which
+// need their moov atom either up front or via -movflags faststart/frag),
+// and so can safely be piped to ffmpeg's stdout by ConvertFormatStream.
+var streamableFormats = map[string]bool{
+	"mkv":  true,
+	"webm": true,
+	"ts":   true,
+	"flv":  true,
+}
+
+// formatSupportsStreaming reports whether targetFormat is safe to mux over a
+// pipe (see streamableFormats). Formats outside this allowlist fall back to
+// the file-based postProcess/uploadResult path.
+func formatSupportsStreaming(targetFormat string) bool {
+	return streamableFormats[strings.TrimPrefix(strings.ToLower(targetFormat), ".")]
 }
 
 // canRemux checks if we can remux (container change) without re-encoding
@@ -258,6 +683,224 @@ func canRemux(inputExt, targetExt string) bool {
 	return false
 }
 
+// packageAndUploadHLS segments inputFile into a multi-bitrate HLS bundle
+// and uploads every file PackageHLS produced to Storage under the job's key
+// prefix, returning a result whose DownloadURL points at the uploaded
+// index.m3u8. A "4k" quality job (the same quality queue.QueueForQuality
+// maps to the "critical" queue) gets the fuller CriticalRenditions ladder.
+func (h *ExtractionHandler) packageAndUploadHLS(ctx context.Context, job *types.ExtractionJob, inputFile string) (*types.ExtractionResult, error) {
+	outputDir := filepath.Join(h.tempDir, job.ID+"_hls")
+	defer os.RemoveAll(outputDir)
+
+	renditions := h.hlsConfig.Renditions
+	if job.Request.Quality == "4k" {
+		renditions = h.hlsConfig.CriticalRenditions
+	}
+
+	var lastProgressPublish time.Time
+	result, err := h.ffmpeg.PackageHLS(ctx, inputFile, renditions, extractor.PackageOptions{
+		OutputDir:      outputDir,
+		SegmentSeconds: h.hlsConfig.SegmentSeconds,
+		TaskID:         job.ID,
+		ProgressCallback: func(p extractor.FFmpegProgress) {
+			if time.Since(lastProgressPublish) < progressCoalesceInterval {
+				return
+			}
+			lastProgressPublish = time.Now()
+
+			adjustedProgress := 70 + int(p.PercentComplete*0.15)
+			h.queue.PublishProgress(ctx, job.ID, types.StagePostProcessing, types.StatusProcessing, adjustedProgress, p.BytesWritten, 0, p.ETASeconds)
+		},
+	})
+	if err != nil {
+		return nil, wrapFFmpegErr(err)
+	}
+
+	prefix := storage.GenerateKey(job.ID, "hls")
+
+	var totalSize int64
+	for _, path := range result.Files {
+		rel, err := filepath.Rel(result.Dir, path)
+		if err != nil {
+			return nil, err
+		}
+		key := pathpkg.Join(prefix, filepath.ToSlash(rel))
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := h.storage.Upload(ctx, path, key); err != nil {
+			return nil, fmt.Errorf("failed to upload %s: %w", rel, err)
+		}
+		totalSize += info.Size()
+	}
+
+	masterKey := pathpkg.Join(prefix, "index.m3u8")
+	downloadURL, err := h.storage.GetPresignedURL(ctx, masterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	h.logger.Info("HLS playlist uploaded",
+		zap.String("job_id", job.ID),
+		zap.String("master_key", masterKey),
+		zap.Int64("total_bytes", totalSize),
+	)
+
+	return &types.ExtractionResult{
+		DownloadURL: downloadURL,
+		Filename:    "index.m3u8",
+		SizeBytes:   totalSize,
+		Format:      "hls",
+		ExpiresAt:   time.Now().Add(24 * time.Hour),
+		Key:         masterKey,
+	}, nil
+}
+
+// generateAndUploadThumbnails samples inputFile into a sprite sheet (or,
+// with ThumbnailSpec.Frames set, individual JPEGs) and uploads every
+// produced file under the same "thumbnails/" key prefix, mirroring
+// packageAndUploadHLS's upload-the-whole-tree approach.
+func (h *ExtractionHandler) generateAndUploadThumbnails(ctx context.Context, job *types.ExtractionJob, inputFile string) (*types.ExtractionResult, error) {
+	outputDir := filepath.Join(h.tempDir, job.ID+"_thumbnails")
+	defer os.RemoveAll(outputDir)
+
+	spec := types.ThumbnailSpec{}
+	if job.Request.Thumbnails != nil {
+		spec = *job.Request.Thumbnails
+	}
+
+	result, err := h.ffmpeg.GenerateThumbnails(ctx, inputFile, extractor.ThumbnailOptions{
+		OutputDir: outputDir,
+		Count:     spec.Count,
+		Interval:  spec.Interval,
+		Width:     spec.Width,
+		Cols:      spec.Cols,
+		Rows:      spec.Rows,
+		Frames:    spec.Frames,
+		Smart:     spec.Smart,
+		Keyframes: spec.Keyframes,
+		TaskID:    job.ID,
+	})
+	if err != nil {
+		return nil, wrapFFmpegErr(err)
+	}
+
+	prefix := storage.GenerateKey(job.ID, "thumbnails")
+
+	var totalSize int64
+	for _, path := range result.Files {
+		rel, err := filepath.Rel(result.Dir, path)
+		if err != nil {
+			return nil, err
+		}
+		key := pathpkg.Join(prefix, filepath.ToSlash(rel))
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := h.storage.Upload(ctx, path, key); err != nil {
+			return nil, fmt.Errorf("failed to upload %s: %w", rel, err)
+		}
+		totalSize += info.Size()
+	}
+
+	// Frames mode has no single representative file - fall back to the
+	// directory prefix itself so ExtractionResult.Filename still means
+	// something to a caller listing the upload.
+	filename := "sprite.jpg"
+	downloadKey := pathpkg.Join(prefix, filename)
+	if spec.Frames {
+		filename = "thumbnails/"
+		downloadKey = pathpkg.Join(prefix, "0001.jpg")
+	}
+
+	downloadURL, err := h.storage.GetPresignedURL(ctx, downloadKey)
+	if err != nil {
+		return nil, err
+	}
+
+	h.logger.Info("Thumbnails uploaded",
+		zap.String("job_id", job.ID),
+		zap.String("prefix", prefix),
+		zap.Int("files", len(result.Files)),
+		zap.Int64("total_bytes", totalSize),
+	)
+
+	return &types.ExtractionResult{
+		DownloadURL: downloadURL,
+		Filename:    filename,
+		SizeBytes:   totalSize,
+		Format:      "thumbnails",
+		ExpiresAt:   time.Now().Add(24 * time.Hour),
+		Key:         downloadKey,
+	}, nil
+}
+
+// convertAndUploadStreaming pipes ffmpeg's converted output directly into a
+// streaming multipart upload (storage.Storage.UploadMultipart), so the
+// converted file never lands on local disk - the streaming counterpart to
+// postProcess+uploadResult for the formats formatSupportsStreaming allows.
+func (h *ExtractionHandler) convertAndUploadStreaming(ctx context.Context, job *types.ExtractionJob, inputFile string) (*types.ExtractionResult, error) {
+	codec := codecForConversion(inputFile, job.Request.Format)
+
+	stream, err := h.ffmpeg.ConvertFormatStream(ctx, inputFile, job.Request.Format, codec, job.ID)
+	if err != nil {
+		return nil, fmt.Errorf("format conversion failed: %w", wrapFFmpegErr(err))
+	}
+	defer stream.Close()
+
+	ext := job.Request.Format
+	if !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	filename := job.ID + ext
+	key := storage.GenerateKey(job.ID, filename)
+
+	counted := &countingReader{r: stream}
+	checksum, err := h.storage.UploadMultipart(ctx, key, 0, counted)
+	if err != nil {
+		return nil, fmt.Errorf("streaming upload failed: %w", err)
+	}
+
+	downloadURL, err := h.storage.GetPresignedURL(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	h.logger.Info("Streaming conversion/upload completed",
+		zap.String("job_id", job.ID),
+		zap.String("key", key),
+		zap.Int64("size_bytes", counted.n),
+	)
+
+	return &types.ExtractionResult{
+		DownloadURL:    downloadURL,
+		Filename:       filename,
+		SizeBytes:      counted.n,
+		Format:         ext,
+		ExpiresAt:      time.Now().Add(24 * time.Hour),
+		ChecksumSHA256: checksum,
+		Key:            key,
+	}, nil
+}
+
+// countingReader wraps an io.Reader to tally bytes read, since
+// UploadMultipart's source reader isn't a file uploadResult can os.Stat
+// after the fact.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
 // uploadResult uploads the processed file to S3 and generates presigned URL
 func (h *ExtractionHandler) uploadResult(ctx context.Context, job *types.ExtractionJob, filePath string) (*types.ExtractionResult, error) {
 	// Get file info
@@ -271,7 +914,8 @@ func (h *ExtractionHandler) uploadResult(ctx context.Context, job *types.Extract
 	key := storage.GenerateKey(job.ID, filename)
 
 	// Upload
-	if err := h.storage.Upload(ctx, filePath, key); err != nil {
+	checksum, err := h.storage.Upload(ctx, filePath, key)
+	if err != nil {
 		return nil, err
 	}
 
@@ -282,32 +926,50 @@ func (h *ExtractionHandler) uploadResult(ctx context.Context, job *types.Extract
 	}
 
 	result := &types.ExtractionResult{
-		DownloadURL: downloadURL,
-		Filename:    filename,
-		SizeBytes:   fileInfo.Size(),
-		Format:      filepath.Ext(filename),
-		ExpiresAt:   time.Now().Add(24 * time.Hour),
+		DownloadURL:    downloadURL,
+		Filename:       filename,
+		SizeBytes:      fileInfo.Size(),
+		Format:         filepath.Ext(filename),
+		ExpiresAt:      time.Now().Add(24 * time.Hour),
+		ChecksumSHA256: checksum,
+		Key:            key,
 	}
 
 	return result, nil
 }
 
-// handleError updates job status with error and logs
-func (h *ExtractionHandler) handleError(ctx context.Context, jobID string, err error) error {
+// handleError updates job status with error and logs, classifying err
+// against the known yt-dlp/ffmpeg failure signatures (see
+// apperrors.Classify) so the failure metric and logs show *why* the job
+// died, not just that it did. The actual retry-vs-permanent-fail routing
+// stays with queue.Server's errclass.Policy (the single source of truth
+// Asynq's RetryDelayFunc also reads from) - classified is used here for
+// observability, not to make a second, possibly-conflicting decision.
+func (h *ExtractionHandler) handleError(ctx context.Context, jobID, platform string, err error) error {
+	classified := apperrors.Classify(err, err.Error()).WithPlatform(platform)
+
 	h.logger.Error("Extraction error",
 		zap.String("job_id", jobID),
+		zap.String("platform", platform),
+		zap.String("error_code", classified.Code),
+		zap.Bool("retryable", classified.Retryable),
 		zap.Error(err),
 	)
 
-	// Record failure metric (platform unknown here, could be improved)
 	metricsInstance := metrics.GetMetrics()
-	metricsInstance.RecordJobFailure("unknown")
+	metricsInstance.RecordJobFailure(platform, classified.Code)
 
 	if updateErr := h.queue.UpdateJobStatus(ctx, jobID, types.StatusFailed, 0, err.Error()); updateErr != nil {
 		h.logger.Error("Failed to update job status",
 			zap.Error(updateErr),
 		)
 	}
+	// Mark resumable so POST /api/jobs/:id/resume can re-enqueue this job
+	// under the same ID rather than the caller having to submit fresh.
+	if markErr := h.queue.MarkResumable(ctx, jobID); markErr != nil {
+		h.logger.Warn("Failed to mark job resumable", zap.String("job_id", jobID), zap.Error(markErr))
+	}
+	h.queue.PublishProgress(ctx, jobID, types.StageFailed, types.StatusFailed, 0, 0, 0, 0)
 
 	return err
 }
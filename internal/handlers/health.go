@@ -2,31 +2,122 @@ package handlers
 
 import (
 	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/hibiken/asynq"
 	"go.uber.org/zap"
 
 	"github.com/KeremKalyoncu/MedYan/internal/cache"
+	"github.com/KeremKalyoncu/MedYan/internal/dedup"
 	"github.com/KeremKalyoncu/MedYan/internal/queue"
 )
 
+const (
+	// versionProbeCacheTTL is how long a successful yt-dlp/ffmpeg version
+	// probe is reused before the next request re-execs the binary. A failed
+	// probe is reused for a shorter window so a binary that just came back
+	// doesn't stay marked unhealthy as long as a genuinely healthy one is
+	// cached.
+	versionProbeCacheTTL    = 30 * time.Second
+	versionProbeNegCacheTTL = 5 * time.Second
+	versionProbeTimeout     = 5 * time.Second
+)
+
+// checkResult is the structured shape every DetailedHealth sub-check
+// reports, so operators get a latency and a timestamp to act on instead of
+// a bare "healthy"/"unhealthy" string.
+type checkResult struct {
+	Status    string `json:"status"`
+	LatencyMS int64  `json:"latency_ms"`
+	Detail    string `json:"detail,omitempty"`
+	CheckedAt string `json:"checked_at"`
+}
+
+func newCheckResult(status string, start time.Time, detail string) checkResult {
+	return checkResult{
+		Status:    status,
+		LatencyMS: time.Since(start).Milliseconds(),
+		Detail:    detail,
+		CheckedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+}
+
 // HealthHandler provides health check endpoints
 type HealthHandler struct {
 	queue  *queue.Client
-	cache  *cache.CacheManager
+	cache  *cache.DistributedCache
 	logger *zap.Logger
+
+	ytdlpPath  string
+	ffmpegPath string
+
+	// probes coalesces and caches the yt-dlp/ffmpeg version subprocess
+	// probes, so a burst of /health/detailed requests doesn't fork a
+	// process per request.
+	probes *dedup.Singleflight
+
+	diskPath         string
+	diskMinFreeBytes uint64
+
+	inspector            *asynq.Inspector
+	criticalQueueName    string
+	criticalQueueMaxSize int
+}
+
+// HealthHandlerConfig configures the real-probe checks DetailedHealth runs,
+// beyond the Redis/cache checks it can already derive from queue.Client and
+// cache.DistributedCache.
+type HealthHandlerConfig struct {
+	YtdlpPath  string
+	FFmpegPath string
+
+	// DiskPath is the output/temp directory whose free space is checked.
+	DiskPath string
+	// DiskMinFreeBytes is the threshold below which the disk check reports
+	// unhealthy.
+	DiskMinFreeBytes uint64
+
+	// RedisAddr backs the Asynq inspector used for the queue-depth check.
+	RedisAddr string
+	// CriticalQueueName is the asynq queue the depth check watches.
+	CriticalQueueName string
+	// CriticalQueueMaxSize is the pending-task count above which the
+	// critical queue is considered backed up.
+	CriticalQueueMaxSize int
 }
 
 // NewHealthHandler creates a health handler
-func NewHealthHandler(queueClient *queue.Client, cacheManager *cache.CacheManager, logger *zap.Logger) *HealthHandler {
+func NewHealthHandler(queueClient *queue.Client, distCache *cache.DistributedCache, cfg HealthHandlerConfig, logger *zap.Logger) *HealthHandler {
 	return &HealthHandler{
-		queue:  queueClient,
-		cache:  cacheManager,
-		logger: logger,
+		queue:      queueClient,
+		cache:      distCache,
+		logger:     logger,
+		ytdlpPath:  cfg.YtdlpPath,
+		ffmpegPath: cfg.FFmpegPath,
+		probes:     dedup.NewSingleflight(),
+
+		diskPath:         cfg.DiskPath,
+		diskMinFreeBytes: cfg.DiskMinFreeBytes,
+
+		inspector:            asynq.NewInspector(asynq.RedisClientOpt{Addr: cfg.RedisAddr}),
+		criticalQueueName:    cfg.CriticalQueueName,
+		criticalQueueMaxSize: cfg.CriticalQueueMaxSize,
 	}
 }
 
+// Close releases the handler's own Asynq inspector connection and stops the
+// version-probe cache's cleanup goroutine. It does not touch queue or cache,
+// which the caller owns.
+func (h *HealthHandler) Close() error {
+	h.probes.Close()
+	return h.inspector.Close()
+}
+
 // BasicHealth returns simple healthy status (for load balancers)
 func (h *HealthHandler) BasicHealth(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{
@@ -39,74 +130,137 @@ func (h *HealthHandler) BasicHealth(c *fiber.Ctx) error {
 func (h *HealthHandler) DetailedHealth(c *fiber.Ctx) error {
 	ctx := context.Background()
 
-	health := fiber.Map{
-		"status":    "healthy",
-		"timestamp": time.Now().UTC().Format(time.RFC3339),
-		"version":   "1.0.0",
-		"checks":    fiber.Map{},
+	checks := fiber.Map{
+		"redis_queue": h.checkRedis(ctx),
+		"cache":       h.checkCache(ctx),
+		"ytdlp":       h.checkYtdlp(),
+		"ffmpeg":      h.checkFFmpeg(),
+		"disk":        h.checkDisk(),
+		"queue_depth": h.checkQueueDepth(),
 	}
 
-	checks := health["checks"].(fiber.Map)
 	allHealthy := true
+	for _, v := range checks {
+		if v.(checkResult).Status != "healthy" {
+			allHealthy = false
+			break
+		}
+	}
 
-	// Check Redis Queue
-	queueStatus := "healthy"
-	if _, err := h.queue.GetJobStatus(ctx, "health-check"); err != nil && err.Error() != "job not found: health-check" {
-		queueStatus = "unhealthy"
-		allHealthy = false
-		h.logger.Warn("Redis queue health check failed", zap.Error(err))
+	status := "healthy"
+	statusCode := fiber.StatusOK
+	if !allHealthy {
+		status = "degraded"
+		statusCode = fiber.StatusServiceUnavailable
 	}
-	checks["redis_queue"] = fiber.Map{
-		"status": queueStatus,
+
+	return c.Status(statusCode).JSON(fiber.Map{
+		"status":    status,
+		"timestamp": time.Now().UTC().Format(time.RFC3339),
+		"version":   "1.0.0",
+		"checks":    checks,
+	})
+}
+
+// checkRedis measures a real Redis PING round-trip.
+func (h *HealthHandler) checkRedis(ctx context.Context) checkResult {
+	start := time.Now()
+	if err := h.queue.GetRedis().Ping(ctx).Err(); err != nil {
+		h.logger.Warn("Redis health check failed", zap.Error(err))
+		return newCheckResult("unhealthy", start, err.Error())
 	}
+	return newCheckResult("healthy", start, "")
+}
 
-	// Check Cache
-	cacheStatus := "healthy"
-	testKey := "health:check:" + time.Now().Format("20060102")
-	if err := h.cache.Set(ctx, testKey, "ok", 10*time.Second); err != nil {
-		cacheStatus = "unhealthy"
-		allHealthy = false
-		h.logger.Warn("Cache health check failed", zap.Error(err))
+// checkCache round-trips an INFO call through the distributed cache's Redis
+// connection.
+func (h *HealthHandler) checkCache(ctx context.Context) checkResult {
+	start := time.Now()
+	if h.cache == nil {
+		return newCheckResult("unhealthy", start, "distributed cache not configured")
 	}
-	checks["cache"] = fiber.Map{
-		"status": cacheStatus,
+	if _, err := h.cache.Stats(ctx); err != nil {
+		h.logger.Warn("Cache health check failed", zap.Error(err))
+		return newCheckResult("unhealthy", start, err.Error())
 	}
+	return newCheckResult("healthy", start, "")
+}
 
-	// Check yt-dlp availability
-	ytdlpStatus := "healthy"
-	// You can add actual yt-dlp version check here
-	checks["ytdlp"] = fiber.Map{
-		"status": ytdlpStatus,
+// checkYtdlp probes `yt-dlp --version`, coalesced and cached for
+// versionProbeCacheTTL so concurrent or frequent health checks don't fork a
+// process per request.
+func (h *HealthHandler) checkYtdlp() checkResult {
+	return h.checkBinaryVersion("ytdlp", h.ytdlpPath, "--version")
+}
+
+// checkFFmpeg probes `ffmpeg -version`, same caching as checkYtdlp.
+func (h *HealthHandler) checkFFmpeg() checkResult {
+	return h.checkBinaryVersion("ffmpeg", h.ffmpegPath, "-version")
+}
+
+func (h *HealthHandler) checkBinaryVersion(name, binaryPath, versionFlag string) checkResult {
+	start := time.Now()
+	result := h.probes.DoWithCache(name, versionProbeCacheTTL, versionProbeNegCacheTTL, func() (interface{}, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), versionProbeTimeout)
+		defer cancel()
+
+		output, err := exec.CommandContext(ctx, binaryPath, versionFlag).CombinedOutput()
+		if err != nil {
+			return "", fmt.Errorf("%s %s failed: %w", binaryPath, versionFlag, err)
+		}
+		version := strings.TrimSpace(strings.SplitN(string(output), "\n", 2)[0])
+		return version, nil
+	})
+
+	if result.Err != nil {
+		h.logger.Warn(name+" health check failed", zap.Error(result.Err))
+		return newCheckResult("unhealthy", start, result.Err.Error())
 	}
+	return newCheckResult("healthy", start, result.Val.(string))
+}
+
+// checkDisk reports the free space on diskPath, failing when it drops below
+// diskMinFreeBytes.
+func (h *HealthHandler) checkDisk() checkResult {
+	start := time.Now()
 
-	// Check FFmpeg availability
-	ffmpegStatus := "healthy"
-	// You can add actual FFmpeg version check here
-	checks["ffmpeg"] = fiber.Map{
-		"status": ffmpegStatus,
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(h.diskPath, &stat); err != nil {
+		h.logger.Warn("Disk health check failed", zap.String("path", h.diskPath), zap.Error(err))
+		return newCheckResult("unhealthy", start, err.Error())
 	}
 
-	// Update overall status
-	if !allHealthy {
-		health["status"] = "degraded"
+	freeBytes := stat.Bavail * uint64(stat.Bsize)
+	detail := fmt.Sprintf("%d bytes free on %s", freeBytes, h.diskPath)
+	if freeBytes < h.diskMinFreeBytes {
+		return newCheckResult("unhealthy", start, detail)
 	}
+	return newCheckResult("healthy", start, detail)
+}
 
-	// Set appropriate HTTP status code
-	statusCode := fiber.StatusOK
-	if health["status"] == "degraded" {
-		statusCode = fiber.StatusServiceUnavailable
+// checkQueueDepth degrades to unhealthy once the critical queue backs up
+// past criticalQueueMaxSize pending tasks.
+func (h *HealthHandler) checkQueueDepth() checkResult {
+	start := time.Now()
+
+	info, err := h.inspector.GetQueueInfo(h.criticalQueueName)
+	if err != nil {
+		h.logger.Warn("Queue depth health check failed", zap.String("queue", h.criticalQueueName), zap.Error(err))
+		return newCheckResult("unhealthy", start, err.Error())
 	}
 
-	return c.Status(statusCode).JSON(health)
+	detail := fmt.Sprintf("%d pending, %d active in %q", info.Pending, info.Active, h.criticalQueueName)
+	if info.Pending > h.criticalQueueMaxSize {
+		return newCheckResult("unhealthy", start, detail)
+	}
+	return newCheckResult("healthy", start, detail)
 }
 
 // Readiness returns whether service is ready to accept traffic
 func (h *HealthHandler) Readiness(c *fiber.Ctx) error {
-	// Check if critical services are available
 	ctx := context.Background()
 
-	// Test Redis
-	if _, err := h.queue.GetJobStatus(ctx, "readiness-check"); err != nil && err.Error() != "job not found: readiness-check" {
+	if err := h.queue.GetRedis().Ping(ctx).Err(); err != nil {
 		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
 			"ready":   false,
 			"message": "Redis not available",
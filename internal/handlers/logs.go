@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/KeremKalyoncu/MedYan/pkg/logbuffer"
+)
+
+// followPollTimeout bounds how long GET /debug/logs?follow=1 holds the
+// connection open waiting for new records before returning an empty batch.
+const followPollTimeout = 25 * time.Second
+
+// RegisterLogBufferRoutes registers the /debug/logs endpoints backed by
+// the given ring buffer, alongside the pprof routes.
+func RegisterLogBufferRoutes(app *fiber.App, buf *logbuffer.Buffer) {
+	debug := app.Group("/debug/logs")
+
+	debug.Get("/", logBufferTail(buf))
+	debug.Get("/download", logBufferDownload(buf))
+}
+
+// logBufferTail handles GET /debug/logs?since=<offset>&follow=1, streaming
+// NDJSON records from the given offset. With follow=1 it long-polls for up
+// to followPollTimeout when there's nothing new yet.
+func logBufferTail(buf *logbuffer.Buffer) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		since := int64(0)
+		if s := c.Query("since"); s != "" {
+			parsed, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": "since must be an integer byte offset",
+				})
+			}
+			since = parsed
+		}
+
+		if c.Query("follow") == "1" {
+			ctx, cancel := context.WithTimeout(context.Background(), followPollTimeout)
+			defer cancel()
+			buf.WaitForData(ctx, since)
+		}
+
+		records, next, err := buf.Read(since)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "failed to read log buffer",
+			})
+		}
+
+		c.Set("Content-Type", "application/x-ndjson")
+		c.Set("X-Log-Next-Offset", strconv.FormatInt(next, 10))
+
+		for _, rec := range records {
+			if _, err := c.Response().BodyWriter().Write(rec.Line); err != nil {
+				return err
+			}
+			if _, err := c.Response().BodyWriter().Write([]byte("\n")); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+// logBufferDownload handles GET /debug/logs/download, returning the
+// concatenated ring buffer contents as a single .zst file.
+func logBufferDownload(buf *logbuffer.Buffer) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.Set("Content-Type", "application/zstd")
+		c.Set("Content-Disposition", "attachment; filename=logs.ndjson.zst")
+
+		if err := buf.Download(c.Response().BodyWriter()); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "failed to assemble log buffer download",
+			})
+		}
+
+		return nil
+	}
+}
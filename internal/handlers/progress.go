@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/KeremKalyoncu/MedYan/internal/queue"
+)
+
+// JobProgressStream returns a fiber.Handler for GET /api/v1/jobs/:id/stream
+// that streams a job's ProgressEvents as Server-Sent Events, replacing
+// client-side polling of GET /api/v1/jobs/:id with push updates as the
+// worker transitions through extraction stages.
+func JobProgressStream(queueClient *queue.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		jobID := c.Params("id")
+
+		reader, err := queueClient.StreamProgress(c.Context(), jobID)
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "job not found",
+			})
+		}
+
+		c.Set("Content-Type", "text/event-stream")
+		c.Set("Cache-Control", "no-cache")
+		c.Set("Connection", "keep-alive")
+		c.Set("X-Accel-Buffering", "no")
+
+		c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+			defer reader.Close()
+
+			ctx := c.Context()
+			for {
+				event, err := reader.Next(ctx)
+				if errors.Is(err, io.EOF) {
+					return
+				}
+				if err != nil {
+					return
+				}
+
+				payload, err := json.Marshal(event)
+				if err != nil {
+					continue
+				}
+
+				if _, err := fmt.Fprintf(w, "data: %s\n\n", payload); err != nil {
+					return
+				}
+				if err := w.Flush(); err != nil {
+					return
+				}
+			}
+		})
+
+		return nil
+	}
+}
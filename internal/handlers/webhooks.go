@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"github.com/KeremKalyoncu/MedYan/internal/webhooks"
+)
+
+// WebhookHandler manages webhook subscriptions and exposes their delivery
+// history for a job.
+type WebhookHandler struct {
+	dispatcher *webhooks.Dispatcher
+	logger     *zap.Logger
+}
+
+// NewWebhookHandler creates a new webhook handler.
+func NewWebhookHandler(dispatcher *webhooks.Dispatcher, logger *zap.Logger) *WebhookHandler {
+	return &WebhookHandler{
+		dispatcher: dispatcher,
+		logger:     logger,
+	}
+}
+
+// Register subscribes a URL to a job's lifecycle events and returns the
+// HMAC secret used to sign deliveries. The secret is only ever returned
+// here; store it, it cannot be retrieved again.
+// POST /api/v1/webhooks/register
+func (h *WebhookHandler) Register(c *fiber.Ctx) error {
+	var req struct {
+		JobID      string `json:"job_id"`
+		WebhookURL string `json:"webhook_url"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if req.JobID == "" || req.WebhookURL == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "job_id and webhook_url are required"})
+	}
+
+	secret, err := h.dispatcher.Register(c.Context(), req.JobID, req.WebhookURL)
+	if err != nil {
+		if errors.Is(err, webhooks.ErrUnsafeWebhookURL) {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		h.logger.Error("Failed to register webhook", zap.String("job_id", req.JobID), zap.Error(err))
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to register webhook"})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Webhook registered successfully",
+		"job_id":  req.JobID,
+		"secret":  secret,
+	})
+}
+
+// Deliveries returns the delivery attempt history for a job's webhook.
+// GET /api/v1/webhooks/:job_id/deliveries
+func (h *WebhookHandler) Deliveries(c *fiber.Ctx) error {
+	jobID := c.Params("job_id")
+
+	deliveries, err := h.dispatcher.GetDeliveries(c.Context(), jobID)
+	if err != nil {
+		h.logger.Error("Failed to load webhook deliveries", zap.String("job_id", jobID), zap.Error(err))
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to load deliveries"})
+	}
+
+	return c.JSON(fiber.Map{
+		"job_id":     jobID,
+		"deliveries": deliveries,
+	})
+}
+
+// Replay forces re-delivery of the most recent event published for a job.
+// POST /api/v1/webhooks/:job_id/replay
+func (h *WebhookHandler) Replay(c *fiber.Ctx) error {
+	jobID := c.Params("job_id")
+
+	if err := h.dispatcher.Replay(c.Context(), jobID); err != nil {
+		h.logger.Warn("Failed to replay webhook", zap.String("job_id", jobID), zap.Error(err))
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{"message": "Replay scheduled"})
+}
+
+// DeadLetters lists every delivery that has exhausted its retry budget.
+// GET /api/v1/webhooks/dead-letters
+func (h *WebhookHandler) DeadLetters(c *fiber.Ctx) error {
+	letters, err := h.dispatcher.ListDeadLetters(c.Context())
+	if err != nil {
+		h.logger.Error("Failed to load dead-lettered webhooks", zap.Error(err))
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to load dead letters"})
+	}
+
+	return c.JSON(fiber.Map{"dead_letters": letters})
+}
+
+// Redeliver re-schedules a dead-lettered delivery with a fresh retry
+// budget.
+// POST /api/v1/webhooks/redeliver/:delivery_id
+func (h *WebhookHandler) Redeliver(c *fiber.Ctx) error {
+	deliveryID := c.Params("delivery_id")
+
+	if err := h.dispatcher.Redeliver(c.Context(), deliveryID); err != nil {
+		h.logger.Warn("Failed to redeliver webhook", zap.String("delivery_id", deliveryID), zap.Error(err))
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{"message": "Redelivery scheduled"})
+}
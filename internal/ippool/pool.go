@@ -0,0 +1,394 @@
+// Package ippool manages a set of egress paths (local source IPs and/or
+// upstream HTTP/SOCKS proxies) that yt-dlp invocations lease from, so a
+// platform throttle or ban on one egress doesn't take every extraction down
+// with it.
+package ippool
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Egress is one source IP or upstream proxy the pool can hand out as a
+// lease. At most one of SourceIP or ProxyURL is set - yt-dlp accepts
+// --source-address or --proxy, never both for the same invocation.
+type Egress struct {
+	SourceIP string
+	ProxyURL string
+}
+
+func egressKey(e Egress) string {
+	if e.ProxyURL != "" {
+		return e.ProxyURL
+	}
+	return e.SourceIP
+}
+
+// Outcome classifies how yt-dlp exited under a lease, so Release knows
+// whether to cool the egress down.
+type Outcome int
+
+const (
+	// OutcomeOK means yt-dlp succeeded, or failed for a reason unrelated to
+	// this egress (e.g. a genuinely private video).
+	OutcomeOK Outcome = iota
+	// OutcomeThrottled means yt-dlp's failure looked like this egress
+	// getting rate-limited or bot-blocked by the platform.
+	OutcomeThrottled
+)
+
+// throttleSignatures are yt-dlp stderr substrings that mark an egress as
+// throttled or blocked.
+var throttleSignatures = []string{
+	"429",
+	"too many requests",
+	"rate limit",
+	"rate-limited",
+	"sign in to confirm you're not a bot",
+	"confirm you're not a bot",
+	"403",
+	"forbidden",
+}
+
+// ClassifyOutcome inspects yt-dlp's combined stdout/stderr for the
+// platform-throttle signatures that should trigger a cooldown on the
+// egress a lease used.
+func ClassifyOutcome(output string) Outcome {
+	lower := strings.ToLower(output)
+	for _, sig := range throttleSignatures {
+		if strings.Contains(lower, sig) {
+			return OutcomeThrottled
+		}
+	}
+	return OutcomeOK
+}
+
+// ErrNoProxyAvailable is returned by Acquire when every configured egress
+// has failed FailureThreshold times in a row - the pool isn't merely
+// waiting out a cooldown, every entry is presumed dead until a future
+// success resets it.
+var ErrNoProxyAvailable = errors.New("ippool: no healthy egress available")
+
+const (
+	defaultCooldown         = 30 * time.Minute
+	defaultFailureThreshold = 5
+	defaultMaxCooldown      = 2 * time.Hour
+	defaultBackoffFactor    = 2.0
+)
+
+// Config configures a Pool.
+type Config struct {
+	// SourceIPs are local source addresses, handed out via
+	// --source-address (YTDLP_SOURCE_IPS).
+	SourceIPs []string
+	// ProxyURLs are upstream HTTP/SOCKS proxies, handed out via --proxy
+	// (YTDLP_PROXIES).
+	ProxyURLs []string
+	// Cooldown is how long an egress is skipped after its first throttle
+	// signature. Defaults to 30 minutes.
+	Cooldown time.Duration
+	// FailureThreshold is how many consecutive throttled releases mark an
+	// egress as exhausted - Acquire treats it as unavailable even once its
+	// cooldown has elapsed, until a success resets the counter. Defaults
+	// to 5.
+	FailureThreshold int
+	// BackoffFactor multiplies an egress's cooldown on each consecutive
+	// throttle, capped at MaxCooldown. Defaults to 2.0.
+	BackoffFactor float64
+	// MaxCooldown caps the exponentially-growing cooldown. Defaults to 2
+	// hours.
+	MaxCooldown time.Duration
+}
+
+// entry is one egress's liveness state. Entries live in exactly one place
+// at a time: in the heap while available to Acquire, or held by an
+// outstanding Lease while checked out.
+type entry struct {
+	egress              Egress
+	availableAt         time.Time
+	consecutiveFailures int
+	cooldown            time.Duration
+	lastUsed            time.Time
+	heapIndex           int
+}
+
+// entryHeap is a container/heap.Interface of entries, ordered by
+// availableAt (earliest first) with ties broken by least-recently-used -
+// the two properties Acquire needs to pick the best candidate among
+// whatever is currently ready.
+type entryHeap []*entry
+
+func (h entryHeap) Len() int { return len(h) }
+func (h entryHeap) Less(i, j int) bool {
+	if h[i].availableAt.Equal(h[j].availableAt) {
+		return h[i].lastUsed.Before(h[j].lastUsed)
+	}
+	return h[i].availableAt.Before(h[j].availableAt)
+}
+func (h entryHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].heapIndex = i
+	h[j].heapIndex = j
+}
+func (h *entryHeap) Push(x interface{}) {
+	e := x.(*entry)
+	e.heapIndex = len(*h)
+	*h = append(*h, e)
+}
+func (h *entryHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	e := old[n-1]
+	old[n-1] = nil
+	e.heapIndex = -1
+	*h = old[:n-1]
+	return e
+}
+
+// allExhausted reports whether every entry in h has reached threshold
+// consecutive failures - used to tell an exhausted pool (waiting won't
+// help) apart from one that's merely still cooling down.
+func allExhausted(h entryHeap, threshold int) bool {
+	for _, e := range h {
+		if e.consecutiveFailures < threshold {
+			return false
+		}
+	}
+	return true
+}
+
+// Pool hands out Egress leases via a min-heap keyed by availableAt, so the
+// next Acquire always considers the egress that's been cooling down
+// longest first. A Pool with no configured egresses is valid - Acquire
+// always succeeds with a zero-value Egress, so wiring ippool into a caller
+// that isn't configured with any proxies/source IPs is a no-op.
+type Pool struct {
+	mu         sync.Mutex
+	heap       entryHeap
+	total      int // configured egress count, independent of heap/checked-out state
+	checkedOut int // entries currently held by an outstanding Lease
+	cfg        Config
+	logger     *zap.Logger
+}
+
+// New creates a Pool from cfg.
+func New(cfg Config, logger *zap.Logger) *Pool {
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = defaultCooldown
+	}
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = defaultFailureThreshold
+	}
+	if cfg.BackoffFactor <= 0 {
+		cfg.BackoffFactor = defaultBackoffFactor
+	}
+	if cfg.MaxCooldown <= 0 {
+		cfg.MaxCooldown = defaultMaxCooldown
+	}
+
+	p := &Pool{cfg: cfg, logger: logger}
+	now := time.Now()
+	add := func(e Egress) {
+		p.heap = append(p.heap, &entry{egress: e, availableAt: now, cooldown: cfg.Cooldown})
+	}
+	for _, ip := range cfg.SourceIPs {
+		add(Egress{SourceIP: ip})
+	}
+	for _, proxy := range cfg.ProxyURLs {
+		add(Egress{ProxyURL: proxy})
+	}
+	p.total = len(p.heap)
+	heap.Init(&p.heap)
+	return p
+}
+
+// Lease is one in-flight use of an Egress. Callers must call Release once
+// yt-dlp exits, passing the outcome so the pool can decide whether to cool
+// the egress down.
+type Lease struct {
+	Egress Egress
+	pool   *Pool
+	entry  *entry
+}
+
+// Args returns the yt-dlp flag pair this lease should be passed as, or nil
+// for a zero-value lease (empty pool, or the caller already supplied its
+// own proxy/source address).
+func (l *Lease) Args() []string {
+	switch {
+	case l.Egress.ProxyURL != "":
+		return []string{"--proxy", l.Egress.ProxyURL}
+	case l.Egress.SourceIP != "":
+		return []string{"--source-address", l.Egress.SourceIP}
+	default:
+		return nil
+	}
+}
+
+// Acquire hands out the next healthy egress, blocking (context-aware)
+// until one is ready if every egress is currently cooling down. It returns
+// ErrNoProxyAvailable, without waiting, only once every configured egress
+// has reached FailureThreshold consecutive failures - at that point a
+// cooldown elapsing won't help, only a fresh success (which Release
+// records) resets an egress back to usable.
+func (p *Pool) Acquire(ctx context.Context, platform string) (*Lease, error) {
+	for {
+		p.mu.Lock()
+		if p.total == 0 {
+			p.mu.Unlock()
+			return &Lease{pool: p}, nil
+		}
+
+		now := time.Now()
+		var ready []*entry
+		for len(p.heap) > 0 && !p.heap[0].availableAt.After(now) {
+			ready = append(ready, heap.Pop(&p.heap).(*entry))
+		}
+
+		var winner *entry
+		for _, e := range ready {
+			if e.consecutiveFailures < p.cfg.FailureThreshold {
+				if winner == nil || e.lastUsed.Before(winner.lastUsed) {
+					winner = e
+				}
+			}
+		}
+
+		if winner != nil {
+			winner.lastUsed = now
+			for _, e := range ready {
+				if e != winner {
+					heap.Push(&p.heap, e)
+				}
+			}
+			p.checkedOut++
+			lease := &Lease{Egress: winner.egress, pool: p, entry: winner}
+			p.mu.Unlock()
+			return lease, nil
+		}
+
+		// Nothing ready was healthy - put them all back and see if
+		// anything still cooling down is worth waiting for.
+		for _, e := range ready {
+			heap.Push(&p.heap, e)
+		}
+
+		if p.checkedOut == 0 && allExhausted(p.heap, p.cfg.FailureThreshold) {
+			// Every egress still in the pool, ready or cooling down, is
+			// over FailureThreshold - a cooldown elapsing won't help, so
+			// looping back around to re-check them would just spin.
+			p.mu.Unlock()
+			return nil, ErrNoProxyAvailable
+		}
+
+		if len(p.heap) == 0 {
+			// Every remaining egress is out on lease with another
+			// caller right now - wait for one of those to come back
+			// rather than declaring the pool exhausted.
+			p.mu.Unlock()
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(50 * time.Millisecond):
+			}
+			continue
+		}
+
+		wait := time.Until(p.heap[0].availableAt)
+		p.mu.Unlock()
+
+		if wait <= 0 {
+			// Every entry left in the heap is both ready and over
+			// FailureThreshold, but a concurrent caller still holds at
+			// least one checked-out egress that might come back healthy
+			// - allExhausted can't declare the pool exhausted yet. Without
+			// this wait, re-popping and re-pushing the same stale entries
+			// every iteration would busy-loop a CPU core and ignore ctx
+			// cancellation until that lease is released.
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(50 * time.Millisecond):
+			}
+			continue
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Release records outcome for the lease, cooling the egress down
+// (exponentially, capped at MaxCooldown) when outcome is OutcomeThrottled,
+// or resetting it to immediately available on success.
+func (l *Lease) Release(outcome Outcome) {
+	if l.entry == nil {
+		return
+	}
+
+	l.pool.mu.Lock()
+	defer l.pool.mu.Unlock()
+
+	now := time.Now()
+	e := l.entry
+
+	if outcome == OutcomeThrottled {
+		e.consecutiveFailures++
+		if e.consecutiveFailures > 1 {
+			e.cooldown = time.Duration(float64(e.cooldown) * l.pool.cfg.BackoffFactor)
+		}
+		if e.cooldown > l.pool.cfg.MaxCooldown {
+			e.cooldown = l.pool.cfg.MaxCooldown
+		}
+		e.availableAt = now.Add(e.cooldown)
+		if l.pool.logger != nil {
+			l.pool.logger.Warn("Egress cooling down",
+				zap.String("egress", egressKey(e.egress)),
+				zap.Duration("cooldown", e.cooldown),
+				zap.Int("consecutive_failures", e.consecutiveFailures),
+			)
+		}
+	} else {
+		e.consecutiveFailures = 0
+		e.cooldown = l.pool.cfg.Cooldown
+		e.availableAt = now
+	}
+
+	l.pool.checkedOut--
+	heap.Push(&l.pool.heap, e)
+}
+
+// Snapshot reports the pool's current state for operators: total
+// configured egresses and which ones are currently cooling down or
+// exhausted (see FailureThreshold).
+func (p *Pool) Snapshot() map[string]interface{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	cooling := 0
+	exhausted := 0
+	for _, e := range p.heap {
+		if e.consecutiveFailures >= p.cfg.FailureThreshold {
+			exhausted++
+		} else if now.Before(e.availableAt) {
+			cooling++
+		}
+	}
+
+	return map[string]interface{}{
+		"total_egresses":     p.total,
+		"cooling_down_count": cooling,
+		"exhausted_count":    exhausted,
+	}
+}
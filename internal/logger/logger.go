@@ -7,6 +7,8 @@ import (
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"gopkg.in/natefinch/lumberjack.v2"
+
+	"github.com/KeremKalyoncu/MedYan/pkg/logbuffer"
 )
 
 // Config holds logger configuration
@@ -19,6 +21,11 @@ type Config struct {
 	Compress      bool   // Whether to compress old files
 	Format        string // json or text
 	ConsoleOutput bool   // Also output to console
+
+	// RingBuffer, if set, receives every log record through an additional
+	// zapcore.Core so it's servable via GET /debug/logs independent of the
+	// file/console sinks above.
+	RingBuffer *logbuffer.Buffer
 }
 
 // New creates a configured logger with file rotation support
@@ -93,6 +100,13 @@ func New(cfg Config) (*zap.Logger, error) {
 		cores = append(cores, consoleCore)
 	}
 
+	// Ring buffer writer - always JSON regardless of cfg.Format, since
+	// /debug/logs consumers parse NDJSON rather than console-formatted text
+	if cfg.RingBuffer != nil {
+		ringEncoder := zapcore.NewJSONEncoder(encoderCfg)
+		cores = append(cores, logbuffer.NewCore(cfg.RingBuffer, ringEncoder, level))
+	}
+
 	// Combine cores
 	combined := zapcore.NewTee(cores...)
 
@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// labelKeySep separates the label components of a LabeledCounter key. "|" is
+// used instead of the more common ":" since platform/class names are plain
+// identifiers and never contain it, keeping splitLabelKey unambiguous.
+const labelKeySep = "|"
+
+// labelKey builds the composite key a LabeledCounter stores platform/class
+// pairs under.
+func labelKey(platform, class string) string {
+	return platform + labelKeySep + class
+}
+
+// splitLabelKey reverses labelKey, reporting ok=false for malformed keys.
+func splitLabelKey(key string) (platform, class string, ok bool) {
+	platform, class, found := strings.Cut(key, labelKeySep)
+	return platform, class, found
+}
+
+// LabeledCounter is a set of atomic counters keyed by an arbitrary composite
+// label string (see labelKey), created lazily on first Add. It follows the
+// same lazy-map-of-atomics shape as LabeledHistogram.
+type LabeledCounter struct {
+	mu    sync.Mutex
+	byKey map[string]*atomic.Uint64
+}
+
+// newLabeledCounter creates an empty LabeledCounter.
+func newLabeledCounter() *LabeledCounter {
+	return &LabeledCounter{byKey: make(map[string]*atomic.Uint64)}
+}
+
+// Add increments the counter for key by delta, creating it on first use.
+func (lc *LabeledCounter) Add(key string, delta uint64) {
+	lc.mu.Lock()
+	c, ok := lc.byKey[key]
+	if !ok {
+		c = &atomic.Uint64{}
+		lc.byKey[key] = c
+	}
+	lc.mu.Unlock()
+
+	c.Add(delta)
+}
+
+// Snapshot returns every key's current count.
+func (lc *LabeledCounter) Snapshot() map[string]uint64 {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	out := make(map[string]uint64, len(lc.byKey))
+	for key, c := range lc.byKey {
+		out[key] = c.Load()
+	}
+	return out
+}
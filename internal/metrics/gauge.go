@@ -0,0 +1,33 @@
+package metrics
+
+import "sync"
+
+// LabeledGauge is a last-value-wins counterpart to LabeledCounter, for
+// metrics like "workers busy" or "queue depth" that go up and down rather
+// than only accumulate (e.g. internal/pool.FFmpegPool's per-pool gauges).
+type LabeledGauge struct {
+	mu     sync.Mutex
+	byName map[string]float64
+}
+
+func newLabeledGauge() *LabeledGauge {
+	return &LabeledGauge{byName: make(map[string]float64)}
+}
+
+// Set overwrites the current value for name.
+func (lg *LabeledGauge) Set(name string, value float64) {
+	lg.mu.Lock()
+	defer lg.mu.Unlock()
+	lg.byName[name] = value
+}
+
+// Snapshot returns a copy of every name's current value.
+func (lg *LabeledGauge) Snapshot() map[string]float64 {
+	lg.mu.Lock()
+	defer lg.mu.Unlock()
+	out := make(map[string]float64, len(lg.byName))
+	for name, value := range lg.byName {
+		out[name] = value
+	}
+	return out
+}
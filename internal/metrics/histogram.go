@@ -0,0 +1,165 @@
+package metrics
+
+import (
+	"math"
+	"sync"
+)
+
+// Histogram is a fixed-bucket distribution counter, the same shape
+// Prometheus histograms use: a fixed set of ascending upper bounds plus an
+// implicit +Inf bucket, a running sum, and a total count. It lets
+// GetSnapshot report p50/p95/p99 and RenderPrometheus emit real
+// _bucket/_sum/_count series instead of just the last observation.
+type Histogram struct {
+	bounds []float64 // ascending upper bounds, exclusive of the implicit +Inf bucket
+
+	mu     sync.Mutex
+	counts []uint64 // counts[i] = observations in (bounds[i-1], bounds[i]]; counts[len(bounds)] is the +Inf bucket
+	sum    float64
+	total  uint64
+}
+
+// newHistogram creates a Histogram with the given ascending bucket bounds.
+func newHistogram(bounds []float64) *Histogram {
+	return &Histogram{bounds: bounds, counts: make([]uint64, len(bounds)+1)}
+}
+
+// Observe records a single sample.
+func (h *Histogram) Observe(v float64) {
+	idx := len(h.bounds)
+	for i, bound := range h.bounds {
+		if v <= bound {
+			idx = i
+			break
+		}
+	}
+
+	h.mu.Lock()
+	h.counts[idx]++
+	h.sum += v
+	h.total++
+	h.mu.Unlock()
+}
+
+// cumulativeCounts returns the running (cumulative) bucket counts aligned
+// with h.bounds, plus the overall total and sum - what RenderPrometheus
+// needs for _bucket/_sum/_count lines.
+func (h *Histogram) cumulativeCounts() ([]uint64, uint64, float64) {
+	h.mu.Lock()
+	counts := append([]uint64(nil), h.counts...)
+	total := h.total
+	sum := h.sum
+	h.mu.Unlock()
+
+	cumulative := make([]uint64, len(h.bounds))
+	var running uint64
+	for i := range counts {
+		running += counts[i]
+		if i < len(cumulative) {
+			cumulative[i] = running
+		}
+	}
+	return cumulative, total, sum
+}
+
+// HistogramSnapshot is a point-in-time read of a Histogram for JSON
+// responses (GetSnapshot).
+type HistogramSnapshot struct {
+	Count uint64  `json:"count"`
+	Sum   float64 `json:"sum"`
+	Mean  float64 `json:"mean"`
+	P50   float64 `json:"p50"`
+	P95   float64 `json:"p95"`
+	P99   float64 `json:"p99"`
+}
+
+// Snapshot computes count/sum/mean and p50/p95/p99 estimates.
+func (h *Histogram) Snapshot() HistogramSnapshot {
+	cumulative, total, sum := h.cumulativeCounts()
+
+	mean := 0.0
+	if total > 0 {
+		mean = sum / float64(total)
+	}
+
+	return HistogramSnapshot{
+		Count: total,
+		Sum:   sum,
+		Mean:  mean,
+		P50:   h.quantile(cumulative, total, 0.50),
+		P95:   h.quantile(cumulative, total, 0.95),
+		P99:   h.quantile(cumulative, total, 0.99),
+	}
+}
+
+// quantile estimates the value at rank q (0-1) as the upper bound of the
+// first bucket whose cumulative count reaches that rank. This is the same
+// coarse, bucket-resolution approximation Prometheus's own
+// histogram_quantile makes, just computed locally instead of at query time.
+func (h *Histogram) quantile(cumulative []uint64, total uint64, q float64) float64 {
+	if total == 0 {
+		return 0
+	}
+
+	target := uint64(math.Ceil(q * float64(total)))
+	for i, c := range cumulative {
+		if c >= target {
+			return h.bounds[i]
+		}
+	}
+	// Rank falls in the +Inf bucket - report the last finite bound rather
+	// than an unbounded value.
+	return h.bounds[len(h.bounds)-1]
+}
+
+// LabeledHistogram is a set of Histograms keyed by a single label value
+// (e.g. platform or ffmpeg operation), created lazily on first Observe.
+type LabeledHistogram struct {
+	bounds []float64
+
+	mu    sync.Mutex
+	byKey map[string]*Histogram
+}
+
+// newLabeledHistogram creates a LabeledHistogram whose per-label Histograms
+// all share the given bucket bounds.
+func newLabeledHistogram(bounds []float64) *LabeledHistogram {
+	return &LabeledHistogram{bounds: bounds, byKey: make(map[string]*Histogram)}
+}
+
+// Observe records v under label, creating that label's Histogram on first
+// use.
+func (lh *LabeledHistogram) Observe(label string, v float64) {
+	lh.mu.Lock()
+	h, ok := lh.byKey[label]
+	if !ok {
+		h = newHistogram(lh.bounds)
+		lh.byKey[label] = h
+	}
+	lh.mu.Unlock()
+
+	h.Observe(v)
+}
+
+// Snapshot returns every label's current HistogramSnapshot.
+func (lh *LabeledHistogram) Snapshot() map[string]HistogramSnapshot {
+	lh.mu.Lock()
+	defer lh.mu.Unlock()
+
+	out := make(map[string]HistogramSnapshot, len(lh.byKey))
+	for label, h := range lh.byKey {
+		out[label] = h.Snapshot()
+	}
+	return out
+}
+
+// forEach calls fn for each label's Histogram, for RenderPrometheus to
+// render one series per label without exposing the internal map.
+func (lh *LabeledHistogram) forEach(fn func(label string, h *Histogram)) {
+	lh.mu.Lock()
+	defer lh.mu.Unlock()
+
+	for label, h := range lh.byKey {
+		fn(label, h)
+	}
+}
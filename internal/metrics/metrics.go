@@ -1,6 +1,10 @@
 package metrics
 
 import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -14,16 +18,71 @@ type Metrics struct {
 	FailedJobs     atomic.Uint64
 	ActiveJobs     atomic.Int64
 
-	// Performance metrics
-	AverageJobDuration atomic.Int64 // microseconds
+	// Performance metrics. JobDurationSeconds and DownloadedMBHist capture
+	// full distributions (so p50/p95/p99 are queryable) rather than just
+	// the last observation.
+	JobDurationSeconds *Histogram
+	DownloadedMBHist   *Histogram
 	TotalDownloadedMB  atomic.Uint64
 
+	// Per-platform/operation exec-time distributions for the extractor's
+	// external processes, surfaced by /metrics/prometheus as
+	// ytdlp_exec_seconds{platform} and ffmpeg_exec_seconds{operation}.
+	YtdlpExecSeconds  *LabeledHistogram
+	FFmpegExecSeconds *LabeledHistogram
+
+	// FFmpeg worker pool admission-control metrics (see internal/pool.
+	// FFmpegPool), keyed by pool name since a single process can run more
+	// than one pool (e.g. cmd/worker's "transcode" and "extraction" pools).
+	FFmpegWorkersBusy *LabeledGauge
+	FFmpegQueueDepth  *LabeledGauge
+	FFmpegWaitSeconds *LabeledHistogram
+
 	// System metrics
 	Uptime      time.Time
 	TotalErrors atomic.Uint64
 
+	// IP pool metrics (see internal/ippool)
+	IPPoolLeasesGranted atomic.Uint64
+	IPPoolCooldowns     atomic.Uint64
+
+	// ErrorClassCounts tracks classified extraction failures (see
+	// internal/errclass) by platform and class, for the "error_classes"
+	// snapshot key and the medyan_errors_by_class_total series.
+	ErrorClassCounts *LabeledCounter
+
+	// JobFailureReasons tracks RecordJobFailure's reason (see
+	// internal/errors.Classify's Code) by platform, for the
+	// "job_failure_reasons" snapshot key and the
+	// medyan_job_failures_by_reason_total series.
+	JobFailureReasons *LabeledCounter
+
+	// DedupHits tracks RecordDedupHit by platform (see internal/dedup and
+	// queue.Client.LookupDedupedResult), for the "dedup_hits" snapshot key
+	// and the medyan_dedup_hits_total series.
+	DedupHits *LabeledCounter
+
 	// Per-platform metrics
 	platformStats sync.Map // platform -> *PlatformStats
+
+	// Temp file cleanup metrics (see internal/cleanup.TempFileCleanup)
+	DiskFreeBytes        atomic.Int64
+	TempFilesCount       atomic.Int64
+	EmergencySweepsTotal atomic.Uint64
+
+	// Circuit breaker metrics (see internal/circuitbreaker.Registry), keyed
+	// by breaker name (e.g. "youtube.com:extract"). CircuitState holds the
+	// numeric circuitbreaker.State (0=closed, 1=open, 2=half-open).
+	CircuitState    *LabeledGauge
+	CircuitTrips    *LabeledCounter
+	CircuitRejected *LabeledCounter
+
+	// LiveStreamActive tracks whether extractor.YtDlp.Download most recently
+	// saw url's platform reporting a live/post-live stream (1) or not (0),
+	// keyed by platform. A breaker's IsFailure can check this instead of
+	// guessing from the error string alone when deciding whether a live
+	// stream's failure should count toward tripping it.
+	LiveStreamActive *LabeledGauge
 }
 
 // PlatformStats tracks metrics per platform
@@ -33,12 +92,34 @@ type PlatformStats struct {
 	FailedJobs     atomic.Uint64
 }
 
+// durationBuckets are JobDurationSeconds' and *ExecSeconds' upper bounds,
+// in seconds, sized for extraction/transcode jobs that run anywhere from
+// sub-second (cached metadata) to several minutes (long transcodes).
+var durationBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300}
+
+// downloadMBBuckets are DownloadedMBHist's upper bounds, in megabytes.
+var downloadMBBuckets = []float64{1, 5, 10, 25, 50, 100, 250, 500, 1000, 2000}
+
 // Global metrics instance
 var globalMetrics *Metrics
 
 func init() {
 	globalMetrics = &Metrics{
-		Uptime: time.Now(),
+		Uptime:             time.Now(),
+		JobDurationSeconds: newHistogram(durationBuckets),
+		DownloadedMBHist:   newHistogram(downloadMBBuckets),
+		YtdlpExecSeconds:   newLabeledHistogram(durationBuckets),
+		FFmpegExecSeconds:  newLabeledHistogram(durationBuckets),
+		FFmpegWorkersBusy:  newLabeledGauge(),
+		FFmpegQueueDepth:   newLabeledGauge(),
+		FFmpegWaitSeconds:  newLabeledHistogram(durationBuckets),
+		ErrorClassCounts:   newLabeledCounter(),
+		JobFailureReasons:  newLabeledCounter(),
+		DedupHits:          newLabeledCounter(),
+		CircuitState:       newLabeledGauge(),
+		CircuitTrips:       newLabeledCounter(),
+		CircuitRejected:    newLabeledCounter(),
+		LiveStreamActive:   newLabeledGauge(),
 	}
 }
 
@@ -58,23 +139,125 @@ func (m *Metrics) RecordJobSuccess(platform string, duration time.Duration, size
 	m.ActiveJobs.Add(-1)
 	m.TotalDownloadedMB.Add(sizeMB)
 
-	// Update average duration (simple moving average)
-	m.AverageJobDuration.Store(duration.Microseconds())
+	m.JobDurationSeconds.Observe(duration.Seconds())
+	m.DownloadedMBHist.Observe(float64(sizeMB))
 
 	// Update platform stats
 	m.updatePlatformStats(platform, true)
 }
 
-// RecordJobFailure records a failed job
-func (m *Metrics) RecordJobFailure(platform string) {
+// RecordJobFailure records a failed job. reason is a machine-readable
+// error code (see internal/errors.Classify's Code), so operators can see
+// *why* jobs die per platform instead of just the raw failure count.
+func (m *Metrics) RecordJobFailure(platform, reason string) {
 	m.FailedJobs.Add(1)
 	m.ActiveJobs.Add(-1)
 	m.TotalErrors.Add(1)
+	m.JobFailureReasons.Add(labelKey(platform, reason), 1)
 
 	// Update platform stats
 	m.updatePlatformStats(platform, false)
 }
 
+// RecordDedupHit records a LookupDedupedResult cache hit for platform -
+// an identical, still-fresh request served from a prior job's result
+// instead of re-extracting.
+func (m *Metrics) RecordDedupHit(platform string) {
+	m.DedupHits.Add(platform, 1)
+}
+
+// RecordIPPoolLease records an ippool.Pool.Acquire call granting a lease.
+func (m *Metrics) RecordIPPoolLease() {
+	m.IPPoolLeasesGranted.Add(1)
+}
+
+// RecordIPPoolCooldown records an ippool.Lease.Release putting an egress
+// into cooldown. The counter is not labeled by platform or egress.
+func (m *Metrics) RecordIPPoolCooldown() {
+	m.IPPoolCooldowns.Add(1)
+}
+
+// RecordYtdlpExec records how long a single yt-dlp invocation for platform
+// took, for the ytdlp_exec_seconds{platform} histogram.
+func (m *Metrics) RecordYtdlpExec(platform string, duration time.Duration) {
+	m.YtdlpExecSeconds.Observe(platform, duration.Seconds())
+}
+
+// RecordFFmpegExec records how long a single tracked ffmpeg invocation for
+// operation (e.g. "convert", "hls_package") took, for the
+// ffmpeg_exec_seconds{operation} histogram.
+func (m *Metrics) RecordFFmpegExec(operation string, duration time.Duration) {
+	m.FFmpegExecSeconds.Observe(operation, duration.Seconds())
+}
+
+// SetFFmpegWorkersBusy records how many of pool's workers are currently
+// running a job, for the ffmpeg_workers_busy{pool} gauge.
+func (m *Metrics) SetFFmpegWorkersBusy(pool string, n int) {
+	m.FFmpegWorkersBusy.Set(pool, float64(n))
+}
+
+// SetFFmpegQueueDepth records how many jobs are waiting for a free worker in
+// pool, for the ffmpeg_queue_depth{pool} gauge.
+func (m *Metrics) SetFFmpegQueueDepth(pool string, n int) {
+	m.FFmpegQueueDepth.Set(pool, float64(n))
+}
+
+// RecordFFmpegWait records how long a job sat in pool's queue before a
+// worker picked it up, for the ffmpeg_wait_seconds{pool} histogram.
+func (m *Metrics) RecordFFmpegWait(pool string, duration time.Duration) {
+	m.FFmpegWaitSeconds.Observe(pool, duration.Seconds())
+}
+
+// SetLiveStreamActive records whether platform's most recent Download saw
+// a live/post-live stream, for the medyan_live_stream_active{platform}
+// gauge - 1 if live, 0 otherwise.
+func (m *Metrics) SetLiveStreamActive(platform string, active bool) {
+	value := 0.0
+	if active {
+		value = 1.0
+	}
+	m.LiveStreamActive.Set(platform, value)
+}
+
+// SetCircuitState records a circuit breaker's current state (0=closed,
+// 1=open, 2=half-open) for the medyan_circuit_state{name} gauge.
+func (m *Metrics) SetCircuitState(name string, state float64) {
+	m.CircuitState.Set(name, state)
+}
+
+// RecordCircuitTrip records a circuit breaker tripping open, for the
+// medyan_circuit_trips_total{name} counter.
+func (m *Metrics) RecordCircuitTrip(name string) {
+	m.CircuitTrips.Add(name, 1)
+}
+
+// RecordCircuitRejected records a request rejected outright by an
+// already-open (or saturated half-open) circuit breaker, for the
+// medyan_circuit_rejected_total{name} counter.
+func (m *Metrics) RecordCircuitRejected(name string) {
+	m.CircuitRejected.Add(name, 1)
+}
+
+// RecordTempCleanupStats records TempFileCleanup's view of tempDir as of its
+// last cleanup pass, for the disk_free_bytes and temp_files_count gauges.
+func (m *Metrics) RecordTempCleanupStats(diskFreeBytes, tempFilesCount int64) {
+	m.DiskFreeBytes.Store(diskFreeBytes)
+	m.TempFilesCount.Store(tempFilesCount)
+}
+
+// RecordEmergencySweep records TempFileCleanup running an emergency,
+// ignore-maxAge sweep because free disk space fell below its low watermark.
+func (m *Metrics) RecordEmergencySweep() {
+	m.EmergencySweepsTotal.Add(1)
+}
+
+// RecordErrorClass records a classified extraction failure (see
+// internal/errclass.Class) for platform, for the "error_classes" snapshot
+// key and the medyan_errors_by_class_total series.
+func (m *Metrics) RecordErrorClass(platform, class string) {
+	m.ErrorClassCounts.Add(labelKey(platform, class), 1)
+}
+
 // RecordJobStart records job start
 func (m *Metrics) RecordJobStart(platform string) {
 	m.ActiveJobs.Add(1)
@@ -112,6 +295,8 @@ func (m *Metrics) GetSnapshot() map[string]interface{} {
 		successRate = float64(m.SuccessfulJobs.Load()) / float64(total) * 100
 	}
 
+	durationSnap := m.JobDurationSeconds.Snapshot()
+
 	snapshot := map[string]interface{}{
 		"uptime_seconds":      int64(uptime.Seconds()),
 		"total_requests":      m.TotalRequests.Load(),
@@ -119,15 +304,59 @@ func (m *Metrics) GetSnapshot() map[string]interface{} {
 		"failed_jobs":         m.FailedJobs.Load(),
 		"active_jobs":         m.ActiveJobs.Load(),
 		"success_rate":        successRate,
-		"avg_job_duration_ms": m.AverageJobDuration.Load() / 1000,
+		"avg_job_duration_ms": durationSnap.Mean * 1000,
+		"job_duration_p50_ms": durationSnap.P50 * 1000,
+		"job_duration_p95_ms": durationSnap.P95 * 1000,
+		"job_duration_p99_ms": durationSnap.P99 * 1000,
 		"total_downloaded_mb": m.TotalDownloadedMB.Load(),
 		"total_errors":        m.TotalErrors.Load(),
+		"ip_pool_leases":      m.IPPoolLeasesGranted.Load(),
+		"ip_pool_cooldowns":   m.IPPoolCooldowns.Load(),
 		"platforms":           m.getPlatformSnapshot(),
+		"error_classes":       m.getErrorClassSnapshot(),
+		"job_failure_reasons": m.getJobFailureReasonSnapshot(),
+		"dedup_hits":          m.DedupHits.Snapshot(),
+		"disk_free_bytes":     m.DiskFreeBytes.Load(),
+		"temp_files_count":    m.TempFilesCount.Load(),
+		"emergency_sweeps":    m.EmergencySweepsTotal.Load(),
+		"ffmpeg_workers_busy": m.FFmpegWorkersBusy.Snapshot(),
+		"ffmpeg_queue_depth":  m.FFmpegQueueDepth.Snapshot(),
+		"circuit_states":      m.CircuitState.Snapshot(),
+		"circuit_trips":       m.CircuitTrips.Snapshot(),
+		"circuit_rejected":    m.CircuitRejected.Snapshot(),
+		"live_stream_active":  m.LiveStreamActive.Snapshot(),
 	}
 
 	return snapshot
 }
 
+// getErrorClassSnapshot returns classified-error counts keyed "platform:class".
+func (m *Metrics) getErrorClassSnapshot() map[string]uint64 {
+	out := make(map[string]uint64)
+	for key, count := range m.ErrorClassCounts.Snapshot() {
+		platform, class, ok := splitLabelKey(key)
+		if !ok {
+			continue
+		}
+		out[platform+":"+class] = count
+	}
+	return out
+}
+
+// getJobFailureReasonSnapshot returns failure-reason counts keyed
+// "platform:reason".
+func (m *Metrics) getJobFailureReasonSnapshot() map[string]uint64 {
+	out := make(map[string]uint64)
+	for key, count := range m.JobFailureReasons.Snapshot() {
+		platform, reason, ok := splitLabelKey(key)
+		if !ok {
+			continue
+		}
+		out[platform+":"+reason] = count
+	}
+	return out
+}
+
 // getPlatformSnapshot returns platform-specific metrics
 func (m *Metrics) getPlatformSnapshot() map[string]interface{} {
 	platforms := make(map[string]interface{})
@@ -154,3 +383,206 @@ func (m *Metrics) getPlatformSnapshot() map[string]interface{} {
 
 	return platforms
 }
+
+// RenderPrometheus renders every counter, gauge, and histogram as
+// Prometheus/OpenMetrics text exposition format, for scraping at
+// /metrics/prometheus. It derives from the same underlying counters and
+// histograms as GetSnapshot, just presented differently.
+func (m *Metrics) RenderPrometheus() string {
+	var b strings.Builder
+
+	writeCounter(&b, "medyan_total_requests", "Total HTTP requests received.", float64(m.TotalRequests.Load()))
+	writeCounter(&b, "medyan_successful_jobs_total", "Total extraction jobs that completed successfully.", float64(m.SuccessfulJobs.Load()))
+	writeCounter(&b, "medyan_failed_jobs_total", "Total extraction jobs that failed.", float64(m.FailedJobs.Load()))
+	writeCounter(&b, "medyan_errors_total", "Total errors recorded across all jobs.", float64(m.TotalErrors.Load()))
+	writeCounter(&b, "medyan_downloaded_mb_total", "Total megabytes downloaded across all jobs.", float64(m.TotalDownloadedMB.Load()))
+
+	writeGauge(&b, "medyan_active_jobs", "Number of extraction jobs currently in flight.", float64(m.ActiveJobs.Load()))
+	writeGauge(&b, "medyan_uptime_seconds", "Seconds since the process started.", time.Since(m.Uptime).Seconds())
+
+	writeHistogram(&b, "medyan_job_duration_seconds", "Extraction job duration in seconds.", nil, m.JobDurationSeconds)
+	writeHistogram(&b, "medyan_downloaded_mb", "Per-job downloaded size in megabytes.", nil, m.DownloadedMBHist)
+
+	writeCounterHeader(&b, "medyan_platform_jobs_total", "Total jobs per platform.")
+	writeCounterHeader(&b, "medyan_platform_jobs_successful_total", "Total successful jobs per platform.")
+	writeCounterHeader(&b, "medyan_platform_jobs_failed_total", "Total failed jobs per platform.")
+	m.platformStats.Range(func(key, value interface{}) bool {
+		platform := key.(string)
+		stats := value.(*PlatformStats)
+		labels := map[string]string{"platform": platform}
+		writeCounterLine(&b, "medyan_platform_jobs_total", float64(stats.TotalJobs.Load()), labels)
+		writeCounterLine(&b, "medyan_platform_jobs_successful_total", float64(stats.SuccessfulJobs.Load()), labels)
+		writeCounterLine(&b, "medyan_platform_jobs_failed_total", float64(stats.FailedJobs.Load()), labels)
+		return true
+	})
+
+	writeLabeledHistogram(&b, "medyan_ytdlp_exec_seconds", "yt-dlp invocation duration in seconds, by platform.", "platform", m.YtdlpExecSeconds)
+	writeLabeledHistogram(&b, "medyan_ffmpeg_exec_seconds", "Tracked ffmpeg invocation duration in seconds, by operation.", "operation", m.FFmpegExecSeconds)
+	writeLabeledHistogram(&b, "medyan_ffmpeg_wait_seconds", "Time a job spent queued in an FFmpegPool before a worker picked it up, by pool.", "pool", m.FFmpegWaitSeconds)
+
+	writeGaugeHeader(&b, "medyan_ffmpeg_workers_busy", "FFmpegPool workers currently running a job, by pool.")
+	for pool, value := range m.FFmpegWorkersBusy.Snapshot() {
+		writeGaugeLine(&b, "medyan_ffmpeg_workers_busy", value, map[string]string{"pool": pool})
+	}
+	writeGaugeHeader(&b, "medyan_ffmpeg_queue_depth", "FFmpegPool jobs waiting for a free worker, by pool.")
+	for pool, value := range m.FFmpegQueueDepth.Snapshot() {
+		writeGaugeLine(&b, "medyan_ffmpeg_queue_depth", value, map[string]string{"pool": pool})
+	}
+
+	writeCounterHeader(&b, "medyan_errors_by_class_total", "Classified extraction errors (see internal/errclass), by platform and class.")
+	for key, count := range m.ErrorClassCounts.Snapshot() {
+		platform, class, ok := splitLabelKey(key)
+		if !ok {
+			continue
+		}
+		writeCounterLine(&b, "medyan_errors_by_class_total", float64(count), map[string]string{"platform": platform, "class": class})
+	}
+
+	writeCounterHeader(&b, "medyan_job_failures_by_reason_total", "Failed extraction jobs (see internal/errors.Classify), by platform and reason.")
+	for key, count := range m.JobFailureReasons.Snapshot() {
+		platform, reason, ok := splitLabelKey(key)
+		if !ok {
+			continue
+		}
+		writeCounterLine(&b, "medyan_job_failures_by_reason_total", float64(count), map[string]string{"platform": platform, "reason": reason})
+	}
+
+	writeCounterHeader(&b, "medyan_dedup_hits_total", "Extraction requests served from a prior job's cached result, by platform.")
+	for platform, count := range m.DedupHits.Snapshot() {
+		writeCounterLine(&b, "medyan_dedup_hits_total", float64(count), map[string]string{"platform": platform})
+	}
+
+	writeGaugeHeader(&b, "medyan_circuit_state", "Per-breaker circuit state (see internal/circuitbreaker.Registry): 0=closed, 1=open, 2=half-open.")
+	for name, value := range m.CircuitState.Snapshot() {
+		writeGaugeLine(&b, "medyan_circuit_state", value, map[string]string{"name": name})
+	}
+	writeCounterHeader(&b, "medyan_circuit_trips_total", "Circuit breakers that have tripped open, by breaker name.")
+	for name, count := range m.CircuitTrips.Snapshot() {
+		writeCounterLine(&b, "medyan_circuit_trips_total", float64(count), map[string]string{"name": name})
+	}
+	writeCounterHeader(&b, "medyan_circuit_rejected_total", "Requests rejected outright by an open or saturated circuit breaker, by breaker name.")
+	for name, count := range m.CircuitRejected.Snapshot() {
+		writeCounterLine(&b, "medyan_circuit_rejected_total", float64(count), map[string]string{"name": name})
+	}
+
+	writeGaugeHeader(&b, "medyan_live_stream_active", "Whether the most recent Download for a platform saw a live/post-live stream (1) or not (0).")
+	for platform, value := range m.LiveStreamActive.Snapshot() {
+		writeGaugeLine(&b, "medyan_live_stream_active", value, map[string]string{"platform": platform})
+	}
+
+	writeGauge(&b, "medyan_disk_free_bytes", "Free space on the temp directory's filesystem, as of the last cleanup pass.", float64(m.DiskFreeBytes.Load()))
+	writeGauge(&b, "medyan_temp_files_count", "Files under the temp directory, as of the last cleanup pass.", float64(m.TempFilesCount.Load()))
+	writeCounter(&b, "medyan_emergency_sweeps_total", "Emergency temp file sweeps triggered by low disk space.", float64(m.EmergencySweepsTotal.Load()))
+
+	return b.String()
+}
+
+func writeCounter(b *strings.Builder, name, help string, value float64, labels ...map[string]string) {
+	writeCounterHeader(b, name, help)
+	fmt.Fprintf(b, "%s%s %s\n", name, labelSuffix(labels...), formatFloat(value))
+}
+
+// writeCounterHeader emits a counter's HELP/TYPE pair. Call once per metric
+// name, then writeCounterLine per label series, to stay exposition-format
+// compliant when rendering multiple series (e.g. one per platform).
+func writeCounterHeader(b *strings.Builder, name, help string) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+}
+
+func writeCounterLine(b *strings.Builder, name string, value float64, labels map[string]string) {
+	fmt.Fprintf(b, "%s%s %s\n", name, labelSuffix(labels), formatFloat(value))
+}
+
+func writeGauge(b *strings.Builder, name, help string, value float64, labels ...map[string]string) {
+	writeGaugeHeader(b, name, help)
+	writeGaugeLine(b, name, value, mergeLabelList(labels))
+}
+
+// writeGaugeHeader emits a gauge's HELP/TYPE pair. Call once per metric
+// name, then writeGaugeLine per label series (see writeCounterHeader).
+func writeGaugeHeader(b *strings.Builder, name, help string) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+}
+
+func writeGaugeLine(b *strings.Builder, name string, value float64, labels map[string]string) {
+	fmt.Fprintf(b, "%s%s %s\n", name, labelSuffix(labels), formatFloat(value))
+}
+
+// mergeLabelList folds a ...map[string]string variadic into one map.
+func mergeLabelList(labels []map[string]string) map[string]string {
+	merged := map[string]string{}
+	for _, lm := range labels {
+		merged = mergeLabels(merged, lm)
+	}
+	return merged
+}
+
+// writeHistogram renders a single (optionally labeled) Prometheus histogram:
+// cumulative _bucket lines, then _sum and _count.
+func writeHistogram(b *strings.Builder, name, help string, labels map[string]string, h *Histogram) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s histogram\n", name, help, name)
+	writeHistogramSeries(b, name, labels, h)
+}
+
+// writeLabeledHistogram renders one histogram series per label value
+// tracked by lh, sharing a single HELP/TYPE header.
+func writeLabeledHistogram(b *strings.Builder, name, help, labelName string, lh *LabeledHistogram) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s histogram\n", name, help, name)
+	lh.forEach(func(label string, h *Histogram) {
+		writeHistogramSeries(b, name, map[string]string{labelName: label}, h)
+	})
+}
+
+func writeHistogramSeries(b *strings.Builder, name string, labels map[string]string, h *Histogram) {
+	cumulative, total, sum := h.cumulativeCounts()
+
+	for i, bound := range h.bounds {
+		bucketLabels := mergeLabels(labels, map[string]string{"le": formatFloat(bound)})
+		fmt.Fprintf(b, "%s%s %d\n", name+"_bucket", labelSuffix(bucketLabels), cumulative[i])
+	}
+	infLabels := mergeLabels(labels, map[string]string{"le": "+Inf"})
+	fmt.Fprintf(b, "%s%s %d\n", name+"_bucket", labelSuffix(infLabels), total)
+	fmt.Fprintf(b, "%s%s %s\n", name+"_sum", labelSuffix(labels), formatFloat(sum))
+	fmt.Fprintf(b, "%s%s %d\n", name+"_count", labelSuffix(labels), total)
+}
+
+func mergeLabels(base, extra map[string]string) map[string]string {
+	merged := make(map[string]string, len(base)+len(extra))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range extra {
+		merged[k] = v
+	}
+	return merged
+}
+
+// labelSuffix renders {k="v",...} for exposition format, with keys sorted
+// for deterministic output. Returns "" for no labels.
+func labelSuffix(labelMaps ...map[string]string) string {
+	merged := map[string]string{}
+	for _, lm := range labelMaps {
+		for k, v := range lm {
+			merged[k] = v
+		}
+	}
+	if len(merged) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(merged))
+	for k := range merged {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", k, merged[k]))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
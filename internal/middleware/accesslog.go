@@ -0,0 +1,199 @@
+package middleware
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+// AccessLogConfig holds access log middleware configuration
+type AccessLogConfig struct {
+	// LogBodies enables capturing a size-capped snapshot of the request and
+	// response bodies alongside the rest of the access log fields.
+	LogBodies bool
+
+	// MaxBodyLogBytes caps how many bytes of each body are logged. Bodies
+	// longer than this are truncated before being attached to the log entry,
+	// not before being read off the wire, so this bounds logging overhead
+	// rather than the request/response size itself.
+	MaxBodyLogBytes int
+
+	// RedactHeaders lists header names (case-insensitive) whose values are
+	// replaced with "***" before logging.
+	RedactHeaders []string
+
+	// RedactFields lists JSON object keys (case-insensitive, matched at any
+	// nesting depth) whose values are replaced with "***" when logging a
+	// captured body.
+	RedactFields []string
+
+	// Next defines a function to skip this middleware for a given request,
+	// e.g. health checks and download endpoints that would otherwise spam
+	// the access log or attempt to buffer large binary bodies.
+	Next func(c *fiber.Ctx) bool
+}
+
+// DefaultAccessLogConfig returns sensible defaults: no body logging, the
+// common sensitive headers redacted, and the routes that would otherwise
+// spam the log or leak binary downloads excluded.
+func DefaultAccessLogConfig() AccessLogConfig {
+	return AccessLogConfig{
+		LogBodies:       false,
+		MaxBodyLogBytes: 4096,
+		RedactHeaders:   []string{"Authorization", "Cookie", "Set-Cookie", "X-Api-Key"},
+		RedactFields:    []string{"password", "secret", "token", "access_token", "refresh_token"},
+		Next: func(c *fiber.Ctx) bool {
+			path := c.Path()
+			return strings.HasPrefix(path, "/downloads/") ||
+				strings.HasPrefix(path, "/debug/pprof/") ||
+				strings.HasPrefix(path, "/debug/logs") ||
+				path == "/health" ||
+				path == "/metrics"
+		},
+	}
+}
+
+// AccessLogMiddleware emits one structured zap log per request: method,
+// path, query, status, latency, bytes in/out, remote IP, and user-agent.
+// When cfg.LogBodies is set, it also attaches size-capped, redacted
+// snapshots of the request and response bodies and the request headers.
+func AccessLogMiddleware(logger *zap.Logger, config ...AccessLogConfig) fiber.Handler {
+	cfg := DefaultAccessLogConfig()
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.MaxBodyLogBytes <= 0 {
+		cfg.MaxBodyLogBytes = 4096
+	}
+
+	redactHeaders := make(map[string]struct{}, len(cfg.RedactHeaders))
+	for _, h := range cfg.RedactHeaders {
+		redactHeaders[strings.ToLower(h)] = struct{}{}
+	}
+
+	redactFields := make(map[string]struct{}, len(cfg.RedactFields))
+	for _, f := range cfg.RedactFields {
+		redactFields[strings.ToLower(f)] = struct{}{}
+	}
+
+	return func(c *fiber.Ctx) error {
+		if cfg.Next != nil && cfg.Next(c) {
+			return c.Next()
+		}
+
+		start := time.Now()
+
+		var reqBody []byte
+		if cfg.LogBodies {
+			reqBody = capBytes(c.Body(), cfg.MaxBodyLogBytes)
+		}
+
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		fields := []zap.Field{
+			zap.String("method", c.Method()),
+			zap.String("path", c.Path()),
+			zap.String("query", string(c.Request().URI().QueryString())),
+			zap.Int("status", status),
+			zap.Duration("latency", time.Since(start)),
+			zap.Int("bytes_in", len(c.Body())),
+			zap.Int("bytes_out", len(c.Response().Body())),
+			zap.String("remote_ip", c.IP()),
+			zap.String("user_agent", c.Get("User-Agent")),
+		}
+
+		if cfg.LogBodies {
+			fields = append(fields,
+				zap.Any("headers", redactHeaderMap(c, redactHeaders)),
+				zap.String("request_body", redactBody(reqBody, redactFields)),
+				zap.String("response_body", redactBody(capBytes(c.Response().Body(), cfg.MaxBodyLogBytes), redactFields)),
+			)
+		}
+
+		level := zap.InfoLevel
+		if status >= 500 {
+			level = zap.ErrorLevel
+		} else if status >= 400 {
+			level = zap.WarnLevel
+		}
+
+		if ce := logger.Check(level, "HTTP request"); ce != nil {
+			ce.Write(fields...)
+		}
+
+		return err
+	}
+}
+
+// redactHeaderMap snapshots the request headers, replacing any value whose
+// header name is in redact with "***".
+func redactHeaderMap(c *fiber.Ctx, redact map[string]struct{}) map[string]string {
+	headers := make(map[string]string)
+	c.Request().Header.VisitAll(func(key, value []byte) {
+		name := string(key)
+		if _, ok := redact[strings.ToLower(name)]; ok {
+			headers[name] = "***"
+			return
+		}
+		headers[name] = string(value)
+	})
+	return headers
+}
+
+// capBytes truncates b to at most max bytes without allocating when b
+// already fits.
+func capBytes(b []byte, max int) []byte {
+	if max <= 0 || len(b) <= max {
+		return b
+	}
+	return b[:max]
+}
+
+// redactBody renders a captured body as a string, masking any JSON object
+// key listed in fields. Bodies that aren't valid JSON (including ones
+// truncated mid-token by capBytes) are logged verbatim since there's
+// nothing structured to redact.
+func redactBody(body []byte, fields map[string]struct{}) string {
+	if len(body) == 0 {
+		return ""
+	}
+	if len(fields) == 0 {
+		return string(body)
+	}
+
+	var parsed interface{}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return string(body)
+	}
+
+	redactValue(parsed, fields)
+
+	out, err := json.Marshal(parsed)
+	if err != nil {
+		return string(body)
+	}
+	return string(out)
+}
+
+// redactValue walks a decoded JSON value in place, replacing the value of
+// any object key present in fields with "***".
+func redactValue(v interface{}, fields map[string]struct{}) {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, child := range val {
+			if _, ok := fields[strings.ToLower(k)]; ok {
+				val[k] = "***"
+				continue
+			}
+			redactValue(child, fields)
+		}
+	case []interface{}:
+		for _, item := range val {
+			redactValue(item, fields)
+		}
+	}
+}
@@ -1,12 +1,20 @@
 package middleware
 
 import (
-	"crypto/md5"
-	"encoding/hex"
+	"bytes"
+	"container/list"
+	"context"
+	"io"
 	"strconv"
+	"sync"
 	"time"
 
+	"github.com/cespare/xxhash/v2"
 	"github.com/gofiber/fiber/v2"
+
+	"github.com/KeremKalyoncu/MedYan/internal/cache"
+	"github.com/KeremKalyoncu/MedYan/internal/dedup"
+	"github.com/KeremKalyoncu/MedYan/internal/pool"
 )
 
 // CacheConfig holds cache middleware configuration
@@ -19,6 +27,10 @@ type CacheConfig struct {
 	MustRevalidate bool
 	// NoTransform prevents proxies from modifying response
 	NoTransform bool
+	// Store is the two-layer response cache backing this middleware. Nil
+	// (the default) falls back to defaultResponseCache, an in-memory-only
+	// LRU - set this to share a ResponseCache wired to Redis across routes.
+	Store *ResponseCache
 }
 
 // DefaultCacheConfig returns sensible defaults
@@ -31,13 +43,151 @@ func DefaultCacheConfig() CacheConfig {
 	}
 }
 
-// CacheMiddleware adds ETag and cache headers for conditional requests
-// Supports 304 Not Modified responses to save bandwidth
+// cachedResponse is one entry in a ResponseCache: a full serialized
+// response plus enough metadata to replay it byte-for-byte on a hit and
+// answer conditional requests without re-invoking the handler.
+type cachedResponse struct {
+	Body        []byte `json:"body"`
+	ETag        string `json:"etag"`
+	ContentType string `json:"content_type"`
+	Status      int    `json:"status"`
+}
+
+// responseCacheEntry is the in-memory LRU's list.Element payload.
+type responseCacheEntry struct {
+	key      string
+	value    cachedResponse
+	expireAt time.Time
+}
+
+// ResponseCache is a two-layer cache for CacheMiddleware: an in-memory LRU
+// (fast, process-local) in front of an optional Redis-backed CacheManager
+// (shared, survives restarts, so every API instance serves the same cached
+// body instead of each one regenerating it independently). A
+// dedup.Singleflight collapses concurrent MISSes for the same key into one
+// upstream c.Next() call, so a stampede of requests for a just-expired key
+// doesn't all regenerate it at once.
+type ResponseCache struct {
+	maxEntries int
+	redis      *cache.CacheManager
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+
+	flight *dedup.Singleflight
+}
+
+// NewResponseCache creates a ResponseCache holding at most maxEntries in
+// memory, optionally backed by redisCache for cross-instance sharing (nil
+// disables the Redis layer, leaving a purely in-process cache).
+func NewResponseCache(maxEntries int, redisCache *cache.CacheManager) *ResponseCache {
+	return &ResponseCache{
+		maxEntries: maxEntries,
+		redis:      redisCache,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+		flight:     dedup.NewSingleflight(),
+	}
+}
+
+// defaultResponseCache backs CacheMiddleware/ConditionalCacheMiddleware
+// calls that don't set CacheConfig.Store - in-memory only, no Redis layer.
+var defaultResponseCache = NewResponseCache(1000, nil)
+
+// get checks the in-memory LRU, then falls back to Redis, promoting a
+// Redis hit back into the LRU so subsequent requests on this instance stay
+// local.
+func (rc *ResponseCache) get(ctx context.Context, key string) (cachedResponse, bool) {
+	rc.mu.Lock()
+	if elem, ok := rc.entries[key]; ok {
+		entry := elem.Value.(*responseCacheEntry)
+		if time.Now().Before(entry.expireAt) {
+			rc.order.MoveToFront(elem)
+			value := entry.value
+			rc.mu.Unlock()
+			return value, true
+		}
+		// Expired - drop it rather than serve stale data.
+		rc.order.Remove(elem)
+		delete(rc.entries, key)
+	}
+	rc.mu.Unlock()
+
+	if rc.redis == nil {
+		return cachedResponse{}, false
+	}
+
+	var value cachedResponse
+	if err := rc.redis.Get(ctx, key, &value); err != nil {
+		return cachedResponse{}, false
+	}
+
+	ttl, err := rc.redis.GetTTL(ctx, key)
+	if err != nil || ttl <= 0 {
+		ttl = time.Minute
+	}
+	rc.setLocal(key, value, ttl)
+	return value, true
+}
+
+// set stores value in both cache layers with the given ttl.
+func (rc *ResponseCache) set(ctx context.Context, key string, value cachedResponse, ttl time.Duration) {
+	rc.setLocal(key, value, ttl)
+	if rc.redis != nil {
+		_ = rc.redis.Set(ctx, key, value, ttl)
+	}
+}
+
+func (rc *ResponseCache) setLocal(key string, value cachedResponse, ttl time.Duration) {
+	if ttl <= 0 {
+		ttl = time.Second
+	}
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	if elem, ok := rc.entries[key]; ok {
+		rc.order.MoveToFront(elem)
+		elem.Value.(*responseCacheEntry).value = value
+		elem.Value.(*responseCacheEntry).expireAt = time.Now().Add(ttl)
+		return
+	}
+
+	elem := rc.order.PushFront(&responseCacheEntry{key: key, value: value, expireAt: time.Now().Add(ttl)})
+	rc.entries[key] = elem
+
+	for rc.order.Len() > rc.maxEntries {
+		back := rc.order.Back()
+		if back == nil {
+			break
+		}
+		rc.order.Remove(back)
+		delete(rc.entries, back.Value.(*responseCacheEntry).key)
+	}
+}
+
+// cacheKey derives a lookup key from the request's method, path, query
+// string and Vary-relevant headers, so e.g. gzip and identity encodings of
+// the same URL don't collide.
+func cacheKey(c *fiber.Ctx) string {
+	return c.Method() + " " + c.OriginalURL() + " accept-encoding=" + c.Get("Accept-Encoding")
+}
+
+// CacheMiddleware adds ETag and cache headers for conditional requests,
+// backed by a ResponseCache so repeat requests are served without
+// re-invoking the handler at all (not just saving bandwidth via 304s).
+// Supports 304 Not Modified responses when the cached body is still fresh
+// but the client already has it.
 func CacheMiddleware(config ...CacheConfig) fiber.Handler {
 	cfg := DefaultCacheConfig()
 	if len(config) > 0 {
 		cfg = config[0]
 	}
+	store := cfg.Store
+	if store == nil {
+		store = defaultResponseCache
+	}
 
 	return func(c *fiber.Ctx) error {
 		// Skip for non-GET requests
@@ -45,76 +195,96 @@ func CacheMiddleware(config ...CacheConfig) fiber.Handler {
 			return c.Next()
 		}
 
-		// Store original send function
-		originalSend := c.Response().BodyWriter()
-
-		// Capture response body
-		var responseBody []byte
-		var statusCode int
+		key := cacheKey(c)
+		ttl := time.Duration(cfg.MaxAge) * time.Second
 
-		// Continue processing
-		err := c.Next()
-		if err != nil {
-			return err
+		if cached, ok := store.get(c.Context(), key); ok {
+			return writeCachedResponse(c, cfg, cached)
 		}
 
-		// Get response data
-		responseBody = c.Response().Body()
-		statusCode = c.Response().StatusCode()
-
-		// Only cache successful responses
-		if statusCode < 200 || statusCode >= 300 {
-			return nil
-		}
-
-		// Generate ETag from response body
-		etag := generateETag(responseBody)
-
-		// Set ETag header
-		c.Set("ETag", etag)
+		// MISS: singleflight collapses concurrent requests for the same key
+		// into one c.Next() call instead of each one regenerating the body.
+		result := store.flight.DoContext(c.Context(), key, func() (interface{}, error) {
+			if err := c.Next(); err != nil {
+				return nil, err
+			}
 
-		// Set Last-Modified header (current time)
-		lastModified := time.Now().UTC().Format(time.RFC1123)
-		c.Set("Last-Modified", lastModified)
+			status := c.Response().StatusCode()
+			if status < 200 || status >= 300 {
+				return cachedResponse{}, nil
+			}
 
-		// Build Cache-Control header
-		cacheControl := buildCacheControl(cfg)
-		c.Set("Cache-Control", cacheControl)
+			body := c.Response().Body()
+			cached := cachedResponse{
+				Body:        append([]byte(nil), body...),
+				ETag:        generateETag(body),
+				ContentType: string(c.Response().Header.ContentType()),
+				Status:      status,
+			}
+			store.set(c.Context(), key, cached, ttl)
+			return cached, nil
+		})
 
-		// Check if client has cached version
-		clientETag := c.Get("If-None-Match")
-		clientModifiedSince := c.Get("If-Modified-Since")
+		if result.Err != nil {
+			return result.Err
+		}
 
-		// ETag match - return 304 Not Modified
-		if clientETag != "" && clientETag == etag {
-			c.Status(fiber.StatusNotModified)
-			c.Response().SetBodyRaw(nil)
+		cached, ok := result.Val.(cachedResponse)
+		if !ok || len(cached.Body) == 0 {
+			// Non-cacheable response (error status, etc.) - whichever
+			// goroutine ran c.Next() already wrote it to its own Ctx.
 			return nil
 		}
 
-		// If-Modified-Since check
-		if clientModifiedSince != "" {
-			clientTime, err := time.Parse(time.RFC1123, clientModifiedSince)
-			if err == nil {
-				serverTime, _ := time.Parse(time.RFC1123, lastModified)
-				if !serverTime.After(clientTime) {
-					c.Status(fiber.StatusNotModified)
-					c.Response().SetBodyRaw(nil)
-					return nil
-				}
-			}
+		if result.Shared {
+			// A sibling request executed c.Next(); this goroutine never
+			// touched c.Response(), so replay the shared result onto it.
+			return writeCachedResponse(c, cfg, cached)
 		}
 
-		// Response is fresh, send it normally
-		_, _ = originalSend.Write(responseBody)
+		// This goroutine ran c.Next() itself - the body is already in
+		// c.Response(), only the cache headers still need setting.
+		applyCacheHeaders(c, cfg, cached.ETag)
+		return nil
+	}
+}
+
+// writeCachedResponse replays a cached entry onto c, honoring conditional
+// request headers the same way a live response would.
+func writeCachedResponse(c *fiber.Ctx, cfg CacheConfig, cached cachedResponse) error {
+	applyCacheHeaders(c, cfg, cached.ETag)
+
+	if clientETag := c.Get("If-None-Match"); clientETag != "" && clientETag == cached.ETag {
+		c.Status(fiber.StatusNotModified)
 		return nil
 	}
+
+	c.Status(cached.Status)
+	if cached.ContentType != "" {
+		c.Set("Content-Type", cached.ContentType)
+	}
+	return c.Send(cached.Body)
+}
+
+// applyCacheHeaders sets the headers shared by cache hits and freshly
+// generated responses.
+func applyCacheHeaders(c *fiber.Ctx, cfg CacheConfig, etag string) {
+	c.Set("ETag", etag)
+	c.Set("Last-Modified", time.Now().UTC().Format(time.RFC1123))
+	c.Set("Cache-Control", buildCacheControl(cfg))
 }
 
-// generateETag creates ETag from response body using MD5 hash
+// generateETag creates a weak ETag from the response body using xxhash
+// (cryptographic strength isn't needed for a cache validator, and xxhash
+// is far faster than MD5 on large bodies), computed incrementally through
+// an io.Writer with a pooled copy buffer instead of handing the hasher a
+// second allocated copy of body.
 func generateETag(body []byte) string {
-	hash := md5.Sum(body)
-	return `"` + hex.EncodeToString(hash[:]) + `"`
+	h := xxhash.New()
+	buf := pool.SmallSlicePool.Get()
+	defer pool.SmallSlicePool.Put(buf)
+	_, _ = io.CopyBuffer(h, bytes.NewReader(body), buf)
+	return `W/"` + strconv.FormatUint(h.Sum64(), 16) + `"`
 }
 
 // buildCacheControl constructs Cache-Control header value
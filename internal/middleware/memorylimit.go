@@ -4,6 +4,7 @@ import (
 	"runtime"
 	"runtime/debug"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -18,7 +19,9 @@ type MemoryLimitConfig struct {
 	// SoftLimitMB triggers GC but allows request to continue
 	SoftLimitMB int64
 
-	// CheckInterval how often to check memory usage during request
+	// CheckInterval how often the shared memory sampler refreshes its
+	// snapshot. Only the first MemoryLimitMiddleware registration's
+	// interval takes effect - the sampler goroutine is process-wide.
 	CheckInterval time.Duration
 
 	// Logger for memory warnings
@@ -38,6 +41,54 @@ func DefaultMemoryLimitConfig() MemoryLimitConfig {
 	}
 }
 
+const defaultMemSampleInterval = 500 * time.Millisecond
+
+// memSnapshot holds the most recently sampled runtime.MemStats, refreshed by
+// the shared sampler goroutine started by startMemSampler. init() seeds it
+// synchronously so readers never observe nil, even before any
+// MemoryLimitMiddleware is registered.
+var memSnapshot atomic.Pointer[runtime.MemStats]
+
+var samplerOnce sync.Once
+
+func init() {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	memSnapshot.Store(&m)
+}
+
+// startMemSampler lazily launches the single process-wide goroutine that
+// periodically refreshes memSnapshot via runtime.ReadMemStats, so request
+// handling never has to pay for that stop-the-world call directly. Safe to
+// call repeatedly (e.g. once per MemoryLimitMiddleware registration) - only
+// the first call's interval takes effect.
+func startMemSampler(interval time.Duration) {
+	samplerOnce.Do(func() {
+		if interval <= 0 {
+			interval = defaultMemSampleInterval
+		}
+		go func() {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for range ticker.C {
+				var m runtime.MemStats
+				runtime.ReadMemStats(&m)
+				memSnapshot.Store(&m)
+			}
+		}()
+	})
+}
+
+// allocDeltaMB estimates the MB of heap growth between two Alloc samples,
+// floored at 0 since a GC between samples can make final appear smaller
+// than initial even though the request itself only allocated.
+func allocDeltaMB(initial, final uint64) int64 {
+	if final <= initial {
+		return 0
+	}
+	return int64(final-initial) / 1024 / 1024
+}
+
 // MemoryLimitMiddleware tracks memory usage during requests
 // Prevents individual requests from consuming too much memory
 func MemoryLimitMiddleware(config ...MemoryLimitConfig) fiber.Handler {
@@ -46,12 +97,7 @@ func MemoryLimitMiddleware(config ...MemoryLimitConfig) fiber.Handler {
 		cfg = config[0]
 	}
 
-	// Memory tracking pool
-	memStatsPool := sync.Pool{
-		New: func() interface{} {
-			return &runtime.MemStats{}
-		},
-	}
+	startMemSampler(cfg.CheckInterval)
 
 	return func(c *fiber.Ctx) error {
 		// Skip if Next returns true
@@ -59,14 +105,7 @@ func MemoryLimitMiddleware(config ...MemoryLimitConfig) fiber.Handler {
 			return c.Next()
 		}
 
-		// Get initial memory state
-		memStats := memStatsPool.Get().(*runtime.MemStats)
-		defer memStatsPool.Put(memStats)
-
-		runtime.ReadMemStats(memStats)
-		initialAlloc := memStats.Alloc
-
-		// Store in context for other handlers
+		initialAlloc := memSnapshot.Load().Alloc
 		c.Locals("mem_initial_alloc", initialAlloc)
 
 		// Process request
@@ -74,19 +113,27 @@ func MemoryLimitMiddleware(config ...MemoryLimitConfig) fiber.Handler {
 			return err
 		}
 
-		// Check final memory usage
-		runtime.ReadMemStats(memStats)
-		finalAlloc := memStats.Alloc
-		requestMemory := int64(finalAlloc - initialAlloc)
-		requestMemoryMB := requestMemory / 1024 / 1024
+		// The periodic sample is cheap but coarse: estimate the delta from
+		// it first, and only pay for a synchronous ReadMemStats when the
+		// estimate suggests we're near the soft threshold.
+		requestMemoryMB := allocDeltaMB(initialAlloc, memSnapshot.Load().Alloc)
+		if requestMemoryMB > cfg.SoftLimitMB {
+			var m runtime.MemStats
+			runtime.ReadMemStats(&m)
+			requestMemoryMB = allocDeltaMB(initialAlloc, m.Alloc)
+		}
 
 		// Log high memory usage
-		if requestMemoryMB > cfg.SoftLimitMB && cfg.Logger != nil {
-			cfg.Logger.Warn("High memory usage during request",
-				zap.String("path", c.Path()),
-				zap.Int64("memory_mb", requestMemoryMB),
-				zap.Int64("soft_limit_mb", cfg.SoftLimitMB),
-			)
+		if requestMemoryMB > cfg.SoftLimitMB {
+			if cfg.Logger != nil {
+				if ce := cfg.Logger.Check(zap.WarnLevel, "High memory usage during request"); ce != nil {
+					ce.Write(
+						zap.String("path", c.Path()),
+						zap.Int64("memory_mb", requestMemoryMB),
+						zap.Int64("soft_limit_mb", cfg.SoftLimitMB),
+					)
+				}
+			}
 
 			// Trigger GC for soft limit
 			runtime.GC()
@@ -94,22 +141,23 @@ func MemoryLimitMiddleware(config ...MemoryLimitConfig) fiber.Handler {
 		}
 
 		if requestMemoryMB > cfg.MaxMemoryMB && cfg.Logger != nil {
-			cfg.Logger.Error("Memory limit exceeded",
-				zap.String("path", c.Path()),
-				zap.Int64("memory_mb", requestMemoryMB),
-				zap.Int64("max_limit_mb", cfg.MaxMemoryMB),
-			)
+			if ce := cfg.Logger.Check(zap.ErrorLevel, "Memory limit exceeded"); ce != nil {
+				ce.Write(
+					zap.String("path", c.Path()),
+					zap.Int64("memory_mb", requestMemoryMB),
+					zap.Int64("max_limit_mb", cfg.MaxMemoryMB),
+				)
+			}
 		}
 
 		return nil
 	}
 }
 
-// GetMemoryUsage returns current memory usage in MB
+// GetMemoryUsage returns current memory usage in MB, read from the shared
+// sampler's last snapshot rather than calling runtime.ReadMemStats directly.
 func GetMemoryUsage() int64 {
-	var m runtime.MemStats
-	runtime.ReadMemStats(&m)
-	return int64(m.Alloc / 1024 / 1024)
+	return int64(memSnapshot.Load().Alloc / 1024 / 1024)
 }
 
 // ForceGC manually triggers garbage collection
@@ -1,102 +1,147 @@
 package middleware
 
 import (
-	"sync"
+	"context"
+	"strconv"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
 )
 
-// RateLimiter implements token bucket rate limiting
+// tokenBucketScript atomically refills and decrements a Redis-backed token
+// bucket in one round trip: HMGET tokens/last_refill -> refill if the
+// window has elapsed -> decrement if a token is available -> HMSET + expire.
+// Doing this in Lua keeps the check-then-decrement atomic across every API
+// replica sharing the same Redis, instead of each process keeping its own
+// in-memory bucket (which effectively multiplies the limit by replica
+// count - the problem this replaces).
+var tokenBucketScript = redis.NewScript(`
+local bucket_key = KEYS[1]
+local capacity = tonumber(ARGV[1])
+local window_ms = tonumber(ARGV[2])
+local now_ms = tonumber(ARGV[3])
+
+local bucket = redis.call("HMGET", bucket_key, "tokens", "last_refill")
+local tokens = tonumber(bucket[1])
+local last_refill = tonumber(bucket[2])
+
+if tokens == nil then
+	tokens = capacity
+	last_refill = now_ms
+end
+
+if now_ms - last_refill >= window_ms then
+	tokens = capacity
+	last_refill = now_ms
+end
+
+local allowed = 0
+if tokens > 0 then
+	allowed = 1
+	tokens = tokens - 1
+end
+
+redis.call("HMSET", bucket_key, "tokens", tokens, "last_refill", last_refill)
+redis.call("PEXPIRE", bucket_key, window_ms * 2)
+
+return {allowed, tokens, last_refill}
+`)
+
+// Policy is a named rate-limit tier: Requests allowed per Window, applied
+// independently per bucket key. Different routes - or the anonymous vs.
+// API-key tiers on the same route - pass different Policy values to the
+// same RateLimiter.
+type Policy struct {
+	Requests int
+	Window   time.Duration
+}
+
+// RateLimiter enforces Policy values against a Redis-backed token bucket,
+// so the limit holds across every API replica instead of being multiplied
+// per-process like the old in-memory map did.
 type RateLimiter struct {
-	clients map[string]*clientBucket
-	mu      sync.RWMutex
-	rate    int           // requests per window
-	window  time.Duration // time window
+	redis  *redis.Client
+	logger *zap.Logger
 }
 
-type clientBucket struct {
-	tokens     int
-	lastRefill time.Time
+// NewRateLimiter creates a rate limiter against redisClient. The caller
+// owns redisClient's lifecycle - it's expected to be the same client the
+// rest of the API already shares (e.g. queue.Client.GetRedis()).
+func NewRateLimiter(redisClient *redis.Client, logger *zap.Logger) *RateLimiter {
+	return &RateLimiter{redis: redisClient, logger: logger}
 }
 
-// NewRateLimiter creates a rate limiter (e.g., 100 requests per minute)
-func NewRateLimiter(requestsPerWindow int, window time.Duration) *RateLimiter {
-	rl := &RateLimiter{
-		clients: make(map[string]*clientBucket),
-		rate:    requestsPerWindow,
-		window:  window,
+// Middleware enforces policy against the requesting client's IP.
+func (rl *RateLimiter) Middleware(policy Policy) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return rl.enforce(c, "ip:"+c.IP(), policy)
 	}
-
-	// Cleanup goroutine - remove stale clients every 5 minutes
-	go rl.cleanup()
-
-	return rl
 }
 
-// Middleware returns Fiber middleware function
-func (rl *RateLimiter) Middleware() fiber.Handler {
+// MiddlewareWithAPIKeyTier enforces anonPolicy against the client's IP, or
+// keyPolicy against the presented API key (X-API-Key header or api_key
+// query parameter) when one is present - so authenticated callers get their
+// own bucket, typically at a higher tier, instead of sharing the anonymous
+// one.
+func (rl *RateLimiter) MiddlewareWithAPIKeyTier(anonPolicy, keyPolicy Policy) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		// Get client IP
-		clientIP := c.IP()
-
-		if !rl.allow(clientIP) {
-			return c.Status(429).JSON(fiber.Map{
-				"error":       "Rate limit exceeded",
-				"message":     "Too many requests. Please try again later.",
-				"retry_after": int(rl.window.Seconds()),
-			})
+		apiKey := c.Get("X-API-Key")
+		if apiKey == "" {
+			apiKey = c.Query("api_key")
 		}
-
-		return c.Next()
+		if apiKey != "" {
+			return rl.enforce(c, "key:"+apiKey, keyPolicy)
+		}
+		return rl.enforce(c, "ip:"+c.IP(), anonPolicy)
 	}
 }
 
-// allow checks if client can make a request
-func (rl *RateLimiter) allow(clientID string) bool {
-	rl.mu.Lock()
-	defer rl.mu.Unlock()
+func (rl *RateLimiter) enforce(c *fiber.Ctx, bucketKey string, policy Policy) error {
+	allowed, remaining, resetAt, err := rl.allow(c.Context(), bucketKey, policy)
+	if err != nil {
+		// Fail open: a Redis hiccup shouldn't take the API down with it.
+		rl.logger.Warn("Rate limiter check failed, allowing request", zap.String("bucket", bucketKey), zap.Error(err))
+		return c.Next()
+	}
 
-	now := time.Now()
+	c.Set("X-RateLimit-Limit", strconv.Itoa(policy.Requests))
+	c.Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
 
-	bucket, exists := rl.clients[clientID]
-	if !exists {
-		bucket = &clientBucket{
-			tokens:     rl.rate,
-			lastRefill: now,
+	if !allowed {
+		retryAfter := int(time.Until(resetAt).Seconds())
+		if retryAfter < 0 {
+			retryAfter = 0
 		}
-		rl.clients[clientID] = bucket
+		c.Set("Retry-After", strconv.Itoa(retryAfter))
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+			"error":       "Rate limit exceeded",
+			"message":     "Too many requests. Please try again later.",
+			"retry_after": retryAfter,
+		})
 	}
 
-	// Refill tokens based on time elapsed
-	elapsed := now.Sub(bucket.lastRefill)
-	if elapsed >= rl.window {
-		bucket.tokens = rl.rate
-		bucket.lastRefill = now
-	}
+	return c.Next()
+}
 
-	// Check if client has tokens
-	if bucket.tokens > 0 {
-		bucket.tokens--
-		return true
+// allow runs tokenBucketScript for bucketKey under policy, returning the
+// allow decision, tokens remaining, and the instant the window resets.
+func (rl *RateLimiter) allow(ctx context.Context, bucketKey string, policy Policy) (allowed bool, remaining int, resetAt time.Time, err error) {
+	now := time.Now()
+	windowMS := policy.Window.Milliseconds()
+
+	res, err := tokenBucketScript.Run(ctx, rl.redis, []string{"ratelimit:" + bucketKey},
+		policy.Requests, windowMS, now.UnixMilli()).Result()
+	if err != nil {
+		return false, 0, time.Time{}, err
 	}
 
-	return false
-}
+	vals := res.([]interface{})
+	allowedInt, _ := vals[0].(int64)
+	tokensLeft, _ := vals[1].(int64)
+	lastRefillMS, _ := vals[2].(int64)
 
-// cleanup removes stale client entries
-func (rl *RateLimiter) cleanup() {
-	ticker := time.NewTicker(5 * time.Minute)
-	defer ticker.Stop()
-
-	for range ticker.C {
-		rl.mu.Lock()
-		now := time.Now()
-		for clientID, bucket := range rl.clients {
-			if now.Sub(bucket.lastRefill) > 10*time.Minute {
-				delete(rl.clients, clientID)
-			}
-		}
-		rl.mu.Unlock()
-	}
+	resetAt = time.UnixMilli(lastRefillMS).Add(policy.Window)
+	return allowedInt == 1, int(tokensLeft), resetAt, nil
 }
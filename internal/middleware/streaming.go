@@ -2,9 +2,18 @@ package middleware
 
 import (
 	"bufio"
+	"encoding/json"
+	"fmt"
 	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
 	"os"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/KeremKalyoncu/MedYan/internal/pool"
 	"github.com/gofiber/fiber/v2"
@@ -56,10 +65,135 @@ func StreamingMiddleware(config ...StreamingConfig) fiber.Handler {
 	}
 }
 
-// StreamFile efficiently streams a file to the client using chunked encoding
-// This keeps memory usage constant regardless of file size
+// httpRange is one byte span parsed from a Range header, [Start, Start+Length).
+type httpRange struct {
+	Start  int64
+	Length int64
+}
+
+// fileETag builds a weak validator from a file's size and modification
+// time - cheap to compute without reading the (potentially multi-GB)
+// file, unlike CacheMiddleware's full-body xxhash.
+func fileETag(size int64, modTime time.Time) string {
+	return fmt.Sprintf(`W/"%x-%x"`, size, modTime.UnixNano())
+}
+
+// parseRangeHeader parses a "bytes=a-b,c-d" Range header against a
+// resource of the given size. Returns (nil, nil) when header is empty
+// (caller should serve the full body). Returns an error when every
+// requested range is unsatisfiable, so the caller can respond 416.
+func parseRangeHeader(header string, size int64) ([]httpRange, error) {
+	if header == "" {
+		return nil, nil
+	}
+	const prefix = "bytes="
+	if !strings.HasPrefix(header, prefix) {
+		return nil, fmt.Errorf("unsupported range unit")
+	}
+
+	var ranges []httpRange
+	for _, spec := range strings.Split(header[len(prefix):], ",") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+
+		dash := strings.IndexByte(spec, '-')
+		if dash < 0 {
+			return nil, fmt.Errorf("malformed range: %s", spec)
+		}
+		startStr, endStr := spec[:dash], spec[dash+1:]
+
+		var start, end int64
+		switch {
+		case startStr == "":
+			// Suffix range: "-N" means the last N bytes.
+			suffixLen, err := strconv.ParseInt(endStr, 10, 64)
+			if err != nil || suffixLen <= 0 {
+				return nil, fmt.Errorf("malformed suffix range: %s", spec)
+			}
+			if suffixLen > size {
+				suffixLen = size
+			}
+			start = size - suffixLen
+			end = size - 1
+		default:
+			s, err := strconv.ParseInt(startStr, 10, 64)
+			if err != nil || s < 0 {
+				return nil, fmt.Errorf("malformed range start: %s", spec)
+			}
+			start = s
+			if endStr == "" {
+				end = size - 1
+			} else {
+				e, err := strconv.ParseInt(endStr, 10, 64)
+				if err != nil || e < start {
+					return nil, fmt.Errorf("malformed range end: %s", spec)
+				}
+				end = e
+			}
+		}
+
+		if start >= size {
+			continue // unsatisfiable on its own - dropped, not fatal
+		}
+		if end >= size {
+			end = size - 1
+		}
+
+		ranges = append(ranges, httpRange{Start: start, Length: end - start + 1})
+	}
+
+	if len(ranges) == 0 {
+		return nil, fmt.Errorf("no satisfiable ranges in %q", header)
+	}
+
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].Start < ranges[j].Start })
+	return ranges, nil
+}
+
+// ifRangeMatches reports whether an If-Range validator still matches the
+// resource's current ETag/Last-Modified. An empty header means there was
+// no If-Range condition, i.e. the Range request should be honored as-is.
+func ifRangeMatches(header, etag string, modTime time.Time) bool {
+	if header == "" {
+		return true
+	}
+	if strings.HasPrefix(header, `"`) || strings.HasPrefix(header, `W/`) {
+		return header == etag
+	}
+	if t, err := http.ParseTime(header); err == nil {
+		return !modTime.Truncate(time.Second).After(t)
+	}
+	return false
+}
+
+// writeRangeHeaders sets the headers shared by every range/full response:
+// Accept-Ranges, ETag, Last-Modified.
+func writeRangeHeaders(c *fiber.Ctx, etag string, modTime time.Time) {
+	c.Set("Accept-Ranges", "bytes")
+	c.Set("ETag", etag)
+	c.Set("Last-Modified", modTime.UTC().Format(time.RFC1123))
+}
+
+// copyRange streams content[start, start+length) from src to w via the
+// shared medium buffer pool, seeking first.
+func copyRange(w io.Writer, src io.ReadSeeker, start, length int64) error {
+	if _, err := src.Seek(start, io.SeekStart); err != nil {
+		return err
+	}
+
+	buffer := pool.MediumSlicePool.Get()
+	defer pool.MediumSlicePool.Put(buffer)
+
+	_, err := io.CopyBuffer(w, io.LimitReader(src, length), buffer)
+	return err
+}
+
+// StreamFile efficiently streams a file to the client using chunked
+// encoding, honoring Range/If-Range for seeking and resumable downloads.
+// This keeps memory usage constant regardless of file size.
 func StreamFile(c *fiber.Ctx, filePath string, filename string) error {
-	// Open file
 	file, err := os.Open(filePath)
 	if err != nil {
 		return c.Status(404).JSON(fiber.Map{
@@ -68,7 +202,6 @@ func StreamFile(c *fiber.Ctx, filePath string, filename string) error {
 	}
 	defer file.Close()
 
-	// Get file info
 	stat, err := file.Stat()
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{
@@ -76,109 +209,351 @@ func StreamFile(c *fiber.Ctx, filePath string, filename string) error {
 		})
 	}
 
-	// Set headers for streaming download
-	c.Set("Content-Type", "application/octet-stream")
 	c.Set("Content-Disposition", "attachment; filename=\""+filename+"\"")
-	c.Set("Content-Length", strconv.FormatInt(stat.Size(), 10))
-	c.Set("Transfer-Encoding", "chunked")
-	c.Set("Cache-Control", "no-cache")
 	c.Set("X-Content-Type-Options", "nosniff")
-
-	// Stream file in chunks using fasthttp.StreamWriter
-	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
-		// Use buffer pool for zero-allocation streaming (64KB)
-		buffer := pool.MediumSlicePool.Get()
-		defer pool.MediumSlicePool.Put(buffer)
-
-		for {
-			n, err := file.Read(buffer)
-			if n > 0 {
-				w.Write(buffer[:n])
-				w.Flush()
-			}
-			if err == io.EOF {
-				break
-			}
-			if err != nil {
-				// Log error but can't return it at this point
-				break
-			}
-		}
-	})
-
-	return nil
+	return serveSeekable(c, file, stat.Size(), stat.ModTime(), "application/octet-stream")
 }
 
-// StreamReader streams data from an io.Reader to the client
-func StreamReader(c *fiber.Ctx, reader io.Reader, contentType string, filename string) error {
-	// Set headers
-	c.Set("Content-Type", contentType)
+// StreamReader streams data from an io.ReadSeeker to the client, honoring
+// Range/If-Range the same way StreamFile does. It requires a ReadSeeker
+// (rather than a bare io.Reader) because serving a range means seeking
+// into the content before streaming it.
+func StreamReader(c *fiber.Ctx, reader io.ReadSeeker, contentType string, filename string) error {
+	size, err := reader.Seek(0, io.SeekEnd)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to determine content length",
+		})
+	}
+	if _, err := reader.Seek(0, io.SeekStart); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to seek content",
+		})
+	}
+
 	if filename != "" {
 		c.Set("Content-Disposition", "attachment; filename=\""+filename+"\"")
 	}
-	c.Set("Transfer-Encoding", "chunked")
+	return serveSeekable(c, reader, size, time.Now(), contentType)
+}
+
+// serveSeekable implements the actual Range/If-Range/multipart-byteranges
+// logic shared by StreamFile and StreamReader.
+func serveSeekable(c *fiber.Ctx, content io.ReadSeeker, size int64, modTime time.Time, contentType string) error {
+	etag := fileETag(size, modTime)
+	writeRangeHeaders(c, etag, modTime)
+	c.Set("Content-Type", contentType)
 	c.Set("Cache-Control", "no-cache")
 
-	// Stream data using fasthttp.StreamWriter
+	rangeHeader := c.Get("Range")
+	if rangeHeader != "" && !ifRangeMatches(c.Get("If-Range"), etag, modTime) {
+		rangeHeader = "" // validator stale - serve the full, current body
+	}
+
+	ranges, err := parseRangeHeader(rangeHeader, size)
+	if err != nil {
+		c.Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+		return c.Status(fiber.StatusRequestedRangeNotSatisfiable).JSON(fiber.Map{
+			"error": "Range Not Satisfiable",
+		})
+	}
+
+	switch len(ranges) {
+	case 0:
+		c.Set("Content-Length", strconv.FormatInt(size, 10))
+		c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+			buffer := pool.MediumSlicePool.Get()
+			defer pool.MediumSlicePool.Put(buffer)
+			io.CopyBuffer(w, content, buffer)
+			w.Flush()
+		})
+		return nil
+
+	case 1:
+		r := ranges[0]
+		c.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", r.Start, r.Start+r.Length-1, size))
+		c.Set("Content-Length", strconv.FormatInt(r.Length, 10))
+		c.Status(fiber.StatusPartialContent)
+		c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+			copyRange(w, content, r.Start, r.Length)
+			w.Flush()
+		})
+		return nil
+
+	default:
+		return serveMultipartRanges(c, content, ranges, size, contentType)
+	}
+}
+
+// serveMultipartRanges writes a multipart/byteranges response for a
+// request naming more than one byte span, e.g. "Range: bytes=0-99,200-299".
+func serveMultipartRanges(c *fiber.Ctx, content io.ReadSeeker, ranges []httpRange, size int64, contentType string) error {
+	boundary := multipartBoundary()
+	c.Set("Content-Type", "multipart/byteranges; boundary="+boundary)
+	c.Status(fiber.StatusPartialContent)
+
 	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
-		// Use buffer pool for zero-allocation streaming (64KB)
-		buffer := pool.MediumSlicePool.Get()
-		defer pool.MediumSlicePool.Put(buffer)
+		mw := multipart.NewWriter(w)
+		mw.SetBoundary(boundary)
 
-		for {
-			n, err := reader.Read(buffer)
-			if n > 0 {
-				w.Write(buffer[:n])
-				w.Flush()
-			}
-			if err == io.EOF {
-				break
-			}
+		for _, r := range ranges {
+			header := textproto.MIMEHeader{}
+			header.Set("Content-Type", contentType)
+			header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", r.Start, r.Start+r.Length-1, size))
+
+			part, err := mw.CreatePart(header)
 			if err != nil {
-				break
+				return
+			}
+			if err := copyRange(part, content, r.Start, r.Length); err != nil {
+				return
 			}
 		}
+
+		mw.Close()
+		w.Flush()
 	})
 
 	return nil
 }
 
-// ChunkedResponse sends a chunked transfer encoded response
-// Useful for streaming large JSON arrays or CSV files
+// multipartBoundary returns a fixed boundary string. Unlike mail messages,
+// the response body here isn't user-controlled input reflected back, so a
+// random boundary isn't needed to prevent spoofing - a constant unlikely
+// to collide with media bytes is enough.
+func multipartBoundary() string {
+	return "MEDYAN-BYTERANGES-BOUNDARY"
+}
+
+// ChunkedResponse sends a chunked transfer encoded response, letting a
+// handler push data as it's produced instead of buffering the whole body
+// in memory first. Internally it bridges the synchronous Write/Flush/Close
+// API callers use to fasthttp's callback-based SetBodyStreamWriter via an
+// io.Pipe: a pooled buffer batches writes until it is full before each
+// flush, and the pipe itself supplies backpressure - Write blocks once the
+// buffer is full until the stream-writing goroutine has drained it.
 type ChunkedResponse struct {
-	ctx       *fiber.Ctx
-	started   bool
+	pw        *io.PipeWriter
+	done      <-chan struct{}
 	chunkSize int
-	buffer    []byte
-	bufferPos int
+
+	mu       sync.Mutex
+	buffer   []byte
+	bufLen   int
+	writeErr error
 }
 
-// NewChunkedResponse creates a new chunked response writer
+// NewChunkedResponse creates a new chunked response writer and immediately
+// registers fasthttp's stream writer, so bytes pushed via Write start
+// reaching the client as soon as the buffer fills or Flush is called.
 func NewChunkedResponse(c *fiber.Ctx, contentType string) *ChunkedResponse {
 	c.Set("Content-Type", contentType)
 	c.Set("Transfer-Encoding", "chunked")
 
-	return &ChunkedResponse{
-		ctx:       c,
+	pr, pw := io.Pipe()
+	done := make(chan struct{})
+
+	cr := &ChunkedResponse{
+		pw:        pw,
+		done:      done,
 		chunkSize: 64 * 1024,
-		buffer:    make([]byte, 64*1024),
+		buffer:    pool.MediumSlicePool.Get(),
 	}
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer close(done)
+		copyBuf := pool.MediumSlicePool.Get()
+		defer pool.MediumSlicePool.Put(copyBuf)
+
+		for {
+			select {
+			case <-c.Context().Done():
+				pr.CloseWithError(c.Context().Err())
+				return
+			default:
+			}
+
+			n, err := pr.Read(copyBuf)
+			if n > 0 {
+				if _, werr := w.Write(copyBuf[:n]); werr != nil {
+					pr.CloseWithError(werr)
+					return
+				}
+				if ferr := w.Flush(); ferr != nil {
+					pr.CloseWithError(ferr)
+					return
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	})
+
+	return cr
 }
 
-// Write writes data to the chunked response
+// Write appends data to the internal buffer, flushing to the client
+// whenever the buffer fills. Blocks if the client (or a disconnect) hasn't
+// drained a prior flush yet.
 func (cr *ChunkedResponse) Write(data []byte) error {
-	// TODO: Implement buffered chunked writing
-	// For now, this is a placeholder for the interface
-	return nil
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+
+	for len(data) > 0 {
+		if cr.writeErr != nil {
+			return cr.writeErr
+		}
+
+		n := copy(cr.buffer[cr.bufLen:], data)
+		cr.bufLen += n
+		data = data[n:]
+
+		if cr.bufLen == len(cr.buffer) {
+			cr.flushLocked()
+		}
+	}
+	return cr.writeErr
 }
 
-// Flush sends any buffered data
+// Flush sends any buffered data immediately instead of waiting for the
+// buffer to fill.
 func (cr *ChunkedResponse) Flush() error {
-	// TODO: Implement flush
-	return nil
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	cr.flushLocked()
+	return cr.writeErr
+}
+
+// flushLocked writes the buffered bytes to the pipe. Caller must hold
+// cr.mu. A write error (typically the client disconnecting) is recorded so
+// subsequent Write/Flush calls fail fast instead of blocking forever.
+func (cr *ChunkedResponse) flushLocked() {
+	if cr.bufLen == 0 || cr.writeErr != nil {
+		return
+	}
+	if _, err := cr.pw.Write(cr.buffer[:cr.bufLen]); err != nil {
+		cr.writeErr = err
+	}
+	cr.bufLen = 0
 }
 
-// Close closes the chunked response
+// Close flushes any remaining buffered data and closes the stream,
+// signaling EOF to the stream-writing goroutine so fasthttp finalizes the
+// response.
 func (cr *ChunkedResponse) Close() error {
-	return cr.Flush()
+	err := cr.Flush()
+
+	cr.mu.Lock()
+	pool.MediumSlicePool.Put(cr.buffer)
+	cr.buffer = nil
+	cr.mu.Unlock()
+
+	if closeErr := cr.pw.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// closingBracket returns the delimiter that matches open, for
+// JSONArrayStream/NDJSONStream-style writers that need to balance
+// whatever bracket the caller opened the stream with.
+func closingBracket(open string) string {
+	switch open {
+	case "[":
+		return "]"
+	case "{":
+		return "}"
+	default:
+		return ""
+	}
+}
+
+// JSONArrayStream streams a JSON array element-by-element over a
+// ChunkedResponse, without ever holding the full slice in memory - useful
+// for large extraction job listings.
+type JSONArrayStream struct {
+	cr      *ChunkedResponse
+	open    string
+	wrote   bool
+	firstEl bool
+}
+
+// NewJSONArrayStream creates a stream that opens the response body with
+// open (e.g. "[") and, on Close, writes the matching closing bracket.
+func NewJSONArrayStream(c *fiber.Ctx, open string) *JSONArrayStream {
+	return &JSONArrayStream{
+		cr:      NewChunkedResponse(c, "application/json"),
+		open:    open,
+		firstEl: true,
+	}
+}
+
+// Append marshals v and writes it as the next array element, inserting a
+// leading comma for every element after the first.
+func (s *JSONArrayStream) Append(v interface{}) error {
+	if !s.wrote {
+		if err := s.cr.Write([]byte(s.open)); err != nil {
+			return err
+		}
+		s.wrote = true
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	if !s.firstEl {
+		if err := s.cr.Write([]byte(",")); err != nil {
+			return err
+		}
+	}
+	s.firstEl = false
+
+	return s.cr.Write(data)
+}
+
+// Close writes the closing bracket (opening one too, if Append was never
+// called, so an empty listing still produces valid JSON) and closes the
+// underlying stream.
+func (s *JSONArrayStream) Close() error {
+	if !s.wrote {
+		if err := s.cr.Write([]byte(s.open)); err != nil {
+			return err
+		}
+	}
+	if err := s.cr.Write([]byte(closingBracket(s.open))); err != nil {
+		return err
+	}
+	return s.cr.Close()
+}
+
+// NDJSONStream streams newline-delimited JSON (one marshaled value per
+// line), the format consumed by most log/event pipelines without needing
+// an enclosing array at all.
+type NDJSONStream struct {
+	cr *ChunkedResponse
+}
+
+// NewNDJSONStream creates an NDJSON stream over c.
+func NewNDJSONStream(c *fiber.Ctx) *NDJSONStream {
+	return &NDJSONStream{cr: NewChunkedResponse(c, "application/x-ndjson")}
+}
+
+// Write marshals v and writes it as the next line.
+func (s *NDJSONStream) Write(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if err := s.cr.Write(data); err != nil {
+		return err
+	}
+	return s.cr.Write([]byte("\n"))
+}
+
+// Close closes the underlying stream.
+func (s *NDJSONStream) Close() error {
+	return s.cr.Close()
 }
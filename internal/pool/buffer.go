@@ -4,29 +4,131 @@ import (
 	"bytes"
 	"io"
 	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
 )
 
+// lowHitRateThreshold is the news/gets ratio above which warnIfLowHitRate
+// logs a suggestion to size the pool larger. A ratio this high means more
+// than a third of Gets are missing the pool entirely and allocating fresh.
+const lowHitRateThreshold = 0.35
+
+// hitRateCheckInterval is how often (in Gets) a pool recomputes its hit
+// rate, so the check is cheap relative to the Get/Put it rides along with.
+const hitRateCheckInterval = 500
+
+// metricsLogger is shared by every pool for the adaptive-sizing warning.
+// nil (the default) makes the warning a no-op, matching this package's
+// existing pattern of not requiring a logger to construct a pool.
+var metricsLogger *zap.Logger
+
+// SetLogger wires a logger used by every pool's adaptive-sizing check.
+// Optional - without one, a low hit rate is tracked in Snapshot() but
+// never logged.
+func SetLogger(logger *zap.Logger) {
+	metricsLogger = logger
+}
+
+// poolStats holds the atomic counters shared by every pool type in this
+// file, since sync.Pool itself exposes none of this (Get/Put/News are
+// opaque from the caller's side).
+type poolStats struct {
+	gets              atomic.Uint64
+	puts              atomic.Uint64
+	news              atomic.Uint64
+	discardsOversized atomic.Uint64
+	bytesInFlight     atomic.Int64
+}
+
+// PoolSnapshot is one pool's point-in-time utilization, returned by each
+// pool's Snapshot() method and aggregated by GetStats() for the /metrics
+// endpoint.
+type PoolSnapshot struct {
+	Gets              uint64  `json:"gets_total"`
+	Puts              uint64  `json:"puts_total"`
+	News              uint64  `json:"news_total"`
+	DiscardsOversized uint64  `json:"discards_oversized_total"`
+	BytesInFlight     int64   `json:"bytes_in_flight"`
+	HitRate           float64 `json:"hit_rate"`
+}
+
+func (s *poolStats) snapshot() PoolSnapshot {
+	gets := s.gets.Load()
+	news := s.news.Load()
+
+	hitRate := float64(1)
+	if gets > 0 {
+		hitRate = 1 - float64(news)/float64(gets)
+	}
+
+	return PoolSnapshot{
+		Gets:              gets,
+		Puts:              s.puts.Load(),
+		News:              news,
+		DiscardsOversized: s.discardsOversized.Load(),
+		BytesInFlight:     s.bytesInFlight.Load(),
+		HitRate:           hitRate,
+	}
+}
+
+// warnIfLowHitRate logs a suggestion to size name's pool larger once every
+// hitRateCheckInterval Gets, if the news/gets ratio crossed
+// lowHitRateThreshold. Checking on a stride instead of every Get keeps
+// this effectively free on the hot path.
+func (s *poolStats) warnIfLowHitRate(name string, currentSize int) {
+	if metricsLogger == nil {
+		return
+	}
+	gets := s.gets.Load()
+	if gets == 0 || gets%hitRateCheckInterval != 0 {
+		return
+	}
+
+	news := s.news.Load()
+	if float64(news)/float64(gets) <= lowHitRateThreshold {
+		return
+	}
+
+	metricsLogger.Warn("Pool hit rate is low, consider a larger default size",
+		zap.String("pool", name),
+		zap.Int("current_size", currentSize),
+		zap.Uint64("gets", gets),
+		zap.Uint64("news", news),
+	)
+}
+
 // BufferPool manages a pool of reusable byte buffers
 // This reduces GC pressure and memory allocations significantly
 type BufferPool struct {
-	pool sync.Pool
-	size int
+	pool  sync.Pool
+	size  int
+	name  string
+	stats poolStats
 }
 
 // NewBufferPool creates a new buffer pool with specified default size
 func NewBufferPool(size int) *BufferPool {
-	return &BufferPool{
-		size: size,
-		pool: sync.Pool{
-			New: func() interface{} {
-				return bytes.NewBuffer(make([]byte, 0, size))
-			},
+	return newNamedBufferPool("buffer", size)
+}
+
+func newNamedBufferPool(name string, size int) *BufferPool {
+	bp := &BufferPool{size: size, name: name}
+	bp.pool = sync.Pool{
+		New: func() interface{} {
+			bp.stats.news.Add(1)
+			return bytes.NewBuffer(make([]byte, 0, size))
 		},
 	}
+	return bp
 }
 
 // Get retrieves a buffer from the pool
 func (bp *BufferPool) Get() *bytes.Buffer {
+	bp.stats.gets.Add(1)
+	bp.stats.bytesInFlight.Add(int64(bp.size))
+	bp.stats.warnIfLowHitRate(bp.name, bp.size)
+
 	buf := bp.pool.Get().(*bytes.Buffer)
 	buf.Reset() // Clear any existing data
 	return buf
@@ -34,64 +136,97 @@ func (bp *BufferPool) Get() *bytes.Buffer {
 
 // Put returns a buffer to the pool for reuse
 func (bp *BufferPool) Put(buf *bytes.Buffer) {
+	bp.stats.bytesInFlight.Add(-int64(bp.size))
+
 	// Don't return extremely large buffers to pool (memory leak prevention)
 	if buf.Cap() > bp.size*10 {
+		bp.stats.discardsOversized.Add(1)
 		return // Let GC handle oversized buffers
 	}
+	bp.stats.puts.Add(1)
 	bp.pool.Put(buf)
 }
 
+// Snapshot returns bp's current utilization statistics.
+func (bp *BufferPool) Snapshot() PoolSnapshot {
+	return bp.stats.snapshot()
+}
+
 // ByteSlicePool manages a pool of reusable byte slices
 type ByteSlicePool struct {
-	pool sync.Pool
-	size int
+	pool  sync.Pool
+	size  int
+	name  string
+	stats poolStats
 }
 
 // NewByteSlicePool creates a new byte slice pool
 func NewByteSlicePool(size int) *ByteSlicePool {
-	return &ByteSlicePool{
-		size: size,
-		pool: sync.Pool{
-			New: func() interface{} {
-				slice := make([]byte, size)
-				return &slice
-			},
+	return newNamedByteSlicePool("slice", size)
+}
+
+func newNamedByteSlicePool(name string, size int) *ByteSlicePool {
+	bsp := &ByteSlicePool{size: size, name: name}
+	bsp.pool = sync.Pool{
+		New: func() interface{} {
+			bsp.stats.news.Add(1)
+			slice := make([]byte, size)
+			return &slice
 		},
 	}
+	return bsp
 }
 
 // Get retrieves a byte slice from the pool
 func (bsp *ByteSlicePool) Get() []byte {
+	bsp.stats.gets.Add(1)
+	bsp.stats.bytesInFlight.Add(int64(bsp.size))
+	bsp.stats.warnIfLowHitRate(bsp.name, bsp.size)
+
 	slicePtr := bsp.pool.Get().(*[]byte)
 	return (*slicePtr)[:bsp.size]
 }
 
 // Put returns a byte slice to the pool
 func (bsp *ByteSlicePool) Put(slice []byte) {
+	bsp.stats.bytesInFlight.Add(-int64(bsp.size))
+
 	if cap(slice) < bsp.size || cap(slice) > bsp.size*2 {
+		bsp.stats.discardsOversized.Add(1)
 		return // Don't pool wrong-sized slices
 	}
+	bsp.stats.puts.Add(1)
 	bsp.pool.Put(&slice)
 }
 
+// Snapshot returns bsp's current utilization statistics.
+func (bsp *ByteSlicePool) Snapshot() PoolSnapshot {
+	return bsp.stats.snapshot()
+}
+
 // ReaderPool manages a pool of io.Reader wrappers
 type ReaderPool struct {
-	pool sync.Pool
+	pool  sync.Pool
+	stats poolStats
 }
 
 // NewReaderPool creates a new reader pool
 func NewReaderPool() *ReaderPool {
-	return &ReaderPool{
-		pool: sync.Pool{
-			New: func() interface{} {
-				return &bytes.Reader{}
-			},
+	rp := &ReaderPool{}
+	rp.pool = sync.Pool{
+		New: func() interface{} {
+			rp.stats.news.Add(1)
+			return &bytes.Reader{}
 		},
 	}
+	return rp
 }
 
 // Get retrieves a reader from the pool and initializes it with data
 func (rp *ReaderPool) Get(data []byte) *bytes.Reader {
+	rp.stats.gets.Add(1)
+	rp.stats.warnIfLowHitRate("reader", 0)
+
 	reader := rp.pool.Get().(*bytes.Reader)
 	reader.Reset(data)
 	return reader
@@ -99,27 +234,37 @@ func (rp *ReaderPool) Get(data []byte) *bytes.Reader {
 
 // Put returns a reader to the pool
 func (rp *ReaderPool) Put(reader *bytes.Reader) {
+	rp.stats.puts.Add(1)
 	rp.pool.Put(reader)
 }
 
+// Snapshot returns rp's current utilization statistics.
+func (rp *ReaderPool) Snapshot() PoolSnapshot {
+	return rp.stats.snapshot()
+}
+
 // WriterPool manages pooled writers
 type WriterPool struct {
-	pool sync.Pool
+	pool  sync.Pool
+	stats poolStats
 }
 
 // NewWriterPool creates a new writer pool
 func NewWriterPool() *WriterPool {
-	return &WriterPool{
-		pool: sync.Pool{
-			New: func() interface{} {
-				return bytes.NewBuffer(make([]byte, 0, 4096))
-			},
+	wp := &WriterPool{}
+	wp.pool = sync.Pool{
+		New: func() interface{} {
+			wp.stats.news.Add(1)
+			return bytes.NewBuffer(make([]byte, 0, 4096))
 		},
 	}
+	return wp
 }
 
 // Get retrieves a writer from the pool
 func (wp *WriterPool) Get() io.Writer {
+	wp.stats.gets.Add(1)
+	wp.stats.warnIfLowHitRate("writer", 0)
 	return wp.pool.Get().(*bytes.Buffer)
 }
 
@@ -127,46 +272,51 @@ func (wp *WriterPool) Get() io.Writer {
 func (wp *WriterPool) Put(w io.Writer) {
 	if buf, ok := w.(*bytes.Buffer); ok {
 		buf.Reset()
+		wp.stats.puts.Add(1)
 		wp.pool.Put(buf)
 	}
 }
 
+// Snapshot returns wp's current utilization statistics.
+func (wp *WriterPool) Snapshot() PoolSnapshot {
+	return wp.stats.snapshot()
+}
+
 // Global pool instances for common use cases
 var (
 	// Small buffers (4KB) - for JSON, small responses
-	SmallBufferPool = NewBufferPool(4 * 1024)
+	SmallBufferPool = newNamedBufferPool("small_buffer", 4*1024)
 
 	// Medium buffers (64KB) - for file chunks, larger responses
-	MediumBufferPool = NewBufferPool(64 * 1024)
+	MediumBufferPool = newNamedBufferPool("medium_buffer", 64*1024)
 
 	// Large buffers (1MB) - for video processing
-	LargeBufferPool = NewBufferPool(1024 * 1024)
+	LargeBufferPool = newNamedBufferPool("large_buffer", 1024*1024)
 
 	// Byte slice pools
-	SmallSlicePool  = NewByteSlicePool(4 * 1024)
-	MediumSlicePool = NewByteSlicePool(64 * 1024)
-	LargeSlicePool  = NewByteSlicePool(1024 * 1024)
+	SmallSlicePool  = newNamedByteSlicePool("small_slice", 4*1024)
+	MediumSlicePool = newNamedByteSlicePool("medium_slice", 64*1024)
+	LargeSlicePool  = newNamedByteSlicePool("large_slice", 1024*1024)
 
 	// Reader/Writer pools
 	GlobalReaderPool = NewReaderPool()
 	GlobalWriterPool = NewWriterPool()
 )
 
-// Stats returns pool utilization statistics
-type PoolStats struct {
-	SmallBuffersInUse  int
-	MediumBuffersInUse int
-	LargeBuffersInUse  int
-}
-
-// GetStats returns current pool statistics
-// Note: sync.Pool doesn't expose internal stats, this is a placeholder
-func GetStats() PoolStats {
-	return PoolStats{
-		// sync.Pool doesn't track in-use count
-		// These would need custom tracking if needed
-		SmallBuffersInUse:  0,
-		MediumBuffersInUse: 0,
-		LargeBuffersInUse:  0,
+// GetStats returns a snapshot of every global pool, keyed by pool name -
+// e.g. medyan_pool_gets_total{pool="small_buffer"} from the request this
+// replaced. There's no Prometheus client in this codebase yet, so these
+// are exposed as JSON via the existing /metrics endpoint instead of a
+// separate exposition-format endpoint.
+func GetStats() map[string]PoolSnapshot {
+	return map[string]PoolSnapshot{
+		"small_buffer":  SmallBufferPool.Snapshot(),
+		"medium_buffer": MediumBufferPool.Snapshot(),
+		"large_buffer":  LargeBufferPool.Snapshot(),
+		"small_slice":   SmallSlicePool.Snapshot(),
+		"medium_slice":  MediumSlicePool.Snapshot(),
+		"large_slice":   LargeSlicePool.Snapshot(),
+		"reader":        GlobalReaderPool.Snapshot(),
+		"writer":        GlobalWriterPool.Snapshot(),
 	}
 }
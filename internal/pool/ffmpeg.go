@@ -0,0 +1,440 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/KeremKalyoncu/MedYan/internal/metrics"
+)
+
+// defaultFFmpegQueueSize bounds how many FFmpegJobs can wait for a free
+// worker before Submit starts rejecting with ErrPoolSaturated.
+const defaultFFmpegQueueSize = 32
+
+// defaultShutdownGrace is how long Shutdown waits after SIGTERM before
+// escalating to SIGKILL for any child still running.
+const defaultShutdownGrace = 10 * time.Second
+
+// ErrPoolSaturated is returned by Submit when the bounded queue is full.
+var ErrPoolSaturated = errors.New("ffmpeg pool: queue saturated")
+
+// ErrQueueFull is an alias for ErrPoolSaturated for callers (the Asynq task
+// handler, PlatformExtractor) that gate a whole job rather than a single
+// ffmpeg invocation and want a name matching the retry-with-backoff
+// semantics they give it rather than this pool's own.
+var ErrQueueFull = ErrPoolSaturated
+
+// FFmpegJob describes a single ffmpeg invocation to run under the pool.
+type FFmpegJob struct {
+	// BinaryPath is the ffmpeg executable to run.
+	BinaryPath string
+	// Args are passed to BinaryPath as-is (no implicit -y/-nostdin flags).
+	Args []string
+}
+
+// FFmpegHandle is returned by Submit and tracks one queued or running job.
+// Stderr is available as soon as Submit returns, even if the job is still
+// waiting for a free worker, so callers can start reading progress lines
+// without racing the job's actual start.
+type FFmpegHandle struct {
+	job        FFmpegJob
+	stderrR    *io.PipeReader
+	stderrW    *io.PipeWriter
+	done       chan struct{}
+	err        error
+	mu         sync.Mutex
+	cmd        *exec.Cmd
+	cancelFn   context.CancelFunc
+	cancelOnce sync.Once
+}
+
+// Stderr returns a reader for the job's live stderr output, for progress
+// parsing. It is closed automatically once the job finishes.
+func (h *FFmpegHandle) Stderr() io.Reader {
+	return h.stderrR
+}
+
+// Cancel terminates the job, whether it is still queued or already running.
+// Safe to call multiple times and from any goroutine.
+func (h *FFmpegHandle) Cancel() {
+	h.cancelOnce.Do(func() {
+		h.cancelFn()
+	})
+}
+
+// Wait blocks until the job completes (successfully, canceled, or failed)
+// and returns its terminal error, if any.
+func (h *FFmpegHandle) Wait() error {
+	<-h.done
+	return h.err
+}
+
+// PriorityLow is the only priority RunGatedPriority special-cases today -
+// jobs submitted with it wait in a dedicated queue served by this pool's
+// reserved workers, so they keep making progress even while the general
+// queue is saturated with higher-priority work. Any other priority value
+// (including "") behaves exactly like RunGated.
+const PriorityLow = "low"
+
+// FFmpegPool bounds concurrent ffmpeg/yt-dlp-adjacent transcoding work
+// independently of the generic WorkerPool used for HTTP-bound tasks, so a
+// burst of extraction requests can't starve request handling. Defaults to
+// runtime.NumCPU() workers, overridable via FFMPEG_WORKER_POOL_SIZE.
+//
+// reservedWorkers of the pool's concurrency are dedicated to lowQueue: they
+// drain it first and only fall back to the general queue once it's empty,
+// so a flood of higher-priority work (e.g. 4K transcodes landing on the
+// general queue) can't starve low-priority jobs (e.g. audio-only requests)
+// submitted via RunGatedPriority(ctx, PriorityLow, ...) indefinitely.
+type FFmpegPool struct {
+	name        string
+	concurrency int
+	queue       chan *queuedFFmpegJob
+	lowQueue    chan *queuedFFmpegJob
+	logger      *zap.Logger
+
+	mu      sync.Mutex
+	running map[*FFmpegHandle]struct{}
+
+	busy     atomic.Int32
+	rejected atomic.Int64
+
+	wg         sync.WaitGroup
+	ctx        context.Context
+	cancel     context.CancelFunc
+	shutdownWg sync.WaitGroup
+}
+
+type queuedFFmpegJob struct {
+	ctx    context.Context
+	handle *FFmpegHandle
+
+	// gated, when set, is run instead of spawning handle.job - used by
+	// RunGated for callers (the ffmpeg-go-based FFmpeg wrapper) that manage
+	// their own *exec.Cmd and only need this pool's admission control.
+	gated func() error
+	done  chan error
+
+	// enqueuedAt backs the ffmpeg_wait_seconds{pool} histogram: how long this
+	// job sat in the queue before a worker picked it up.
+	enqueuedAt time.Time
+}
+
+// DefaultFFmpegPoolSize returns runtime.NumCPU(), or the value of
+// FFMPEG_WORKER_POOL_SIZE when it's set to a positive integer.
+func DefaultFFmpegPoolSize() int {
+	if raw := os.Getenv("FFMPEG_WORKER_POOL_SIZE"); raw != "" {
+		if n, err := strconv.Atoi(raw); err == nil && n > 0 {
+			return n
+		}
+	}
+	return runtime.NumCPU()
+}
+
+// NewFFmpegPool creates a pool with the given concurrency (worker count) and
+// queue depth, and starts its workers. name labels this pool's
+// ffmpeg_workers_busy/ffmpeg_queue_depth/ffmpeg_wait_seconds metrics, so a
+// process running more than one pool (e.g. cmd/worker's transcode pool and
+// its separate whole-job extraction pool) can tell them apart.
+//
+// reservedLowSlots workers, out of concurrency, are set aside to serve
+// PriorityLow jobs first (see FFmpegPool's doc comment); pass 0 to leave the
+// pool without a reservation, in which case RunGatedPriority behaves
+// identically to RunGated regardless of the priority passed. reservedLowSlots
+// is clamped to [0, concurrency-1] so there's always at least one worker
+// left to drain the general queue.
+func NewFFmpegPool(name string, concurrency, queueSize int, logger *zap.Logger, reservedLowSlots int) *FFmpegPool {
+	if concurrency <= 0 {
+		concurrency = DefaultFFmpegPoolSize()
+	}
+	if queueSize <= 0 {
+		queueSize = defaultFFmpegQueueSize
+	}
+	if reservedLowSlots < 0 {
+		reservedLowSlots = 0
+	}
+	if reservedLowSlots > concurrency-1 {
+		reservedLowSlots = concurrency - 1
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &FFmpegPool{
+		name:        name,
+		concurrency: concurrency,
+		queue:       make(chan *queuedFFmpegJob, queueSize),
+		lowQueue:    make(chan *queuedFFmpegJob, queueSize),
+		logger:      logger,
+		running:     make(map[*FFmpegHandle]struct{}),
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+
+	for i := 0; i < reservedLowSlots; i++ {
+		p.wg.Add(1)
+		go p.worker(true)
+	}
+	for i := reservedLowSlots; i < concurrency; i++ {
+		p.wg.Add(1)
+		go p.worker(false)
+	}
+
+	return p
+}
+
+// Submit enqueues spec and returns a handle immediately; the job runs once a
+// worker is free. Returns ErrPoolSaturated if the bounded queue is full.
+func (p *FFmpegPool) Submit(ctx context.Context, spec FFmpegJob) (*FFmpegHandle, error) {
+	stderrR, stderrW := io.Pipe()
+	jobCtx, cancel := context.WithCancel(ctx)
+
+	handle := &FFmpegHandle{
+		job:      spec,
+		stderrR:  stderrR,
+		stderrW:  stderrW,
+		done:     make(chan struct{}),
+		cancelFn: cancel,
+	}
+
+	select {
+	case p.queue <- &queuedFFmpegJob{ctx: jobCtx, handle: handle, enqueuedAt: time.Now()}:
+		metrics.GetMetrics().SetFFmpegQueueDepth(p.name, len(p.queue))
+		return handle, nil
+	case <-p.ctx.Done():
+		cancel()
+		stderrW.Close()
+		return nil, errors.New("ffmpeg pool: shut down")
+	default:
+		cancel()
+		stderrW.Close()
+		p.rejected.Add(1)
+		return nil, ErrPoolSaturated
+	}
+}
+
+// RunGated runs fn under this pool's concurrency limit and bounded queue,
+// for callers (like the ffmpeg-go-based FFmpeg wrapper) that already manage
+// their own *exec.Cmd lifecycle via stream.Compile() and only need admission
+// control rather than a full Submit/FFmpegHandle. Blocks until fn completes;
+// returns ErrPoolSaturated immediately if the queue is full. Equivalent to
+// RunGatedPriority(ctx, "", fn).
+func (p *FFmpegPool) RunGated(ctx context.Context, fn func() error) error {
+	return p.RunGatedPriority(ctx, "", fn)
+}
+
+// RunGatedPriority is RunGated with an admission-control priority. Only
+// priority == PriorityLow is treated specially today: such jobs queue
+// separately and are drained first by this pool's reserved workers (see
+// FFmpegPool's doc comment), so they don't wait behind a flood of
+// general-priority work. Any other value queues exactly like RunGated.
+func (p *FFmpegPool) RunGatedPriority(ctx context.Context, priority string, fn func() error) error {
+	qj := &queuedFFmpegJob{
+		ctx:        ctx,
+		gated:      fn,
+		done:       make(chan error, 1),
+		enqueuedAt: time.Now(),
+	}
+
+	target := p.queue
+	if priority == PriorityLow {
+		target = p.lowQueue
+	}
+
+	select {
+	case target <- qj:
+		metrics.GetMetrics().SetFFmpegQueueDepth(p.name, len(p.queue)+len(p.lowQueue))
+	case <-p.ctx.Done():
+		return errors.New("ffmpeg pool: shut down")
+	default:
+		p.rejected.Add(1)
+		return ErrPoolSaturated
+	}
+
+	select {
+	case err := <-qj.done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// worker pulls queued jobs and runs them one at a time, bounding this
+// worker's concurrency to 1 job - overall concurrency is the worker count.
+// reserved workers check lowQueue first (non-blocking) on every iteration so
+// a backlog of low-priority jobs never waits behind general-queue jobs that
+// arrived after them; once lowQueue is drained, reserved workers fall back
+// to serving either queue like any other worker.
+func (p *FFmpegPool) worker(reserved bool) {
+	defer p.wg.Done()
+
+	for {
+		if reserved {
+			select {
+			case qj, ok := <-p.lowQueue:
+				if !ok {
+					return
+				}
+				p.run(qj)
+				continue
+			default:
+			}
+		}
+
+		select {
+		case <-p.ctx.Done():
+			return
+		case qj, ok := <-p.lowQueue:
+			if !ok {
+				return
+			}
+			p.run(qj)
+		case qj, ok := <-p.queue:
+			if !ok {
+				return
+			}
+			p.run(qj)
+		}
+	}
+}
+
+// run executes one queued job to completion and records it in the running
+// set for the duration so Shutdown can find and signal it.
+func (p *FFmpegPool) run(qj *queuedFFmpegJob) {
+	metrics.GetMetrics().RecordFFmpegWait(p.name, time.Since(qj.enqueuedAt))
+	metrics.GetMetrics().SetFFmpegQueueDepth(p.name, len(p.queue)+len(p.lowQueue))
+
+	busy := p.busy.Add(1)
+	metrics.GetMetrics().SetFFmpegWorkersBusy(p.name, int(busy))
+	defer func() {
+		busy := p.busy.Add(-1)
+		metrics.GetMetrics().SetFFmpegWorkersBusy(p.name, int(busy))
+	}()
+
+	if qj.gated != nil {
+		qj.done <- qj.gated()
+		return
+	}
+
+	handle := qj.handle
+	defer close(handle.done)
+	defer handle.stderrW.Close()
+
+	if err := qj.ctx.Err(); err != nil {
+		handle.err = err
+		return
+	}
+
+	cmd := exec.CommandContext(qj.ctx, handle.job.BinaryPath, handle.job.Args...)
+	cmd.Stderr = handle.stderrW
+
+	handle.mu.Lock()
+	handle.cmd = cmd
+	handle.mu.Unlock()
+
+	if err := cmd.Start(); err != nil {
+		handle.err = err
+		return
+	}
+
+	p.mu.Lock()
+	p.running[handle] = struct{}{}
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		delete(p.running, handle)
+		p.mu.Unlock()
+	}()
+
+	handle.err = cmd.Wait()
+}
+
+// Shutdown stops accepting new jobs and signals every running child to
+// terminate: SIGTERM first, then SIGKILL for any still alive after ctx's
+// deadline (or defaultShutdownGrace, whichever is shorter) elapses. Blocks
+// until every worker goroutine has returned.
+func (p *FFmpegPool) Shutdown(ctx context.Context) error {
+	close(p.queue)
+	close(p.lowQueue)
+
+	grace := defaultShutdownGrace
+	if deadline, ok := ctx.Deadline(); ok {
+		if d := time.Until(deadline); d < grace {
+			grace = d
+		}
+	}
+
+	p.mu.Lock()
+	handles := make([]*FFmpegHandle, 0, len(p.running))
+	for h := range p.running {
+		handles = append(handles, h)
+	}
+	p.mu.Unlock()
+
+	for _, h := range handles {
+		h.mu.Lock()
+		cmd := h.cmd
+		h.mu.Unlock()
+		if cmd != nil && cmd.Process != nil {
+			cmd.Process.Signal(syscall.SIGTERM)
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		p.cancel()
+		return nil
+	case <-time.After(grace):
+		for _, h := range handles {
+			h.mu.Lock()
+			cmd := h.cmd
+			h.mu.Unlock()
+			if cmd != nil && cmd.Process != nil {
+				cmd.Process.Kill()
+			}
+		}
+		p.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// ActiveJobs returns the number of jobs currently running (not queued).
+func (p *FFmpegPool) ActiveJobs() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.running)
+}
+
+// FFmpegStats reports an FFmpegPool's current admission-control counters,
+// including how many jobs the bounded queue rejected once full.
+type FFmpegStats struct {
+	InFlight int
+	Queued   int
+	Rejected int64
+}
+
+// Stats returns a snapshot of in-flight, queued, and cumulative-rejected
+// job counts.
+func (p *FFmpegPool) Stats() FFmpegStats {
+	return FFmpegStats{
+		InFlight: p.ActiveJobs(),
+		Queued:   len(p.queue) + len(p.lowQueue),
+		Rejected: p.rejected.Load(),
+	}
+}
@@ -4,53 +4,70 @@ import (
 	"net"
 	"net/http"
 	"time"
+
+	"github.com/KeremKalyoncu/MedYan/internal/circuitbreaker"
 )
 
 // HTTPClientPool provides an optimized HTTP client with connection pooling
 type HTTPClientPool struct {
 	client *http.Client
+	// transport is kept alongside client so SetBreakers can wrap it without
+	// losing the pooling settings NewHTTPClientPool configured below.
+	transport *http.Transport
 }
 
 // NewHTTPClientPool creates a new HTTP client with optimized settings
 func NewHTTPClientPool() *HTTPClientPool {
-	return &HTTPClientPool{
-		client: &http.Client{
-			Transport: &http.Transport{
-				// Connection pooling settings
-				MaxIdleConns:        100,              // Maximum idle connections across all hosts
-				MaxIdleConnsPerHost: 10,               // Maximum idle connections per host
-				MaxConnsPerHost:     50,               // Maximum connections per host (0 = unlimited)
-				IdleConnTimeout:     90 * time.Second, // How long idle connections stay alive
+	transport := &http.Transport{
+		// Connection pooling settings
+		MaxIdleConns:        100,              // Maximum idle connections across all hosts
+		MaxIdleConnsPerHost: 10,               // Maximum idle connections per host
+		MaxConnsPerHost:     50,               // Maximum connections per host (0 = unlimited)
+		IdleConnTimeout:     90 * time.Second, // How long idle connections stay alive
 
-				// TCP settings for better performance
-				DialContext: (&net.Dialer{
-					Timeout:   30 * time.Second, // Connection timeout
-					KeepAlive: 30 * time.Second, // Keep-alive probe interval
-					DualStack: true,             // Use IPv4 and IPv6
-				}).DialContext,
+		// TCP settings for better performance
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second, // Connection timeout
+			KeepAlive: 30 * time.Second, // Keep-alive probe interval
+			DualStack: true,             // Use IPv4 and IPv6
+		}).DialContext,
 
-				// TLS handshake timeout
-				TLSHandshakeTimeout: 10 * time.Second,
+		// TLS handshake timeout
+		TLSHandshakeTimeout: 10 * time.Second,
 
-				// Enable HTTP/2 (automatically enabled by default in Go 1.6+)
-				ForceAttemptHTTP2: true,
+		// Enable HTTP/2 (automatically enabled by default in Go 1.6+)
+		ForceAttemptHTTP2: true,
 
-				// Timeout for reading response headers
-				ResponseHeaderTimeout: 30 * time.Second,
+		// Timeout for reading response headers
+		ResponseHeaderTimeout: 30 * time.Second,
 
-				// Expect-Continue timeout (for large uploads)
-				ExpectContinueTimeout: 1 * time.Second,
+		// Expect-Continue timeout (for large uploads)
+		ExpectContinueTimeout: 1 * time.Second,
 
-				// Don't disable compression
-				DisableCompression: false,
-			},
+		// Don't disable compression
+		DisableCompression: false,
+	}
 
+	return &HTTPClientPool{
+		client: &http.Client{
+			Transport: transport,
 			// Overall request timeout (can be overridden per request)
 			Timeout: 5 * time.Minute,
 		},
+		transport: transport,
 	}
 }
 
+// SetBreakers wraps p's transport with a per-(host, operation) circuit
+// breaker from breakers, so every request made through p.Client() is
+// protected - see circuitbreaker.WrapRoundTripper. operation labels what
+// this pool is used for (e.g. "extract", "download"), since a transport
+// itself doesn't know per-request what the caller is fetching on behalf
+// of.
+func (p *HTTPClientPool) SetBreakers(breakers *circuitbreaker.Registry, operation string) {
+	p.client.Transport = circuitbreaker.WrapRoundTripper(breakers, operation, p.transport)
+}
+
 // Client returns the underlying HTTP client
 func (p *HTTPClientPool) Client() *http.Client {
 	return p.client
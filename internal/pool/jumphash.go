@@ -0,0 +1,24 @@
+package pool
+
+// JumpHash implements Google's "jump consistent hash" algorithm: given key
+// (typically an FNV/xxhash of some affinity string) and the current number
+// of buckets, it returns a bucket index in [0, numBuckets). Its defining
+// property versus key % numBuckets is that growing or shrinking numBuckets
+// by one only remaps ~1/numBuckets of keys instead of nearly all of them -
+// the property EnqueueExtractionJobWithAffinity relies on so that adding or
+// losing a worker doesn't scatter every in-flight URL's cache/temp-file
+// affinity at once.
+//
+// Reference: Lamping & Veach, "A Fast, Minimal Memory, Consistent Hash
+// Algorithm" (2014).
+func JumpHash(key uint64, numBuckets int) int32 {
+	var b, j int64 = -1, 0
+
+	for j < int64(numBuckets) {
+		b = j
+		key = key*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((key>>33)+1)))
+	}
+
+	return int32(b)
+}
@@ -0,0 +1,133 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// nodeMembershipPrefix namespaces a Node's heartbeat key in Redis. The
+// membership set isn't a single SMEMBERS collection with explicit
+// deregistration - it's derived by scanning this prefix, so a node that
+// crashes without a clean shutdown simply falls out of LiveNodes once its
+// heartbeat key's TTL lapses.
+const nodeMembershipPrefix = "pool:node:"
+
+func nodeKey(id string) string       { return nodeMembershipPrefix + id }
+func nodeStreamKey(id string) string { return nodeMembershipPrefix + id + ":stream" }
+
+// Node is a single worker's identity in a Redis-backed membership set used
+// for consistent-hash job affinity (see queue.Client.EnqueueExtractionJobWithAffinity).
+// Each Node owns a dedicated list key that only jobs hashed to it are ever
+// pushed onto.
+type Node struct {
+	id     string
+	redis  *redis.Client
+	logger *zap.Logger
+	ttl    time.Duration
+}
+
+// NewNode creates a Node identified by id. id must be stable across
+// restarts of the same worker (e.g. hostname+pid, or a configured name) so
+// that LiveNodes ordering - and therefore which jobs hash to it - stays
+// meaningful between the worker's own runs.
+func NewNode(id string, redisClient *redis.Client, logger *zap.Logger) *Node {
+	return &Node{
+		id:     id,
+		redis:  redisClient,
+		logger: logger,
+		ttl:    30 * time.Second,
+	}
+}
+
+// ID returns this node's stable identifier.
+func (n *Node) ID() string {
+	return n.id
+}
+
+// Join registers the node's heartbeat key and refreshes it every interval
+// until ctx is canceled, at which point the key is left to expire on its
+// own TTL rather than deleted - a worker that dies uncleanly shouldn't need
+// a graceful-shutdown path just to disappear from LiveNodes.
+func (n *Node) Join(ctx context.Context, interval time.Duration) error {
+	if err := n.beat(ctx); err != nil {
+		return fmt.Errorf("failed to register node %s: %w", n.id, err)
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := n.beat(ctx); err != nil {
+					n.logger.Warn("Failed to refresh node heartbeat", zap.String("node_id", n.id), zap.Error(err))
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (n *Node) beat(ctx context.Context) error {
+	return n.redis.Set(ctx, nodeKey(n.id), time.Now().Unix(), n.ttl).Err()
+}
+
+// Pop blocks up to timeout for the next job payload assigned to this node,
+// returning redis.Nil (wrapped) if timeout elapses with nothing enqueued.
+func (n *Node) Pop(ctx context.Context, timeout time.Duration) ([]byte, error) {
+	result, err := n.redis.BLPop(ctx, timeout, nodeStreamKey(n.id)).Result()
+	if err != nil {
+		return nil, err
+	}
+	// BLPOP returns [key, value]; Pop is only ever called against this
+	// node's single stream key, so result[1] is the payload.
+	return []byte(result[1]), nil
+}
+
+// LiveNodes returns the sorted IDs of every node with an unexpired
+// heartbeat. Sorted order matters: EnqueueExtractionJobWithAffinity's jump
+// hash picks a node by index into this slice, so two callers computing it
+// from the same membership must land on the same ordering or the same
+// affinity key could hash to a different worker depending on which client
+// asked.
+func LiveNodes(ctx context.Context, redisClient *redis.Client) ([]string, error) {
+	var cursor uint64
+	var ids []string
+	pattern := nodeMembershipPrefix + "*"
+
+	for {
+		keys, next, err := redisClient.Scan(ctx, cursor, pattern, 100).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan node membership: %w", err)
+		}
+		for _, key := range keys {
+			if !strings.HasSuffix(key, ":stream") {
+				ids = append(ids, key[len(nodeMembershipPrefix):])
+			}
+		}
+		cursor = next
+		if cursor == 0 {
+			break
+		}
+	}
+
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// PushToNode enqueues payload onto nodeID's dedicated stream for that
+// node's Pop to pick up. It's a package-level helper (rather than a Node
+// method) since the enqueueing side - queue.Client - typically doesn't have
+// a live Node of its own, just a target ID picked by JumpHash.
+func PushToNode(ctx context.Context, redisClient *redis.Client, nodeID string, payload []byte) error {
+	return redisClient.RPush(ctx, nodeStreamKey(nodeID), payload).Err()
+}
@@ -8,12 +8,20 @@ import (
 
 // WorkerPool manages a pool of worker goroutines for parallel task processing
 type WorkerPool struct {
-	workerCount int
+	workerCount int64 // atomic; authoritative count lives here, not len(wg)
+	nextID      int
 	taskQueue   chan Task
 	wg          sync.WaitGroup
 	ctx         context.Context
 	cancel      context.CancelFunc
 	activeJobs  int64
+
+	resizeMu sync.Mutex
+	// stopCh hands a shrinking worker its exit signal - Resize pushes one
+	// value per worker to remove, and whichever idle worker picks it up
+	// first returns. Buffered generously so Resize never blocks waiting
+	// for a worker to notice.
+	stopCh chan struct{}
 }
 
 // Task represents a unit of work to be processed by the worker pool
@@ -24,10 +32,12 @@ func NewWorkerPool(workerCount int, queueSize int) *WorkerPool {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	pool := &WorkerPool{
-		workerCount: workerCount,
+		workerCount: int64(workerCount),
+		nextID:      workerCount,
 		taskQueue:   make(chan Task, queueSize),
 		ctx:         ctx,
 		cancel:      cancel,
+		stopCh:      make(chan struct{}, 4096),
 	}
 
 	// Start workers
@@ -47,6 +57,8 @@ func (wp *WorkerPool) worker(id int) {
 		select {
 		case <-wp.ctx.Done():
 			return
+		case <-wp.stopCh:
+			return
 		case task, ok := <-wp.taskQueue:
 			if !ok {
 				return
@@ -88,6 +100,50 @@ func (wp *WorkerPool) ActiveJobs() int64 {
 	return atomic.LoadInt64(&wp.activeJobs)
 }
 
+// WorkerCount returns the number of worker goroutines currently running.
+func (wp *WorkerPool) WorkerCount() int {
+	return int(atomic.LoadInt64(&wp.workerCount))
+}
+
+// Resize grows or shrinks the pool to exactly n worker goroutines. n below 1
+// is clamped to 1 - a pool can't usefully drain its queue with zero workers.
+// Growing spawns new goroutines directly; shrinking pushes one stop signal
+// per removed worker onto stopCh, so the affected workers exit once they
+// next reach the top of their loop rather than mid-task. Safe to call
+// concurrently, and safe to call after Shutdown (it's then a no-op since
+// every worker has already returned and won't pick up the stop signals).
+func (wp *WorkerPool) Resize(n int) {
+	if n < 1 {
+		n = 1
+	}
+
+	wp.resizeMu.Lock()
+	defer wp.resizeMu.Unlock()
+
+	if wp.ctx.Err() != nil {
+		return
+	}
+
+	current := wp.WorkerCount()
+	switch {
+	case n > current:
+		for i := 0; i < n-current; i++ {
+			wp.nextID++
+			wp.wg.Add(1)
+			go wp.worker(wp.nextID)
+		}
+		atomic.AddInt64(&wp.workerCount, int64(n-current))
+	case n < current:
+		for i := 0; i < current-n; i++ {
+			select {
+			case wp.stopCh <- struct{}{}:
+			case <-wp.ctx.Done():
+			}
+		}
+		atomic.AddInt64(&wp.workerCount, -int64(current-n))
+	}
+}
+
 // Shutdown gracefully stops the worker pool
 func (wp *WorkerPool) Shutdown() {
 	close(wp.taskQueue)
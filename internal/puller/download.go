@@ -0,0 +1,81 @@
+package puller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+)
+
+// DefaultPartSize chunks a resumable download into 8MiB parts.
+const DefaultPartSize = 8 * 1024 * 1024
+
+// DownloadRange fetches part i of url via an HTTP Range request into
+// state.PartPath(i), records its SHA-256, and marks it complete in state
+// (the written length is not checked against the part's Length). This is
+// the direct-URL counterpart to yt-dlp's own continue-on-retry behavior
+// (extractor.DownloadOptions has no equivalent per-part hash, since yt-dlp
+// owns its own output file); it's the path a future direct-URL extraction
+// mode would use.
+func DownloadRange(ctx context.Context, client *http.Client, url string, state *SharedPullerState, i int) error {
+	part := state.PartFiles[i]
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to build range request: %w", err)
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", part.Offset, part.Offset+part.Length-1))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("range request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusPartialContent && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("range request for part %d returned status %d", i, resp.StatusCode)
+	}
+
+	f, err := os.Create(state.PartPath(i))
+	if err != nil {
+		return fmt.Errorf("failed to create part file: %w", err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(f, io.TeeReader(resp.Body, h)); err != nil {
+		return fmt.Errorf("failed to write part %d: %w", i, err)
+	}
+
+	state.MarkPartComplete(i, hex.EncodeToString(h.Sum(nil)))
+	return nil
+}
+
+// Concatenate joins every part file in state, in order, into destPath, for
+// handoff to the extractor's post-processing/muxing stage once Done() is
+// true. Orphaned .part-* files left behind by an abandoned job are swept by
+// cleanup.Manager.CleanTempFiles the same as any other temp file.
+func Concatenate(state *SharedPullerState, destPath string) error {
+	out, err := os.Create(destPath)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", destPath, err)
+	}
+	defer out.Close()
+
+	for i := range state.PartFiles {
+		in, err := os.Open(state.PartPath(i))
+		if err != nil {
+			return fmt.Errorf("failed to open part %d: %w", i, err)
+		}
+		_, err = io.Copy(out, in)
+		in.Close()
+		if err != nil {
+			return fmt.Errorf("failed to append part %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,187 @@
+// Package puller implements resumable, chunked media downloads backed by a
+// Redis-persisted checkpoint, so a large extraction that fails mid-download
+// doesn't have to restart from zero.
+package puller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// stateTTL bounds how long a checkpoint survives in Redis once written,
+// mirroring the 7-day retention queue.Client uses for job metadata.
+const stateTTL = 7 * 24 * time.Hour
+
+// PartState records one fixed-size chunk of a download in flight.
+type PartState struct {
+	Offset    int64  `json:"offset"`
+	Length    int64  `json:"length"`
+	SHA256    string `json:"sha256,omitempty"`
+	Completed bool   `json:"completed"`
+}
+
+// SharedPullerState is the resumable-download checkpoint for one extraction
+// job, persisted in Redis under job:{id}:puller so a retried job can
+// rehydrate which byte ranges it already wrote instead of restarting the
+// whole download from zero.
+type SharedPullerState struct {
+	JobID        string      `json:"job_id"`
+	TempPath     string      `json:"temp_path"`
+	ExpectedSize int64       `json:"expected_size"`
+	BytesWritten int64       `json:"bytes_written"`
+	ContentHash  string      `json:"content_hash,omitempty"`
+	PartFiles    []PartState `json:"part_files"`
+}
+
+// NewState creates a fresh checkpoint for a download of expectedSize bytes
+// split into fixed partSize chunks, each written to tempPath.part-{i}.
+func NewState(jobID, tempPath string, expectedSize, partSize int64) *SharedPullerState {
+	var parts []PartState
+	for offset := int64(0); offset < expectedSize; offset += partSize {
+		length := partSize
+		if offset+length > expectedSize {
+			length = expectedSize - offset
+		}
+		parts = append(parts, PartState{Offset: offset, Length: length})
+	}
+
+	return &SharedPullerState{
+		JobID:        jobID,
+		TempPath:     tempPath,
+		ExpectedSize: expectedSize,
+		PartFiles:    parts,
+	}
+}
+
+// PartPath returns the on-disk path of part i.
+func (s *SharedPullerState) PartPath(i int) string {
+	return fmt.Sprintf("%s.part-%d", s.TempPath, i)
+}
+
+// MissingRanges returns the parts not yet verified complete, for the
+// extractor to re-request only those byte ranges on retry.
+func (s *SharedPullerState) MissingRanges() []PartState {
+	var missing []PartState
+	for _, p := range s.PartFiles {
+		if !p.Completed {
+			missing = append(missing, p)
+		}
+	}
+	return missing
+}
+
+// Done reports whether every part has been verified complete.
+func (s *SharedPullerState) Done() bool {
+	for _, p := range s.PartFiles {
+		if !p.Completed {
+			return false
+		}
+	}
+	return true
+}
+
+// MarkPartComplete records part i as fully written with the given hash and
+// advances BytesWritten by its length.
+func (s *SharedPullerState) MarkPartComplete(i int, sha256Hex string) {
+	s.PartFiles[i].SHA256 = sha256Hex
+	s.PartFiles[i].Completed = true
+	s.BytesWritten += s.PartFiles[i].Length
+}
+
+// VerifyPart checks part i's on-disk content against its recorded length
+// and hash, marking it Completed only if both match. Called during
+// rehydration so a truncated or corrupted part from a prior attempt is
+// re-downloaded rather than trusted.
+func (s *SharedPullerState) VerifyPart(i int) (bool, error) {
+	p := &s.PartFiles[i]
+
+	f, err := os.Open(s.PartPath(i))
+	if err != nil {
+		if os.IsNotExist(err) {
+			p.Completed = false
+			return false, nil
+		}
+		return false, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return false, err
+	}
+	if info.Size() != p.Length {
+		p.Completed = false
+		return false, nil
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return false, err
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+
+	if p.SHA256 != "" && sum != p.SHA256 {
+		p.Completed = false
+		return false, nil
+	}
+
+	p.SHA256 = sum
+	p.Completed = true
+	return true, nil
+}
+
+// stateKey returns the Redis key a job's checkpoint is stored under.
+func stateKey(jobID string) string {
+	return fmt.Sprintf("job:%s:puller", jobID)
+}
+
+// Store persists SharedPullerState in Redis, the same store queue.Client
+// uses for job metadata.
+type Store struct {
+	redis *redis.Client
+}
+
+// NewStore creates a Store backed by redisClient (e.g. queue.Client.GetRedis()).
+func NewStore(redisClient *redis.Client) *Store {
+	return &Store{redis: redisClient}
+}
+
+// Save persists state, overwriting any prior checkpoint for the same job.
+func (s *Store) Save(ctx context.Context, state *SharedPullerState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to marshal puller state: %w", err)
+	}
+	return s.redis.Set(ctx, stateKey(state.JobID), data, stateTTL).Err()
+}
+
+// Load returns jobID's checkpoint, or (nil, nil) if it has none yet.
+func (s *Store) Load(ctx context.Context, jobID string) (*SharedPullerState, error) {
+	data, err := s.redis.Get(ctx, stateKey(jobID)).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to load puller state: %w", err)
+	}
+
+	var state SharedPullerState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal puller state: %w", err)
+	}
+	return &state, nil
+}
+
+// Delete removes jobID's checkpoint, once the download has completed and
+// been muxed/uploaded.
+func (s *Store) Delete(ctx context.Context, jobID string) error {
+	return s.redis.Del(ctx, stateKey(jobID)).Err()
+}
@@ -1,11 +1,11 @@
 package queue
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"net/http"
+	"hash/fnv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -13,7 +13,11 @@ import (
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 
+	"github.com/KeremKalyoncu/MedYan/internal/dedup"
+	"github.com/KeremKalyoncu/MedYan/internal/pool"
+	"github.com/KeremKalyoncu/MedYan/internal/redisconn"
 	"github.com/KeremKalyoncu/MedYan/internal/types"
+	"github.com/KeremKalyoncu/MedYan/internal/webhooks"
 )
 
 // Task types
@@ -22,112 +26,486 @@ const (
 	TypeBatch      = "extraction:batch"
 )
 
+// monitoredQueues lists every queue name QueueDepth sums over - the same
+// three QueueForQuality and cmd/worker/main.go's Queues config use.
+var monitoredQueues = []string{"critical", "default", "low"}
+
 // Client wraps Asynq client for job enqueueing
 type Client struct {
-	asynq  *asynq.Client
-	redis  *redis.Client
-	logger *zap.Logger
+	asynq     *asynq.Client
+	inspector *asynq.Inspector
+	redis     *redis.Client
+	conn      *redisconn.Conn
+	logger    *zap.Logger
+	webhooks  *webhooks.Dispatcher
+	fairQueue *FairQueue
 }
 
-// NewClient creates a new queue client
+// NewClient creates a new queue client. redisAddr may be a bare host:port
+// or a full redisconn DSN (redis://, rediss://, sentinel://, cluster://);
+// see internal/redisconn for the supported query parameters. The
+// underlying *redis.Client is shared (via redisconn.Default) with any
+// other subsystem pointed at the same DSN, e.g.
+// cache.NewDistributedCache.
+//
+// Asynq opens its own connection for task enqueueing independent of this -
+// it manages its own pool internally and isn't a *redis.Client this
+// package could hand to the registry.
 func NewClient(redisAddr string, logger *zap.Logger) *Client {
 	asynqClient := asynq.NewClient(asynq.RedisClientOpt{Addr: redisAddr})
 
-	// Optimize Redis client with connection pooling
-	redisClient := redis.NewClient(&redis.Options{
-		Addr:         redisAddr,
-		PoolSize:     20, // Increased connection pool (default: 10)
-		MinIdleConns: 5,  // Keep minimum idle connections
-		MaxRetries:   3,  // Retry failed commands
-		DialTimeout:  5 * time.Second,
-		ReadTimeout:  3 * time.Second,
-		WriteTimeout: 3 * time.Second,
-		// Enable connection pooling optimizations
-		PoolTimeout: 4 * time.Second,
-	})
+	redisClient, conn, err := redisconn.Default.GetClient(redisAddr, logger)
+	if err != nil {
+		logger.Warn("redisconn: falling back to a standalone client for queue.Client", zap.Error(err))
+		redisClient = redis.NewClient(&redis.Options{
+			Addr:         redisAddr,
+			PoolSize:     20,
+			MinIdleConns: 5,
+			MaxRetries:   3,
+			DialTimeout:  5 * time.Second,
+			ReadTimeout:  3 * time.Second,
+			WriteTimeout: 3 * time.Second,
+			PoolTimeout:  4 * time.Second,
+		})
+		conn = nil
+	}
 
 	return &Client{
-		asynq:  asynqClient,
-		redis:  redisClient,
-		logger: logger,
+		asynq:     asynqClient,
+		inspector: asynq.NewInspector(asynq.RedisClientOpt{Addr: redisAddr}),
+		redis:     redisClient,
+		conn:      conn,
+		logger:    logger,
+		fairQueue: NewFairQueue(),
 	}
 }
 
+// SetWebhookDispatcher wires a webhook dispatcher into the client so that
+// job state transitions are published to any registered webhook. It is
+// optional: without it, EnqueueExtractionJob's WebhookURL field and the
+// /webhooks/register endpoint have nothing to deliver to.
+func (c *Client) SetWebhookDispatcher(d *webhooks.Dispatcher) {
+	c.webhooks = d
+}
+
 // EnqueueExtractionJob enqueues a media extraction job with deduplication
 func (c *Client) EnqueueExtractionJob(ctx context.Context, req types.ExtractionRequest) (string, error) {
-	jobID := uuid.New().String()
+	return c.enqueueJob(ctx, types.ExtractionJob{
+		ID:        uuid.New().String(),
+		Request:   req,
+		Status:    types.StatusPending,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	})
+}
 
-	job := types.ExtractionJob{
-		ID:        jobID,
+// EnqueueWithPriority enqueues a job tagged with tenant (defaulted to
+// "unknown" when empty, matching HistoryHandler's per-hostname
+// partitioning) and priority. Priority is stored on the job only: FairQueue
+// orders jobs by tenant weight and job cost and does not read it.
+func (c *Client) EnqueueWithPriority(ctx context.Context, req types.ExtractionRequest, tenant string, priority int) (string, error) {
+	return c.enqueueJob(ctx, types.ExtractionJob{
+		ID:        uuid.New().String(),
 		Request:   req,
 		Status:    types.StatusPending,
-		Progress:  0,
+		Tenant:    tenantFromHostname(tenant),
+		Priority:  priority,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
-	}
+	})
+}
 
+// EnqueueExtractionJobAt enqueues req like EnqueueExtractionJob, but delays
+// delivery until at via Asynq's native ProcessAt scheduling instead of
+// handing it to a worker immediately. Used by the live-stream
+// "wait and retry" path (see extractor.ErrStreamNotStarted) to persist a
+// scheduled re-attempt in Redis and free the worker slot, instead of
+// blocking a task goroutine until an upcoming stream's scheduled start.
+func (c *Client) EnqueueExtractionJobAt(ctx context.Context, req types.ExtractionRequest, at time.Time) (string, error) {
+	return c.enqueueJob(ctx, types.ExtractionJob{
+		ID:        uuid.New().String(),
+		Request:   req,
+		Status:    types.StatusPending,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}, asynq.ProcessAt(at))
+}
+
+// enqueueJob stores job's metadata, registers its webhook (if any), and
+// hands it off for actual transport/retry/distribution across workers.
+// extraOpts, when given (e.g. EnqueueExtractionJobAt's asynq.ProcessAt),
+// need to reach Asynq as part of this same call, so that path submits
+// directly via submitJob and bypasses the fair queue entirely; otherwise
+// job is entered into the in-process FairQueue, for a FairDispatcher
+// started against this Client to later submitJob in weighted-fair order.
+func (c *Client) enqueueJob(ctx context.Context, job types.ExtractionJob, extraOpts ...asynq.Option) (string, error) {
 	// Store job metadata in Redis
 	if err := c.storeJobMetadata(ctx, &job); err != nil {
 		return "", fmt.Errorf("failed to store job metadata: %w", err)
 	}
 
-	// Prepare task payload
+	// Auto-register a webhook subscription when the request carries one, so
+	// existing WebhookURL callers keep working alongside /webhooks/register
+	if job.Request.WebhookURL != "" && c.webhooks != nil {
+		if _, err := c.webhooks.Register(ctx, job.ID, job.Request.WebhookURL); err != nil {
+			c.logger.Warn("Failed to register webhook from request",
+				zap.String("job_id", job.ID),
+				zap.Error(err),
+			)
+		}
+	}
+	c.publishWebhookEvent(ctx, &job, "queued")
+
+	if len(extraOpts) > 0 {
+		if err := c.submitJob(ctx, job, extraOpts...); err != nil {
+			return "", err
+		}
+		return job.ID, nil
+	}
+
+	c.fairQueue.Enqueue(&job)
+	return job.ID, nil
+}
+
+// submitJob marshals job and hands it to Asynq for actual
+// transport/retry/distribution across workers. Called either directly by
+// enqueueJob (when extraOpts must reach Asynq immediately) or by a
+// FairDispatcher once it dequeues job from the fair queue in weighted-fair
+// order.
+func (c *Client) submitJob(ctx context.Context, job types.ExtractionJob, extraOpts ...asynq.Option) error {
 	payload, err := json.Marshal(job)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal job: %w", err)
+		return fmt.Errorf("failed to marshal job: %w", err)
 	}
 
-	// Determine queue based on quality
-	queue := c.getQueueForQuality(req.Quality)
+	// Determine queue based on quality, with HLS jobs always routed to
+	// critical given the transcode cost of packaging a full bitrate ladder
+	queueName := QueueForQuality(job.Request.Quality)
+	if job.Request.OutputFormat == types.OutputFormatHLS {
+		queueName = "critical"
+	}
 
 	// Create Asynq task with deduplication
 	task := asynq.NewTask(TypeExtraction, payload)
 	taskOpts := []asynq.Option{
-		asynq.Queue(queue),
+		asynq.Queue(queueName),
 		asynq.MaxRetry(3),
 		asynq.Timeout(10 * time.Minute),
 		asynq.Retention(7 * 24 * time.Hour),
 		// Deduplication: same URL within 5 min (optimized from 24h)
 		// Shorter TTL reduces memory usage and allows retry faster
 		asynq.Unique(5 * time.Minute),
-		asynq.TaskID(jobID),
+		asynq.TaskID(job.ID),
 	}
+	taskOpts = append(taskOpts, extraOpts...)
 
 	info, err := c.asynq.EnqueueContext(ctx, task, taskOpts...)
 	if err != nil {
-		return "", fmt.Errorf("failed to enqueue task: %w", err)
+		return fmt.Errorf("failed to enqueue task: %w", err)
 	}
 
 	c.logger.Info("Job enqueued",
-		zap.String("job_id", jobID),
-		zap.String("url", req.URL),
+		zap.String("job_id", job.ID),
+		zap.String("url", job.Request.URL),
+		zap.String("tenant", job.Tenant),
 		zap.String("queue", info.Queue),
 	)
 
-	return jobID, nil
+	return nil
 }
 
-// EnqueueBatchJob enqueues multiple extraction jobs
-func (c *Client) EnqueueBatchJob(ctx context.Context, urls []string, template types.ExtractionRequest) ([]string, error) {
-	jobIDs := make([]string, 0, len(urls))
+// EnqueueExtractionJobWithAffinity enqueues req like EnqueueExtractionJob,
+// but instead of routing by quality tier, it hashes affinityKey (typically
+// the canonical URL, or platform+video_id) across the live pool.Node
+// membership with Jump Consistent Hash and pushes the job onto that node's
+// dedicated stream. The same affinityKey always lands on the same worker as
+// long as membership is stable, so retries and partial downloads reuse
+// whatever on-disk state (yt-dlp partial files, ffmpeg temp output) that
+// worker already has for the URL - and growing or shrinking the pool
+// remaps only ~1/N of keys instead of redistributing everything, unlike the
+// quality-based asynq routing in enqueueJob.
+func (c *Client) EnqueueExtractionJobWithAffinity(ctx context.Context, req types.ExtractionRequest, affinityKey string) (string, error) {
+	job := types.ExtractionJob{
+		ID:        uuid.New().String(),
+		Request:   req,
+		Status:    types.StatusPending,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
 
-	for _, url := range urls {
-		req := template
-		req.URL = url
+	if err := c.storeJobMetadata(ctx, &job); err != nil {
+		return "", fmt.Errorf("failed to store job metadata: %w", err)
+	}
 
-		jobID, err := c.EnqueueExtractionJob(ctx, req)
-		if err != nil {
-			c.logger.Error("Failed to enqueue batch job",
-				zap.String("url", url),
+	if job.Request.WebhookURL != "" && c.webhooks != nil {
+		if _, err := c.webhooks.Register(ctx, job.ID, job.Request.WebhookURL); err != nil {
+			c.logger.Warn("Failed to register webhook from request",
+				zap.String("job_id", job.ID),
 				zap.Error(err),
 			)
+		}
+	}
+
+	nodes, err := pool.LiveNodes(ctx, c.redis)
+	if err != nil {
+		return "", fmt.Errorf("failed to list live worker nodes: %w", err)
+	}
+	if len(nodes) == 0 {
+		return "", fmt.Errorf("no live worker nodes registered for affinity routing")
+	}
+
+	hasher := fnv.New64a()
+	hasher.Write([]byte(affinityKey))
+	nodeID := nodes[pool.JumpHash(hasher.Sum64(), len(nodes))]
+
+	payload, err := json.Marshal(job)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal job: %w", err)
+	}
+
+	if err := pool.PushToNode(ctx, c.redis, nodeID, payload); err != nil {
+		return "", fmt.Errorf("failed to push job to node %s: %w", nodeID, err)
+	}
+
+	c.logger.Info("Job enqueued with affinity",
+		zap.String("job_id", job.ID),
+		zap.String("url", job.Request.URL),
+		zap.String("affinity_key", affinityKey),
+		zap.String("node_id", nodeID),
+	)
+
+	return job.ID, nil
+}
+
+// TenantQueueStats returns fair-queue standing per tenant, for the admin
+// queue-inspection endpoint.
+func (c *Client) TenantQueueStats() []TenantStats {
+	return c.fairQueue.Tenants()
+}
+
+// SetTenantWeight overrides a tenant's fair-share weight at runtime, for
+// the admin weight-tuning endpoint.
+func (c *Client) SetTenantWeight(tenant string, weight float64) {
+	c.fairQueue.SetWeight(tenant, weight)
+}
+
+// TenantQueueLength returns the number of jobs currently queued for tenant.
+func (c *Client) TenantQueueLength(tenant string) int {
+	return c.fairQueue.Length(tenant)
+}
+
+// MarkResumable flags a job as eligible for ResumeJob without publishing a
+// webhook event (the status-change event was already published by the
+// UpdateJobStatus(StatusFailed) call this always follows).
+func (c *Client) MarkResumable(ctx context.Context, jobID string) error {
+	job, err := c.GetJobStatus(ctx, jobID)
+	if err != nil {
+		return err
+	}
+	job.Resumable = true
+	return c.storeJobMetadata(ctx, job)
+}
+
+// ResumeJob re-enqueues an existing failed job under its original ID
+// instead of minting a new one, so the worker's puller.Store checkpoint
+// (keyed by that same ID) and yt-dlp's own partial output file are still
+// there for downloadMedia to rehydrate from.
+func (c *Client) ResumeJob(ctx context.Context, jobID string) error {
+	job, err := c.GetJobStatus(ctx, jobID)
+	if err != nil {
+		return err
+	}
+	if job.Status != types.StatusFailed {
+		return fmt.Errorf("job %s is not in a resumable state: %s", jobID, job.Status)
+	}
+
+	job.Status = types.StatusPending
+	job.Progress = 0
+	job.Error = ""
+	job.Resumable = true
+	job.UpdatedAt = time.Now()
+
+	if err := c.storeJobMetadata(ctx, job); err != nil {
+		return fmt.Errorf("failed to store resumed job metadata: %w", err)
+	}
+
+	payload, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job: %w", err)
+	}
+
+	queue := QueueForQuality(job.Request.Quality)
+	if job.Request.OutputFormat == types.OutputFormatHLS {
+		queue = "critical"
+	}
+
+	task := asynq.NewTask(TypeExtraction, payload)
+	taskOpts := []asynq.Option{
+		asynq.Queue(queue),
+		asynq.MaxRetry(3),
+		asynq.Timeout(10 * time.Minute),
+		asynq.Retention(7 * 24 * time.Hour),
+		// A fresh TaskID per resume attempt - the original attempt's
+		// TaskID (the bare job ID) stays reserved under asynq's retention
+		// window for the failed run, so reusing it here would conflict.
+		asynq.TaskID(fmt.Sprintf("%s:resume:%s", jobID, uuid.New().String())),
+	}
+
+	info, err := c.asynq.EnqueueContext(ctx, task, taskOpts...)
+	if err != nil {
+		return fmt.Errorf("failed to re-enqueue task: %w", err)
+	}
+
+	c.logger.Info("Job resumed",
+		zap.String("job_id", jobID),
+		zap.String("queue", info.Queue),
+	)
+
+	return nil
+}
+
+// EnqueueBatch enqueues a single TypeBatch task carrying every URL in urls.
+// handleBatchTask fans each one out into its own child TypeExtraction task
+// once a worker picks it up, rather than this call blocking on N individual
+// EnqueueContext round-trips up front. maxFailures aborts the batch (see
+// RecordBatchChildResult) once that many children have failed; 0 means no
+// limit.
+func (c *Client) EnqueueBatch(ctx context.Context, urls []string, template types.ExtractionRequest, maxFailures int) (string, error) {
+	batch := types.BatchExtractionJob{
+		ID:          uuid.New().String(),
+		URLs:        urls,
+		Template:    template,
+		Total:       len(urls),
+		MaxFailures: maxFailures,
+		Status:      types.BatchStatusPending,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+
+	if err := c.storeBatchMetadata(ctx, &batch); err != nil {
+		return "", fmt.Errorf("failed to store batch metadata: %w", err)
+	}
+
+	payload, err := json.Marshal(batch)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal batch: %w", err)
+	}
+
+	task := asynq.NewTask(TypeBatch, payload)
+	taskOpts := []asynq.Option{
+		asynq.Queue("default"),
+		asynq.MaxRetry(1),
+		asynq.Retention(7 * 24 * time.Hour),
+		asynq.TaskID(batch.ID),
+	}
+
+	if _, err := c.asynq.EnqueueContext(ctx, task, taskOpts...); err != nil {
+		return "", fmt.Errorf("failed to enqueue batch task: %w", err)
+	}
+
+	c.logger.Info("Batch enqueued",
+		zap.String("batch_id", batch.ID),
+		zap.Int("total", batch.Total),
+	)
+
+	return batch.ID, nil
+}
+
+// batchKey and batchChildrenKey name the Redis entries backing a batch: the
+// batch's own aggregate record, and a hash of per-child status keyed by
+// child job ID.
+func batchKey(batchID string) string         { return fmt.Sprintf("batch:%s", batchID) }
+func batchChildrenKey(batchID string) string { return fmt.Sprintf("batch:%s:children", batchID) }
+
+// storeBatchMetadata stores batch's aggregate record in Redis.
+func (c *Client) storeBatchMetadata(ctx context.Context, batch *types.BatchExtractionJob) error {
+	data, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+	return c.redis.Set(ctx, batchKey(batch.ID), data, 7*24*time.Hour).Err()
+}
+
+// GetBatchStatus retrieves a batch's aggregate record and its children's
+// individual statuses, for the HTTP layer to poll without looping over
+// GetJobStatus per child.
+func (c *Client) GetBatchStatus(ctx context.Context, batchID string) (*types.BatchExtractionJob, []types.BatchChildStatus, error) {
+	data, err := c.redis.Get(ctx, batchKey(batchID)).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, nil, fmt.Errorf("batch not found: %s", batchID)
+		}
+		return nil, nil, fmt.Errorf("failed to get batch: %w", err)
+	}
+
+	var batch types.BatchExtractionJob
+	if err := json.Unmarshal(data, &batch); err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal batch: %w", err)
+	}
+
+	raw, err := c.redis.HGetAll(ctx, batchChildrenKey(batchID)).Result()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get batch children: %w", err)
+	}
+
+	children := make([]types.BatchChildStatus, 0, len(raw))
+	for _, v := range raw {
+		var child types.BatchChildStatus
+		if err := json.Unmarshal([]byte(v), &child); err != nil {
 			continue
 		}
+		children = append(children, child)
+	}
+
+	return &batch, children, nil
+}
+
+// RecordBatchChildResult updates one batch child's status and re-evaluates
+// the batch's aggregate state: Completed once every child has reached a
+// terminal status, or Aborted as soon as Failed reaches MaxFailures (when
+// set above 0). Aborting doesn't cancel children already in flight - like
+// MarkResumable, it only changes what GetBatchStatus reports, not an
+// in-flight attempt. The read-modify-write on the batch record is
+// best-effort, not transactional: concurrent children finishing at the same
+// instant can race, same as the rest of this client's Redis usage.
+func (c *Client) RecordBatchChildResult(ctx context.Context, batchID, jobID, url string, status types.JobStatus, errMsg string) error {
+	child := types.BatchChildStatus{JobID: jobID, URL: url, Status: status, Error: errMsg}
+	data, err := json.Marshal(child)
+	if err != nil {
+		return err
+	}
+	if err := c.redis.HSet(ctx, batchChildrenKey(batchID), jobID, data).Err(); err != nil {
+		return fmt.Errorf("failed to record batch child: %w", err)
+	}
+	c.redis.Expire(ctx, batchChildrenKey(batchID), 7*24*time.Hour)
+
+	raw, err := c.redis.Get(ctx, batchKey(batchID)).Bytes()
+	if err != nil {
+		return fmt.Errorf("failed to load batch: %w", err)
+	}
+	var batch types.BatchExtractionJob
+	if err := json.Unmarshal(raw, &batch); err != nil {
+		return fmt.Errorf("failed to unmarshal batch: %w", err)
+	}
 
-		jobIDs = append(jobIDs, jobID)
+	switch status {
+	case types.StatusCompleted:
+		batch.Completed++
+	case types.StatusFailed:
+		batch.Failed++
+	}
+	batch.UpdatedAt = time.Now()
+
+	switch {
+	case batch.MaxFailures > 0 && batch.Failed >= batch.MaxFailures:
+		batch.Status = types.BatchStatusAborted
+	case batch.Completed+batch.Failed >= batch.Total:
+		batch.Status = types.BatchStatusCompleted
+	case batch.Completed+batch.Failed > 0:
+		batch.Status = types.BatchStatusRunning
 	}
 
-	return jobIDs, nil
+	return c.storeBatchMetadata(ctx, &batch)
 }
 
 // GetJobStatus retrieves the current status of a job
@@ -149,13 +527,75 @@ func (c *Client) GetJobStatus(ctx context.Context, jobID string) (*types.Extract
 	return &job, nil
 }
 
-// UpdateJobStatus updates the status of a job
+// ActiveJobIDs returns the IDs of jobs currently pending or processing, by
+// scanning job:{id} metadata keys (job:{id}:puller/:progress and other
+// suffixed keys are skipped, not job metadata). Satisfies
+// cleanup.ActiveJobLister, so TempFileCleanup can skip a slow job's temp
+// files instead of judging them by age alone.
+func (c *Client) ActiveJobIDs(ctx context.Context) (map[string]struct{}, error) {
+	active := make(map[string]struct{})
+
+	iter := c.redis.Scan(ctx, 0, "job:*", 200).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+		id := strings.TrimPrefix(key, "job:")
+		if id == key || strings.Contains(id, ":") {
+			continue
+		}
+
+		data, err := c.redis.Get(ctx, key).Bytes()
+		if err != nil {
+			continue // vanished between Scan and Get
+		}
+		var job types.ExtractionJob
+		if err := json.Unmarshal(data, &job); err != nil {
+			continue
+		}
+		if job.Status == types.StatusPending || job.Status == types.StatusProcessing {
+			active[job.ID] = struct{}{}
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return active, fmt.Errorf("failed to scan active jobs: %w", err)
+	}
+
+	return active, nil
+}
+
+// progressMilestones are the percentages UpdateJobStatus fires a "progress"
+// webhook event for, so long-running extractions give subscribers more to
+// go on than a single jump from "started" straight to "completed".
+var progressMilestones = []int{25, 50, 75}
+
+// crossedMilestone reports the first entry in progressMilestones that lies
+// in (oldProgress, newProgress], if any - i.e. the milestone this update
+// just passed, so UpdateJobStatus fires it once rather than on every call
+// that happens to still be above it.
+func crossedMilestone(oldProgress, newProgress int) (int, bool) {
+	for _, m := range progressMilestones {
+		if oldProgress < m && newProgress >= m {
+			return m, true
+		}
+	}
+	return 0, false
+}
+
+// UpdateJobStatus updates the status of a job. A webhook event is published
+// whenever the status changes (firing "started" in addition to the status
+// name itself on the first pending->processing transition), and separately
+// whenever progress crosses one of progressMilestones, so progress-only
+// updates between milestones (the worker calls this repeatedly while
+// processing) don't flood registered webhooks.
 func (c *Client) UpdateJobStatus(ctx context.Context, jobID string, status types.JobStatus, progress int, errorMsg string) error {
 	job, err := c.GetJobStatus(ctx, jobID)
 	if err != nil {
 		return err
 	}
 
+	oldStatus := job.Status
+	oldProgress := job.Progress
+	statusChanged := oldStatus != status
+
 	job.Status = status
 	job.Progress = progress
 	job.UpdatedAt = time.Now()
@@ -163,10 +603,27 @@ func (c *Client) UpdateJobStatus(ctx context.Context, jobID string, status types
 		job.Error = errorMsg
 	}
 
-	return c.storeJobMetadata(ctx, job)
+	if err := c.storeJobMetadata(ctx, job); err != nil {
+		return err
+	}
+
+	switch {
+	case statusChanged:
+		if status == types.StatusProcessing && oldStatus == types.StatusPending {
+			c.publishWebhookEvent(ctx, job, "started")
+		}
+		c.publishWebhookEvent(ctx, job, string(status))
+	default:
+		if milestone, ok := crossedMilestone(oldProgress, progress); ok {
+			c.publishWebhookEvent(ctx, job, fmt.Sprintf("progress_%d", milestone))
+		}
+	}
+
+	return nil
 }
 
-// UpdateJobResult updates the job with extraction result
+// UpdateJobResult updates the job with extraction result and publishes the
+// completion webhook event.
 func (c *Client) UpdateJobResult(ctx context.Context, jobID string, result *types.ExtractionResult, metadata *types.MediaMetadata) error {
 	job, err := c.GetJobStatus(ctx, jobID)
 	if err != nil {
@@ -183,14 +640,108 @@ func (c *Client) UpdateJobResult(ctx context.Context, jobID string, result *type
 		return err
 	}
 
-	// Trigger webhook if configured
-	if job.Request.WebhookURL != "" {
-		go c.triggerWebhook(job)
-	}
+	c.storeDedupPointer(ctx, job)
+	c.publishWebhookEvent(ctx, job, string(types.StatusCompleted))
 
 	return nil
 }
 
+// dedupResultKey returns the Redis key a completed job's ID is pointed to
+// from under, for LookupDedupedResult.
+func dedupResultKey(key string) string {
+	return fmt.Sprintf("dedup:result:%s", key)
+}
+
+// storeDedupPointer points job's dedup.Key at job.ID, expiring alongside
+// its Result's presigned URL, so LookupDedupedResult never hands back a
+// result whose download link has gone stale. Best-effort: a failure here
+// just means the next identical request re-extracts instead of hitting
+// the cache, not a broken job.
+func (c *Client) storeDedupPointer(ctx context.Context, job *types.ExtractionJob) {
+	if job.Result == nil {
+		return
+	}
+	ttl := time.Until(job.Result.ExpiresAt)
+	if ttl <= 0 {
+		return
+	}
+	key := dedupResultKey(dedup.Key(job.Request))
+	if err := c.redis.Set(ctx, key, job.ID, ttl).Err(); err != nil {
+		c.logger.Warn("Failed to store dedup pointer", zap.String("job_id", job.ID), zap.Error(err))
+	}
+}
+
+// LookupDedupedResult returns the most recent completed job for req's
+// content-affecting fields (see dedup.Key), if one exists, hasn't expired,
+// and its presigned download URL is still live. Callers use this ahead of
+// enqueueing a new job so identical requests for the same (still-fresh)
+// content reuse the existing result instead of re-extracting it.
+func (c *Client) LookupDedupedResult(ctx context.Context, req types.ExtractionRequest) (*types.ExtractionJob, bool, error) {
+	jobID, err := c.redis.Get(ctx, dedupResultKey(dedup.Key(req))).Result()
+	if err == redis.Nil {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to look up dedup cache: %w", err)
+	}
+
+	job, err := c.GetJobStatus(ctx, jobID)
+	if err != nil {
+		// The job metadata expired or was evicted out from under a still-live
+		// dedup pointer - treat it as a miss rather than erroring the caller.
+		return nil, false, nil
+	}
+	if job.Status != types.StatusCompleted || job.Result == nil || time.Now().After(job.Result.ExpiresAt) {
+		return nil, false, nil
+	}
+
+	return job, true, nil
+}
+
+// publishWebhookEvent schedules delivery of event for job to any registered
+// webhook. It is a no-op when no dispatcher is configured or no webhook is
+// registered for the job. event is usually job.Status's string form, but
+// UpdateJobStatus also fires intermediate lifecycle events ("started",
+// "progress_25", ...) that don't correspond to a JobStatus value.
+func (c *Client) publishWebhookEvent(ctx context.Context, job *types.ExtractionJob, event string) {
+	if c.webhooks == nil {
+		return
+	}
+
+	payload := map[string]interface{}{
+		"job_id":     job.ID,
+		"status":     job.Status,
+		"progress":   job.Progress,
+		"url":        job.Request.URL,
+		"format":     job.Request.Format,
+		"error":      job.Error,
+		"created_at": job.CreatedAt,
+		"updated_at": job.UpdatedAt,
+	}
+
+	if job.Status == types.StatusCompleted && job.Result != nil {
+		payload["download_url"] = job.Result.DownloadURL
+		payload["size_bytes"] = job.Result.SizeBytes
+		payload["filename"] = job.Result.Filename
+		payload["format"] = job.Result.Format
+		payload["expires_at"] = job.Result.ExpiresAt
+	}
+
+	if job.Metadata != nil {
+		payload["title"] = job.Metadata.Title
+		payload["duration"] = job.Metadata.Duration
+		payload["platform"] = job.Metadata.Platform
+	}
+
+	if err := c.webhooks.Publish(ctx, job.ID, event, payload); err != nil {
+		c.logger.Warn("Failed to publish webhook event",
+			zap.String("job_id", job.ID),
+			zap.String("event", event),
+			zap.Error(err),
+		)
+	}
+}
+
 // storeJobMetadata stores job metadata in Redis
 func (c *Client) storeJobMetadata(ctx context.Context, job *types.ExtractionJob) error {
 	key := fmt.Sprintf("job:%s", job.ID)
@@ -203,8 +754,12 @@ func (c *Client) storeJobMetadata(ctx context.Context, job *types.ExtractionJob)
 	return c.redis.Set(ctx, key, data, 7*24*time.Hour).Err()
 }
 
-// getQueueForQuality determines the priority queue based on quality setting
-func (c *Client) getQueueForQuality(quality string) string {
+// QueueForQuality determines the Asynq priority queue - and, for callers
+// gating ffmpeg work through an internal/pool.FFmpegPool, the
+// RunGatedPriority priority - based on a job's requested quality. Shared by
+// Client (enqueueing) and Server (admission control) so the two stay in
+// sync on what counts as low priority.
+func QueueForQuality(quality string) string {
 	switch quality {
 	case "4k":
 		return "critical"
@@ -215,115 +770,36 @@ func (c *Client) getQueueForQuality(quality string) string {
 	}
 }
 
-// triggerWebhook sends a POST request to the webhook URL (non-blocking)
-func (c *Client) triggerWebhook(job *types.ExtractionJob) {
-	// Skip if no webhook URL provided
-	if job.Request.WebhookURL == "" {
-		return
-	}
-
-	// Run in goroutine to avoid blocking
-	go func() {
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
-
-		// Prepare webhook payload
-		payload := map[string]interface{}{
-			"job_id":     job.ID,
-			"status":     job.Status,
-			"url":        job.Request.URL,
-			"format":     job.Request.Format,
-			"error":      job.Error,
-			"created_at": job.CreatedAt,
-			"updated_at": job.UpdatedAt,
-		}
-
-		// Add result data if successful
-		if job.Status == "completed" && job.Result != nil {
-			payload["download_url"] = job.Result.DownloadURL
-			payload["size_bytes"] = job.Result.SizeBytes
-			payload["filename"] = job.Result.Filename
-			payload["format"] = job.Result.Format
-			payload["expires_at"] = job.Result.ExpiresAt
-		}
-
-		// Add metadata if available
-		if job.Metadata != nil {
-			payload["title"] = job.Metadata.Title
-			payload["duration"] = job.Metadata.Duration
-			payload["platform"] = job.Metadata.Platform
-		}
-
-		jsonData, err := json.Marshal(payload)
+// QueueDepth returns the total number of pending-plus-active tasks across
+// monitoredQueues, via Asynq's own Inspector rather than reading its
+// internal Redis keys directly - so this stays correct if Asynq ever
+// changes its on-disk layout. Intended as the queue-pressure signal for a
+// DynamicConcurrency's LoadSampler.
+func (c *Client) QueueDepth(ctx context.Context) (int64, error) {
+	var depth int64
+	for _, q := range monitoredQueues {
+		info, err := c.inspector.GetQueueInfo(q)
 		if err != nil {
-			c.logger.Error("Failed to marshal webhook payload",
-				zap.String("job_id", job.ID),
-				zap.Error(err),
-			)
-			return
+			return 0, fmt.Errorf("failed to inspect queue %q: %w", q, err)
 		}
-
-		// Create HTTP request
-		req, err := http.NewRequestWithContext(ctx, "POST", job.Request.WebhookURL, bytes.NewBuffer(jsonData))
-		if err != nil {
-			c.logger.Error("Failed to create webhook request",
-				zap.String("job_id", job.ID),
-				zap.Error(err),
-			)
-			return
-		}
-
-		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set("User-Agent", "MediaExtraction-Webhook/1.0")
-
-		// Send request with retry logic (3 attempts)
-		client := &http.Client{Timeout: 10 * time.Second}
-		var lastErr error
-		for attempt := 1; attempt <= 3; attempt++ {
-			resp, err := client.Do(req)
-			if err != nil {
-				lastErr = err
-				c.logger.Warn("Webhook request failed, retrying...",
-					zap.String("job_id", job.ID),
-					zap.Int("attempt", attempt),
-					zap.Error(err),
-				)
-				time.Sleep(time.Duration(attempt) * time.Second) // Exponential backoff
-				continue
-			}
-			defer resp.Body.Close()
-
-			if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-				c.logger.Info("Webhook triggered successfully",
-					zap.String("job_id", job.ID),
-					zap.String("webhook_url", job.Request.WebhookURL),
-					zap.Int("status_code", resp.StatusCode),
-				)
-				return
-			}
-
-			lastErr = fmt.Errorf("webhook returned status %d", resp.StatusCode)
-			c.logger.Warn("Webhook returned non-2xx status, retrying...",
-				zap.String("job_id", job.ID),
-				zap.Int("status_code", resp.StatusCode),
-				zap.Int("attempt", attempt),
-			)
-			time.Sleep(time.Duration(attempt) * time.Second)
-		}
-
-		c.logger.Error("Webhook failed after 3 attempts",
-			zap.String("job_id", job.ID),
-			zap.String("webhook_url", job.Request.WebhookURL),
-			zap.Error(lastErr),
-		)
-	}()
+		depth += int64(info.Pending + info.Active)
+	}
+	return depth, nil
 }
 
-// Close closes the client connections
+// Close closes the client connections. The shared redis connection is only
+// actually closed once every other holder of it (e.g. a
+// cache.DistributedCache on the same DSN) has released its own reference.
 func (c *Client) Close() error {
+	if err := c.inspector.Close(); err != nil {
+		c.logger.Warn("Failed to close Asynq inspector", zap.Error(err))
+	}
 	if err := c.asynq.Close(); err != nil {
 		return err
 	}
+	if c.conn != nil {
+		return c.conn.Close()
+	}
 	return c.redis.Close()
 }
 
@@ -1,39 +1,86 @@
 package queue
 
 import (
-	"runtime"
+	"context"
+	"math"
+	"os"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"go.uber.org/zap"
 )
 
-// DynamicConcurrency adjusts worker concurrency based on system load
-// This prevents resource exhaustion during high traffic
+// LoadSampler supplies DynamicConcurrency with the real signals its
+// gradient controller needs each tick. Implementations wrap whatever this
+// process actually has on hand - pool.WorkerPool.ActiveJobs for Inflight,
+// queue.Client.QueueDepth for QueueDepth - so tests can inject fakes instead
+// of standing up a real worker pool and Redis.
+type LoadSampler interface {
+	// Inflight returns the number of requests/jobs currently being
+	// processed - Little's Law's "L".
+	Inflight() int64
+	// QueueDepth returns the number of jobs waiting or running across the
+	// queues this process cares about. Used as a backlog-pressure nudge
+	// alongside the gradient, not as a replacement for it: a deep queue
+	// with low latency growth should still grow concurrency, not just get
+	// throttled further.
+	QueueDepth(ctx context.Context) (int64, error)
+}
+
+// DynamicConcurrency adjusts worker concurrency based on real load signals
+// (per-job latency, in-flight count, queue depth) rather than goroutine
+// count - see RecordLatency and LoadSampler. It implements the
+// gradient/AIMD scheme from Netflix's concurrency-limits: each tick it
+// estimates a target concurrency via Little's Law (limit = inflight *
+// gradient, gradient = rttNoLoad/rttCurrent clamped to [minGradient, 1]),
+// then additively increases toward it when under, multiplicatively
+// decreases when over.
 type DynamicConcurrency struct {
 	minWorkers     int
 	maxWorkers     int
 	currentWorkers int
-	targetCPU      float64 // Target CPU utilization (0.0 - 1.0)
 	interval       time.Duration
+	sampler        LoadSampler
 	logger         *zap.Logger
-	mu             sync.RWMutex
-	closeCh        chan struct{}
+
+	mu      sync.RWMutex
+	closeCh chan struct{}
+
+	rtt *rttTracker
+
+	// updates is drained-and-replaced rather than blocking, per
+	// GetConcurrency's old doc comment complaint that nobody reads
+	// it - a slow or absent consumer must never stall the control loop.
+	updates chan int
 }
 
-// NewDynamicConcurrency creates a dynamic concurrency controller
-// minWorkers: Minimum concurrent workers (e.g., 2)
-// maxWorkers: Maximum concurrent workers (e.g., 12)
-// targetCPU: Target CPU utilization 0.7 = 70% (scale up if under, down if over)
-func NewDynamicConcurrency(minWorkers, maxWorkers int, targetCPU float64, logger *zap.Logger) *DynamicConcurrency {
+// minGradient floors how aggressively a single bad tick can shrink the
+// target - without it, one slow request could suggest dropping to near
+// zero concurrency.
+const minGradient = 0.5
+
+// decreaseFactor is the multiplicative-decrease applied to currentWorkers
+// on a tick where the gradient-derived target is below it.
+const decreaseFactor = 0.9
+
+// NewDynamicConcurrency creates a dynamic concurrency controller.
+// minWorkers/maxWorkers bound the output; sampler supplies the per-tick
+// inflight and queue-depth signals. Feed real per-job latency via
+// RecordLatency as jobs complete - without any samples, the gradient stays
+// at 1.0 and adjustment is driven by queue depth alone.
+func NewDynamicConcurrency(minWorkers, maxWorkers int, sampler LoadSampler, logger *zap.Logger) *DynamicConcurrency {
 	return &DynamicConcurrency{
 		minWorkers:     minWorkers,
 		maxWorkers:     maxWorkers,
 		currentWorkers: minWorkers,
-		targetCPU:      targetCPU,
 		interval:       30 * time.Second, // Adjust every 30 seconds
+		sampler:        sampler,
 		logger:         logger,
 		closeCh:        make(chan struct{}),
+		rtt:            newRTTTracker(),
+		updates:        make(chan int, 1),
 	}
 }
 
@@ -47,82 +94,237 @@ func (dc *DynamicConcurrency) Stop() {
 	close(dc.closeCh)
 }
 
-// GetConcurrency returns current worker count
+// RecordLatency feeds a single job's observed wall-clock latency into the
+// gradient controller's rttCurrent/rttNoLoad tracking. Callers with a real
+// per-job timing (e.g. handlers.ExtractionHandler.HandleExtraction's
+// duration := time.Since(startTime)) should call this as each job finishes;
+// it is safe to call from multiple goroutines concurrently.
+func (dc *DynamicConcurrency) RecordLatency(d time.Duration) {
+	dc.rtt.record(d)
+}
+
+// GetConcurrency returns the current worker count. Prefer Updates() for
+// callers that want to react to changes rather than poll.
 func (dc *DynamicConcurrency) GetConcurrency() int {
 	dc.mu.RLock()
 	defer dc.mu.RUnlock()
 	return dc.currentWorkers
 }
 
+// Updates returns a channel that receives the current worker count after
+// every tick that changes it. The channel is buffered to 1 and drained on
+// send, so a slow or absent consumer sees only the latest value rather than
+// blocking the control loop or building up a backlog of stale ones.
+func (dc *DynamicConcurrency) Updates() <-chan int {
+	return dc.updates
+}
+
+func (dc *DynamicConcurrency) publish(n int) {
+	select {
+	case <-dc.updates:
+	default:
+	}
+	select {
+	case dc.updates <- n:
+	default:
+	}
+}
+
 func (dc *DynamicConcurrency) monitor() {
 	ticker := time.NewTicker(dc.interval)
 	defer ticker.Stop()
 
-	var prevIdleTime, prevTotalTime uint64
-
 	for {
 		select {
 		case <-ticker.C:
-			cpuUsage := dc.getCPUUsage(&prevIdleTime, &prevTotalTime)
-			dc.adjust(cpuUsage)
+			dc.tick()
 		case <-dc.closeCh:
 			return
 		}
 	}
 }
 
-func (dc *DynamicConcurrency) getCPUUsage(prevIdle, prevTotal *uint64) float64 {
-	// Simple CPU estimation based on goroutine count and NumCPU
-	numCPU := runtime.NumCPU()
-	numGoroutine := runtime.NumGoroutine()
+func (dc *DynamicConcurrency) tick() {
+	inflight := dc.sampler.Inflight()
 
-	// Estimate: high goroutine count relative to CPU = high load
-	estimatedUsage := float64(numGoroutine) / float64(numCPU*100)
-	if estimatedUsage > 1.0 {
-		estimatedUsage = 0.95 // Cap at 95%
+	var queueDepth int64
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	depth, err := dc.sampler.QueueDepth(ctx)
+	cancel()
+	if err != nil {
+		dc.logger.Warn("Failed to sample queue depth, proceeding without backlog pressure", zap.Error(err))
+	} else {
+		queueDepth = depth
 	}
 
-	return estimatedUsage
+	gradient := dc.rtt.gradient()
+	dc.adjust(inflight, queueDepth, gradient)
 }
 
-func (dc *DynamicConcurrency) adjust(cpuUsage float64) {
+// adjust moves currentWorkers one step toward the gradient/Little's-Law
+// target: limit = inflight * gradient, with sqrt(currentWorkers) of slack
+// (the concurrency-limits "queueSize" allowance, so a handful of idle
+// workers above the strict Little's Law estimate isn't immediately clawed
+// back) and a backlog nudge so a growing queue pushes the target up even
+// before latency has had a chance to climb.
+func (dc *DynamicConcurrency) adjust(inflight, queueDepth int64, gradient float64) {
 	dc.mu.Lock()
 	defer dc.mu.Unlock()
 
 	oldWorkers := dc.currentWorkers
 
-	// Scale up if CPU below target (more capacity available)
-	if cpuUsage < dc.targetCPU*0.8 && dc.currentWorkers < dc.maxWorkers {
+	target := float64(inflight)*gradient + math.Sqrt(float64(dc.currentWorkers))
+	if queueDepth > int64(dc.currentWorkers) {
+		target += math.Log1p(float64(queueDepth - int64(dc.currentWorkers)))
+	}
+
+	switch {
+	case target > float64(dc.currentWorkers) && dc.currentWorkers < dc.maxWorkers:
 		dc.currentWorkers++
-		dc.logger.Info("Scaling up workers",
-			zap.Int("old_workers", oldWorkers),
-			zap.Int("new_workers", dc.currentWorkers),
-			zap.Float64("cpu_usage", cpuUsage),
-		)
+	case target < float64(dc.currentWorkers) && dc.currentWorkers > dc.minWorkers:
+		dc.currentWorkers = int(float64(dc.currentWorkers) * decreaseFactor)
+		if dc.currentWorkers < dc.minWorkers {
+			dc.currentWorkers = dc.minWorkers
+		}
 	}
 
-	// Scale down if CPU above target (overloaded)
-	if cpuUsage > dc.targetCPU*1.2 && dc.currentWorkers > dc.minWorkers {
-		dc.currentWorkers--
-		dc.logger.Info("Scaling down workers",
+	if dc.currentWorkers != oldWorkers {
+		dc.logger.Info("Adjusting dynamic concurrency",
 			zap.Int("old_workers", oldWorkers),
 			zap.Int("new_workers", dc.currentWorkers),
-			zap.Float64("cpu_usage", cpuUsage),
+			zap.Int64("inflight", inflight),
+			zap.Int64("queue_depth", queueDepth),
+			zap.Float64("gradient", gradient),
+			zap.Float64("target", target),
 		)
+		dc.publish(dc.currentWorkers)
 	}
 }
 
 // Stats returns current concurrency statistics
 func (dc *DynamicConcurrency) Stats() map[string]interface{} {
 	dc.mu.RLock()
-	defer dc.mu.RUnlock()
+	currentWorkers := dc.currentWorkers
+	dc.mu.RUnlock()
 
-	return map[string]interface{}{
-		"current_workers": dc.currentWorkers,
+	stats := map[string]interface{}{
+		"current_workers": currentWorkers,
 		"min_workers":     dc.minWorkers,
 		"max_workers":     dc.maxWorkers,
-		"target_cpu":      dc.targetCPU,
-		"num_cpu":         runtime.NumCPU(),
-		"num_goroutine":   runtime.NumGoroutine(),
+		"rtt_current_ms":  dc.rtt.current().Milliseconds(),
+		"rtt_no_load_ms":  dc.rtt.noLoad().Milliseconds(),
+		"gradient":        dc.rtt.gradient(),
+	}
+	if cpu, ok := readCgroupCPUUsage(); ok {
+		stats["cgroup_cpu_usage"] = cpu
+	}
+	return stats
+}
+
+// rttTracker maintains an EWMA of recent per-job latency (rttCurrent) and a
+// slow-decaying floor of the best latency seen (rttNoLoad), the two inputs
+// the gradient controller compares each tick.
+type rttTracker struct {
+	mu         sync.Mutex
+	currentRTT time.Duration
+	noLoadRTT  time.Duration
+	hasSample  bool
+}
+
+// rttEWMAAlpha weights how much each new sample moves rttCurrent - low
+// enough that one slow job doesn't swing the gradient on its own.
+const rttEWMAAlpha = 0.2
+
+// rttNoLoadDecay lets rttNoLoad creep back up slowly (rather than staying
+// pinned to the single fastest job ever observed) so a permanent latency
+// regime change is eventually reflected instead of forever looking
+// "overloaded" relative to a stale floor.
+const rttNoLoadDecay = 1.001
+
+func newRTTTracker() *rttTracker {
+	return &rttTracker{}
+}
+
+func (t *rttTracker) record(d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.hasSample {
+		t.currentRTT = d
+		t.noLoadRTT = d
+		t.hasSample = true
+		return
+	}
+
+	t.currentRTT = time.Duration(rttEWMAAlpha*float64(d) + (1-rttEWMAAlpha)*float64(t.currentRTT))
+
+	if d < t.noLoadRTT {
+		t.noLoadRTT = d
+	} else {
+		t.noLoadRTT = time.Duration(float64(t.noLoadRTT) * rttNoLoadDecay)
+	}
+}
+
+func (t *rttTracker) current() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.currentRTT
+}
+
+func (t *rttTracker) noLoad() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.noLoadRTT
+}
+
+// gradient returns rttNoLoad/rttCurrent clamped to [minGradient, 1] - 1.0
+// (no throttling) until at least one RecordLatency sample exists.
+func (t *rttTracker) gradient() float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.hasSample || t.currentRTT <= 0 {
+		return 1.0
+	}
+	g := float64(t.noLoadRTT) / float64(t.currentRTT)
+	if g > 1.0 {
+		g = 1.0
+	}
+	if g < minGradient {
+		g = minGradient
+	}
+	return g
+}
+
+// readCgroupCPUUsage best-effort reads this process's cgroup v2 cumulative
+// CPU usage (the raw usage_usec value, in microseconds) from
+// /sys/fs/cgroup/cpu.stat, falling back to reporting unavailable rather
+// than erroring - this is surfaced via Stats() only, not fed into adjust(),
+// per the request marking it optional. cgroup v1 and non-Linux hosts
+// simply report ok=false.
+func readCgroupCPUUsage() (float64, bool) {
+	data, err := os.ReadFile("/sys/fs/cgroup/cpu.stat")
+	if err != nil {
+		return 0, false
+	}
+
+	var usageUsec float64
+	found := false
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 || fields[0] != "usage_usec" {
+			continue
+		}
+		v, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			return 0, false
+		}
+		usageUsec = v
+		found = true
+		break
+	}
+	if !found {
+		return 0, false
 	}
+	return usageUsec, true
 }
@@ -0,0 +1,67 @@
+package queue
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// fairDispatchPollInterval bounds how long a newly enqueued job can sit
+// before FairDispatcher notices the fair queue is no longer empty.
+const fairDispatchPollInterval = 20 * time.Millisecond
+
+// FairDispatcher drains a Client's FairQueue in weighted-fair order and
+// submits each job to Asynq via submitJob. Without it, fq.Enqueue (called
+// by enqueueJob) has no matching consumer: a tenant's weight and
+// virtualTime bookkeeping would have zero effect on real dispatch order,
+// and every tenantQueue's backing slice would grow without bound.
+type FairDispatcher struct {
+	client *Client
+	logger *zap.Logger
+}
+
+// NewFairDispatcher creates a dispatcher bound to client. Construct one per
+// process that enqueues through client (cmd/api and cmd/worker both do -
+// see enqueueJob's callers) and call Start once; each Client has its own
+// FairQueue, so a dispatcher only drains the jobs entered through its own
+// Client instance.
+func NewFairDispatcher(client *Client, logger *zap.Logger) *FairDispatcher {
+	return &FairDispatcher{client: client, logger: logger}
+}
+
+// Start runs the draining loop in the background until ctx is canceled.
+func (d *FairDispatcher) Start(ctx context.Context) {
+	go d.run(ctx)
+}
+
+func (d *FairDispatcher) run(ctx context.Context) {
+	ticker := time.NewTicker(fairDispatchPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.drain(ctx)
+		}
+	}
+}
+
+// drain submits every job currently ready in the fair queue, so a burst of
+// enqueues doesn't sit waiting a full fairDispatchPollInterval per job.
+func (d *FairDispatcher) drain(ctx context.Context) {
+	for {
+		job := d.client.fairQueue.Dequeue()
+		if job == nil {
+			return
+		}
+		if err := d.client.submitJob(ctx, *job); err != nil {
+			d.logger.Warn("Failed to submit fair-dispatched job",
+				zap.String("job_id", job.ID),
+				zap.Error(err),
+			)
+		}
+	}
+}
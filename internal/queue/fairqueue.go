@@ -0,0 +1,213 @@
+package queue
+
+import (
+	"container/heap"
+	"sync"
+
+	"github.com/KeremKalyoncu/MedYan/internal/types"
+)
+
+// defaultJobCostSeconds estimates a job's processing cost when its metadata
+// doesn't carry a MediaMetadata.Duration yet (e.g. before metadata
+// extraction has run).
+const defaultJobCostSeconds = 60.0
+
+// defaultTenantWeight is the weight a tenant gets until SetWeight has been
+// called for it - every tenant starts on equal footing.
+const defaultTenantWeight = 1.0
+
+// tenantQueue is one tenant's FIFO sub-queue plus its fair-queuing
+// bookkeeping, entered into fairHeap so Dequeue can always pick the tenant
+// with the smallest virtualTime.
+type tenantQueue struct {
+	tenant      string
+	weight      float64
+	virtualTime float64
+	jobs        []*types.ExtractionJob
+	index       int // heap.Interface bookkeeping
+}
+
+// fairHeap orders tenantQueues by virtualTime, ascending, skipping any
+// tenant with no jobs queued (it isn't consuming its share right now).
+type fairHeap []*tenantQueue
+
+func (h fairHeap) Len() int { return len(h) }
+func (h fairHeap) Less(i, j int) bool {
+	return h[i].virtualTime < h[j].virtualTime
+}
+func (h fairHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+func (h *fairHeap) Push(x interface{}) {
+	tq := x.(*tenantQueue)
+	tq.index = len(*h)
+	*h = append(*h, tq)
+}
+func (h *fairHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	tq := old[n-1]
+	old[n-1] = nil
+	tq.index = -1
+	*h = old[:n-1]
+	return tq
+}
+
+// FairQueue is a weighted fair queue of ExtractionJobs: Dequeue always
+// returns a job from whichever tenant has consumed the least weighted
+// processing time so far, so one noisy tenant can't starve the rest of the
+// worker pool the way a flat FIFO would let it.
+type FairQueue struct {
+	mu      sync.Mutex
+	tenants map[string]*tenantQueue
+	h       fairHeap
+	inHeap  map[string]bool
+	nJobs   int
+}
+
+// NewFairQueue creates an empty FairQueue.
+func NewFairQueue() *FairQueue {
+	return &FairQueue{
+		tenants: make(map[string]*tenantQueue),
+		inHeap:  make(map[string]bool),
+	}
+}
+
+// tenantFor returns (creating if necessary) tenant's bookkeeping entry.
+// Caller must hold fq.mu.
+func (fq *FairQueue) tenantFor(tenant string) *tenantQueue {
+	tq, ok := fq.tenants[tenant]
+	if !ok {
+		tq = &tenantQueue{tenant: tenant, weight: defaultTenantWeight}
+		fq.tenants[tenant] = tq
+	}
+	return tq
+}
+
+// jobCost estimates a job's processing cost in seconds from its metadata
+// duration, falling back to defaultJobCostSeconds before metadata has been
+// extracted.
+func jobCost(job *types.ExtractionJob) float64 {
+	if job.Metadata != nil && job.Metadata.Duration > 0 {
+		return float64(job.Metadata.Duration)
+	}
+	return defaultJobCostSeconds
+}
+
+// Enqueue adds job under tenant (job.Tenant, defaulted by the caller to a
+// hostname when empty). A tenant new to the queue starts at the minimum
+// virtualTime currently in flight, so it doesn't have to "catch up" to
+// tenants that have been running for a while.
+func (fq *FairQueue) Enqueue(job *types.ExtractionJob) {
+	fq.mu.Lock()
+	defer fq.mu.Unlock()
+
+	tq := fq.tenantFor(job.Tenant)
+	if len(tq.jobs) == 0 && len(fq.h) > 0 {
+		tq.virtualTime = fq.h[0].virtualTime
+	}
+	tq.jobs = append(tq.jobs, job)
+	fq.nJobs++
+
+	if !fq.inHeap[job.Tenant] {
+		heap.Push(&fq.h, tq)
+		fq.inHeap[job.Tenant] = true
+	} else {
+		heap.Fix(&fq.h, tq.index)
+	}
+}
+
+// Dequeue pops the next job from the tenant with the smallest virtualTime,
+// then advances that tenant's virtualTime by cost/weight so the next call
+// naturally favors whichever tenant has consumed the least weighted share.
+func (fq *FairQueue) Dequeue() *types.ExtractionJob {
+	fq.mu.Lock()
+	defer fq.mu.Unlock()
+
+	if len(fq.h) == 0 {
+		return nil
+	}
+
+	tq := fq.h[0]
+	job := tq.jobs[0]
+	tq.jobs = tq.jobs[1:]
+	fq.nJobs--
+
+	weight := tq.weight
+	if weight <= 0 {
+		weight = defaultTenantWeight
+	}
+	tq.virtualTime += jobCost(job) / weight
+
+	if len(tq.jobs) == 0 {
+		heap.Remove(&fq.h, tq.index)
+		delete(fq.inHeap, tq.tenant)
+	} else {
+		heap.Fix(&fq.h, tq.index)
+	}
+
+	return job
+}
+
+// Length returns the number of queued jobs for tenant, or the total across
+// all tenants when tenant is "".
+func (fq *FairQueue) Length(tenant string) int {
+	fq.mu.Lock()
+	defer fq.mu.Unlock()
+
+	if tenant == "" {
+		return fq.nJobs
+	}
+	if tq, ok := fq.tenants[tenant]; ok {
+		return len(tq.jobs)
+	}
+	return 0
+}
+
+// SetWeight overrides tenant's fair-share weight (higher runs more often
+// relative to other tenants). Applies to jobs dequeued after the call.
+func (fq *FairQueue) SetWeight(tenant string, weight float64) {
+	fq.mu.Lock()
+	defer fq.mu.Unlock()
+
+	fq.tenantFor(tenant).weight = weight
+}
+
+// TenantStats describes one tenant's current standing in the fair queue.
+type TenantStats struct {
+	Tenant      string  `json:"tenant"`
+	Weight      float64 `json:"weight"`
+	VirtualTime float64 `json:"virtual_time"`
+	QueueLength int     `json:"queue_length"`
+}
+
+// Tenants returns stats for every tenant the queue currently knows about
+// (including ones with an empty queue but a non-default weight), for the
+// admin queue-inspection endpoint.
+func (fq *FairQueue) Tenants() []TenantStats {
+	fq.mu.Lock()
+	defer fq.mu.Unlock()
+
+	stats := make([]TenantStats, 0, len(fq.tenants))
+	for _, tq := range fq.tenants {
+		stats = append(stats, TenantStats{
+			Tenant:      tq.tenant,
+			Weight:      tq.weight,
+			VirtualTime: tq.virtualTime,
+			QueueLength: len(tq.jobs),
+		})
+	}
+	return stats
+}
+
+// tenantFromHostname defaults ExtractionJob.Tenant to a request's hostname,
+// matching HistoryHandler's per-site partitioning (site:{hostname}:history),
+// when the caller didn't set one explicitly.
+func tenantFromHostname(hostname string) string {
+	if hostname == "" {
+		return "unknown"
+	}
+	return hostname
+}
@@ -0,0 +1,100 @@
+package queue
+
+import (
+	"testing"
+
+	"github.com/KeremKalyoncu/MedYan/internal/types"
+)
+
+func newJob(id, tenant string) *types.ExtractionJob {
+	return &types.ExtractionJob{ID: id, Tenant: tenant}
+}
+
+// TestFairQueueNoisyTenantDoesNotStarve is the invariant FairQueue exists
+// for: one tenant enqueuing far more jobs than another must not make the
+// other wait behind all of them - Dequeue should alternate between the two
+// once both have jobs queued, rather than draining the noisy tenant first.
+func TestFairQueueNoisyTenantDoesNotStarve(t *testing.T) {
+	fq := NewFairQueue()
+
+	for i := 0; i < 10; i++ {
+		fq.Enqueue(newJob("noisy-"+string(rune('a'+i)), "noisy"))
+	}
+	fq.Enqueue(newJob("quiet-1", "quiet"))
+
+	// "quiet" should come up within the first two dequeues - a flat FIFO
+	// would instead make it wait behind all 10 of "noisy"'s jobs.
+	sawQuiet := false
+	for i := 0; i < 2; i++ {
+		job := fq.Dequeue()
+		if job == nil {
+			t.Fatalf("Dequeue returned nil before queue was drained")
+		}
+		if job.Tenant == "quiet" {
+			sawQuiet = true
+		}
+	}
+	if !sawQuiet {
+		t.Fatalf("noisy tenant's backlog starved quiet tenant's job")
+	}
+}
+
+// TestFairQueueDequeueEmpty ensures Dequeue on an empty queue returns nil
+// rather than panicking, since FairDispatcher polls it unconditionally.
+func TestFairQueueDequeueEmpty(t *testing.T) {
+	fq := NewFairQueue()
+	if job := fq.Dequeue(); job != nil {
+		t.Fatalf("expected nil from an empty FairQueue, got %+v", job)
+	}
+}
+
+// TestFairQueueWeightFavorsHigherWeightTenant checks that SetWeight actually
+// changes dequeue order: a tenant given a much higher weight than another
+// advances its virtualTime much more slowly per job, so it should win
+// nearly every dequeue until its own backlog is drained.
+func TestFairQueueWeightFavorsHigherWeightTenant(t *testing.T) {
+	fq := NewFairQueue()
+	fq.SetWeight("heavy", 1000.0)
+
+	for _, id := range []string{"heavy-1", "heavy-2", "heavy-3"} {
+		fq.Enqueue(newJob(id, "heavy"))
+	}
+	for _, id := range []string{"light-1", "light-2", "light-3"} {
+		fq.Enqueue(newJob(id, "light"))
+	}
+
+	heavyCount, lightCount := 0, 0
+	for i := 0; i < 4; i++ {
+		switch fq.Dequeue().Tenant {
+		case "heavy":
+			heavyCount++
+		case "light":
+			lightCount++
+		}
+	}
+
+	if heavyCount < 3 {
+		t.Fatalf("expected heavy's much higher weight to win at least 3 of the first 4 dequeues, got heavy=%d light=%d", heavyCount, lightCount)
+	}
+}
+
+// TestFairQueueLength checks both the per-tenant and total Length views stay
+// consistent across Enqueue/Dequeue.
+func TestFairQueueLength(t *testing.T) {
+	fq := NewFairQueue()
+	fq.Enqueue(newJob("a-1", "a"))
+	fq.Enqueue(newJob("a-2", "a"))
+	fq.Enqueue(newJob("b-1", "b"))
+
+	if got := fq.Length(""); got != 3 {
+		t.Fatalf("Length(\"\") = %d, want 3", got)
+	}
+	if got := fq.Length("a"); got != 2 {
+		t.Fatalf("Length(\"a\") = %d, want 2", got)
+	}
+
+	fq.Dequeue()
+	if got := fq.Length(""); got != 2 {
+		t.Fatalf("Length(\"\") after one Dequeue = %d, want 2", got)
+	}
+}
@@ -0,0 +1,64 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// latencyChannel is the Redis pub/sub channel a worker process publishes
+// each completed job's wall-clock duration to. DynamicConcurrency is built
+// to resize a pool off in-process RecordLatency calls, but the pool it
+// resizes and the code that actually runs jobs can live in different
+// processes (cmd/api's workerPool vs. cmd/worker's ExtractionHandler) -
+// this channel is what bridges RecordLatency across that boundary.
+const latencyChannel = "queue:job_latency"
+
+// PublishLatency publishes a completed job's wall-clock duration for any
+// ConsumeLatency subscriber to pick up. Best-effort, like PublishProgress: a
+// subscriber that isn't listening at the moment simply misses the sample,
+// which only costs the gradient controller one data point.
+func (c *Client) PublishLatency(ctx context.Context, d time.Duration) {
+	if err := c.redis.Publish(ctx, latencyChannel, strconv.FormatInt(d.Milliseconds(), 10)).Err(); err != nil {
+		c.logger.Warn("Failed to publish job latency sample", zap.Error(err))
+	}
+}
+
+// ConsumeLatency subscribes to latencyChannel and feeds every sample into
+// dc.RecordLatency from a background goroutine until ctx is canceled.
+// Intended for the process that owns the DynamicConcurrency instance (e.g.
+// cmd/api) when job latency is only observable from elsewhere (e.g.
+// cmd/worker's ExtractionHandler).
+func (c *Client) ConsumeLatency(ctx context.Context, dc *DynamicConcurrency) error {
+	pubsub := c.redis.Subscribe(ctx, latencyChannel)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return fmt.Errorf("failed to subscribe to latency channel: %w", err)
+	}
+
+	go func() {
+		defer pubsub.Close()
+		ch := pubsub.Channel()
+		for {
+			select {
+			case msg, ok := <-ch:
+				if !ok {
+					return
+				}
+				ms, err := strconv.ParseInt(msg.Payload, 10, 64)
+				if err != nil {
+					c.logger.Warn("Failed to parse job latency sample", zap.String("payload", msg.Payload), zap.Error(err))
+					continue
+				}
+				dc.RecordLatency(time.Duration(ms) * time.Millisecond)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return nil
+}
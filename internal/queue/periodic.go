@@ -0,0 +1,247 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+
+	"github.com/KeremKalyoncu/MedYan/internal/types"
+)
+
+// Redis keys backing the periodic job subsystem: policies is a hash of
+// policy ID -> JSON PeriodicPolicy, schedule is a sorted set of policy ID
+// scored by its next-run Unix time, lock is the SET NX EX leader election
+// key the enqueuer loop uses, and dedupPrefix namespaces the per-fire
+// markers that stop a lock handoff from double-enqueuing a policy.
+const (
+	periodicPoliciesKey = "periodic:policies"
+	periodicScheduleKey = "periodic:schedule"
+	periodicLockKey     = "periodic:enqueuer:lock"
+	periodicDedupPrefix = "periodic:dedup:"
+)
+
+const (
+	periodicLockTTL      = 15 * time.Second
+	periodicTickInterval = 5 * time.Second
+	periodicDedupWindow  = 60 * time.Second
+	periodicJitterMax    = 3 * time.Second
+)
+
+// cronParser accepts the standard 5-field crontab format (minute hour
+// day-of-month month day-of-week), matching what SchedulePeriodic's callers
+// are expected to pass.
+var cronParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
+
+// PeriodicPolicy is a recurring extraction job template: the same
+// ExtractionRequest gets re-enqueued every time cronExpr fires, until
+// UnschedulePeriodic removes it.
+type PeriodicPolicy struct {
+	ID        string                  `json:"id"`
+	CronExpr  string                  `json:"cron_expr"`
+	Template  types.ExtractionRequest `json:"template"`
+	CreatedAt time.Time               `json:"created_at"`
+}
+
+// SchedulePeriodic registers template to be enqueued on cronExpr's schedule
+// and returns the policy ID UnschedulePeriodic later takes.
+func (c *Client) SchedulePeriodic(ctx context.Context, cronExpr string, template types.ExtractionRequest) (string, error) {
+	schedule, err := cronParser.Parse(cronExpr)
+	if err != nil {
+		return "", fmt.Errorf("invalid cron expression %q: %w", cronExpr, err)
+	}
+
+	policy := PeriodicPolicy{
+		ID:        uuid.New().String(),
+		CronExpr:  cronExpr,
+		Template:  template,
+		CreatedAt: time.Now(),
+	}
+
+	data, err := json.Marshal(policy)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal periodic policy: %w", err)
+	}
+
+	pipe := c.redis.Pipeline()
+	pipe.HSet(ctx, periodicPoliciesKey, policy.ID, data)
+	pipe.ZAdd(ctx, periodicScheduleKey, redis.Z{
+		Score:  float64(schedule.Next(time.Now()).Unix()),
+		Member: policy.ID,
+	})
+	if _, err := pipe.Exec(ctx); err != nil {
+		return "", fmt.Errorf("failed to schedule periodic policy: %w", err)
+	}
+
+	c.logger.Info("Periodic policy scheduled",
+		zap.String("policy_id", policy.ID),
+		zap.String("cron_expr", cronExpr),
+	)
+
+	return policy.ID, nil
+}
+
+// UnschedulePeriodic removes policyID so it stops firing.
+func (c *Client) UnschedulePeriodic(ctx context.Context, policyID string) error {
+	pipe := c.redis.Pipeline()
+	pipe.HDel(ctx, periodicPoliciesKey, policyID)
+	pipe.ZRem(ctx, periodicScheduleKey, policyID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to unschedule periodic policy %s: %w", policyID, err)
+	}
+
+	c.logger.Info("Periodic policy unscheduled", zap.String("policy_id", policyID))
+	return nil
+}
+
+// ListPeriodicPolicies returns every currently registered periodic policy.
+func (c *Client) ListPeriodicPolicies(ctx context.Context) ([]PeriodicPolicy, error) {
+	raw, err := c.redis.HGetAll(ctx, periodicPoliciesKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list periodic policies: %w", err)
+	}
+
+	policies := make([]PeriodicPolicy, 0, len(raw))
+	for _, v := range raw {
+		var policy PeriodicPolicy
+		if err := json.Unmarshal([]byte(v), &policy); err != nil {
+			continue
+		}
+		policies = append(policies, policy)
+	}
+	return policies, nil
+}
+
+// PeriodicScheduler runs the single leader-elected loop that fires due
+// PeriodicPolicy entries. Every API/worker process can safely construct and
+// Start one - the periodicLockKey SET NX EX lock (renewed on each tick by
+// whichever instance already holds it) decides which one actually does the
+// work, the same leader-via-Redis-lock shape DownloadHandler.Redeem already
+// uses for one-shot token redemption, just renewed instead of one-shot.
+type PeriodicScheduler struct {
+	client *Client
+	lockID string
+	logger *zap.Logger
+}
+
+// NewPeriodicScheduler creates a scheduler bound to client. Construct one
+// per process and call Start in each - they'll contend for leadership
+// automatically.
+func NewPeriodicScheduler(client *Client, logger *zap.Logger) *PeriodicScheduler {
+	return &PeriodicScheduler{
+		client: client,
+		lockID: uuid.New().String(),
+		logger: logger,
+	}
+}
+
+// Start runs the enqueuer loop in the background until ctx is canceled.
+func (s *PeriodicScheduler) Start(ctx context.Context) {
+	go s.run(ctx)
+}
+
+func (s *PeriodicScheduler) run(ctx context.Context) {
+	ticker := time.NewTicker(periodicTickInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if s.holdsLock(ctx) {
+				s.fireDuePolicies(ctx)
+			}
+		}
+	}
+}
+
+// holdsLock renews the leader lock if this instance already holds it, or
+// attempts to acquire it if it's free (expired or never held).
+func (s *PeriodicScheduler) holdsLock(ctx context.Context) bool {
+	holder, err := s.client.redis.Get(ctx, periodicLockKey).Result()
+	if err == nil && holder == s.lockID {
+		s.client.redis.Expire(ctx, periodicLockKey, periodicLockTTL)
+		return true
+	}
+
+	acquired, err := s.client.redis.SetNX(ctx, periodicLockKey, s.lockID, periodicLockTTL).Result()
+	if err != nil {
+		s.logger.Warn("Failed to attempt periodic scheduler leader lock", zap.Error(err))
+		return false
+	}
+	return acquired
+}
+
+func (s *PeriodicScheduler) fireDuePolicies(ctx context.Context) {
+	now := time.Now()
+	due, err := s.client.redis.ZRangeByScore(ctx, periodicScheduleKey, &redis.ZRangeBy{
+		Min: "-inf",
+		Max: fmt.Sprintf("%d", now.Unix()),
+	}).Result()
+	if err != nil {
+		s.logger.Warn("Failed to query due periodic policies", zap.Error(err))
+		return
+	}
+
+	for _, policyID := range due {
+		s.fireOne(ctx, policyID, now)
+	}
+}
+
+// fireOne enqueues policyID's template and reschedules it, guarded by a
+// short-lived dedup marker so a leader handoff mid-tick can't enqueue the
+// same due policy twice.
+func (s *PeriodicScheduler) fireOne(ctx context.Context, policyID string, now time.Time) {
+	reserved, err := s.client.redis.SetNX(ctx, periodicDedupPrefix+policyID, now.Unix(), periodicDedupWindow).Result()
+	if err != nil {
+		s.logger.Warn("Failed to check periodic dedup marker", zap.String("policy_id", policyID), zap.Error(err))
+		return
+	}
+	if !reserved {
+		return
+	}
+
+	raw, err := s.client.redis.HGet(ctx, periodicPoliciesKey, policyID).Result()
+	if err != nil {
+		if err == redis.Nil {
+			// Unscheduled between ZRANGEBYSCORE and here; drop the stale entry.
+			s.client.redis.ZRem(ctx, periodicScheduleKey, policyID)
+		} else {
+			s.logger.Warn("Failed to load periodic policy", zap.String("policy_id", policyID), zap.Error(err))
+		}
+		return
+	}
+
+	var policy PeriodicPolicy
+	if err := json.Unmarshal([]byte(raw), &policy); err != nil {
+		s.logger.Warn("Failed to unmarshal periodic policy", zap.String("policy_id", policyID), zap.Error(err))
+		return
+	}
+
+	if _, err := s.client.EnqueueExtractionJob(ctx, policy.Template); err != nil {
+		s.logger.Warn("Failed to enqueue periodic job", zap.String("policy_id", policyID), zap.Error(err))
+		return
+	}
+
+	schedule, err := cronParser.Parse(policy.CronExpr)
+	if err != nil {
+		s.logger.Warn("Failed to parse periodic policy cron expression", zap.String("policy_id", policyID), zap.Error(err))
+		return
+	}
+
+	jitter := time.Duration(rand.Int63n(int64(periodicJitterMax)))
+	nextRun := schedule.Next(now).Add(jitter)
+	if err := s.client.redis.ZAdd(ctx, periodicScheduleKey, redis.Z{
+		Score:  float64(nextRun.Unix()),
+		Member: policyID,
+	}).Err(); err != nil {
+		s.logger.Warn("Failed to reschedule periodic policy", zap.String("policy_id", policyID), zap.Error(err))
+	}
+}
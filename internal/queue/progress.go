@@ -0,0 +1,152 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	"github.com/KeremKalyoncu/MedYan/internal/types"
+)
+
+// progressChannel returns the Redis pub/sub channel a job's fine-grained
+// ProgressEvents are published to.
+func progressChannel(jobID string) string {
+	return fmt.Sprintf("job:%s:progress", jobID)
+}
+
+// PublishProgress publishes a fine-grained ProgressEvent for jobID to its
+// pub/sub channel. It is best-effort: a subscriber that isn't listening at
+// the moment simply misses the event, same as any other pub/sub fan-out -
+// GetJobStatus remains the source of truth for the job's last known state.
+func (c *Client) PublishProgress(ctx context.Context, jobID string, stage types.ProgressStage, status types.JobStatus, percent int, bytesTransferred, bytesTotal int64, etaSeconds int) error {
+	event := types.ProgressEvent{
+		JobID:            jobID,
+		Stage:            stage,
+		Percent:          percent,
+		BytesTransferred: bytesTransferred,
+		BytesTotal:       bytesTotal,
+		ETASeconds:       etaSeconds,
+		Status:           status,
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal progress event: %w", err)
+	}
+
+	if err := c.redis.Publish(ctx, progressChannel(jobID), payload).Err(); err != nil {
+		c.logger.Warn("Failed to publish progress event",
+			zap.String("job_id", jobID),
+			zap.String("stage", string(stage)),
+			zap.Error(err),
+		)
+		return err
+	}
+
+	return nil
+}
+
+// ProgressReader streams ProgressEvents for a single job, modeled on the
+// repo's other pull-based readers (e.g. pkg/logbuffer's offset-based Read):
+// callers drive it with Next rather than receiving a push callback.
+type ProgressReader struct {
+	jobID           string
+	pubsub          *redis.PubSub
+	ch              <-chan *redis.Message
+	done            bool
+	pendingTerminal *types.ProgressEvent
+}
+
+// StreamProgress subscribes to jobID's progress channel and returns a
+// ProgressReader whose Next blocks until the next stage event, a terminal
+// status, or ctx is canceled. The initial job status is checked first so a
+// job that has already finished yields a single terminal event and io.EOF
+// instead of hanging on a channel nothing will ever publish to again.
+func (c *Client) StreamProgress(ctx context.Context, jobID string) (*ProgressReader, error) {
+	job, err := c.GetJobStatus(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	pubsub := c.redis.Subscribe(ctx, progressChannel(jobID))
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, fmt.Errorf("failed to subscribe to progress channel: %w", err)
+	}
+
+	reader := &ProgressReader{
+		jobID:  jobID,
+		pubsub: pubsub,
+		ch:     pubsub.Channel(),
+	}
+
+	if isTerminalStatus(job.Status) {
+		// The job already reached a terminal state, possibly before this
+		// reader subscribed; synthesize the final event from job metadata
+		// rather than waiting on a channel nobody will publish to again.
+		reader.done = true
+		terminalStage := types.StageCompleted
+		if job.Status == types.StatusFailed {
+			terminalStage = types.StageFailed
+		}
+		reader.pendingTerminal = &types.ProgressEvent{
+			JobID:     jobID,
+			Stage:     terminalStage,
+			Percent:   job.Progress,
+			Status:    job.Status,
+			Message:   job.Error,
+			Timestamp: job.UpdatedAt,
+		}
+	}
+
+	return reader, nil
+}
+
+func isTerminalStatus(status types.JobStatus) bool {
+	return status == types.StatusCompleted || status == types.StatusFailed
+}
+
+// Next blocks until the next ProgressEvent is published, the job reaches a
+// terminal status (in which case it returns that event followed by io.EOF
+// on the subsequent call), or ctx is canceled.
+func (r *ProgressReader) Next(ctx context.Context) (types.ProgressEvent, error) {
+	if r.pendingTerminal != nil {
+		event := *r.pendingTerminal
+		r.pendingTerminal = nil
+		return event, nil
+	}
+	if r.done {
+		return types.ProgressEvent{}, io.EOF
+	}
+
+	select {
+	case msg, ok := <-r.ch:
+		if !ok {
+			r.done = true
+			return types.ProgressEvent{}, io.EOF
+		}
+
+		var event types.ProgressEvent
+		if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+			return types.ProgressEvent{}, fmt.Errorf("failed to unmarshal progress event: %w", err)
+		}
+
+		if isTerminalStatus(event.Status) {
+			r.done = true
+		}
+
+		return event, nil
+	case <-ctx.Done():
+		return types.ProgressEvent{}, ctx.Err()
+	}
+}
+
+// Close releases the underlying Redis subscription. Safe to call once Next
+// has returned io.EOF or the caller is otherwise done consuming.
+func (r *ProgressReader) Close() error {
+	return r.pubsub.Close()
+}
@@ -4,10 +4,16 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/hibiken/asynq"
 	"go.uber.org/zap"
 
+	"github.com/KeremKalyoncu/MedYan/internal/errclass"
+	"github.com/KeremKalyoncu/MedYan/internal/metrics"
+	"github.com/KeremKalyoncu/MedYan/internal/pool"
 	"github.com/gsker/media-extraction-saas/internal/types"
 )
 
@@ -17,6 +23,28 @@ type Server struct {
 	mux     *asynq.ServeMux
 	logger  *zap.Logger
 	handler JobHandler
+	ffmpeg  *pool.FFmpegPool
+	client  *Client
+	policy  errclass.Policy
+}
+
+// SetClient wires the Client this server's handlers use to fan batches out
+// into child tasks and to record batch aggregation state in Redis. Optional
+// for standalone (non-batch) extraction handling - a nil client makes
+// handleBatchTask fail loudly instead of silently dropping the batch.
+func (s *Server) SetClient(c *Client) {
+	s.client = c
+}
+
+// SetFFmpegPool wires an internal/pool.FFmpegPool so each extraction task
+// runs under its concurrency cap rather than spawning its yt-dlp/ffmpeg
+// work unbounded. Optional - a nil pool (the default) runs tasks directly,
+// same as before this was introduced. When the pool's queue is full, the
+// task handler returns pool.ErrQueueFull, which Asynq retries with backoff
+// like any other task error, instead of this server thrashing CPU/RAM by
+// accepting more concurrent extractions than the box can actually run.
+func (s *Server) SetFFmpegPool(p *pool.FFmpegPool) {
+	s.ffmpeg = p
 }
 
 // JobHandler defines the interface for processing extraction jobs
@@ -26,12 +54,16 @@ type JobHandler interface {
 
 // ServerConfig holds server configuration
 type ServerConfig struct {
-	RedisAddr      string
-	Concurrency    int
-	Queues         map[string]int
+	RedisAddr       string
+	Concurrency     int
+	Queues          map[string]int
 	ShutdownTimeout int // seconds
-	Logger         *zap.Logger
-	Handler        JobHandler
+	Logger          *zap.Logger
+	Handler         JobHandler
+	// RetryPolicy decides, per errclass.Class, how (and whether) a failed
+	// extraction task should be retried. The zero value falls back to
+	// Policy's own defaults (3 retries, 2s base delay).
+	RetryPolicy errclass.Policy
 }
 
 // NewServer creates a new queue server
@@ -39,16 +71,27 @@ func NewServer(cfg ServerConfig) *Server {
 	asynqServer := asynq.NewServer(
 		asynq.RedisClientOpt{Addr: cfg.RedisAddr},
 		asynq.Config{
-			Concurrency: cfg.Concurrency,
-			Queues:      cfg.Queues,
+			Concurrency:    cfg.Concurrency,
+			Queues:         cfg.Queues,
 			StrictPriority: false, // Fair distribution across queues
-			Logger:      NewAsynqLogger(cfg.Logger),
+			Logger:         NewAsynqLogger(cfg.Logger),
 			ErrorHandler: asynq.ErrorHandlerFunc(func(ctx context.Context, task *asynq.Task, err error) {
 				cfg.Logger.Error("Task failed",
 					zap.String("type", task.Type()),
 					zap.Error(err),
 				)
 			}),
+			// RetryDelayFunc routes delay decisions through the same
+			// errclass.Policy handleExtractionTask uses to decide
+			// SkipRetry/RotateEgress, so Asynq's own backoff schedule
+			// matches the classification that produced the error instead
+			// of asynq's default fixed exponential curve.
+			RetryDelayFunc: func(attempt int, err error, task *asynq.Task) time.Duration {
+				class := errclass.Classify(err.Error(), -1)
+				// asynq passes the retries made so far (0 on the first
+				// failure); Decide counts failures from 1.
+				return cfg.RetryPolicy.Decide(class, attempt+1).Delay
+			},
 		},
 	)
 
@@ -59,6 +102,7 @@ func NewServer(cfg ServerConfig) *Server {
 		mux:     mux,
 		logger:  cfg.Logger,
 		handler: cfg.Handler,
+		policy:  cfg.RetryPolicy,
 	}
 
 	// Register task handlers
@@ -97,31 +141,170 @@ func (s *Server) handleExtractionTask(ctx context.Context, task *asynq.Task) err
 	job.Status = types.StatusProcessing
 	job.Progress = 10
 
-	// Delegate to handler
-	if err := s.handler.HandleExtraction(ctx, &job); err != nil {
-		job.Status = types.StatusFailed
+	// Delegate to handler, gated by the ffmpeg pool when one is wired so a
+	// burst of tasks can't spawn unbounded concurrent yt-dlp/ffmpeg work.
+	// Priority matches the Asynq queue this job was enqueued onto (see
+	// Client.enqueueJob), so a flood of "critical"/"default" jobs landing on
+	// the pool's general queue can't starve "low" ones (audio-only, etc.)
+	// indefinitely when the pool has a reservation for them.
+	runExtraction := func() error { return s.handler.HandleExtraction(ctx, &job) }
+	if s.ffmpeg != nil {
+		priority := QueueForQuality(job.Request.Quality)
+		if job.Request.OutputFormat == types.OutputFormatHLS {
+			priority = "critical"
+		}
+		runExtraction = func() error {
+			return s.ffmpeg.RunGatedPriority(ctx, priority, func() error {
+				return s.handler.HandleExtraction(ctx, &job)
+			})
+		}
+	}
+
+	if err := runExtraction(); err != nil {
+		platform := detectPlatform(job.Request.URL)
+		class := errclass.Classify(err.Error(), -1)
+		metrics.GetMetrics().RecordErrorClass(platform, class.String())
+
+		attempt := asynq.GetRetryCount(ctx) + 1
+		decision := s.policy.Decide(class, attempt)
+
+		if decision.NeedsCookies {
+			job.Status = types.StatusNeedsCookies
+		} else {
+			job.Status = types.StatusFailed
+		}
 		job.Error = err.Error()
+
 		s.logger.Error("Extraction failed",
 			zap.String("job_id", job.ID),
+			zap.String("platform", platform),
+			zap.String("error_class", class.String()),
+			zap.Bool("skip_retry", decision.SkipRetry),
+			zap.Bool("rotate_egress", decision.RotateEgress),
 			zap.Error(err),
 		)
+		if decision.RotateEgress {
+			// Actual egress rotation happens on the next attempt's own
+			// ippool.Acquire call (see internal/ippool, wired in
+			// cmd/worker/main.go) - this is just making the decision
+			// visible in the logs.
+			s.logger.Warn("Egress rotation recommended for next attempt",
+				zap.String("job_id", job.ID),
+				zap.String("platform", platform),
+			)
+		}
+		s.recordBatchChild(ctx, &job)
+
+		if decision.SkipRetry {
+			return fmt.Errorf("%w: %s", asynq.SkipRetry, err)
+		}
 		return err
 	}
 
 	s.logger.Info("Extraction completed",
 		zap.String("job_id", job.ID),
 	)
+	job.Status = types.StatusCompleted
+	s.recordBatchChild(ctx, &job)
 
 	return nil
 }
 
-// handleBatchTask processes a batch extraction task
+// recordBatchChild reports job's terminal status back to its batch's
+// aggregation state, a no-op for standalone jobs (job.BatchID empty) or
+// when no Client has been wired via SetClient.
+func (s *Server) recordBatchChild(ctx context.Context, job *types.ExtractionJob) {
+	if job.BatchID == "" || s.client == nil {
+		return
+	}
+	if err := s.client.RecordBatchChildResult(ctx, job.BatchID, job.ID, job.Request.URL, job.Status, job.Error); err != nil {
+		s.logger.Warn("Failed to record batch child result",
+			zap.String("batch_id", job.BatchID),
+			zap.String("job_id", job.ID),
+			zap.Error(err),
+		)
+	}
+}
+
+// handleBatchTask fans a BatchExtractionJob out into one child
+// TypeExtraction task per URL, tagging each with BatchID so
+// handleExtractionTask can report its outcome back for aggregation.
 func (s *Server) handleBatchTask(ctx context.Context, task *asynq.Task) error {
-	// TODO: Implement batch processing logic
-	s.logger.Info("Processing batch task")
+	var batch types.BatchExtractionJob
+	if err := json.Unmarshal(task.Payload(), &batch); err != nil {
+		return fmt.Errorf("failed to unmarshal batch: %w", err)
+	}
+
+	if s.client == nil {
+		return fmt.Errorf("batch %s: no queue client wired via SetClient", batch.ID)
+	}
+
+	s.logger.Info("Fanning out batch",
+		zap.String("batch_id", batch.ID),
+		zap.Int("total", batch.Total),
+	)
+
+	for _, url := range batch.URLs {
+		req := batch.Template
+		req.URL = url
+
+		job := types.ExtractionJob{
+			ID:        uuid.New().String(),
+			Request:   req,
+			Status:    types.StatusPending,
+			BatchID:   batch.ID,
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		}
+
+		if err := s.client.RecordBatchChildResult(ctx, batch.ID, job.ID, url, types.StatusPending, ""); err != nil {
+			s.logger.Warn("Failed to seed batch child status",
+				zap.String("batch_id", batch.ID),
+				zap.String("url", url),
+				zap.Error(err),
+			)
+		}
+
+		if _, err := s.client.enqueueJob(ctx, job); err != nil {
+			s.logger.Error("Failed to enqueue batch child",
+				zap.String("batch_id", batch.ID),
+				zap.String("url", url),
+				zap.Error(err),
+			)
+			if recErr := s.client.RecordBatchChildResult(ctx, batch.ID, job.ID, url, types.StatusFailed, err.Error()); recErr != nil {
+				s.logger.Warn("Failed to record batch child enqueue failure", zap.Error(recErr))
+			}
+		}
+	}
+
 	return nil
 }
 
+// detectPlatform detects platform from URL, for error-class metrics
+// labeling. Kept local rather than imported since extractor.detectPlatform
+// and handlers.(*ExtractionHandler).detectPlatform are themselves
+// unexported, package-local copies of the same small lookup.
+func detectPlatform(url string) string {
+	url = strings.ToLower(url)
+
+	if strings.Contains(url, "youtube.com") || strings.Contains(url, "youtu.be") {
+		return "youtube"
+	}
+	if strings.Contains(url, "instagram.com") {
+		return "instagram"
+	}
+	if strings.Contains(url, "tiktok.com") {
+		return "tiktok"
+	}
+	if strings.Contains(url, "twitter.com") || strings.Contains(url, "x.com") {
+		return "twitter"
+	}
+	if strings.Contains(url, "facebook.com") || strings.Contains(url, "fb.watch") {
+		return "facebook"
+	}
+	return "other"
+}
+
 // AsynqLogger adapts zap.Logger to asynq.Logger interface
 type AsynqLogger struct {
 	logger *zap.Logger
@@ -0,0 +1,207 @@
+// Package redisconn parses Redis connection URIs and hands out shared
+// *redis.Client/redis.UniversalClient instances keyed by normalized DSN, so
+// subsystems that happen to point at the same Redis endpoint (the job
+// queue, the distributed metadata cache, the response cache) share one
+// connection pool instead of each opening its own. Before this package
+// existed, internal/queue.Client and internal/cache.DistributedCache each
+// built an identical redis.Options block by hand - this is that
+// duplication's one home.
+package redisconn
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Mode selects which redis.UniversalOptions shape a DSN resolves to.
+type Mode int
+
+const (
+	// ModeSingle talks to one redis.Client. Used by "redis://"/"rediss://".
+	ModeSingle Mode = iota
+	// ModeSentinel talks to a redis.FailoverClient via "sentinel://".
+	ModeSentinel
+	// ModeCluster talks to a redis.ClusterClient via "cluster://".
+	ModeCluster
+)
+
+// Options is the parsed form of a Redis DSN, with the same pooling
+// defaults every hand-rolled redis.Options block in this repo used to
+// repeat individually.
+type Options struct {
+	Mode Mode
+	// Addrs is one host:port for ModeSingle, and every seed node for
+	// ModeSentinel/ModeCluster.
+	Addrs        []string
+	Username     string
+	Password     string
+	DB           int
+	MasterName   string // sentinel:// only
+	TLS          bool
+	PoolSize     int
+	MinIdleConns int
+	MaxRetries   int
+	DialTimeout  time.Duration
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	PoolTimeout  time.Duration
+}
+
+// defaultOptions matches the pooling settings internal/queue/client.go,
+// internal/cache/cache.go, and internal/cache/distributed.go each
+// hardcoded independently before this package existed.
+func defaultOptions() Options {
+	return Options{
+		PoolSize:     20,
+		MinIdleConns: 5,
+		MaxRetries:   3,
+		DialTimeout:  5 * time.Second,
+		ReadTimeout:  3 * time.Second,
+		WriteTimeout: 3 * time.Second,
+		PoolTimeout:  4 * time.Second,
+	}
+}
+
+// Parse reads a Redis DSN of the form
+//
+//	redis://[user:pass@]host:port[/db][?pool_size=N&min_idle_conns=N&max_retries=N&dial_timeout=5s&read_timeout=3s&write_timeout=3s&pool_timeout=4s&tls=true]
+//	rediss://... (same as redis://, with TLS forced on)
+//	sentinel://[user:pass@]host1:port1,host2:port2[/db]?master_name=mymaster
+//	cluster://[user:pass@]host1:port1,host2:port2[?...]
+//
+// A bare "host:port" with no "scheme://" is also accepted, for call sites
+// migrating from a plain REDIS_ADDR env var - it's treated as
+// "redis://host:port/0".
+func Parse(dsn string) (Options, error) {
+	return ParseWithDefaultDB(dsn, 0)
+}
+
+// ParseWithDefaultDB is like Parse, but uses defaultDB instead of 0 when
+// dsn doesn't specify a database itself - for callers like
+// cache.NewDistributedCache that have historically defaulted to a
+// non-zero DB when only given a bare host:port.
+func ParseWithDefaultDB(dsn string, defaultDB int) (Options, error) {
+	if !strings.Contains(dsn, "://") {
+		dsn = "redis://" + dsn
+	}
+
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return Options{}, fmt.Errorf("redisconn: invalid DSN: %w", err)
+	}
+
+	opts := defaultOptions()
+	opts.DB = defaultDB
+
+	switch u.Scheme {
+	case "redis":
+		opts.Mode = ModeSingle
+	case "rediss":
+		opts.Mode = ModeSingle
+		opts.TLS = true
+	case "sentinel":
+		opts.Mode = ModeSentinel
+	case "cluster":
+		opts.Mode = ModeCluster
+	default:
+		return Options{}, fmt.Errorf("redisconn: unsupported scheme %q", u.Scheme)
+	}
+
+	if u.Host == "" {
+		return Options{}, fmt.Errorf("redisconn: DSN is missing a host")
+	}
+	opts.Addrs = strings.Split(u.Host, ",")
+
+	if u.User != nil {
+		opts.Username = u.User.Username()
+		opts.Password, _ = u.User.Password()
+	}
+
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		n, err := strconv.Atoi(db)
+		if err != nil {
+			return Options{}, fmt.Errorf("redisconn: invalid db %q: %w", db, err)
+		}
+		opts.DB = n
+	}
+
+	q := u.Query()
+	if v := q.Get("tls"); v != "" {
+		opts.TLS, err = strconv.ParseBool(v)
+		if err != nil {
+			return Options{}, fmt.Errorf("redisconn: invalid tls value %q: %w", v, err)
+		}
+	}
+	opts.MasterName = q.Get("master_name")
+
+	if err := applyIntParam(q, "pool_size", &opts.PoolSize); err != nil {
+		return Options{}, err
+	}
+	if err := applyIntParam(q, "min_idle_conns", &opts.MinIdleConns); err != nil {
+		return Options{}, err
+	}
+	if err := applyIntParam(q, "max_retries", &opts.MaxRetries); err != nil {
+		return Options{}, err
+	}
+	if err := applyDurationParam(q, "dial_timeout", &opts.DialTimeout); err != nil {
+		return Options{}, err
+	}
+	if err := applyDurationParam(q, "read_timeout", &opts.ReadTimeout); err != nil {
+		return Options{}, err
+	}
+	if err := applyDurationParam(q, "write_timeout", &opts.WriteTimeout); err != nil {
+		return Options{}, err
+	}
+	if err := applyDurationParam(q, "pool_timeout", &opts.PoolTimeout); err != nil {
+		return Options{}, err
+	}
+
+	if opts.Mode == ModeSentinel && opts.MasterName == "" {
+		return Options{}, fmt.Errorf("redisconn: sentinel:// DSN requires master_name")
+	}
+
+	return opts, nil
+}
+
+func applyIntParam(q url.Values, name string, dest *int) error {
+	v := q.Get(name)
+	if v == "" {
+		return nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return fmt.Errorf("redisconn: invalid %s %q: %w", name, v, err)
+	}
+	*dest = n
+	return nil
+}
+
+func applyDurationParam(q url.Values, name string, dest *time.Duration) error {
+	v := q.Get(name)
+	if v == "" {
+		return nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return fmt.Errorf("redisconn: invalid %s %q: %w", name, v, err)
+	}
+	*dest = d
+	return nil
+}
+
+// key returns a normalized string identifying this Options value, used by
+// Registry to decide whether two DSNs should share a pool. Two DSNs that
+// differ only in query-param order, or that spell the same default value
+// explicitly vs. implicitly, normalize to the same key.
+func (o Options) key() string {
+	addrs := append([]string(nil), o.Addrs...)
+	// Addrs order matters for UniversalOptions (cluster/sentinel seed
+	// order can affect which node is tried first), so it's left as-is -
+	// callers wanting the same shared client must list seeds identically.
+	return fmt.Sprintf("%d|%s|%s|%s|%d|%s|%t|%d|%d|%d|%s|%s|%s|%s",
+		o.Mode, strings.Join(addrs, ","), o.Username, o.Password, o.DB, o.MasterName, o.TLS,
+		o.PoolSize, o.MinIdleConns, o.MaxRetries, o.DialTimeout, o.ReadTimeout, o.WriteTimeout, o.PoolTimeout)
+}
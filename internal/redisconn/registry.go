@@ -0,0 +1,209 @@
+package redisconn
+
+import (
+	"crypto/tls"
+	"fmt"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// Registry hands out shared redis.UniversalClient instances keyed by
+// normalized DSN, refcounted so the underlying connection is only closed
+// once every caller holding it has released it.
+type Registry struct {
+	mu      sync.Mutex
+	entries map[string]*entry
+}
+
+type entry struct {
+	client   redis.UniversalClient
+	opts     Options
+	refCount int
+}
+
+// Default is the process-wide Registry every package in this repo should
+// use unless it has a specific reason to isolate its own connections
+// (tests mainly - see NewRegistry).
+var Default = NewRegistry()
+
+// NewRegistry creates an empty, independent Registry. Most callers want
+// Default instead; this exists for tests that need pools isolated from the
+// rest of the process.
+func NewRegistry() *Registry {
+	return &Registry{entries: make(map[string]*entry)}
+}
+
+// Conn is a checked-out reference to a shared client. Close releases this
+// caller's reference; the underlying client is only closed once every
+// other caller sharing it has released theirs too.
+type Conn struct {
+	redis.UniversalClient
+	registry *Registry
+	key      string
+}
+
+// Close releases this Conn's reference to its underlying shared client.
+// Safe to call once per Get/GetClient call that returned this Conn.
+func (c *Conn) Close() error {
+	return c.registry.release(c.key)
+}
+
+// Get parses dsn and returns a Conn wrapping the shared
+// redis.UniversalClient for its normalized form, creating one if this is
+// the first caller to ask for it. logger is only used if a new client is
+// created.
+func (r *Registry) Get(dsn string, logger *zap.Logger) (*Conn, error) {
+	opts, err := Parse(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return r.getParsed(opts, logger)
+}
+
+// GetWithOptions is like Get, but for callers that already have a parsed
+// Options value (e.g. from ParseWithDefaultDB) rather than a raw DSN
+// string.
+func (r *Registry) GetWithOptions(opts Options, logger *zap.Logger) (*Conn, error) {
+	return r.getParsed(opts, logger)
+}
+
+// GetClientWithOptions is the Options-based counterpart to GetClient.
+func (r *Registry) GetClientWithOptions(opts Options, logger *zap.Logger) (*redis.Client, *Conn, error) {
+	if opts.Mode != ModeSingle {
+		return nil, nil, fmt.Errorf("redisconn: GetClientWithOptions requires a single-node DSN, got mode %d", opts.Mode)
+	}
+	conn, err := r.getParsed(opts, logger)
+	if err != nil {
+		return nil, nil, err
+	}
+	client, ok := conn.UniversalClient.(*redis.Client)
+	if !ok {
+		_ = conn.Close()
+		return nil, nil, fmt.Errorf("redisconn: shared client for this DSN is not a *redis.Client")
+	}
+	return client, conn, nil
+}
+
+func (r *Registry) getParsed(opts Options, logger *zap.Logger) (*Conn, error) {
+	key := opts.key()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if e, ok := r.entries[key]; ok {
+		e.refCount++
+		return &Conn{UniversalClient: e.client, registry: r, key: key}, nil
+	}
+
+	client := buildClient(opts)
+	r.entries[key] = &entry{client: client, opts: opts, refCount: 1}
+	logger.Info("redisconn: opened shared Redis connection",
+		zap.Int("mode", int(opts.Mode)),
+		zap.Strings("addrs", opts.Addrs),
+		zap.Int("db", opts.DB),
+		zap.Bool("tls", opts.TLS),
+	)
+	return &Conn{UniversalClient: client, registry: r, key: key}, nil
+}
+
+// GetClient is like Get, but additionally requires dsn to resolve to a
+// single-node connection (redis://, rediss://, or a bare host:port) and
+// returns its concrete *redis.Client - for the existing call sites in this
+// repo that were built against *redis.Client directly rather than the
+// redis.UniversalClient interface.
+func (r *Registry) GetClient(dsn string, logger *zap.Logger) (*redis.Client, *Conn, error) {
+	opts, err := Parse(dsn)
+	if err != nil {
+		return nil, nil, err
+	}
+	if opts.Mode != ModeSingle {
+		return nil, nil, fmt.Errorf("redisconn: GetClient requires a single-node DSN, got mode %d", opts.Mode)
+	}
+
+	conn, err := r.getParsed(opts, logger)
+	if err != nil {
+		return nil, nil, err
+	}
+	client, ok := conn.UniversalClient.(*redis.Client)
+	if !ok {
+		// Unreachable given the ModeSingle check above - buildClient only
+		// returns *redis.Client for ModeSingle - but fail safely rather
+		// than panic on a bad type assertion if that ever changes.
+		_ = conn.Close()
+		return nil, nil, fmt.Errorf("redisconn: shared client for this DSN is not a *redis.Client")
+	}
+	return client, conn, nil
+}
+
+func (r *Registry) release(key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e, ok := r.entries[key]
+	if !ok {
+		return nil
+	}
+	e.refCount--
+	if e.refCount > 0 {
+		return nil
+	}
+	delete(r.entries, key)
+	return e.client.Close()
+}
+
+func buildClient(opts Options) redis.UniversalClient {
+	var tlsConfig *tls.Config
+	if opts.TLS {
+		tlsConfig = &tls.Config{}
+	}
+
+	switch opts.Mode {
+	case ModeSentinel:
+		return redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:    opts.MasterName,
+			SentinelAddrs: opts.Addrs,
+			Username:      opts.Username,
+			Password:      opts.Password,
+			DB:            opts.DB,
+			PoolSize:      opts.PoolSize,
+			MinIdleConns:  opts.MinIdleConns,
+			MaxRetries:    opts.MaxRetries,
+			DialTimeout:   opts.DialTimeout,
+			ReadTimeout:   opts.ReadTimeout,
+			WriteTimeout:  opts.WriteTimeout,
+			PoolTimeout:   opts.PoolTimeout,
+			TLSConfig:     tlsConfig,
+		})
+	case ModeCluster:
+		return redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:        opts.Addrs,
+			Username:     opts.Username,
+			Password:     opts.Password,
+			PoolSize:     opts.PoolSize,
+			MinIdleConns: opts.MinIdleConns,
+			MaxRetries:   opts.MaxRetries,
+			DialTimeout:  opts.DialTimeout,
+			ReadTimeout:  opts.ReadTimeout,
+			WriteTimeout: opts.WriteTimeout,
+			PoolTimeout:  opts.PoolTimeout,
+			TLSConfig:    tlsConfig,
+		})
+	default:
+		return redis.NewClient(&redis.Options{
+			Addr:         opts.Addrs[0],
+			Username:     opts.Username,
+			Password:     opts.Password,
+			DB:           opts.DB,
+			PoolSize:     opts.PoolSize,
+			MinIdleConns: opts.MinIdleConns,
+			MaxRetries:   opts.MaxRetries,
+			DialTimeout:  opts.DialTimeout,
+			ReadTimeout:  opts.ReadTimeout,
+			WriteTimeout: opts.WriteTimeout,
+			PoolTimeout:  opts.PoolTimeout,
+			TLSConfig:    tlsConfig,
+		})
+	}
+}
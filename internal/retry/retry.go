@@ -6,6 +6,8 @@ import (
 	"math"
 	"math/rand"
 	"time"
+
+	"github.com/KeremKalyoncu/MedYan/internal/circuitbreaker"
 )
 
 var (
@@ -30,6 +32,11 @@ type Config struct {
 	RetryableErrors func(err error) bool
 	// OnRetry is called before each retry attempt
 	OnRetry func(attempt int, delay time.Duration, err error)
+	// Breaker, when set, gates every attempt through it instead of calling
+	// fn directly - an open (or half-open-exhausted) breaker makes Retry
+	// return circuitbreaker.ErrCircuitOpen/ErrTooManyRequests immediately,
+	// without consuming a retry attempt or waiting out a backoff delay.
+	Breaker *circuitbreaker.Breaker
 }
 
 // DefaultConfig returns sensible default retry configuration
@@ -88,8 +95,16 @@ func Retry(ctx context.Context, config Config, fn func() error) error {
 		default:
 		}
 
-		// Execute function
-		err := fn()
+		// Execute function, gated through the breaker if one is set
+		var err error
+		if config.Breaker != nil {
+			err = config.Breaker.Execute(ctx, fn)
+			if errors.Is(err, circuitbreaker.ErrCircuitOpen) || errors.Is(err, circuitbreaker.ErrTooManyRequests) {
+				return err
+			}
+		} else {
+			err = fn()
+		}
 
 		// Success
 		if err == nil {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"sync"
 
+	"github.com/gsker/media-extraction-saas/internal/queue"
 	"github.com/gsker/media-extraction-saas/internal/types"
 	"go.uber.org/zap"
 )
@@ -130,18 +131,22 @@ func (m *MockStorage) FileCount() int {
 	return len(m.files)
 }
 
-// MockQueue is a mock implementation of the Queue interface
+// MockQueue is a mock implementation of the Queue interface. Dequeue order
+// matches the real queue.Client: a queue.FairQueue picks the tenant with
+// the smallest virtualTime rather than flat FIFO, so tests can assert
+// fairness invariants (e.g. one tenant enqueuing many jobs doesn't starve
+// another's).
 type MockQueue struct {
-	mu    sync.Mutex
-	jobs  map[string]*types.ExtractionJob
-	queue []*types.ExtractionJob
+	mu   sync.Mutex
+	jobs map[string]*types.ExtractionJob
+	fair *queue.FairQueue
 }
 
 // NewMockQueue creates a new mock queue
 func NewMockQueue() *MockQueue {
 	return &MockQueue{
-		jobs:  make(map[string]*types.ExtractionJob),
-		queue: make([]*types.ExtractionJob, 0),
+		jobs: make(map[string]*types.ExtractionJob),
+		fair: queue.NewFairQueue(),
 	}
 }
 
@@ -151,22 +156,17 @@ func (m *MockQueue) Enqueue(job *types.ExtractionJob) error {
 	defer m.mu.Unlock()
 
 	m.jobs[job.ID] = job
-	m.queue = append(m.queue, job)
+	m.fair.Enqueue(job)
 	return nil
 }
 
-// Dequeue removes and returns the next job from the queue
+// Dequeue removes and returns the next job from the queue, per FairQueue's
+// weighted ordering.
 func (m *MockQueue) Dequeue() *types.ExtractionJob {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if len(m.queue) == 0 {
-		return nil
-	}
-
-	job := m.queue[0]
-	m.queue = m.queue[1:]
-	return job
+	return m.fair.Dequeue()
 }
 
 // GetJob returns a job by ID
@@ -186,12 +186,20 @@ func (m *MockQueue) UpdateJob(job *types.ExtractionJob) error {
 	return nil
 }
 
-// Length returns the number of jobs in the queue
+// Length returns the number of jobs in the queue, across all tenants.
 func (m *MockQueue) Length() int {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+	return m.fair.Length("")
+}
+
+// TenantLength returns the number of queued jobs for tenant.
+func (m *MockQueue) TenantLength(tenant string) int {
+	return m.fair.Length(tenant)
+}
 
-	return len(m.queue)
+// SetTenantWeight overrides a tenant's fair-share weight, mirroring
+// queue.Client.SetTenantWeight.
+func (m *MockQueue) SetTenantWeight(tenant string, weight float64) {
+	m.fair.SetWeight(tenant, weight)
 }
 
 // Clear clears the queue
@@ -199,8 +207,8 @@ func (m *MockQueue) Clear() {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	m.queue = make([]*types.ExtractionJob, 0)
 	m.jobs = make(map[string]*types.ExtractionJob)
+	m.fair = queue.NewFairQueue()
 }
 
 // Test fixtures and helpers
@@ -4,19 +4,76 @@ import "time"
 
 // ExtractionRequest represents a media extraction job request
 type ExtractionRequest struct {
-	URL           string   `json:"url" validate:"required,url"`
-	Quality       string   `json:"quality"`                  // 4k, 1080p, 720p, 480p, best
-	Format        string   `json:"format"`                   // mp4, avi, mkv, webm
-	ExtractAudio  bool     `json:"extract_audio"`            // Extract audio only
-	AudioFormat   string   `json:"audio_format"`             // mp3, aac, flac
-	AudioBitrate  string   `json:"audio_bitrate"`            // 128k, 192k, 320k
-	Subtitles     []string `json:"subtitles"`                // ["en", "tr"]
-	CookiesBase64 string   `json:"cookies_base64,omitempty"` // Base64 encoded cookie file
-	UserAgent     string   `json:"user_agent,omitempty"`     // Custom user agent
-	ProxyURL      string   `json:"proxy_url,omitempty"`      // Custom proxy
-	WebhookURL    string   `json:"webhook_url,omitempty"`    // Callback URL on completion
+	URL           string         `json:"url" validate:"required,url"`
+	Quality       string         `json:"quality"`                  // 4k, 1080p, 720p, 480p, best
+	Format        string         `json:"format"`                   // mp4, avi, mkv, webm
+	ExtractAudio  bool           `json:"extract_audio"`            // Extract audio only
+	AudioFormat   string         `json:"audio_format"`             // mp3, aac, flac
+	AudioBitrate  string         `json:"audio_bitrate"`            // 128k, 192k, 320k
+	Subtitles     []string       `json:"subtitles"`                // ["en", "tr"]
+	CookiesBase64 string         `json:"cookies_base64,omitempty"` // Base64 encoded cookie file
+	UserAgent     string         `json:"user_agent,omitempty"`     // Custom user agent
+	ProxyURL      string         `json:"proxy_url,omitempty"`      // Custom proxy
+	WebhookURL    string         `json:"webhook_url,omitempty"`    // Callback URL on completion
+	OutputFormat  string         `json:"output_format,omitempty"`  // file (default), hls, or thumbnails
+	Clip          *ClipSpec      `json:"clip,omitempty"`           // Extract a sub-range (or concatenated ranges) instead of the whole media
+	Thumbnails    *ThumbnailSpec `json:"thumbnails,omitempty"`     // Sprite sheet / scrubbing thumbnails, used when OutputFormat is "thumbnails"
+	// Preview, unlike Thumbnails, doesn't replace the downloaded media - it
+	// makes downloadMedia additionally generate a scrub-preview sprite
+	// sheet (or frame set) from the downloaded file afterward, surfaced as
+	// MediaMetadata.Thumbnails. Works alongside any OutputFormat. Skipped
+	// automatically when ExtractAudio is set, since there's no video to
+	// sample.
+	Preview *ThumbnailSpec `json:"preview,omitempty"`
+	// LiveMode tells downloadMedia how to handle a URL whose metadata
+	// reports a live or upcoming stream: "" (default) refuses with
+	// extractor.ErrLiveStream, "record" records a live stream from its
+	// start, "wait_and_retry" waits for an upcoming stream's scheduled
+	// start before downloading.
+	LiveMode string `json:"live_mode,omitempty"`
 }
 
+// ThumbnailSpec requests sprite-sheet or per-frame scrubbing thumbnails
+// instead of the usual downloaded/transcoded media, mirroring
+// extractor.ThumbnailOptions.
+type ThumbnailSpec struct {
+	Count     int     `json:"count,omitempty"`     // number of samples across the duration, default 100
+	Interval  float64 `json:"interval,omitempty"`  // seconds between samples; overrides Count when set
+	Width     int     `json:"width,omitempty"`     // thumbnail width in pixels, default 160
+	Cols      int     `json:"cols,omitempty"`      // sprite sheet grid columns, auto-sized when 0
+	Rows      int     `json:"rows,omitempty"`      // sprite sheet grid rows, auto-sized when 0
+	Frames    bool    `json:"frames,omitempty"`    // emit individual %04d.jpg frames instead of a tiled sprite
+	Smart     bool    `json:"smart,omitempty"`     // pick the most representative frame per window instead of equidistant samples
+	Keyframes bool    `json:"keyframes,omitempty"` // sample at I-frames (via ffprobe) instead of equidistant ticks; takes precedence over Smart/Interval/Count
+}
+
+// ClipSegment is a single [Start, End) cut, either ClipSpec's top-level
+// Start/End or one entry of its Ranges.
+type ClipSegment struct {
+	// Start and End accept either a plain seconds duration ("90.5") or an
+	// ffmpeg-style timestamp ("00:01:30.5"), parsed by
+	// extractor.ParseClipSeconds.
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// ClipSpec describes the clip extractor.FFmpeg.ExtractClip should cut from
+// a downloaded media file. A single Start/End pair produces one clip;
+// Ranges, when non-empty, takes precedence and produces one clip per entry,
+// concatenated into a single output via ffmpeg's concat demuxer.
+type ClipSpec struct {
+	Start  string        `json:"start,omitempty"`
+	End    string        `json:"end,omitempty"`
+	Ranges []ClipSegment `json:"ranges,omitempty"`
+}
+
+// OutputFormat values for ExtractionRequest.OutputFormat
+const (
+	OutputFormatFile       = "file"
+	OutputFormatHLS        = "hls"
+	OutputFormatThumbnails = "thumbnails"
+)
+
 // ExtractionJob represents a job in the queue
 type ExtractionJob struct {
 	ID        string            `json:"id"`
@@ -28,6 +85,61 @@ type ExtractionJob struct {
 	CreatedAt time.Time         `json:"created_at"`
 	UpdatedAt time.Time         `json:"updated_at"`
 	Result    *ExtractionResult `json:"result,omitempty"`
+	// Resumable marks a job whose download can rehydrate from an
+	// internal/puller.SharedPullerState checkpoint instead of restarting
+	// from zero. Set once a job first fails mid-download.
+	Resumable bool `json:"resumable,omitempty"`
+	// Priority is an opaque client-supplied hint. queue.FairQueue does not
+	// read it: each tenant's sub-queue is FIFO, and fairness between
+	// tenants is governed by the per-tenant weight.
+	Priority int `json:"priority,omitempty"`
+	// Tenant partitions jobs for queue.FairQueue, defaulting to the
+	// requesting hostname (see HistoryHandler's site:{hostname}:history
+	// partitioning) so one noisy embed site can't monopolize the worker
+	// pool at every other site's expense.
+	Tenant string `json:"tenant,omitempty"`
+	// BatchID links this job to a BatchExtractionJob it was fanned out from
+	// by queue.Server.handleBatchTask, empty for standalone jobs.
+	BatchID string `json:"batch_id,omitempty"`
+}
+
+// BatchExtractionJob groups N independent URLs sharing one ExtractionRequest
+// template into a single logical batch. queue.Client.EnqueueBatch enqueues
+// one of these as a TypeBatch task; queue.Server.handleBatchTask fans it out
+// into one child ExtractionJob per URL so an individual failure (e.g. a
+// deleted video mid-channel-import) doesn't abort the rest, mirroring how
+// bulk channel-import tools fan out one job per video.
+type BatchExtractionJob struct {
+	ID          string            `json:"id"`
+	URLs        []string          `json:"urls"`
+	Template    ExtractionRequest `json:"template"`
+	Total       int               `json:"total"`
+	Completed   int               `json:"completed"`
+	Failed      int               `json:"failed"`
+	MaxFailures int               `json:"max_failures,omitempty"` // abort once Failed reaches this; 0 = unlimited
+	Status      BatchStatus       `json:"status"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+}
+
+// BatchStatus is the aggregate state of a BatchExtractionJob, derived from
+// its children's individual statuses.
+type BatchStatus string
+
+const (
+	BatchStatusPending   BatchStatus = "pending"
+	BatchStatusRunning   BatchStatus = "running"
+	BatchStatusCompleted BatchStatus = "completed"
+	BatchStatusAborted   BatchStatus = "aborted"
+)
+
+// BatchChildStatus is one URL's status within a BatchExtractionJob, stored
+// in the batch's `batch:{id}:children` Redis hash keyed by child job ID.
+type BatchChildStatus struct {
+	JobID  string    `json:"job_id"`
+	URL    string    `json:"url"`
+	Status JobStatus `json:"status"`
+	Error  string    `json:"error,omitempty"`
 }
 
 // JobStatus represents the current state of a job
@@ -38,8 +150,54 @@ const (
 	StatusProcessing JobStatus = "processing"
 	StatusCompleted  JobStatus = "completed"
 	StatusFailed     JobStatus = "failed"
+	// StatusNeedsCookies means the job stopped retrying because the
+	// extractor's classified error (see internal/errclass) required a fresh
+	// cookie jar rather than another attempt.
+	StatusNeedsCookies JobStatus = "needs_cookies"
+	// StatusScheduled means the job's stream hasn't started yet
+	// (extractor.ErrStreamNotStarted under LiveModeWaitAndRetry) - a fresh
+	// job was persisted via queue.Client.EnqueueExtractionJobAt to retry once
+	// it goes live, and this job ID won't be retried itself.
+	StatusScheduled JobStatus = "scheduled"
+)
+
+// ProgressStage identifies the pipeline stage a ProgressEvent was emitted
+// from, so streaming consumers can render a pipeline rather than a single
+// percentage.
+type ProgressStage string
+
+const (
+	StageQueued         ProgressStage = "queued"
+	StageMetadata       ProgressStage = "metadata"
+	StageDownloading    ProgressStage = "downloading"
+	StagePostProcessing ProgressStage = "post_processing"
+	// StagePreview covers downloadMedia's optional scrub-preview sprite
+	// sheet generation (ExtractionRequest.Preview), so a client can show
+	// "generating previews" instead of looking stuck between download and
+	// upload.
+	StagePreview   ProgressStage = "generating_preview"
+	StageUploading ProgressStage = "uploading"
+	StageCompleted ProgressStage = "completed"
+	StageFailed    ProgressStage = "failed"
 )
 
+// ProgressEvent is a single fine-grained update published on a job's
+// `job:{id}:progress` pub/sub channel as yt-dlp/ffmpeg report progress.
+// Unlike ExtractionJob.Progress, a stream of these lets a caller render
+// stage transitions and transfer-rate detail instead of polling a bare
+// 0-100 integer.
+type ProgressEvent struct {
+	JobID            string        `json:"job_id"`
+	Stage            ProgressStage `json:"stage"`
+	Percent          int           `json:"percent"`
+	BytesTransferred int64         `json:"bytes_transferred,omitempty"`
+	BytesTotal       int64         `json:"bytes_total,omitempty"`
+	ETASeconds       int           `json:"eta_seconds,omitempty"`
+	Status           JobStatus     `json:"status"`
+	Message          string        `json:"message,omitempty"`
+	Timestamp        time.Time     `json:"timestamp"`
+}
+
 // MediaMetadata contains information about the extracted media
 type MediaMetadata struct {
 	Title       string        `json:"title"`
@@ -57,8 +215,53 @@ type MediaMetadata struct {
 	VideoCodec  string        `json:"video_codec,omitempty"`
 	AudioCodec  string        `json:"audio_codec,omitempty"`
 	Formats     []FormatEntry `json:"formats,omitempty"` // Available formats from yt-dlp
+
+	// LiveStatus mirrors yt-dlp's own live_status field (falling back to
+	// is_live/was_live on older yt-dlp versions that don't report it), so
+	// callers can distinguish a completed VOD from an upcoming, in-progress,
+	// or just-ended livestream.
+	LiveStatus LiveStatus `json:"live_status,omitempty"`
+	// ReleaseTimestamp is the scheduled start time (unix seconds) yt-dlp
+	// reports for an upcoming stream. Zero when not applicable.
+	ReleaseTimestamp int64 `json:"release_timestamp,omitempty"`
+
+	// Thumbnails is set when Download's DownloadOptions.Thumbnails asked for
+	// a scrub-preview sprite sheet (or frame set) from the downloaded file,
+	// mirroring extractor.ThumbnailResult. Nil when no preview was generated.
+	Thumbnails *ThumbnailSet `json:"thumbnails,omitempty"`
+}
+
+// ThumbnailSet describes the scrub-preview sprite sheet (or frame set)
+// Download generated from the downloaded file. SpritePath/VTTPath are empty
+// in frame mode, where every sampled frame is listed in Files instead.
+type ThumbnailSet struct {
+	SpritePath string   `json:"sprite_path,omitempty"`
+	VTTPath    string   `json:"vtt_path,omitempty"`
+	Files      []string `json:"files,omitempty"`
+	Cols       int      `json:"cols,omitempty"`
+	Rows       int      `json:"rows,omitempty"`
+	TileWidth  int      `json:"tile_width,omitempty"`
+	TileHeight int      `json:"tile_height,omitempty"`
 }
 
+// LiveStatus is the livestream state of a piece of media, as yt-dlp's own
+// live_status metadata field reports it.
+type LiveStatus string
+
+const (
+	// LiveStatusNone is a regular, already-complete VOD.
+	LiveStatusNone LiveStatus = "none"
+	// LiveStatusUpcoming is a scheduled stream that hasn't started.
+	LiveStatusUpcoming LiveStatus = "upcoming"
+	// LiveStatusLive is currently broadcasting.
+	LiveStatusLive LiveStatus = "live"
+	// LiveStatusPostLiveDVR just ended and yt-dlp may still be assembling
+	// the on-demand recording (its reported duration can be incomplete).
+	LiveStatusPostLiveDVR LiveStatus = "post_live_dvr"
+	// LiveStatusWasLive was a stream, now fully available as a normal VOD.
+	LiveStatusWasLive LiveStatus = "was_live"
+)
+
 // FormatEntry represents a single format option from yt-dlp
 type FormatEntry struct {
 	FormatID   string `json:"format_id"`
@@ -72,17 +275,26 @@ type FormatEntry struct {
 	Codec      string `json:"codec,omitempty"`
 	VideoCodec string `json:"vcodec,omitempty"`
 	AudioCodec string `json:"acodec,omitempty"`
+	// URL is the format's direct media or manifest URL (HLS .m3u8 or DASH
+	// .mpd for a live stream), from yt-dlp's own "url" field.
+	URL string `json:"url,omitempty"`
+	// Protocol is yt-dlp's format protocol (e.g. "m3u8", "m3u8_native",
+	// "http_dash_segments", "https"), used to tell a streaming manifest
+	// format apart from a plain progressive download.
+	Protocol string `json:"protocol,omitempty"`
 }
 
 // ExtractionResult contains the output of a successful extraction
 type ExtractionResult struct {
-	DownloadURL  string    `json:"download_url"` // Presigned S3 URL
-	Filename     string    `json:"filename"`
-	SizeBytes    int64     `json:"size_bytes"`
-	Format       string    `json:"format"`
-	SubtitleURLs []string  `json:"subtitle_urls,omitempty"` // Multiple language subtitles
-	ThumbnailURL string    `json:"thumbnail_url,omitempty"`
-	ExpiresAt    time.Time `json:"expires_at"` // Presigned URL expiry
+	DownloadURL    string    `json:"download_url"` // Presigned S3 URL
+	Filename       string    `json:"filename"`
+	SizeBytes      int64     `json:"size_bytes"`
+	Format         string    `json:"format"`
+	SubtitleURLs   []string  `json:"subtitle_urls,omitempty"` // Multiple language subtitles
+	ThumbnailURL   string    `json:"thumbnail_url,omitempty"`
+	ExpiresAt      time.Time `json:"expires_at"`                // Presigned URL expiry
+	ChecksumSHA256 string    `json:"checksum_sha256,omitempty"` // Base64 SHA-256 of the uploaded object (S3's composite checksum for multipart uploads)
+	Key            string    `json:"-"`                         // Storage object key, never serialized to clients
 }
 
 // Platform represents supported platforms
@@ -0,0 +1,106 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+)
+
+// resolvePublicAddrs resolves host and rejects it unless every address it
+// resolves to is public/routable (see isPublicAddr) - a mixed public/private
+// answer is rejected outright rather than trusting the caller to pick the
+// public one, since DNS rebinding means the resolver, not the caller,
+// decides which address actually gets used.
+func resolvePublicAddrs(ctx context.Context, host string) ([]net.IPAddr, error) {
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to resolve host: %v", ErrUnsafeWebhookURL, err)
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("%w: host did not resolve to any address", ErrUnsafeWebhookURL)
+	}
+	for _, addr := range addrs {
+		if !isPublicAddr(addr.IP) {
+			return nil, fmt.Errorf("%w: %s resolves to non-public address %s", ErrUnsafeWebhookURL, host, addr.IP)
+		}
+	}
+	return addrs, nil
+}
+
+// ErrUnsafeWebhookURL is returned by validateWebhookURL when a candidate
+// webhook URL isn't https, or resolves to a loopback/private/link-local/
+// multicast address. Register rejects a URL that fails this check outright,
+// and the dispatcher's CheckRedirect (see NewDispatcher) applies the same
+// check to every redirect hop - otherwise any API-key holder could register
+// a webhook against, say, the cloud metadata endpoint and have the
+// dispatcher's signed, automatically-retried POST hit it on their behalf.
+var ErrUnsafeWebhookURL = errors.New("webhook url must be https and resolve to a public, non-internal address")
+
+// validateWebhookURL rejects anything but an https URL whose host resolves
+// to only public, routable addresses. It resolves the host itself (rather
+// than trusting a literal IP in the URL) so a hostname can't be swapped to
+// something private after registration via a lowered DNS TTL.
+func validateWebhookURL(ctx context.Context, rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnsafeWebhookURL, err)
+	}
+	if parsed.Scheme != "https" {
+		return fmt.Errorf("%w: scheme must be https", ErrUnsafeWebhookURL)
+	}
+	host := parsed.Hostname()
+	if host == "" {
+		return fmt.Errorf("%w: missing host", ErrUnsafeWebhookURL)
+	}
+
+	_, err = resolvePublicAddrs(ctx, host)
+	return err
+}
+
+// safeWebhookDialContext is the webhook Dispatcher's http.Transport.DialContext
+// (see NewDispatcher). It re-resolves addr's host itself and dials one of the
+// addresses resolvePublicAddrs validated, rather than handing the unvalidated
+// host straight to the default dialer - pinning the connection to a checked
+// address is what closes the TOCTOU gap a plain "validate then call
+// http.Client.Do" still has between the check and the real DNS resolution a
+// stock dialer would otherwise perform on its own.
+func safeWebhookDialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	addrs, err := resolvePublicAddrs(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	var dialer net.Dialer
+	var lastErr error
+	for _, a := range addrs {
+		conn, dialErr := dialer.DialContext(ctx, network, net.JoinHostPort(a.IP.String(), port))
+		if dialErr == nil {
+			return conn, nil
+		}
+		lastErr = dialErr
+	}
+	return nil, lastErr
+}
+
+// isPublicAddr reports whether ip is a routable public address - not
+// loopback, private (RFC 1918/4193), link-local, multicast, or unspecified.
+func isPublicAddr(ip net.IP) bool {
+	switch {
+	case ip.IsLoopback(),
+		ip.IsPrivate(),
+		ip.IsLinkLocalUnicast(),
+		ip.IsLinkLocalMulticast(),
+		ip.IsInterfaceLocalMulticast(),
+		ip.IsMulticast(),
+		ip.IsUnspecified():
+		return false
+	}
+	return true
+}
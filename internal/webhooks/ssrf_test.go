@@ -0,0 +1,78 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestIsPublicAddr(t *testing.T) {
+	cases := []struct {
+		name string
+		ip   string
+		want bool
+	}{
+		{"public v4", "8.8.8.8", true},
+		{"public v6", "2001:4860:4860::8888", true},
+		{"loopback v4", "127.0.0.1", false},
+		{"loopback v6", "::1", false},
+		{"private rfc1918", "10.0.0.1", false},
+		{"private rfc1918 192", "192.168.1.1", false},
+		{"link-local (cloud metadata)", "169.254.169.254", false},
+		{"unique-local v6", "fd00::1", false},
+		{"unspecified", "0.0.0.0", false},
+		{"multicast", "224.0.0.1", false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ip := net.ParseIP(tc.ip)
+			if ip == nil {
+				t.Fatalf("net.ParseIP(%q) returned nil", tc.ip)
+			}
+			if got := isPublicAddr(ip); got != tc.want {
+				t.Errorf("isPublicAddr(%s) = %v, want %v", tc.ip, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestValidateWebhookURLRejectsNonHTTPS(t *testing.T) {
+	err := validateWebhookURL(context.Background(), "http://8.8.8.8/webhook")
+	if !errors.Is(err, ErrUnsafeWebhookURL) {
+		t.Fatalf("expected ErrUnsafeWebhookURL for a non-https URL, got %v", err)
+	}
+}
+
+func TestValidateWebhookURLRejectsMissingHost(t *testing.T) {
+	err := validateWebhookURL(context.Background(), "https:///webhook")
+	if !errors.Is(err, ErrUnsafeWebhookURL) {
+		t.Fatalf("expected ErrUnsafeWebhookURL for a URL with no host, got %v", err)
+	}
+}
+
+func TestValidateWebhookURLRejectsPrivateAndLinkLocalAddresses(t *testing.T) {
+	urls := []string{
+		"https://127.0.0.1/webhook",
+		"https://10.0.0.1/webhook",
+		"https://169.254.169.254/webhook", // cloud metadata endpoint
+		"https://[::1]/webhook",
+	}
+	for _, raw := range urls {
+		t.Run(raw, func(t *testing.T) {
+			err := validateWebhookURL(context.Background(), raw)
+			if !errors.Is(err, ErrUnsafeWebhookURL) {
+				t.Errorf("validateWebhookURL(%q) = %v, want ErrUnsafeWebhookURL", raw, err)
+			}
+		})
+	}
+}
+
+func TestValidateWebhookURLAcceptsPublicAddress(t *testing.T) {
+	// A literal public IP resolves locally without a real DNS lookup, so
+	// this doesn't depend on network access in the test environment.
+	if err := validateWebhookURL(context.Background(), "https://8.8.8.8/webhook"); err != nil {
+		t.Fatalf("validateWebhookURL rejected a public address: %v", err)
+	}
+}
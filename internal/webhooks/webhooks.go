@@ -0,0 +1,609 @@
+// Package webhooks implements durable, signed webhook delivery for job
+// lifecycle events. Registrations and in-flight deliveries are persisted in
+// Redis so that retries survive process restarts.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	mrand "math/rand"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// retention bounds how long registrations and delivery history are kept in
+// Redis; it mirrors the job retention window in internal/queue.
+const retention = 7 * 24 * time.Hour
+
+// backoffSchedule is the delay before each retry attempt. A delivery is
+// abandoned once all attempts are exhausted (about 73 minutes after the
+// first attempt, before jitter).
+var backoffSchedule = []time.Duration{
+	10 * time.Second,
+	30 * time.Second,
+	2 * time.Minute,
+	10 * time.Minute,
+	1 * time.Hour,
+}
+
+const pollInterval = 2 * time.Second
+const historyLimit = 50
+const pendingSetKey = "webhook:pending"
+const deadLetterListKey = "webhook:dead"
+
+// Registration is a webhook subscription for a single job.
+type Registration struct {
+	JobID     string    `json:"job_id"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Delivery records the outcome of a single delivery attempt (successful or
+// not) for GET /webhooks/:job_id/deliveries.
+type Delivery struct {
+	Event       string     `json:"event"`
+	Attempt     int        `json:"attempt"`
+	Success     bool       `json:"success"`
+	StatusCode  int        `json:"status_code,omitempty"`
+	Error       string     `json:"error,omitempty"`
+	LatencyMS   int64      `json:"latency_ms"`
+	AttemptedAt time.Time  `json:"attempted_at"`
+	NextRetryAt *time.Time `json:"next_retry_at,omitempty"`
+}
+
+// pendingDelivery is the durable unit of work tracked in the Redis sorted
+// set, keyed by its next attempt time.
+type pendingDelivery struct {
+	ID      string                 `json:"id"`
+	JobID   string                 `json:"job_id"`
+	URL     string                 `json:"url"`
+	Secret  string                 `json:"secret"`
+	Event   string                 `json:"event"`
+	Payload map[string]interface{} `json:"payload"`
+	Attempt int                    `json:"attempt"`
+}
+
+// DeadLetter records a delivery that exhausted every retry attempt in
+// backoffSchedule, kept around for inspection and manual redelivery via
+// Dispatcher.Redeliver.
+type DeadLetter struct {
+	ID             string                 `json:"id"`
+	JobID          string                 `json:"job_id"`
+	URL            string                 `json:"url"`
+	Secret         string                 `json:"secret"`
+	Event          string                 `json:"event"`
+	Payload        map[string]interface{} `json:"payload"`
+	Attempts       int                    `json:"attempts"`
+	LastStatusCode int                    `json:"last_status_code,omitempty"`
+	LastError      string                 `json:"last_error,omitempty"`
+	FailedAt       time.Time              `json:"failed_at"`
+}
+
+// lastEvent is the most recently published event for a job, kept around so
+// Replay can re-deliver it without the caller having to resend the payload.
+type lastEvent struct {
+	Event   string                 `json:"event"`
+	Payload map[string]interface{} `json:"payload"`
+}
+
+// Dispatcher persists webhook registrations and delivers job lifecycle
+// events to them, retrying with exponential backoff across restarts.
+type Dispatcher struct {
+	redis      *redis.Client
+	httpClient *http.Client
+	logger     *zap.Logger
+	closeCh    chan struct{}
+	stoppedCh  chan struct{}
+}
+
+// maxWebhookRedirects bounds how many redirect hops attempt follows -
+// matches net/http's own default cap, which checkWebhookRedirect (our
+// CheckRedirect) has to re-implement since setting CheckRedirect at all
+// disables that default.
+const maxWebhookRedirects = 10
+
+// NewDispatcher creates a new webhook dispatcher backed by redisClient.
+func NewDispatcher(redisClient *redis.Client, logger *zap.Logger) *Dispatcher {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = safeWebhookDialContext
+
+	return &Dispatcher{
+		redis: redisClient,
+		httpClient: &http.Client{
+			Timeout:       10 * time.Second,
+			Transport:     transport,
+			CheckRedirect: checkWebhookRedirect,
+		},
+		logger:    logger,
+		closeCh:   make(chan struct{}),
+		stoppedCh: make(chan struct{}),
+	}
+}
+
+// checkWebhookRedirect re-applies validateWebhookURL to every redirect
+// target, so a registered URL that passed validation can't hand the
+// dispatcher off to a loopback/private/link-local address via a 3xx
+// response.
+func checkWebhookRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxWebhookRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxWebhookRedirects)
+	}
+	return validateWebhookURL(req.Context(), req.URL.String())
+}
+
+// Start begins the delivery loop in the background.
+func (d *Dispatcher) Start(ctx context.Context) {
+	go d.run(ctx)
+}
+
+// Stop halts the delivery loop and waits for the in-flight tick to finish.
+func (d *Dispatcher) Stop() {
+	close(d.closeCh)
+	<-d.stoppedCh
+}
+
+func (d *Dispatcher) run(ctx context.Context) {
+	defer close(d.stoppedCh)
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			d.deliverDue(ctx)
+		case <-d.closeCh:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func registrationKey(jobID string) string { return "webhook:reg:" + jobID }
+func deliveryKey(id string) string        { return "webhook:delivery:" + id }
+func historyKey(jobID string) string      { return "webhook:history:" + jobID }
+func lastEventKey(jobID string) string    { return "webhook:last:" + jobID }
+func deadLetterKey(id string) string      { return "webhook:dead:" + id }
+
+// Register persists a webhook subscription for jobID and returns the HMAC
+// secret the caller must use to verify delivered signatures. Re-registering
+// a job replaces its previous subscription and secret. Rejects url outright
+// (see validateWebhookURL) rather than persisting it and failing quietly on
+// first delivery.
+func (d *Dispatcher) Register(ctx context.Context, jobID, url string) (string, error) {
+	if err := validateWebhookURL(ctx, url); err != nil {
+		return "", err
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+
+	reg := Registration{
+		JobID:     jobID,
+		URL:       url,
+		Secret:    secret,
+		CreatedAt: time.Now(),
+	}
+
+	data, err := json.Marshal(reg)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal registration: %w", err)
+	}
+
+	if err := d.redis.Set(ctx, registrationKey(jobID), data, retention).Err(); err != nil {
+		return "", fmt.Errorf("failed to store registration: %w", err)
+	}
+
+	return secret, nil
+}
+
+func generateSecret() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(raw), nil
+}
+
+// Publish schedules immediate delivery of event for jobID, if a webhook is
+// registered for it. It is a no-op when no registration exists, so callers
+// can publish unconditionally on every job state transition.
+func (d *Dispatcher) Publish(ctx context.Context, jobID, event string, payload map[string]interface{}) error {
+	reg, err := d.getRegistration(ctx, jobID)
+	if err != nil {
+		return err
+	}
+	if reg == nil {
+		return nil
+	}
+
+	if err := d.rememberLastEvent(ctx, jobID, event, payload); err != nil {
+		d.logger.Warn("Failed to persist last webhook event", zap.String("job_id", jobID), zap.Error(err))
+	}
+
+	return d.schedule(ctx, reg, event, payload, 0, time.Now())
+}
+
+// Replay re-delivers the most recently published event for jobID, starting
+// a fresh retry schedule.
+func (d *Dispatcher) Replay(ctx context.Context, jobID string) error {
+	reg, err := d.getRegistration(ctx, jobID)
+	if err != nil {
+		return err
+	}
+	if reg == nil {
+		return fmt.Errorf("no webhook registered for job %s", jobID)
+	}
+
+	data, err := d.redis.Get(ctx, lastEventKey(jobID)).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return fmt.Errorf("no delivered event to replay for job %s", jobID)
+		}
+		return fmt.Errorf("failed to load last event: %w", err)
+	}
+
+	var last lastEvent
+	if err := json.Unmarshal(data, &last); err != nil {
+		return fmt.Errorf("failed to unmarshal last event: %w", err)
+	}
+
+	return d.schedule(ctx, reg, last.Event, last.Payload, 0, time.Now())
+}
+
+// ListDeadLetters returns the deliveries that have exhausted their retry
+// budget, most recently failed first; the list keeps only the latest
+// historyLimit of them.
+func (d *Dispatcher) ListDeadLetters(ctx context.Context) ([]DeadLetter, error) {
+	ids, err := d.redis.LRange(ctx, deadLetterListKey, 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead letters: %w", err)
+	}
+
+	letters := make([]DeadLetter, 0, len(ids))
+	for _, id := range ids {
+		data, err := d.redis.Get(ctx, deadLetterKey(id)).Bytes()
+		if err != nil {
+			continue
+		}
+		var dl DeadLetter
+		if err := json.Unmarshal(data, &dl); err != nil {
+			continue
+		}
+		letters = append(letters, dl)
+	}
+	return letters, nil
+}
+
+// Redeliver re-schedules a dead-lettered delivery for immediate redelivery
+// with a fresh retry budget, and removes it from the dead-letter list.
+func (d *Dispatcher) Redeliver(ctx context.Context, deliveryID string) error {
+	data, err := d.redis.Get(ctx, deadLetterKey(deliveryID)).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return fmt.Errorf("no dead-lettered delivery found for %s", deliveryID)
+		}
+		return fmt.Errorf("failed to load dead-lettered delivery: %w", err)
+	}
+
+	var dl DeadLetter
+	if err := json.Unmarshal(data, &dl); err != nil {
+		return fmt.Errorf("failed to unmarshal dead-lettered delivery: %w", err)
+	}
+
+	reg := &Registration{JobID: dl.JobID, URL: dl.URL, Secret: dl.Secret}
+	if err := d.schedule(ctx, reg, dl.Event, dl.Payload, 0, time.Now()); err != nil {
+		return fmt.Errorf("failed to reschedule delivery: %w", err)
+	}
+
+	pipe := d.redis.TxPipeline()
+	pipe.Del(ctx, deadLetterKey(deliveryID))
+	pipe.LRem(ctx, deadLetterListKey, 1, deliveryID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		d.logger.Warn("Failed to clear dead letter after redelivery", zap.String("delivery_id", deliveryID), zap.Error(err))
+	}
+
+	return nil
+}
+
+// GetDeliveries returns the attempt history for jobID, oldest first.
+func (d *Dispatcher) GetDeliveries(ctx context.Context, jobID string) ([]Delivery, error) {
+	entries, err := d.redis.LRange(ctx, historyKey(jobID), 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load delivery history: %w", err)
+	}
+
+	deliveries := make([]Delivery, 0, len(entries))
+	for i := len(entries) - 1; i >= 0; i-- {
+		var delivery Delivery
+		if err := json.Unmarshal([]byte(entries[i]), &delivery); err != nil {
+			continue
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, nil
+}
+
+func (d *Dispatcher) getRegistration(ctx context.Context, jobID string) (*Registration, error) {
+	data, err := d.redis.Get(ctx, registrationKey(jobID)).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to load webhook registration: %w", err)
+	}
+
+	var reg Registration
+	if err := json.Unmarshal(data, &reg); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal registration: %w", err)
+	}
+	return &reg, nil
+}
+
+func (d *Dispatcher) rememberLastEvent(ctx context.Context, jobID, event string, payload map[string]interface{}) error {
+	data, err := json.Marshal(lastEvent{Event: event, Payload: payload})
+	if err != nil {
+		return err
+	}
+	return d.redis.Set(ctx, lastEventKey(jobID), data, retention).Err()
+}
+
+// schedule persists a pending delivery and adds it to the due set at
+// nextAttempt.
+func (d *Dispatcher) schedule(ctx context.Context, reg *Registration, event string, payload map[string]interface{}, attempt int, nextAttempt time.Time) error {
+	pd := pendingDelivery{
+		ID:      uuid.New().String(),
+		JobID:   reg.JobID,
+		URL:     reg.URL,
+		Secret:  reg.Secret,
+		Event:   event,
+		Payload: payload,
+		Attempt: attempt,
+	}
+
+	data, err := json.Marshal(pd)
+	if err != nil {
+		return fmt.Errorf("failed to marshal pending delivery: %w", err)
+	}
+
+	pipe := d.redis.TxPipeline()
+	pipe.Set(ctx, deliveryKey(pd.ID), data, retention)
+	pipe.ZAdd(ctx, pendingSetKey, redis.Z{Score: float64(nextAttempt.Unix()), Member: pd.ID})
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// deliverDue attempts delivery of every pending delivery whose scheduled
+// time has passed.
+func (d *Dispatcher) deliverDue(ctx context.Context) {
+	now := time.Now()
+	ids, err := d.redis.ZRangeByScore(ctx, pendingSetKey, &redis.ZRangeBy{
+		Min: "0",
+		Max: fmt.Sprintf("%d", now.Unix()),
+	}).Result()
+	if err != nil {
+		d.logger.Error("Failed to scan due webhook deliveries", zap.Error(err))
+		return
+	}
+
+	for _, id := range ids {
+		d.deliverOne(ctx, id)
+	}
+}
+
+func (d *Dispatcher) deliverOne(ctx context.Context, id string) {
+	data, err := d.redis.Get(ctx, deliveryKey(id)).Bytes()
+	if err != nil {
+		// The delivery record expired or was already cleared; drop the
+		// stale sorted-set entry so it isn't rescanned forever.
+		d.redis.ZRem(ctx, pendingSetKey, id)
+		return
+	}
+
+	var pd pendingDelivery
+	if err := json.Unmarshal(data, &pd); err != nil {
+		d.logger.Error("Failed to unmarshal pending webhook delivery", zap.String("delivery_id", id), zap.Error(err))
+		d.redis.ZRem(ctx, pendingSetKey, id)
+		d.redis.Del(ctx, deliveryKey(id))
+		return
+	}
+
+	statusCode, latency, deliverErr := d.attempt(ctx, pd)
+	success := deliverErr == nil
+
+	delivery := Delivery{
+		Event:       pd.Event,
+		Attempt:     pd.Attempt + 1,
+		Success:     success,
+		StatusCode:  statusCode,
+		LatencyMS:   latency.Milliseconds(),
+		AttemptedAt: time.Now(),
+	}
+	if deliverErr != nil {
+		delivery.Error = deliverErr.Error()
+	}
+
+	if success {
+		if ce := d.logger.Check(zap.InfoLevel, "Webhook delivered"); ce != nil {
+			ce.Write(
+				zap.String("job_id", pd.JobID),
+				zap.Int("status_code", statusCode),
+			)
+		}
+		d.finish(ctx, id, pd.JobID, delivery)
+		return
+	}
+
+	nextAttempt := pd.Attempt + 1
+	if pd.Attempt >= len(backoffSchedule) {
+		delivery.Error = fmt.Sprintf("giving up after %d attempts: %s", delivery.Attempt, delivery.Error)
+		d.logger.Error("Webhook delivery abandoned",
+			zap.String("job_id", pd.JobID),
+			zap.String("url", pd.URL),
+			zap.Int("attempts", delivery.Attempt),
+		)
+		d.deadLetter(ctx, id, pd, statusCode, delivery.Error)
+		d.finish(ctx, id, pd.JobID, delivery)
+		return
+	}
+
+	retryAt := time.Now().Add(withJitter(backoffSchedule[pd.Attempt]))
+	delivery.NextRetryAt = &retryAt
+
+	pd.Attempt = nextAttempt
+	updated, err := json.Marshal(pd)
+	if err != nil {
+		d.logger.Error("Failed to marshal retried webhook delivery", zap.Error(err))
+		return
+	}
+
+	pipe := d.redis.TxPipeline()
+	pipe.Set(ctx, deliveryKey(id), updated, retention)
+	pipe.ZAdd(ctx, pendingSetKey, redis.Z{Score: float64(retryAt.Unix()), Member: id})
+	if _, err := pipe.Exec(ctx); err != nil {
+		d.logger.Error("Failed to reschedule webhook delivery", zap.Error(err))
+	}
+
+	d.appendHistory(ctx, pd.JobID, delivery)
+
+	d.logger.Warn("Webhook delivery failed, will retry",
+		zap.String("job_id", pd.JobID),
+		zap.Int("attempt", delivery.Attempt),
+		zap.Time("next_retry_at", retryAt),
+	)
+}
+
+func (d *Dispatcher) finish(ctx context.Context, deliveryID, jobID string, delivery Delivery) {
+	d.redis.ZRem(ctx, pendingSetKey, deliveryID)
+	d.redis.Del(ctx, deliveryKey(deliveryID))
+	d.appendHistory(ctx, jobID, delivery)
+}
+
+// deadLetter persists a terminally-failed delivery as a DeadLetter so
+// Redeliver can retry it later with the operator's explicit say-so, rather
+// than the dropped attempt just disappearing into the history list once
+// historyLimit rolls past it.
+func (d *Dispatcher) deadLetter(ctx context.Context, deliveryID string, pd pendingDelivery, lastStatusCode int, lastError string) {
+	dl := DeadLetter{
+		ID:             deliveryID,
+		JobID:          pd.JobID,
+		URL:            pd.URL,
+		Secret:         pd.Secret,
+		Event:          pd.Event,
+		Payload:        pd.Payload,
+		Attempts:       pd.Attempt + 1,
+		LastStatusCode: lastStatusCode,
+		LastError:      lastError,
+		FailedAt:       time.Now(),
+	}
+
+	data, err := json.Marshal(dl)
+	if err != nil {
+		d.logger.Warn("Failed to marshal dead letter", zap.String("delivery_id", deliveryID), zap.Error(err))
+		return
+	}
+
+	pipe := d.redis.TxPipeline()
+	pipe.Set(ctx, deadLetterKey(deliveryID), data, retention)
+	pipe.LPush(ctx, deadLetterListKey, deliveryID)
+	pipe.LTrim(ctx, deadLetterListKey, 0, historyLimit-1)
+	if _, err := pipe.Exec(ctx); err != nil {
+		d.logger.Warn("Failed to record dead-lettered webhook delivery", zap.String("delivery_id", deliveryID), zap.Error(err))
+	}
+}
+
+func (d *Dispatcher) appendHistory(ctx context.Context, jobID string, delivery Delivery) {
+	data, err := json.Marshal(delivery)
+	if err != nil {
+		return
+	}
+
+	pipe := d.redis.TxPipeline()
+	pipe.LPush(ctx, historyKey(jobID), data)
+	pipe.LTrim(ctx, historyKey(jobID), 0, historyLimit-1)
+	pipe.Expire(ctx, historyKey(jobID), retention)
+	if _, err := pipe.Exec(ctx); err != nil {
+		d.logger.Warn("Failed to record webhook delivery history", zap.String("job_id", jobID), zap.Error(err))
+	}
+}
+
+// attempt performs a single signed HTTP delivery attempt. Deliveries retry
+// for about 73 minutes (see backoffSchedule) against a URL registered up to 7 days
+// earlier (see retention), long enough for a low-TTL DNS record to move from
+// the public address validateWebhookURL saw at Register time to an internal
+// one - so every attempt re-validates pd.URL itself, not just Register and
+// redirect hops, and the transport's DialContext (see NewDispatcher) pins
+// the connection to whichever address that same check resolved.
+func (d *Dispatcher) attempt(ctx context.Context, pd pendingDelivery) (statusCode int, latency time.Duration, err error) {
+	if err := validateWebhookURL(ctx, pd.URL); err != nil {
+		return 0, 0, err
+	}
+
+	body, err := json.Marshal(pd.Payload)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	timestamp := fmt.Sprintf("%d", time.Now().Unix())
+	signature := sign(pd.Secret, timestamp, body)
+
+	reqCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodPost, pd.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "MedYan-Webhook/1.0")
+	req.Header.Set("X-MedYan-Event", pd.Event)
+	req.Header.Set("X-MedYan-Timestamp", timestamp)
+	req.Header.Set("X-MedYan-Signature", "sha256="+signature)
+
+	start := time.Now()
+	resp, err := d.httpClient.Do(req)
+	latency = time.Since(start)
+	if err != nil {
+		return 0, latency, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, latency, fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, latency, nil
+}
+
+// sign computes the HMAC-SHA256 signature over timestamp+"."+body, hex
+// encoded. Verifying servers should recompute it the same way and compare
+// against the X-MedYan-Signature header.
+func sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// withJitter applies +/-20% jitter to d so a burst of failing deliveries
+// doesn't all retry in lockstep.
+func withJitter(d time.Duration) time.Duration {
+	jitterAmount := float64(d) * 0.2
+	jittered := float64(d) - jitterAmount + (mrand.Float64() * jitterAmount * 2)
+	return time.Duration(jittered)
+}
@@ -0,0 +1,57 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+// TestSignMatchesManualHMAC checks sign against a signature computed the way
+// a receiving server is expected to verify it - recomputing HMAC-SHA256 over
+// timestamp+"."+body with the shared secret and comparing hex digests.
+func TestSignMatchesManualHMAC(t *testing.T) {
+	secret := "shared-secret"
+	timestamp := "1700000000"
+	body := []byte(`{"event":"completed","job_id":"abc123"}`)
+
+	got := sign(secret, timestamp, body)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	want := hex.EncodeToString(mac.Sum(nil))
+
+	if got != want {
+		t.Fatalf("sign() = %q, want %q", got, want)
+	}
+}
+
+// TestSignIsDeterministic checks that the same inputs always produce the
+// same signature, since a receiver recomputes it independently and expects
+// an exact match.
+func TestSignIsDeterministic(t *testing.T) {
+	a := sign("secret", "1700000000", []byte("body"))
+	b := sign("secret", "1700000000", []byte("body"))
+	if a != b {
+		t.Fatalf("sign() is not deterministic: %q != %q", a, b)
+	}
+}
+
+// TestSignDiffersOnSecretTimestampOrBody checks that changing any one of
+// secret, timestamp, or body changes the signature - a verifying server
+// relies on each mattering to catch tampering or replay.
+func TestSignDiffersOnSecretTimestampOrBody(t *testing.T) {
+	base := sign("secret", "1700000000", []byte("body"))
+
+	if sign("other-secret", "1700000000", []byte("body")) == base {
+		t.Fatalf("sign() unaffected by a changed secret")
+	}
+	if sign("secret", "1700000001", []byte("body")) == base {
+		t.Fatalf("sign() unaffected by a changed timestamp")
+	}
+	if sign("secret", "1700000000", []byte("other body")) == base {
+		t.Fatalf("sign() unaffected by a changed body")
+	}
+}
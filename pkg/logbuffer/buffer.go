@@ -0,0 +1,415 @@
+// Package logbuffer implements a fixed-size, file-backed circular log
+// buffer. It plugs into zap as an additional zapcore.Core alongside the
+// file/console cores built in internal/logger, giving /debug/logs a window
+// into recent log history without holding everything in memory or
+// depending on an external log aggregator.
+package logbuffer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	indexFileName = "index.json"
+	openSuffix    = ".log"
+	sealedSuffix  = ".log.zst"
+
+	defaultTotalBytes = 64 * 1024 * 1024
+	defaultQueueSize  = 4096
+)
+
+// Config controls the ring buffer's on-disk layout and capacity.
+type Config struct {
+	// Dir is the directory chunk files and the index live under. Created if
+	// missing.
+	Dir string
+	// TotalBytes bounds the buffer's total on-disk size across all chunks,
+	// measured uncompressed. Defaults to 64 MiB.
+	TotalBytes int64
+	// ChunkBytes bounds a single chunk before it's sealed (compressed) and
+	// rotated. Defaults to TotalBytes/8.
+	ChunkBytes int64
+	// QueueSize bounds how many pending records the writer goroutine may
+	// buffer before new records are dropped. Defaults to 4096.
+	QueueSize int
+}
+
+func (c *Config) setDefaults() {
+	if c.TotalBytes <= 0 {
+		c.TotalBytes = defaultTotalBytes
+	}
+	if c.ChunkBytes <= 0 {
+		c.ChunkBytes = c.TotalBytes / 8
+	}
+	if c.QueueSize <= 0 {
+		c.QueueSize = defaultQueueSize
+	}
+}
+
+// chunkMeta describes one chunk file in the ring.
+type chunkMeta struct {
+	Seq         int64 `json:"seq"`
+	StartOffset int64 `json:"start_offset"`
+	EndOffset   int64 `json:"end_offset"` // exclusive; grows while open, frozen once sealed
+	Sealed      bool  `json:"sealed"`
+}
+
+func (m chunkMeta) fileName() string {
+	if m.Sealed {
+		return fmt.Sprintf("chunk-%08d%s", m.Seq, sealedSuffix)
+	}
+	return fmt.Sprintf("chunk-%08d%s", m.Seq, openSuffix)
+}
+
+// indexFile is the on-disk, atomically-rewritten manifest of the ring's
+// chunks. It's what lets the buffer survive a restart: on startup we
+// reload it and resume appending to the last open chunk instead of
+// starting from empty.
+type indexFile struct {
+	Chunks  []chunkMeta `json:"chunks"`
+	Dropped int64       `json:"dropped"`
+}
+
+// Buffer is a fixed-size, file-backed circular log buffer. Writes are
+// non-blocking: Offer enqueues a copy of the record and returns
+// immediately, dropping (and counting) the record if the internal queue is
+// full. A single background worker goroutine owns all file I/O.
+type Buffer struct {
+	cfg Config
+
+	records chan []byte
+	done    chan struct{}
+
+	dropped int64 // atomic
+
+	mu      sync.Mutex
+	chunks  []chunkMeta
+	current *os.File
+
+	notifyMu sync.Mutex
+	notifyCh chan struct{}
+}
+
+// Open creates (or resumes) a ring buffer rooted at cfg.Dir and starts its
+// background writer goroutine.
+func Open(cfg Config) (*Buffer, error) {
+	cfg.setDefaults()
+
+	if err := os.MkdirAll(cfg.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("logbuffer: create dir: %w", err)
+	}
+
+	b := &Buffer{
+		cfg:      cfg,
+		records:  make(chan []byte, cfg.QueueSize),
+		done:     make(chan struct{}),
+		notifyCh: make(chan struct{}),
+	}
+
+	if err := b.restore(); err != nil {
+		return nil, err
+	}
+
+	go b.run()
+
+	return b, nil
+}
+
+// restore loads index.json if present and reopens the last chunk for
+// append, otherwise starts a fresh, empty chunk at sequence 0.
+func (b *Buffer) restore() error {
+	idxPath := filepath.Join(b.cfg.Dir, indexFileName)
+
+	data, err := os.ReadFile(idxPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return fmt.Errorf("logbuffer: read index: %w", err)
+		}
+		return b.startChunk(0, 0)
+	}
+
+	var idx indexFile
+	if err := json.Unmarshal(data, &idx); err != nil {
+		// A corrupt index is not fatal to the rest of the service; start
+		// over rather than refusing to boot.
+		return b.startChunk(0, 0)
+	}
+
+	atomic.StoreInt64(&b.dropped, idx.Dropped)
+	b.chunks = idx.Chunks
+
+	if len(b.chunks) == 0 {
+		return b.startChunk(0, 0)
+	}
+
+	last := b.chunks[len(b.chunks)-1]
+	if last.Sealed {
+		return b.startChunk(last.Seq+1, last.EndOffset)
+	}
+
+	f, err := os.OpenFile(filepath.Join(b.cfg.Dir, last.fileName()), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		// The chunk file is missing or unreadable (e.g. removed out of
+		// band); drop it from the index and start clean past it.
+		b.chunks = b.chunks[:len(b.chunks)-1]
+		return b.startChunk(last.Seq+1, last.EndOffset)
+	}
+	b.current = f
+
+	return nil
+}
+
+// startChunk begins a new open chunk at the given sequence/offset.
+func (b *Buffer) startChunk(seq, startOffset int64) error {
+	meta := chunkMeta{Seq: seq, StartOffset: startOffset, EndOffset: startOffset}
+
+	f, err := os.OpenFile(filepath.Join(b.cfg.Dir, meta.fileName()), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("logbuffer: create chunk: %w", err)
+	}
+
+	b.current = f
+	b.chunks = append(b.chunks, meta)
+
+	return nil
+}
+
+// Offer enqueues a single encoded log record (without its trailing
+// newline) for the background writer. It never blocks: if the queue is
+// full the record is dropped and the drop counter is incremented.
+func (b *Buffer) Offer(record []byte) {
+	cp := make([]byte, len(record))
+	copy(cp, record)
+
+	select {
+	case b.records <- cp:
+	default:
+		atomic.AddInt64(&b.dropped, 1)
+	}
+}
+
+// run is the single writer goroutine; it owns every mutation of chunk
+// files and the index.
+func (b *Buffer) run() {
+	for {
+		select {
+		case rec := <-b.records:
+			b.append(rec)
+		case <-b.done:
+			return
+		}
+	}
+}
+
+func (b *Buffer) append(rec []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	line := append(rec, '\n')
+	if _, err := b.current.Write(line); err != nil {
+		// Nothing useful to do with a write error on the ring buffer
+		// itself; drop the record rather than risk blocking or crashing
+		// logging for the rest of the process.
+		atomic.AddInt64(&b.dropped, 1)
+		return
+	}
+
+	last := &b.chunks[len(b.chunks)-1]
+	last.EndOffset += int64(len(line))
+
+	if last.EndOffset-last.StartOffset >= b.cfg.ChunkBytes {
+		b.rotateLocked()
+	}
+
+	b.evictLocked()
+	b.saveIndexLocked()
+	b.broadcast()
+}
+
+// rotateLocked seals the current chunk (compress, write-then-rename) and
+// opens a fresh one. Must be called with b.mu held.
+func (b *Buffer) rotateLocked() {
+	idx := len(b.chunks) - 1
+	sealed := b.chunks[idx]
+
+	if err := b.current.Close(); err != nil {
+		return
+	}
+
+	openPath := filepath.Join(b.cfg.Dir, sealed.fileName())
+	sealed.Sealed = true
+	sealedPath := filepath.Join(b.cfg.Dir, sealed.fileName())
+	tmpPath := sealedPath + ".tmp"
+
+	if err := compressFile(openPath, tmpPath); err != nil {
+		// Compression failed: keep serving the uncompressed chunk rather
+		// than losing it, and don't mark it sealed.
+		sealed.Sealed = false
+		b.chunks[idx] = sealed
+		b.reopenLocked(openPath)
+		return
+	}
+
+	if err := os.Rename(tmpPath, sealedPath); err != nil {
+		os.Remove(tmpPath)
+		b.reopenLocked(openPath)
+		return
+	}
+	os.Remove(openPath)
+
+	b.chunks[idx] = sealed
+
+	_ = b.startChunk(sealed.Seq+1, sealed.EndOffset)
+}
+
+// reopenLocked reopens an existing chunk file for append after a failed
+// rotation attempt, so the writer goroutine doesn't keep trying to write
+// through a closed file handle until the process restarts.
+func (b *Buffer) reopenLocked(path string) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err == nil {
+		b.current = f
+	}
+}
+
+// evictLocked drops the oldest sealed chunks once the buffer's total size
+// exceeds cfg.TotalBytes, always leaving at least the current open chunk.
+func (b *Buffer) evictLocked() {
+	total := int64(0)
+	for _, c := range b.chunks {
+		total += c.EndOffset - c.StartOffset
+	}
+
+	i := 0
+	for total > b.cfg.TotalBytes && i < len(b.chunks)-1 {
+		victim := b.chunks[i]
+		if !victim.Sealed {
+			break
+		}
+		os.Remove(filepath.Join(b.cfg.Dir, victim.fileName()))
+		total -= victim.EndOffset - victim.StartOffset
+		i++
+	}
+
+	if i > 0 {
+		b.chunks = append([]chunkMeta{}, b.chunks[i:]...)
+	}
+}
+
+// saveIndexLocked atomically persists the chunk manifest and drop counter.
+func (b *Buffer) saveIndexLocked() {
+	idx := indexFile{Chunks: b.chunks, Dropped: atomic.LoadInt64(&b.dropped)}
+
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return
+	}
+
+	idxPath := filepath.Join(b.cfg.Dir, indexFileName)
+	tmpPath := idxPath + ".tmp"
+
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return
+	}
+	os.Rename(tmpPath, idxPath)
+}
+
+// broadcast wakes any reader blocked in WaitForData.
+func (b *Buffer) broadcast() {
+	b.notifyMu.Lock()
+	close(b.notifyCh)
+	b.notifyCh = make(chan struct{})
+	b.notifyMu.Unlock()
+}
+
+// waitChan returns the channel that closes the next time new data is
+// committed, for use by long-polling readers.
+func (b *Buffer) waitChan() chan struct{} {
+	b.notifyMu.Lock()
+	defer b.notifyMu.Unlock()
+	return b.notifyCh
+}
+
+// Stats reports buffer health for /metrics and /debug/logs.
+type Stats struct {
+	Chunks       int   `json:"chunks"`
+	Dropped      int64 `json:"dropped"`
+	HeadOffset   int64 `json:"head_offset"`
+	TailOffset   int64 `json:"tail_offset"`
+	TotalBytes   int64 `json:"total_bytes"`
+	ConfiguredAt int64 `json:"configured_max_bytes"`
+}
+
+// Stats returns a point-in-time snapshot of the buffer's state.
+func (b *Buffer) Stats() Stats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s := Stats{Chunks: len(b.chunks), Dropped: atomic.LoadInt64(&b.dropped), ConfiguredAt: b.cfg.TotalBytes}
+	if len(b.chunks) > 0 {
+		s.HeadOffset = b.chunks[0].StartOffset
+		s.TailOffset = b.chunks[len(b.chunks)-1].EndOffset
+	}
+	for _, c := range b.chunks {
+		s.TotalBytes += c.EndOffset - c.StartOffset
+	}
+
+	return s
+}
+
+// Close stops the background writer. It does not flush or close the
+// current chunk file synchronously with in-flight appends; callers should
+// stop sending logs through the core before closing.
+func (b *Buffer) Close() error {
+	close(b.done)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.current != nil {
+		return b.current.Close()
+	}
+	return nil
+}
+
+func compressFile(srcPath, dstPath string) error {
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.Create(dstPath)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	enc, err := zstd.NewWriter(dst)
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, 64*1024)
+	for {
+		n, rerr := src.Read(buf)
+		if n > 0 {
+			if _, werr := enc.Write(buf[:n]); werr != nil {
+				enc.Close()
+				return werr
+			}
+		}
+		if rerr != nil {
+			break
+		}
+	}
+
+	return enc.Close()
+}
@@ -0,0 +1,61 @@
+package logbuffer
+
+import "go.uber.org/zap/zapcore"
+
+// ringCore is a zapcore.Core that encodes every log entry it sees and
+// offers it to a Buffer. It never blocks zap's caller: Buffer.Offer is
+// itself non-blocking and drops records under backpressure.
+type ringCore struct {
+	enc   zapcore.Encoder
+	level zapcore.LevelEnabler
+	buf   *Buffer
+}
+
+// NewCore wraps buf as a zapcore.Core using enc to render each entry,
+// gated by level. Combine it with zapcore.NewTee alongside the file and
+// console cores built in internal/logger.New.
+func NewCore(buf *Buffer, enc zapcore.Encoder, level zapcore.LevelEnabler) zapcore.Core {
+	return &ringCore{enc: enc, level: level, buf: buf}
+}
+
+func (c *ringCore) Enabled(lvl zapcore.Level) bool {
+	return c.level.Enabled(lvl)
+}
+
+func (c *ringCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := c.enc.Clone()
+	for _, f := range fields {
+		f.AddTo(clone)
+	}
+	return &ringCore{enc: clone, level: c.level, buf: c.buf}
+}
+
+func (c *ringCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *ringCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buffer, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	defer buffer.Free()
+
+	// EncodeEntry already appends zapcore.DefaultLineEnding; Offer/append
+	// store records without their trailing newline and add one back on
+	// write, so trim it here to avoid doubling up.
+	line := buffer.Bytes()
+	if n := len(line); n > 0 && line[n-1] == '\n' {
+		line = line[:n-1]
+	}
+
+	c.buf.Offer(line)
+	return nil
+}
+
+func (c *ringCore) Sync() error {
+	return nil
+}
@@ -0,0 +1,167 @@
+package logbuffer
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Record is a single NDJSON log line together with the byte offset it
+// starts at in the buffer's logical, ever-increasing stream.
+type Record struct {
+	Offset int64  `json:"offset"`
+	Line   []byte `json:"-"`
+}
+
+// Read returns every record at or after since, plus the offset the next
+// call should pass as since to continue where this one left off. A chunk
+// that has been partially overwritten or truncated out from under the
+// reader (e.g. concurrently rotated) is skipped rather than treated as an
+// error - callers get the best-effort tail of what's still available.
+func (b *Buffer) Read(since int64) ([]Record, int64, error) {
+	b.mu.Lock()
+	chunks := append([]chunkMeta{}, b.chunks...)
+	b.mu.Unlock()
+
+	var records []Record
+	next := since
+
+	for _, c := range chunks {
+		if c.EndOffset <= since {
+			continue
+		}
+
+		lines, err := readChunk(b.cfg.Dir, c)
+		if err != nil {
+			// Tolerate a chunk disappearing or failing to decompress mid-
+			// read (e.g. it was evicted or is still being sealed); skip it
+			// and keep going rather than failing the whole read.
+			continue
+		}
+
+		offset := c.StartOffset
+		for _, line := range lines {
+			lineEnd := offset + int64(len(line)) + 1
+			if lineEnd > since {
+				records = append(records, Record{Offset: offset, Line: line})
+			}
+			offset = lineEnd
+		}
+
+		if offset > next {
+			next = offset
+		}
+	}
+
+	return records, next, nil
+}
+
+// readChunk loads and splits one chunk's content into lines, decompressing
+// sealed chunks on the fly.
+func readChunk(dir string, c chunkMeta) ([][]byte, error) {
+	path := filepath.Join(dir, c.fileName())
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if c.Sealed {
+		zr, err := zstd.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		r = zr
+	}
+
+	var lines [][]byte
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := make([]byte, len(scanner.Bytes()))
+		copy(line, scanner.Bytes())
+		lines = append(lines, line)
+	}
+	// A scanner error here typically means the chunk was truncated
+	// mid-record (e.g. read while being rotated); return whatever complete
+	// lines were already read instead of failing the whole chunk.
+
+	return lines, nil
+}
+
+// WaitForData blocks until new records are committed after since or ctx is
+// done, whichever comes first; it has no timeout of its own. It's the
+// building block behind GET /debug/logs?follow=1 long-polling.
+func (b *Buffer) WaitForData(ctx context.Context, since int64) {
+	b.mu.Lock()
+	tail := int64(0)
+	if len(b.chunks) > 0 {
+		tail = b.chunks[len(b.chunks)-1].EndOffset
+	}
+	b.mu.Unlock()
+
+	if tail > since {
+		return
+	}
+
+	ch := b.waitChan()
+	select {
+	case <-ch:
+	case <-ctx.Done():
+	}
+}
+
+// Download writes the full, concatenated buffer contents to w as a single
+// zstd stream - sealed chunks are decompressed and re-encoded into it along
+// with the current open chunk.
+func (b *Buffer) Download(w io.Writer) error {
+	b.mu.Lock()
+	chunks := append([]chunkMeta{}, b.chunks...)
+	b.mu.Unlock()
+
+	enc, err := zstd.NewWriter(w)
+	if err != nil {
+		return err
+	}
+	defer enc.Close()
+
+	for _, c := range chunks {
+		if err := copyChunk(enc, b.cfg.Dir, c); err != nil {
+			// Skip a chunk that vanished or failed to read rather than
+			// aborting the whole download.
+			continue
+		}
+	}
+
+	return nil
+}
+
+func copyChunk(dst io.Writer, dir string, c chunkMeta) error {
+	path := filepath.Join(dir, c.fileName())
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if c.Sealed {
+		zr, err := zstd.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		_, err = io.Copy(dst, zr)
+		return err
+	}
+
+	_, err = io.Copy(dst, f)
+	return err
+}
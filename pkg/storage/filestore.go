@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// ObjectInfo describes a stored object's metadata.
+type ObjectInfo struct {
+	Key          string
+	SizeBytes    int64
+	LastModified time.Time
+}
+
+// FileStore is the storage backend abstraction workers and handlers depend
+// on, so the backend (S3/MinIO vs local disk) can be swapped via
+// STORAGE_BACKEND without touching call sites. S3Storage and LocalStorage
+// both implement it.
+type FileStore interface {
+	// Put uploads from a reader and returns the base64-encoded SHA-256
+	// checksum of the stored object.
+	Put(ctx context.Context, key string, r io.Reader, size int64) (checksumSHA256 string, err error)
+	// PutFile uploads a file from disk and returns its checksum.
+	PutFile(ctx context.Context, key, filePath string) (checksumSHA256 string, err error)
+	// Get opens the object for reading.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// GetRange opens a byte range [off, off+n) of the object, for honoring
+	// client Range requests (resumable downloads, mobile players).
+	GetRange(ctx context.Context, key string, off, n int64) (io.ReadCloser, error)
+	// PresignGet returns a time-limited URL (or, for backends with no native
+	// presigning, a token-gated path) that grants GET access to key.
+	PresignGet(ctx context.Context, key string, ttl time.Duration) (string, error)
+	Delete(ctx context.Context, key string) error
+	Stat(ctx context.Context, key string) (ObjectInfo, error)
+	// ListByPrefix lists every object whose key starts with prefix, for
+	// sweeps like cleanup.S3Cleaner that need to find finished-job output
+	// without knowing exact keys up front.
+	ListByPrefix(ctx context.Context, prefix string) ([]ObjectInfo, error)
+	// HasInternalEndpoint reports whether this backend is reachable by
+	// clients directly (public S3) or must be proxied through this process
+	// (MinIO/R2 behind a private endpoint, or local disk).
+	HasInternalEndpoint() bool
+}
+
+// TokenIssuer is implemented by FileStore backends that can mint and verify
+// signed, expiring download tokens (see TokenSigner). Verification does not
+// track redemptions, so it does not make a token single-use by itself.
+type TokenIssuer interface {
+	IssueDownloadToken(key string, ttl time.Duration, clientIP string) (string, error)
+	VerifyDownloadToken(token, clientIP string) (string, error)
+}
+
+// SignedFileStore is a FileStore whose objects can be served through the
+// HMAC token layer behind /proxy/d/:token.
+type SignedFileStore interface {
+	FileStore
+	TokenIssuer
+}
@@ -7,7 +7,25 @@ import (
 
 // Storage is the interface for file storage operations
 type Storage interface {
-	Upload(ctx context.Context, filePath, key string) error
-	UploadStream(ctx context.Context, reader io.Reader, key string) error
+	// Upload uploads a file and returns the base64-encoded SHA-256 checksum
+	// of the uploaded object for integrity verification. When S3Storage
+	// uploads a large file in parts, this is S3's composite checksum (a
+	// digest of the per-part digests), not a SHA-256 of the whole object.
+	Upload(ctx context.Context, filePath, key string) (checksumSHA256 string, err error)
+	// UploadStream uploads from a reader and returns the base64-encoded
+	// SHA-256 checksum of the uploaded object.
+	UploadStream(ctx context.Context, reader io.Reader, key string) (checksumSHA256 string, err error)
+	// UploadMultipart drives a true streaming multipart upload from reader,
+	// splitting it into partSize-sized chunks as they're read rather than
+	// requiring the full object up front, so a caller can pipe an ffmpeg
+	// output (or anything else non-seekable) straight through without ever
+	// landing it on local disk. partSize <= 0 uses the backend's own
+	// default. Returns a base64-encoded checksum: a SHA-256 of the object
+	// for local disk, S3's composite checksum of the per-part digests for
+	// S3Storage.
+	UploadMultipart(ctx context.Context, key string, partSize int64, reader io.Reader) (checksumSHA256 string, err error)
 	GetPresignedURL(ctx context.Context, key string) (string, error)
+	// PutPresignedURL generates a presigned URL for uploading, carrying any
+	// SSE-C headers the caller must replay on the PUT request.
+	PutPresignedURL(ctx context.Context, key string) (string, error)
 }
@@ -2,10 +2,13 @@ package storage
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"go.uber.org/zap"
@@ -13,95 +16,213 @@ import (
 
 // LocalStorage handles file uploads to local filesystem
 type LocalStorage struct {
-	basePath string
-	logger   *zap.Logger
+	basePath    string
+	tokenSigner *TokenSigner
+	logger      *zap.Logger
 }
 
-// NewLocalStorage creates a new local storage handler
-func NewLocalStorage(basePath string, logger *zap.Logger) (*LocalStorage, error) {
+// NewLocalStorage creates a new local storage handler. tokenSigningKey backs
+// the signed download tokens minted by PresignGet/IssueDownloadToken.
+func NewLocalStorage(basePath, tokenSigningKey string, logger *zap.Logger) (*LocalStorage, error) {
 	// Create base directory if it doesn't exist
 	if err := os.MkdirAll(basePath, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create storage directory: %w", err)
 	}
 
 	return &LocalStorage{
-		basePath: basePath,
-		logger:   logger,
+		basePath:    basePath,
+		tokenSigner: NewTokenSigner(tokenSigningKey),
+		logger:      logger,
 	}, nil
 }
 
-// Upload uploads a file to local storage
-func (ls *LocalStorage) Upload(ctx context.Context, filePath, key string) error {
+// IssueDownloadToken mints a signed download token for key (see TokenSigner).
+func (ls *LocalStorage) IssueDownloadToken(key string, ttl time.Duration, clientIP string) (string, error) {
+	return ls.tokenSigner.IssueDownloadToken(key, ttl, clientIP)
+}
+
+// VerifyDownloadToken validates a signed download token (see TokenSigner).
+func (ls *LocalStorage) VerifyDownloadToken(token, clientIP string) (string, error) {
+	return ls.tokenSigner.VerifyDownloadToken(token, clientIP)
+}
+
+// localPath resolves key to its on-disk path under basePath. Exported to
+// the rest of the package (not outside it) so TieredStorage can mirror
+// files in and out of the local tier without duplicating this join.
+func (ls *LocalStorage) localPath(key string) string {
+	return filepath.Join(ls.basePath, key)
+}
+
+// Upload uploads a file to local storage and returns its SHA-256 checksum
+func (ls *LocalStorage) Upload(ctx context.Context, filePath, key string) (string, error) {
 	// Create subdirectories if needed
 	fullPath := filepath.Join(ls.basePath, key)
 	dir := filepath.Dir(fullPath)
 
 	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("failed to create directory: %w", err)
+		return "", fmt.Errorf("failed to create directory: %w", err)
 	}
 
 	// Copy file
 	src, err := os.Open(filePath)
 	if err != nil {
-		return fmt.Errorf("failed to open source file: %w", err)
+		return "", fmt.Errorf("failed to open source file: %w", err)
 	}
 	defer src.Close()
 
 	dst, err := os.Create(fullPath)
 	if err != nil {
-		return fmt.Errorf("failed to create destination file: %w", err)
+		return "", fmt.Errorf("failed to create destination file: %w", err)
 	}
 	defer dst.Close()
 
-	if _, err := io.Copy(dst, src); err != nil {
-		return fmt.Errorf("failed to copy file: %w", err)
+	hasher := sha256.New()
+	if _, err := io.Copy(dst, io.TeeReader(src, hasher)); err != nil {
+		return "", fmt.Errorf("failed to copy file: %w", err)
 	}
 
+	checksum := base64.StdEncoding.EncodeToString(hasher.Sum(nil))
+
 	ls.logger.Info("File uploaded to local storage",
 		zap.String("key", key),
 		zap.String("path", fullPath),
+		zap.String("checksum_sha256", checksum),
 	)
 
-	return nil
+	return checksum, nil
 }
 
-// UploadStream uploads from a reader to local storage
-func (ls *LocalStorage) UploadStream(ctx context.Context, reader io.Reader, key string) error {
+// UploadStream uploads from a reader to local storage and returns its SHA-256 checksum
+func (ls *LocalStorage) UploadStream(ctx context.Context, reader io.Reader, key string) (string, error) {
 	fullPath := filepath.Join(ls.basePath, key)
 	dir := filepath.Dir(fullPath)
 
 	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("failed to create directory: %w", err)
+		return "", fmt.Errorf("failed to create directory: %w", err)
 	}
 
 	file, err := os.Create(fullPath)
 	if err != nil {
-		return fmt.Errorf("failed to create file: %w", err)
+		return "", fmt.Errorf("failed to create file: %w", err)
 	}
 	defer file.Close()
 
-	if _, err := io.Copy(file, reader); err != nil {
-		return fmt.Errorf("failed to write file: %w", err)
+	hasher := sha256.New()
+	if _, err := io.Copy(file, io.TeeReader(reader, hasher)); err != nil {
+		return "", fmt.Errorf("failed to write file: %w", err)
 	}
 
-	ls.logger.Info("Stream uploaded to local storage", zap.String("key", key))
-	return nil
+	checksum := base64.StdEncoding.EncodeToString(hasher.Sum(nil))
+
+	ls.logger.Info("Stream uploaded to local storage",
+		zap.String("key", key),
+		zap.String("checksum_sha256", checksum),
+	)
+	return checksum, nil
+}
+
+// UploadMultipart implements Storage. Local disk has no multipart concept to
+// speak of - there's nothing to abort or parallelize - so this just streams
+// reader straight to disk, ignoring partSize.
+func (ls *LocalStorage) UploadMultipart(ctx context.Context, key string, partSize int64, reader io.Reader) (string, error) {
+	return ls.UploadStream(ctx, reader, key)
 }
 
 // GetPresignedURL returns a URL to download the file
-// For local storage, this is a relative path
+// For local storage, this is a relative path. HLS playlists are served
+// under /hls/ instead of /downloads/ so the API can set player-friendly
+// content types and attachment-free responses (see cmd/api's /hls/* route);
+// relative variant-playlist/segment references in the playlist then resolve
+// against that same prefix.
 func (ls *LocalStorage) GetPresignedURL(ctx context.Context, key string) (string, error) {
+	if strings.HasSuffix(key, ".m3u8") {
+		return "/hls/" + key, nil
+	}
 	// Return URL path relative to downloads folder
 	// Frontend will need to access it as /downloads/{key}
 	return "/downloads/" + key, nil
 }
 
+// PutPresignedURL returns a URL for uploading a file
+// Local storage has no credential boundary to cross, so this mirrors the
+// download path; there are no SSE-C headers to carry.
+func (ls *LocalStorage) PutPresignedURL(ctx context.Context, key string) (string, error) {
+	return "/downloads/" + key, nil
+}
+
 // GetFile returns file content for direct download
 func (ls *LocalStorage) GetFile(ctx context.Context, key string) (*os.File, error) {
 	fullPath := filepath.Join(ls.basePath, key)
 	return os.Open(fullPath)
 }
 
+// Put implements FileStore by streaming a reader to disk.
+func (ls *LocalStorage) Put(ctx context.Context, key string, r io.Reader, size int64) (string, error) {
+	return ls.UploadStream(ctx, r, key)
+}
+
+// PutFile implements FileStore by copying a file from disk.
+func (ls *LocalStorage) PutFile(ctx context.Context, key, filePath string) (string, error) {
+	return ls.Upload(ctx, filePath, key)
+}
+
+// Get implements FileStore by opening the object for streaming reads.
+func (ls *LocalStorage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	return ls.GetFile(ctx, key)
+}
+
+// GetRange implements FileStore by seeking into the object on disk. Local
+// storage has no read cache of its own - the object is already local.
+func (ls *LocalStorage) GetRange(ctx context.Context, key string, off, n int64) (io.ReadCloser, error) {
+	file, err := ls.GetFile(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := file.Seek(off, io.SeekStart); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to seek to offset %d: %w", off, err)
+	}
+	return &limitedFile{file: file, r: io.LimitReader(file, n)}, nil
+}
+
+// limitedFile bounds reads to a byte range while still closing the
+// underlying *os.File.
+type limitedFile struct {
+	file *os.File
+	r    io.Reader
+}
+
+func (l *limitedFile) Read(p []byte) (int, error) { return l.r.Read(p) }
+func (l *limitedFile) Close() error               { return l.file.Close() }
+
+// PresignGet implements FileStore. Local storage has no native presigning,
+// so this returns a /proxy/d/:token path carrying a signed download token
+// valid for ttl. TieredStorage.PresignGet uses it for objects in its local
+// tier.
+func (ls *LocalStorage) PresignGet(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	token, err := ls.tokenSigner.IssueDownloadToken(key, ttl, "")
+	if err != nil {
+		return "", err
+	}
+	return "/proxy/d/" + token, nil
+}
+
+// Stat implements FileStore via os.Stat.
+func (ls *LocalStorage) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+	fullPath := filepath.Join(ls.basePath, key)
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	return ObjectInfo{Key: key, SizeBytes: info.Size(), LastModified: info.ModTime()}, nil
+}
+
+// HasInternalEndpoint always returns true for local disk: there is no public
+// URL to redirect to, so callers must stream the object through this process.
+func (ls *LocalStorage) HasInternalEndpoint() bool {
+	return true
+}
+
 // Delete removes a file from local storage
 func (ls *LocalStorage) Delete(ctx context.Context, key string) error {
 	fullPath := filepath.Join(ls.basePath, key)
@@ -111,6 +232,42 @@ func (ls *LocalStorage) Delete(ctx context.Context, key string) error {
 	return nil
 }
 
+// ListByPrefix lists every file under basePath whose key (its path relative
+// to basePath, with '/' separators) starts with prefix.
+func (ls *LocalStorage) ListByPrefix(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	root := filepath.Join(ls.basePath, prefix)
+
+	var objects []ObjectInfo
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil // prefix doesn't exist (yet) - no matches
+			}
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(ls.basePath, path)
+		if err != nil {
+			return err
+		}
+
+		objects = append(objects, ObjectInfo{
+			Key:          filepath.ToSlash(rel),
+			SizeBytes:    info.Size(),
+			LastModified: info.ModTime(),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files under %s: %w", prefix, err)
+	}
+
+	return objects, nil
+}
+
 // Cleanup removes old files (older than maxAge)
 func (ls *LocalStorage) Cleanup(ctx context.Context, maxAge time.Duration) error {
 	return filepath.Walk(ls.basePath, func(path string, info os.FileInfo, err error) error {
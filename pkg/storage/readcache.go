@@ -0,0 +1,211 @@
+package storage
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ReadCache is an LRU-by-size on-disk cache for ranged object reads. It
+// memoizes reads keyed by (etag, offset, length) so repeat downloads of the
+// same hot byte range don't re-fetch from S3.
+type ReadCache struct {
+	dir      string
+	maxBytes int64
+	logger   *zap.Logger
+
+	mu      sync.Mutex
+	entries map[string]*list.Element // key -> element in order, MRU at front
+	order   *list.List
+	size    int64
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+type cacheEntry struct {
+	key   string
+	bytes int64
+}
+
+// NewReadCache creates an on-disk read cache rooted at dir, evicting
+// least-recently-used entries once total size would exceed maxBytes.
+func NewReadCache(dir string, maxBytes int64, logger *zap.Logger) (*ReadCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create read cache directory: %w", err)
+	}
+
+	return &ReadCache{
+		dir:      dir,
+		maxBytes: maxBytes,
+		logger:   logger,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+	}, nil
+}
+
+// RangeKey derives a cache key from an object's identity and byte range.
+func RangeKey(etag string, offset, length int64) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s:%d:%d", etag, offset, length)))
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *ReadCache) path(key string) string {
+	return filepath.Join(c.dir, key)
+}
+
+// Get returns a reader for key if cached, bumping it to most-recently-used.
+func (c *ReadCache) Get(key string) (io.ReadCloser, bool) {
+	c.mu.Lock()
+	elem, ok := c.entries[key]
+	if ok {
+		c.order.MoveToFront(elem)
+	}
+	c.mu.Unlock()
+
+	if !ok {
+		c.misses.Add(1)
+		return nil, false
+	}
+
+	file, err := os.Open(c.path(key))
+	if err != nil {
+		c.misses.Add(1)
+		return nil, false
+	}
+
+	c.hits.Add(1)
+	return file, true
+}
+
+// wrap tees body into the cache as the caller reads it, committing the
+// entry only once body is read through to EOF - a caller that disconnects
+// partway through must not poison the cache with a truncated range.
+func (c *ReadCache) wrap(body io.ReadCloser, key string) io.ReadCloser {
+	tmpPath := fmt.Sprintf("%s.tmp-%d", c.path(key), time.Now().UnixNano())
+	tmpFile, err := os.Create(tmpPath)
+	if err != nil {
+		c.logger.Warn("Failed to stage read cache entry, serving uncached", zap.Error(err))
+		return body
+	}
+
+	return &cachingReadCloser{body: body, tmpFile: tmpFile, tmpPath: tmpPath, cache: c, key: key}
+}
+
+// commit finalizes a staged cache entry and runs LRU eviction.
+func (c *ReadCache) commit(key, tmpPath string, written int64) {
+	if err := os.Rename(tmpPath, c.path(key)); err != nil {
+		os.Remove(tmpPath)
+		c.logger.Warn("Failed to finalize read cache entry", zap.Error(err))
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		c.order.MoveToFront(elem)
+		c.size += written - elem.Value.(*cacheEntry).bytes
+		elem.Value.(*cacheEntry).bytes = written
+	} else {
+		elem := c.order.PushFront(&cacheEntry{key: key, bytes: written})
+		c.entries[key] = elem
+		c.size += written
+	}
+
+	c.evictLocked()
+}
+
+// evictLocked removes least-recently-used entries until size fits within
+// maxBytes. Caller must hold c.mu.
+func (c *ReadCache) evictLocked() {
+	for c.size > c.maxBytes {
+		back := c.order.Back()
+		if back == nil {
+			return
+		}
+		entry := back.Value.(*cacheEntry)
+		c.order.Remove(back)
+		delete(c.entries, entry.key)
+		c.size -= entry.bytes
+
+		if err := os.Remove(c.path(entry.key)); err != nil && !os.IsNotExist(err) {
+			c.logger.Warn("Failed to evict read cache entry", zap.String("key", entry.key), zap.Error(err))
+		}
+	}
+}
+
+// Stats reports cache effectiveness, surfaced on the /metrics endpoint.
+func (c *ReadCache) Stats() map[string]interface{} {
+	hits := c.hits.Load()
+	misses := c.misses.Load()
+	total := hits + misses
+
+	hitRate := float64(0)
+	if total > 0 {
+		hitRate = float64(hits) / float64(total) * 100
+	}
+
+	c.mu.Lock()
+	size := c.size
+	c.mu.Unlock()
+
+	return map[string]interface{}{
+		"hits":       hits,
+		"misses":     misses,
+		"hit_rate":   hitRate,
+		"bytes_used": size,
+		"max_bytes":  c.maxBytes,
+	}
+}
+
+// cachingReadCloser tees a GetRange response body into a temp file as the
+// caller consumes it, committing to the cache on Close only if the body was
+// read through to EOF.
+type cachingReadCloser struct {
+	body    io.ReadCloser
+	tmpFile *os.File
+	tmpPath string
+	cache   *ReadCache
+	key     string
+	written int64
+	failed  bool
+	eof     bool
+}
+
+func (c *cachingReadCloser) Read(p []byte) (int, error) {
+	n, err := c.body.Read(p)
+	if n > 0 {
+		if _, werr := c.tmpFile.Write(p[:n]); werr != nil {
+			c.failed = true
+		} else {
+			c.written += int64(n)
+		}
+	}
+	if err == io.EOF {
+		c.eof = true
+	}
+	return n, err
+}
+
+func (c *cachingReadCloser) Close() error {
+	err := c.body.Close()
+	c.tmpFile.Close()
+
+	if c.failed || !c.eof {
+		os.Remove(c.tmpPath)
+		return err
+	}
+
+	c.cache.commit(c.key, c.tmpPath, c.written)
+	return err
+}
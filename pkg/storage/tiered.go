@@ -0,0 +1,468 @@
+package storage
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// TieredConfig controls TieredStorage's local cache tier.
+type TieredConfig struct {
+	// MaxCacheBytes bounds the total size of objects kept in the local
+	// cache; the least-recently-used entry is evicted once exceeded.
+	MaxCacheBytes int64
+	// MirrorWorkers sizes the background worker pool that mirrors uploads
+	// to the S3 origin and warms the local cache on a read miss.
+	MirrorWorkers int
+	// MirrorQueueSize bounds how many pending mirror/warm jobs may queue
+	// before new ones are dropped (mirroring/warming is best-effort; the
+	// object is never lost, just briefly single-tiered).
+	MirrorQueueSize int
+}
+
+func (c *TieredConfig) setDefaults() {
+	if c.MaxCacheBytes <= 0 {
+		c.MaxCacheBytes = 5 * 1024 * 1024 * 1024 // 5 GiB
+	}
+	if c.MirrorWorkers <= 0 {
+		c.MirrorWorkers = 4
+	}
+	if c.MirrorQueueSize <= 0 {
+		c.MirrorQueueSize = 256
+	}
+}
+
+// TieredStorage fronts a fast LocalStorage cache in front of an S3Storage
+// origin. Uploads land on local disk first and are mirrored to S3 in the
+// background; reads prefer the local copy when present, falling back to
+// S3 on a miss and populating the cache as they go. S3 remains the durable
+// source of truth - the local tier is a bounded, LRU-evicted cache in
+// front of it, giving operators cheap egress and fast repeat downloads.
+type TieredStorage struct {
+	local  *LocalStorage
+	origin *S3Storage
+	logger *zap.Logger
+	cfg    TieredConfig
+
+	jobs chan tieredJob
+	done chan struct{}
+
+	mu        sync.Mutex
+	entries   map[string]*list.Element // key -> element, MRU at front
+	order     *list.List
+	cacheSize int64
+}
+
+type tieredCacheEntry struct {
+	key   string
+	bytes int64
+}
+
+type tieredJobKind int
+
+const (
+	jobMirror tieredJobKind = iota // push a freshly-written local object up to S3
+	jobWarm                        // pull a cold object down from S3 into the local cache
+)
+
+type tieredJob struct {
+	kind tieredJobKind
+	key  string
+}
+
+// NewTieredStorage wraps local in front of origin and starts the
+// background mirror/warm worker pool.
+func NewTieredStorage(local *LocalStorage, origin *S3Storage, logger *zap.Logger, cfg TieredConfig) *TieredStorage {
+	cfg.setDefaults()
+
+	t := &TieredStorage{
+		local:   local,
+		origin:  origin,
+		logger:  logger,
+		cfg:     cfg,
+		jobs:    make(chan tieredJob, cfg.MirrorQueueSize),
+		done:    make(chan struct{}),
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+
+	for i := 0; i < cfg.MirrorWorkers; i++ {
+		go t.worker()
+	}
+
+	return t
+}
+
+func (t *TieredStorage) worker() {
+	for {
+		select {
+		case job := <-t.jobs:
+			switch job.kind {
+			case jobMirror:
+				t.mirrorOne(job.key)
+			case jobWarm:
+				t.warmOne(job.key)
+			}
+		case <-t.done:
+			return
+		}
+	}
+}
+
+// enqueue is a non-blocking best-effort submit; a full queue just means the
+// object stays single-tiered until the next write/read gives it another
+// chance, so we drop rather than block the caller.
+func (t *TieredStorage) enqueue(job tieredJob) {
+	select {
+	case t.jobs <- job:
+	default:
+		t.logger.Warn("Tiered storage worker queue full, skipping job",
+			zap.String("key", job.key), zap.Int("kind", int(job.kind)))
+	}
+}
+
+func (t *TieredStorage) mirrorOne(key string) {
+	if _, err := t.origin.PutFile(context.Background(), key, t.local.localPath(key)); err != nil {
+		t.logger.Error("Failed to mirror object to S3 origin", zap.String("key", key), zap.Error(err))
+		return
+	}
+	t.logger.Debug("Mirrored object to S3 origin", zap.String("key", key))
+}
+
+func (t *TieredStorage) warmOne(key string) {
+	if _, err := t.local.Stat(context.Background(), key); err == nil {
+		return // already warmed, e.g. by a concurrent reader
+	}
+
+	rc, err := t.origin.Get(context.Background(), key)
+	if err != nil {
+		t.logger.Warn("Failed to warm local cache from origin", zap.String("key", key), zap.Error(err))
+		return
+	}
+	defer rc.Close()
+
+	if _, err := t.local.Put(context.Background(), key, rc, -1); err != nil {
+		t.logger.Warn("Failed to write warmed object to local cache", zap.String("key", key), zap.Error(err))
+		return
+	}
+
+	if info, err := t.local.Stat(context.Background(), key); err == nil {
+		t.trackLocal(key, info.SizeBytes)
+	}
+}
+
+// trackLocal records (or bumps) a key's presence in the local cache and
+// runs LRU eviction if it pushed the cache over its byte budget.
+func (t *TieredStorage) trackLocal(key string, size int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if elem, ok := t.entries[key]; ok {
+		t.order.MoveToFront(elem)
+		entry := elem.Value.(*tieredCacheEntry)
+		t.cacheSize += size - entry.bytes
+		entry.bytes = size
+	} else {
+		elem := t.order.PushFront(&tieredCacheEntry{key: key, bytes: size})
+		t.entries[key] = elem
+		t.cacheSize += size
+	}
+
+	t.evictLocked()
+}
+
+func (t *TieredStorage) touchLocal(key string) {
+	t.mu.Lock()
+	if elem, ok := t.entries[key]; ok {
+		t.order.MoveToFront(elem)
+	}
+	t.mu.Unlock()
+}
+
+func (t *TieredStorage) untrack(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if elem, ok := t.entries[key]; ok {
+		entry := elem.Value.(*tieredCacheEntry)
+		t.order.Remove(elem)
+		delete(t.entries, key)
+		t.cacheSize -= entry.bytes
+	}
+}
+
+// evictLocked removes least-recently-used local cache entries until
+// cacheSize fits within cfg.MaxCacheBytes. Caller must hold t.mu. Eviction
+// only ever removes the local copy - the object remains durable in S3.
+func (t *TieredStorage) evictLocked() {
+	for t.cacheSize > t.cfg.MaxCacheBytes {
+		back := t.order.Back()
+		if back == nil {
+			return
+		}
+		entry := back.Value.(*tieredCacheEntry)
+		t.order.Remove(back)
+		delete(t.entries, entry.key)
+		t.cacheSize -= entry.bytes
+
+		if err := t.local.Delete(context.Background(), entry.key); err != nil {
+			t.logger.Warn("Failed to evict local cache entry", zap.String("key", entry.key), zap.Error(err))
+		}
+	}
+}
+
+// Put implements FileStore: writes land locally first, then are mirrored
+// to S3 in the background.
+func (t *TieredStorage) Put(ctx context.Context, key string, r io.Reader, size int64) (string, error) {
+	checksum, err := t.local.Put(ctx, key, r, size)
+	if err != nil {
+		return "", err
+	}
+	if info, statErr := t.local.Stat(ctx, key); statErr == nil {
+		t.trackLocal(key, info.SizeBytes)
+	}
+	t.enqueue(tieredJob{kind: jobMirror, key: key})
+	return checksum, nil
+}
+
+// PutFile implements FileStore, mirroring Put's local-first semantics.
+func (t *TieredStorage) PutFile(ctx context.Context, key, filePath string) (string, error) {
+	checksum, err := t.local.PutFile(ctx, key, filePath)
+	if err != nil {
+		return "", err
+	}
+	if info, statErr := t.local.Stat(ctx, key); statErr == nil {
+		t.trackLocal(key, info.SizeBytes)
+	}
+	t.enqueue(tieredJob{kind: jobMirror, key: key})
+	return checksum, nil
+}
+
+// Upload implements the legacy Storage interface in terms of Put.
+func (t *TieredStorage) Upload(ctx context.Context, filePath, key string) (string, error) {
+	return t.PutFile(ctx, key, filePath)
+}
+
+// UploadStream implements the legacy Storage interface in terms of Put.
+func (t *TieredStorage) UploadStream(ctx context.Context, reader io.Reader, key string) (string, error) {
+	return t.Put(ctx, key, reader, -1)
+}
+
+// UploadMultipart implements Storage by streaming straight to the S3 origin,
+// bypassing the local cache tier entirely - a streaming multipart upload's
+// whole point is that the object never lands on disk in full, so staging it
+// into the local cache first would defeat it.
+func (t *TieredStorage) UploadMultipart(ctx context.Context, key string, partSize int64, reader io.Reader) (string, error) {
+	return t.origin.UploadMultipart(ctx, key, partSize, reader)
+}
+
+// Get implements FileStore: serves from the local cache when hot, otherwise
+// streams from S3 while simultaneously staging a copy into the local cache
+// so the next read is served locally.
+func (t *TieredStorage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	if rc, err := t.local.Get(ctx, key); err == nil {
+		t.touchLocal(key)
+		return rc, nil
+	}
+
+	rc, err := t.origin.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.teeToCache(key, rc), nil
+}
+
+// teeToCache wraps an S3 response body so reading it to completion also
+// stages a copy into the local cache. Like pkg/storage's ReadCache, it
+// commits only on a clean EOF so a client that disconnects mid-download
+// can't poison the cache with a truncated object.
+func (t *TieredStorage) teeToCache(key string, body io.ReadCloser) io.ReadCloser {
+	fullPath := t.local.localPath(key)
+	tmpPath := fmt.Sprintf("%s.tmp-%d", fullPath, time.Now().UnixNano())
+
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return body
+	}
+
+	tmpFile, err := os.Create(tmpPath)
+	if err != nil {
+		t.logger.Warn("Failed to stage local cache entry, serving uncached", zap.String("key", key), zap.Error(err))
+		return body
+	}
+
+	return &tieredCachingReader{body: body, tmpFile: tmpFile, tmpPath: tmpPath, key: key, t: t}
+}
+
+type tieredCachingReader struct {
+	body    io.ReadCloser
+	tmpFile *os.File
+	tmpPath string
+	key     string
+	written int64
+	failed  bool
+	eof     bool
+	t       *TieredStorage
+}
+
+func (r *tieredCachingReader) Read(p []byte) (int, error) {
+	n, err := r.body.Read(p)
+	if n > 0 {
+		if _, werr := r.tmpFile.Write(p[:n]); werr != nil {
+			r.failed = true
+		} else {
+			r.written += int64(n)
+		}
+	}
+	if err == io.EOF {
+		r.eof = true
+	}
+	return n, err
+}
+
+func (r *tieredCachingReader) Close() error {
+	err := r.body.Close()
+	r.tmpFile.Close()
+
+	if r.failed || !r.eof {
+		os.Remove(r.tmpPath)
+		return err
+	}
+
+	if renameErr := os.Rename(r.tmpPath, r.t.local.localPath(r.key)); renameErr != nil {
+		os.Remove(r.tmpPath)
+		return err
+	}
+
+	r.t.trackLocal(r.key, r.written)
+	return err
+}
+
+// GetRange implements FileStore. Ranged reads are only served from the
+// local tier when the whole object is already cached there; a cold range
+// falls straight through to S3 without attempting to populate the cache,
+// since caching a partial object under the same key as the full one would
+// corrupt later whole-object reads.
+func (t *TieredStorage) GetRange(ctx context.Context, key string, off, n int64) (io.ReadCloser, error) {
+	if _, err := t.local.Stat(ctx, key); err == nil {
+		t.touchLocal(key)
+		return t.local.GetRange(ctx, key, off, n)
+	}
+	return t.origin.GetRange(ctx, key, off, n)
+}
+
+// PresignGet implements FileStore: returns a local, proxied URL when the
+// object is cached, otherwise a direct S3 presigned URL while kicking off
+// a background warm so the next request is served locally.
+func (t *TieredStorage) PresignGet(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	if _, err := t.local.Stat(ctx, key); err == nil {
+		t.touchLocal(key)
+		return t.local.PresignGet(ctx, key, ttl)
+	}
+
+	url, err := t.origin.PresignGet(ctx, key, ttl)
+	if err != nil {
+		return "", err
+	}
+
+	t.enqueue(tieredJob{kind: jobWarm, key: key})
+
+	return url, nil
+}
+
+// GetPresignedURL implements the legacy Storage interface in terms of
+// PresignGet, using each backend's own default TTL.
+func (t *TieredStorage) GetPresignedURL(ctx context.Context, key string) (string, error) {
+	if _, err := t.local.Stat(ctx, key); err == nil {
+		t.touchLocal(key)
+		return t.local.GetPresignedURL(ctx, key)
+	}
+
+	url, err := t.origin.GetPresignedURL(ctx, key)
+	if err != nil {
+		return "", err
+	}
+
+	t.enqueue(tieredJob{kind: jobWarm, key: key})
+
+	return url, nil
+}
+
+// PutPresignedURL implements the legacy Storage interface. Uploads always
+// land on the local tier first so they can be mirrored, so this always
+// returns a local upload URL. Note this means an out-of-band PUT against
+// the returned URL bypasses TieredStorage.Put/PutFile and so is never
+// mirrored automatically - callers that need the mirror guarantee should
+// upload through Put/PutFile instead of a presigned PUT URL.
+func (t *TieredStorage) PutPresignedURL(ctx context.Context, key string) (string, error) {
+	return t.local.PutPresignedURL(ctx, key)
+}
+
+// Stat implements FileStore, preferring the local copy when cached.
+func (t *TieredStorage) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+	if info, err := t.local.Stat(ctx, key); err == nil {
+		return info, nil
+	}
+	return t.origin.Stat(ctx, key)
+}
+
+// Delete implements FileStore: removes the object from S3 (the source of
+// truth) and best-effort evicts it from the local cache.
+func (t *TieredStorage) Delete(ctx context.Context, key string) error {
+	if err := t.origin.Delete(ctx, key); err != nil {
+		return err
+	}
+	_ = t.local.Delete(ctx, key)
+	t.untrack(key)
+	return nil
+}
+
+// HasInternalEndpoint always returns true: the local tier has no public
+// URL, so callers must always be prepared to stream through this process
+// (Get/GetRange already fall back to S3 transparently on a cache miss).
+func (t *TieredStorage) HasInternalEndpoint() bool {
+	return true
+}
+
+// ListByPrefix delegates to the origin (S3), which is the source of truth
+// for what objects exist - the local tier is only a partial cache of them.
+func (t *TieredStorage) ListByPrefix(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	return t.origin.ListByPrefix(ctx, prefix)
+}
+
+// IssueDownloadToken implements TokenIssuer via the local tier's signer,
+// regardless of whether the object is currently hot or cold.
+func (t *TieredStorage) IssueDownloadToken(key string, ttl time.Duration, clientIP string) (string, error) {
+	return t.local.IssueDownloadToken(key, ttl, clientIP)
+}
+
+// VerifyDownloadToken implements TokenIssuer via the local tier's signer.
+func (t *TieredStorage) VerifyDownloadToken(token, clientIP string) (string, error) {
+	return t.local.VerifyDownloadToken(token, clientIP)
+}
+
+// Cleanup prunes local cache entries older than maxAge. It never touches
+// S3 - the origin is the durable copy, so pruning the cache is always
+// safe and only affects how often subsequent reads fall back to S3.
+func (t *TieredStorage) Cleanup(ctx context.Context, maxAge time.Duration) error {
+	return t.local.Cleanup(ctx, maxAge)
+}
+
+// CacheStats reports the local tier's occupancy, surfaced on /metrics.
+func (t *TieredStorage) CacheStats() map[string]interface{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return map[string]interface{}{
+		"entries":    len(t.entries),
+		"bytes_used": t.cacheSize,
+		"max_bytes":  t.cfg.MaxCacheBytes,
+	}
+}
@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TokenSigner issues and verifies HMAC-signed, expiring, opaque download
+// tokens. It is shared by every FileStore backend so the /proxy/d/:token
+// redemption flow works the same way regardless of where the object lives.
+type TokenSigner struct {
+	signingKey []byte
+}
+
+// NewTokenSigner creates a TokenSigner from a raw signing key. An empty key
+// is accepted here; IssueDownloadToken/VerifyDownloadToken reject it at call
+// time so backends without a configured key fail loudly only when used.
+func NewTokenSigner(signingKey string) *TokenSigner {
+	return &TokenSigner{signingKey: []byte(signingKey)}
+}
+
+// IssueDownloadToken creates an HMAC-signed, expiring, opaque token that
+// grants access to a single object key without handing out a long-lived
+// presigned URL. When clientIP is non-empty, the token is also bound to
+// that IP and VerifyDownloadToken will reject redemption from anywhere
+// else.
+func (ts *TokenSigner) IssueDownloadToken(key string, ttl time.Duration, clientIP string) (string, error) {
+	if len(ts.signingKey) == 0 {
+		return "", fmt.Errorf("download token signing key is not configured")
+	}
+
+	expiry := time.Now().Add(ttl).Unix()
+	nonce := uuid.New().String()
+
+	payload := strings.Join([]string{key, strconv.FormatInt(expiry, 10), clientIP, nonce}, "|")
+	payloadEncoded := base64.RawURLEncoding.EncodeToString([]byte(payload))
+
+	return payloadEncoded + "." + ts.signToken(payloadEncoded), nil
+}
+
+// VerifyDownloadToken validates the HMAC signature, expiry, and (when the
+// token was bound to one) the requesting client's IP, returning the object
+// key the token grants access to.
+func (ts *TokenSigner) VerifyDownloadToken(token, clientIP string) (string, error) {
+	if len(ts.signingKey) == 0 {
+		return "", fmt.Errorf("download token signing key is not configured")
+	}
+
+	payloadEncoded, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", fmt.Errorf("malformed download token")
+	}
+
+	if !hmac.Equal([]byte(sig), []byte(ts.signToken(payloadEncoded))) {
+		return "", fmt.Errorf("invalid download token signature")
+	}
+
+	payloadBytes, err := base64.RawURLEncoding.DecodeString(payloadEncoded)
+	if err != nil {
+		return "", fmt.Errorf("malformed download token payload: %w", err)
+	}
+
+	fields := strings.SplitN(string(payloadBytes), "|", 4)
+	if len(fields) != 4 {
+		return "", fmt.Errorf("malformed download token payload")
+	}
+	key, expiryStr, boundIP := fields[0], fields[1], fields[2]
+
+	expiry, err := strconv.ParseInt(expiryStr, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("malformed download token expiry: %w", err)
+	}
+	if time.Now().Unix() > expiry {
+		return "", fmt.Errorf("download token has expired")
+	}
+
+	if boundIP != "" && boundIP != clientIP {
+		return "", fmt.Errorf("download token is not valid for this client")
+	}
+
+	return key, nil
+}
+
+// signToken computes the base64url-encoded HMAC-SHA256 over an encoded
+// token payload.
+func (ts *TokenSigner) signToken(payloadEncoded string) string {
+	mac := hmac.New(sha256.New, ts.signingKey)
+	mac.Write([]byte(payloadEncoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}